@@ -0,0 +1,81 @@
+package BatchJobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"whispering-tiger-ui/SendMessageChannel"
+)
+
+// TranscribeFileSegment is a timed segment of a transcribed audio file.
+type TranscribeFileSegment struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+// TranscribeFileResult is the answer of the backend to a transcribe_file request.
+type TranscribeFileResult struct {
+	Path     string                  `json:"path"`
+	Text     string                  `json:"text"`
+	Language string                  `json:"language"`
+	Segments []TranscribeFileSegment `json:"segments"`
+	Error    string                  `json:"error,omitempty"`
+}
+
+var (
+	pendingMutex          sync.Mutex
+	pendingTranscriptions = map[string]chan TranscribeFileResult{}
+)
+
+// HandleTranscribeFileResult passes a transcribe_file_result message to the waiting batch job.
+func HandleTranscribeFileResult(data []byte) error {
+	var result TranscribeFileResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return err
+	}
+	pendingMutex.Lock()
+	defer pendingMutex.Unlock()
+	resultChannel, ok := pendingTranscriptions[result.Path]
+	if !ok {
+		return errors.New("no batch job waiting for " + result.Path)
+	}
+	resultChannel <- result
+	delete(pendingTranscriptions, result.Path)
+	return nil
+}
+
+func transcribeFile(ctx context.Context, fileName, language string) (TranscribeFileResult, error) {
+	resultChannel := make(chan TranscribeFileResult, 1)
+	pendingMutex.Lock()
+	pendingTranscriptions[fileName] = resultChannel
+	pendingMutex.Unlock()
+	defer func() {
+		pendingMutex.Lock()
+		delete(pendingTranscriptions, fileName)
+		pendingMutex.Unlock()
+	}()
+
+	sendMessage := SendMessageChannel.SendMessageStruct{
+		Type: "transcribe_file",
+		Value: struct {
+			Path     string `json:"path"`
+			Language string `json:"language"`
+		}{
+			Path:     fileName,
+			Language: language,
+		},
+	}
+	sendMessage.SendMessage()
+
+	select {
+	case result := <-resultChannel:
+		if result.Error != "" {
+			return result, errors.New(result.Error)
+		}
+		return result, nil
+	case <-ctx.Done():
+		return TranscribeFileResult{}, ctx.Err()
+	}
+}
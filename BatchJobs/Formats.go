@@ -0,0 +1,209 @@
+package BatchJobs
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// document is a parsed input file. Segments are the texts to translate,
+// render creates the output file content from the translated segments.
+type document struct {
+	segments []string
+	render   func(translations []string) ([]byte, error)
+}
+
+var textExtensions = []string{".txt", ".srt", ".vtt", ".csv"}
+var audioExtensions = []string{".wav", ".mp3", ".flac", ".ogg", ".opus", ".m4a", ".aac", ".webm", ".mp4", ".mkv"}
+
+func hasExtension(fileName string, extensions []string) bool {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	for _, e := range extensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+func IsTextFile(fileName string) bool {
+	return hasExtension(fileName, textExtensions)
+}
+
+func IsAudioFile(fileName string) bool {
+	return hasExtension(fileName, audioExtensions)
+}
+
+// SupportedExtensions returns all file extensions that can be queued.
+func SupportedExtensions() []string {
+	return append(append([]string{}, textExtensions...), audioExtensions...)
+}
+
+func readDocument(fileName string) (*document, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+	text := strings.ReplaceAll(string(content), "\r\n", "\n")
+
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".txt":
+		return parsePlainText(text), nil
+	case ".srt", ".vtt":
+		return parseSubtitles(text), nil
+	case ".csv":
+		return parseCSV(content)
+	}
+	return nil, fmt.Errorf("unsupported file type: %s", filepath.Ext(fileName))
+}
+
+// parsePlainText translates every non-empty line and keeps empty lines and indentation.
+func parsePlainText(text string) *document {
+	lines := strings.Split(text, "\n")
+	doc := &document{}
+	var lineSegment []int
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			lineSegment = append(lineSegment, -1)
+			continue
+		}
+		lineSegment = append(lineSegment, len(doc.segments))
+		doc.segments = append(doc.segments, strings.TrimSpace(line))
+	}
+	doc.render = func(translations []string) ([]byte, error) {
+		var out strings.Builder
+		for i, line := range lines {
+			if i > 0 {
+				out.WriteString("\n")
+			}
+			if lineSegment[i] < 0 {
+				out.WriteString(line)
+				continue
+			}
+			indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+			out.WriteString(indent + translations[lineSegment[i]])
+		}
+		return []byte(out.String()), nil
+	}
+	return doc
+}
+
+// parseSubtitles handles SRT and WebVTT. Cue numbers, timings, headers and
+// NOTE/STYLE blocks are kept, only the cue text is translated.
+func parseSubtitles(text string) *document {
+	blocks := strings.Split(strings.Trim(text, "\n"), "\n\n")
+	doc := &document{}
+	type cue struct {
+		header  []string
+		segment int
+	}
+	cues := make([]cue, 0, len(blocks))
+	for _, block := range blocks {
+		lines := strings.Split(strings.Trim(block, "\n"), "\n")
+		timingLine := -1
+		for i, line := range lines {
+			if strings.Contains(line, "-->") {
+				timingLine = i
+				break
+			}
+		}
+		if timingLine < 0 || timingLine == len(lines)-1 {
+			cues = append(cues, cue{header: lines, segment: -1})
+			continue
+		}
+		var cueText []string
+		for _, line := range lines[timingLine+1:] {
+			cueText = append(cueText, strings.TrimSpace(line))
+		}
+		cues = append(cues, cue{header: lines[:timingLine+1], segment: len(doc.segments)})
+		doc.segments = append(doc.segments, strings.Join(cueText, " "))
+	}
+	doc.render = func(translations []string) ([]byte, error) {
+		var out strings.Builder
+		for i, c := range cues {
+			if i > 0 {
+				out.WriteString("\n\n")
+			}
+			out.WriteString(strings.Join(c.header, "\n"))
+			if c.segment >= 0 {
+				out.WriteString("\n" + translations[c.segment])
+			}
+		}
+		out.WriteString("\n")
+		return []byte(out.String()), nil
+	}
+	return doc
+}
+
+// parseCSV expects a header row. The "text" or "source" column (or the first column) is translated
+// and the translation is added as new last column.
+func parseCSV(content []byte) (*document, error) {
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("empty CSV file")
+	}
+	column := 0
+	for i, name := range records[0] {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "text" || name == "source" {
+			column = i
+			break
+		}
+	}
+	doc := &document{}
+	rowSegment := make([]int, len(records))
+	for i, record := range records {
+		rowSegment[i] = -1
+		if i == 0 || column >= len(record) || strings.TrimSpace(record[column]) == "" {
+			continue
+		}
+		rowSegment[i] = len(doc.segments)
+		doc.segments = append(doc.segments, strings.TrimSpace(record[column]))
+	}
+	doc.render = func(translations []string) ([]byte, error) {
+		var out bytes.Buffer
+		writer := csv.NewWriter(&out)
+		for i, record := range records {
+			row := append([]string{}, record...)
+			switch {
+			case i == 0:
+				row = append(row, "translation")
+			case rowSegment[i] >= 0:
+				row = append(row, translations[rowSegment[i]])
+			default:
+				row = append(row, "")
+			}
+			if err := writer.Write(row); err != nil {
+				return nil, err
+			}
+		}
+		writer.Flush()
+		return out.Bytes(), writer.Error()
+	}
+	return doc, nil
+}
+
+// writeOutputFile writes to a temporary file first, so an interrupted job never leaves a half written output.
+func writeOutputFile(fileName string, content []byte) error {
+	tmpFile := fileName + ".tmp"
+	if err := os.WriteFile(tmpFile, content, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, fileName)
+}
+
+func formatSrtTime(seconds float64) string {
+	milliseconds := int64(seconds*1000 + 0.5)
+	return fmt.Sprintf("%02d:%02d:%02d,%03d",
+		milliseconds/3600000, milliseconds/60000%60, milliseconds/1000%60, milliseconds%1000)
+}
@@ -0,0 +1,355 @@
+package BatchJobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"whispering-tiger-ui/Logging"
+	"whispering-tiger-ui/Translator"
+
+	"github.com/getsentry/sentry-go"
+)
+
+type JobType string
+
+const (
+	JobTypeTranslate  JobType = "translate"
+	JobTypeTranscribe JobType = "transcribe"
+)
+
+type JobStatus string
+
+const (
+	StatusQueued    JobStatus = "queued"
+	StatusRunning   JobStatus = "running"
+	StatusPaused    JobStatus = "paused"
+	StatusDone      JobStatus = "done"
+	StatusFailed    JobStatus = "failed"
+	StatusCancelled JobStatus = "cancelled"
+)
+
+type Job struct {
+	ID         int
+	Type       JobType
+	InputFile  string
+	OutputDir  string
+	FromLang   string
+	ToLang     string
+	OutputFile string
+
+	mutex    sync.Mutex
+	status   JobStatus
+	done     int
+	total    int
+	err      error
+	paused   bool
+	resume   chan struct{}
+	cancel   context.CancelFunc
+	ctx      context.Context
+	onChange func()
+}
+
+// JobState is a snapshot of the job state for displaying.
+type JobState struct {
+	Status JobStatus
+	Done   int
+	Total  int
+	Err    error
+}
+
+func (j *Job) State() JobState {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return JobState{Status: j.status, Done: j.done, Total: j.total, Err: j.err}
+}
+
+// Progress returns the progress between 0 and 1. Jobs without known total return 0 until they are done.
+func (s JobState) Progress() float64 {
+	if s.Status == StatusDone {
+		return 1
+	}
+	if s.Total <= 0 {
+		return 0
+	}
+	return float64(s.Done) / float64(s.Total)
+}
+
+func (j *Job) update(change func()) {
+	j.mutex.Lock()
+	change()
+	onChange := j.onChange
+	j.mutex.Unlock()
+	if onChange != nil {
+		onChange()
+	}
+}
+
+func (j *Job) Pause() {
+	j.update(func() {
+		if j.status != StatusQueued && j.status != StatusRunning {
+			return
+		}
+		if !j.paused {
+			j.paused = true
+			j.resume = make(chan struct{})
+		}
+		j.status = StatusPaused
+	})
+}
+
+func (j *Job) Resume() {
+	j.update(func() {
+		if !j.paused {
+			return
+		}
+		j.paused = false
+		close(j.resume)
+		j.status = StatusQueued
+		if j.total > 0 || j.done > 0 {
+			j.status = StatusRunning
+		}
+	})
+}
+
+func (j *Job) Cancel() {
+	j.update(func() {
+		if j.status == StatusDone || j.status == StatusFailed || j.status == StatusCancelled {
+			return
+		}
+		j.status = StatusCancelled
+		j.cancel()
+	})
+}
+
+// waitIfPaused blocks while the job is paused. Returns an error if the job was cancelled.
+func (j *Job) waitIfPaused() error {
+	j.mutex.Lock()
+	resume := j.resume
+	paused := j.paused
+	j.mutex.Unlock()
+	if paused {
+		select {
+		case <-resume:
+		case <-j.ctx.Done():
+		}
+	}
+	return j.ctx.Err()
+}
+
+func (j *Job) run() {
+	if err := j.waitIfPaused(); err != nil {
+		return
+	}
+	j.update(func() { j.status = StatusRunning })
+
+	var err error
+	switch j.Type {
+	case JobTypeTranslate:
+		err = j.runTranslate()
+	case JobTypeTranscribe:
+		err = j.runTranscribe()
+	default:
+		err = fmt.Errorf("unknown job type: %s", j.Type)
+	}
+
+	j.update(func() {
+		switch {
+		case errors.Is(err, context.Canceled):
+			j.status = StatusCancelled
+		case err != nil:
+			j.status = StatusFailed
+			j.err = err
+		default:
+			j.status = StatusDone
+		}
+	})
+	if err != nil && !errors.Is(err, context.Canceled) {
+		log.Printf("Batch job %d (%s) failed: %v", j.ID, j.InputFile, err)
+	}
+}
+
+func (j *Job) outputFileName(suffix, ext string) string {
+	baseName := strings.TrimSuffix(filepath.Base(j.InputFile), filepath.Ext(j.InputFile))
+	if suffix != "" {
+		baseName += "." + suffix
+	}
+	return filepath.Join(j.OutputDir, baseName+ext)
+}
+
+func (j *Job) runTranslate() error {
+	doc, err := readDocument(j.InputFile)
+	if err != nil {
+		return err
+	}
+	j.update(func() { j.total = len(doc.segments) })
+
+	translations := make([]string, len(doc.segments))
+	for i, segment := range doc.segments {
+		if err = j.waitIfPaused(); err != nil {
+			return err
+		}
+		translations[i], err = Translator.Translate(j.ctx, segment, j.FromLang, j.ToLang)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", i+1, err)
+		}
+		j.update(func() { j.done = i + 1 })
+	}
+
+	content, err := doc.render(translations)
+	if err != nil {
+		return err
+	}
+	j.OutputFile = j.outputFileName(j.ToLang, filepath.Ext(j.InputFile))
+	return writeOutputFile(j.OutputFile, content)
+}
+
+func (j *Job) runTranscribe() error {
+	absolutePath, err := filepath.Abs(j.InputFile)
+	if err != nil {
+		return err
+	}
+	result, err := transcribeFile(j.ctx, absolutePath, j.FromLang)
+	if err != nil {
+		return err
+	}
+
+	j.OutputFile = j.outputFileName("", ".txt")
+	text := result.Text
+	if text == "" {
+		var segmentTexts []string
+		for _, segment := range result.Segments {
+			segmentTexts = append(segmentTexts, strings.TrimSpace(segment.Text))
+		}
+		text = strings.Join(segmentTexts, "\n")
+	}
+	if err = writeOutputFile(j.OutputFile, []byte(text+"\n")); err != nil {
+		return err
+	}
+
+	if len(result.Segments) > 0 {
+		var srt strings.Builder
+		for i, segment := range result.Segments {
+			fmt.Fprintf(&srt, "%d\n%s --> %s\n%s\n\n", i+1, formatSrtTime(segment.Start), formatSrtTime(segment.End), strings.TrimSpace(segment.Text))
+		}
+		if err = writeOutputFile(j.outputFileName("", ".srt"), []byte(srt.String())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Manager runs the queued jobs one after another, since the backend handles one request at a time.
+type Manager struct {
+	mutex    sync.Mutex
+	jobs     []*Job
+	nextID   int
+	queue    chan *Job
+	OnChange func()
+}
+
+var Default = NewManager()
+
+func NewManager() *Manager {
+	m := &Manager{queue: make(chan *Job, 1000)}
+	go m.worker()
+	return m
+}
+
+func (m *Manager) worker() {
+	defer Logging.GoRoutineErrorHandler(func(scope *sentry.Scope) {
+		scope.SetTag("GoRoutine", "BatchJobs\\Jobs->worker")
+	})
+	for job := range m.queue {
+		if job.ctx.Err() != nil {
+			continue
+		}
+		job.run()
+	}
+}
+
+func (m *Manager) changed() {
+	m.mutex.Lock()
+	onChange := m.OnChange
+	m.mutex.Unlock()
+	if onChange != nil {
+		onChange()
+	}
+}
+
+// SetOnChange sets the function called after jobs are added or change their state.
+func (m *Manager) SetOnChange(onChange func()) {
+	m.mutex.Lock()
+	m.OnChange = onChange
+	m.mutex.Unlock()
+}
+
+// Add queues a file. Text files are translated, audio files are transcribed.
+func (m *Manager) Add(inputFile, outputDir, fromLang, toLang string) (*Job, error) {
+	var jobType JobType
+	switch {
+	case IsTextFile(inputFile):
+		jobType = JobTypeTranslate
+		if toLang == "" {
+			return nil, errors.New("no target language selected")
+		}
+	case IsAudioFile(inputFile):
+		jobType = JobTypeTranscribe
+	default:
+		return nil, fmt.Errorf("unsupported file type: %s", filepath.Ext(inputFile))
+	}
+	if outputDir == "" {
+		outputDir = filepath.Dir(inputFile)
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.mutex.Lock()
+	m.nextID++
+	job := &Job{
+		ID:        m.nextID,
+		Type:      jobType,
+		InputFile: inputFile,
+		OutputDir: outputDir,
+		FromLang:  fromLang,
+		ToLang:    toLang,
+		status:    StatusQueued,
+		ctx:       ctx,
+		cancel:    cancel,
+		onChange:  m.changed,
+	}
+	m.jobs = append(m.jobs, job)
+	m.mutex.Unlock()
+
+	m.queue <- job
+	m.changed()
+	return job, nil
+}
+
+func (m *Manager) Jobs() []*Job {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]*Job{}, m.jobs...)
+}
+
+// RemoveFinished removes done, failed and cancelled jobs from the list.
+func (m *Manager) RemoveFinished() {
+	m.mutex.Lock()
+	var jobs []*Job
+	for _, job := range m.jobs {
+		switch job.State().Status {
+		case StatusDone, StatusFailed, StatusCancelled:
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	m.jobs = jobs
+	m.mutex.Unlock()
+	m.changed()
+}
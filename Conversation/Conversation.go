@@ -0,0 +1,279 @@
+package Conversation
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"whispering-tiger-ui/Fields"
+	"whispering-tiger-ui/Logging"
+	"whispering-tiger-ui/SendMessageChannel"
+	"whispering-tiger-ui/Settings"
+	"whispering-tiger-ui/Translator"
+	"whispering-tiger-ui/Utilities"
+
+	"github.com/getsentry/sentry-go"
+	"gopkg.in/yaml.v3"
+)
+
+const configFileName = "conversation.yaml"
+
+// Lane is one side of the conversation.
+// Language is the language the person speaks, Voice the TTS voice used to speak translations into their language.
+type Lane struct {
+	Name     string `yaml:"name" json:"name"`
+	Language string `yaml:"language" json:"language"`
+	Voice    string `yaml:"voice" json:"voice"`
+}
+
+type Config struct {
+	Lanes             [2]Lane `yaml:"lanes" json:"lanes"`
+	SpeakTranslations bool    `yaml:"speak_translations" json:"speak_translations"`
+	SendOsc           bool    `yaml:"send_osc" json:"send_osc"`
+}
+
+var DefaultConfig = Config{
+	Lanes: [2]Lane{
+		{Name: "A"},
+		{Name: "B"},
+	},
+	SpeakTranslations: true,
+}
+
+// Message is a single utterance of the conversation transcript.
+type Message struct {
+	Time             time.Time
+	Lane             int
+	Text             string
+	Translation      string
+	DetectedLanguage string
+	Err              error
+}
+
+// backendOptions are turned off in the backend while conversation mode is enabled.
+var backendOptions = []string{"txt_translate", "tts_answer", "osc_auto_processing_enabled"}
+
+var (
+	mutex    sync.Mutex
+	config   = DefaultConfig
+	enabled  bool
+	messages []Message
+	onChange func()
+
+	// processMutex keeps the order of utterances while they are translated.
+	processMutex sync.Mutex
+)
+
+func ConfigFilePath() string {
+	return filepath.Join(Settings.GetUiDataDir(), configFileName)
+}
+
+// Load reads the conversation configuration. A missing file keeps the default configuration.
+func Load() error {
+	conf := DefaultConfig
+	yamlFile, err := os.ReadFile(ConfigFilePath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err = yaml.Unmarshal(yamlFile, &conf); err != nil {
+		return err
+	}
+	SetConfig(conf)
+	return nil
+}
+
+func Save() error {
+	yamlFile, err := yaml.Marshal(GetConfig())
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(ConfigFilePath(), yamlFile, 0644)
+}
+
+func GetConfig() Config {
+	mutex.Lock()
+	defer mutex.Unlock()
+	return config
+}
+
+func SetConfig(conf Config) {
+	mutex.Lock()
+	config = conf
+	mutex.Unlock()
+}
+
+func IsEnabled() bool {
+	mutex.Lock()
+	defer mutex.Unlock()
+	return enabled
+}
+
+// SetEnabled turns conversation mode on or off. While it is on, the backend does not translate and output
+// transcripts by itself, the conversation translates and outputs each utterance. The options are restored when it is turned off.
+func SetEnabled(value bool) {
+	mutex.Lock()
+	enabled = value
+	mutex.Unlock()
+	var options []string
+	if value {
+		options = backendOptions
+	}
+	go Fields.SendBackendOptions(Fields.HoldBackendOptions("conversation", options))
+}
+
+// SetOnChange sets the function called after the transcript changed.
+func SetOnChange(function func()) {
+	mutex.Lock()
+	onChange = function
+	mutex.Unlock()
+}
+
+// Messages returns a copy of the transcript, oldest first.
+func Messages() []Message {
+	mutex.Lock()
+	defer mutex.Unlock()
+	return append([]Message{}, messages...)
+}
+
+func ClearMessages() {
+	mutex.Lock()
+	messages = nil
+	mutex.Unlock()
+	changed()
+}
+
+func changed() {
+	mutex.Lock()
+	function := onChange
+	mutex.Unlock()
+	if function != nil {
+		function()
+	}
+}
+
+// normalizeLanguage reduces a language code to ISO 639-1 where possible,
+// so Whisper codes ("en") match translator codes ("eng_Latn").
+func normalizeLanguage(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if code == "" {
+		return ""
+	}
+	for _, mapping := range Utilities.LanguageMapList.LanguageMappings {
+		if strings.EqualFold(mapping.ISO1, code) {
+			return strings.ToLower(mapping.ISO1)
+		}
+		for _, iso3 := range mapping.ISO3 {
+			if strings.EqualFold(iso3, code) {
+				return strings.ToLower(mapping.ISO1)
+			}
+		}
+	}
+	base, _, _ := strings.Cut(code, "_")
+	return base
+}
+
+// DetectLane returns the lane that speaks the detected language.
+// If the language matches no lane, the lane that did not speak last is used.
+func DetectLane(conf Config, detectedLanguage string) int {
+	detected := normalizeLanguage(detectedLanguage)
+	for i, lane := range conf.Lanes {
+		if detected != "" && normalizeLanguage(lane.Language) == detected {
+			return i
+		}
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(messages) > 0 {
+		return 1 - messages[len(messages)-1].Lane
+	}
+	return 0
+}
+
+// HandleTranscript translates an utterance into the language of the other lane and speaks it with the other lane's voice.
+// Returns false if conversation mode is disabled.
+func HandleTranscript(text, detectedLanguage string) bool {
+	if !IsEnabled() || strings.TrimSpace(text) == "" {
+		return false
+	}
+	go processTranscript(text, detectedLanguage)
+	return true
+}
+
+func processTranscript(text, detectedLanguage string) {
+	defer Logging.GoRoutineErrorHandler(func(scope *sentry.Scope) {
+		scope.SetTag("GoRoutine", "Conversation\\Conversation->processTranscript")
+	})
+	processMutex.Lock()
+	defer processMutex.Unlock()
+
+	conf := GetConfig()
+	laneIndex := DetectLane(conf, detectedLanguage)
+	speaker := conf.Lanes[laneIndex]
+	listener := conf.Lanes[1-laneIndex]
+
+	message := Message{
+		Time:             time.Now(),
+		Lane:             laneIndex,
+		Text:             text,
+		DetectedLanguage: detectedLanguage,
+	}
+	mutex.Lock()
+	messages = append(messages, message)
+	messageIndex := len(messages) - 1
+	mutex.Unlock()
+	changed()
+
+	fromLang := speaker.Language
+	if fromLang == "" {
+		fromLang = "auto"
+	}
+	translation, err := Translator.Translate(context.Background(), text, fromLang, listener.Language)
+	mutex.Lock()
+	// the transcript might have been cleared in the meantime
+	if messageIndex < len(messages) && messages[messageIndex].Time.Equal(message.Time) {
+		messages[messageIndex].Translation = translation
+		messages[messageIndex].Err = err
+	}
+	mutex.Unlock()
+	changed()
+	if err != nil {
+		log.Printf("Conversation translation failed: %v", err)
+		return
+	}
+
+	if conf.SpeakTranslations {
+		// the voice is sent with the request, so the voice of the profile is not changed
+		sendMessage := SendMessageChannel.SendMessageStruct{
+			Type: "tts_req",
+			Value: struct {
+				Text     string `json:"text"`
+				ToDevice bool   `json:"to_device"`
+				Download bool   `json:"download"`
+				Voice    string `json:"voice,omitempty"`
+			}{
+				Text:     translation,
+				ToDevice: true,
+				Download: false,
+				Voice:    listener.Voice,
+			},
+		}
+		sendMessage.SendMessage()
+	}
+	if conf.SendOsc {
+		sendMessage := SendMessageChannel.SendMessageStruct{
+			Type: "send_osc",
+			Value: struct {
+				Text string `json:"text"`
+			}{
+				Text: translation,
+			},
+		}
+		sendMessage.SendMessage()
+	}
+}
@@ -0,0 +1,106 @@
+package Fields
+
+import (
+	"log"
+	"slices"
+	"sort"
+	"sync"
+	"whispering-tiger-ui/SendMessageChannel"
+	"whispering-tiger-ui/Settings"
+)
+
+// The backend outputs (and translates) transcripts by itself, before the UI can change them.
+// Features that have to change the text first hold these backend options: the backend gets them turned off
+// and the UI does the output itself. The loaded settings, the profile and the checkboxes keep the values chosen by the user.
+// The turned off values the backend stores in its settings file are not written back (see Settings.IgnoreBackendChange),
+// so the chosen values are kept after a crash or a profile switch as well.
+
+var (
+	heldOptionsMutex sync.Mutex
+	// heldOptions maps a holder to the boolean backend options it holds.
+	heldOptions = map[string][]string{}
+)
+
+func init() {
+	// the backend stores held options turned off, these values must not replace the ones chosen by the user
+	Settings.IgnoreBackendChange = IsBackendOptionHeld
+}
+
+func heldOptionNames() []string {
+	var names []string
+	for _, options := range heldOptions {
+		for _, option := range options {
+			if !slices.Contains(names, option) {
+				names = append(names, option)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// HoldBackendOptions sets the backend options held by holder. Options held by no holder anymore are released.
+// Returns the options whose held state changed, the caller sends them with SendBackendOptions once the backend is running.
+func HoldBackendOptions(holder string, options []string) []string {
+	heldOptionsMutex.Lock()
+	defer heldOptionsMutex.Unlock()
+	before := heldOptionNames()
+	if len(options) == 0 {
+		delete(heldOptions, holder)
+	} else {
+		heldOptions[holder] = append([]string(nil), options...)
+	}
+	after := heldOptionNames()
+
+	var changed []string
+	for _, option := range before {
+		if !slices.Contains(after, option) {
+			changed = append(changed, option)
+		}
+	}
+	for _, option := range after {
+		if !slices.Contains(before, option) {
+			changed = append(changed, option)
+		}
+	}
+	return changed
+}
+
+// HeldBackendOptions returns all options that are currently held.
+func HeldBackendOptions() []string {
+	heldOptionsMutex.Lock()
+	defer heldOptionsMutex.Unlock()
+	return heldOptionNames()
+}
+
+func IsBackendOptionHeld(option string) bool {
+	return slices.Contains(HeldBackendOptions(), option)
+}
+
+// BackendOptionValue returns the value the backend gets for an option. Held options are turned off.
+func BackendOptionValue(option string, value interface{}) interface{} {
+	if _, ok := value.(bool); !ok {
+		return value
+	}
+	if IsBackendOptionHeld(option) {
+		return false
+	}
+	return value
+}
+
+// SendBackendOptions sends options of the loaded profile to the backend. Held options are sent turned off.
+func SendBackendOptions(options []string) {
+	for _, option := range options {
+		value, err := Settings.Config.GetOption(option)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		sendMessage := SendMessageChannel.SendMessageStruct{
+			Type:  "setting_change",
+			Name:  option,
+			Value: value,
+		}
+		sendMessage.SendMessage()
+	}
+}
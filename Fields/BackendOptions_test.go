@@ -0,0 +1,55 @@
+package Fields
+
+import (
+	"reflect"
+	"testing"
+	"whispering-tiger-ui/Settings"
+)
+
+func TestHoldBackendOptions(t *testing.T) {
+	steps := []struct {
+		holder      string
+		options     []string
+		wantChanged []string
+		wantHeld    []string
+	}{
+		{holder: "text_processing", options: []string{"tts_answer"}, wantChanged: []string{"tts_answer"}, wantHeld: []string{"tts_answer"}},
+		{holder: "conversation", options: []string{"tts_answer", "txt_translate"}, wantChanged: []string{"txt_translate"}, wantHeld: []string{"tts_answer", "txt_translate"}},
+		{holder: "text_processing", options: nil, wantChanged: nil, wantHeld: []string{"tts_answer", "txt_translate"}},
+		{holder: "conversation", options: nil, wantChanged: []string{"tts_answer", "txt_translate"}, wantHeld: nil},
+	}
+	heldOptions = map[string][]string{}
+	for i, step := range steps {
+		changed := HoldBackendOptions(step.holder, step.options)
+		if !reflect.DeepEqual(changed, step.wantChanged) {
+			t.Errorf("step %d: HoldBackendOptions() = %v, want %v", i, changed, step.wantChanged)
+		}
+		if held := HeldBackendOptions(); !reflect.DeepEqual(held, step.wantHeld) {
+			t.Errorf("step %d: HeldBackendOptions() = %v, want %v", i, held, step.wantHeld)
+		}
+	}
+}
+
+func TestBackendOptionValue(t *testing.T) {
+	heldOptions = map[string][]string{"text_processing": {"tts_answer"}}
+	t.Cleanup(func() { heldOptions = map[string][]string{} })
+	tests := []struct {
+		option string
+		value  interface{}
+		want   interface{}
+	}{
+		{option: "tts_answer", value: true, want: false},
+		{option: "tts_answer", value: false, want: false},
+		{option: "osc_auto_processing_enabled", value: true, want: true},
+		{option: "tts_voice", value: "voice", want: "voice"},
+	}
+	for _, tt := range tests {
+		if got := BackendOptionValue(tt.option, tt.value); got != tt.want {
+			t.Errorf("BackendOptionValue(%s, %v) = %v, want %v", tt.option, tt.value, got, tt.want)
+		}
+	}
+	// the turned off values the backend stores are not written back to the profile
+	if Settings.IgnoreBackendChange == nil || !Settings.IgnoreBackendChange("tts_answer") || Settings.IgnoreBackendChange("osc_auto_processing_enabled") {
+		t.Error("Settings.IgnoreBackendChange does not report the held options")
+	}
+}
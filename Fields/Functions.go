@@ -3,6 +3,7 @@ package Fields
 import (
 	"strconv"
 	"strings"
+	"whispering-tiger-ui/SendMessageChannel"
 	"whispering-tiger-ui/Settings"
 )
 
@@ -32,3 +33,38 @@ func AdditionalLanguagesCountString(prefix string, wrapping string) string {
 	}
 	return numOfAdditionalLanguagesLabelText
 }
+
+// SendTextToEnabledOutputs sends a text to TTS and OSC, depending on the enabled automatic options.
+func SendTextToEnabledOutputs(text string) {
+	if text == "" {
+		return
+	}
+	ttsEnabled, _ := DataBindings.TextToSpeechEnabledDataBinding.Get()
+	oscEnabled, _ := DataBindings.OSCEnabledDataBinding.Get()
+	if ttsEnabled {
+		sendMessage := SendMessageChannel.SendMessageStruct{
+			Type: "tts_req",
+			Value: struct {
+				Text     string `json:"text"`
+				ToDevice bool   `json:"to_device"`
+				Download bool   `json:"download"`
+			}{
+				Text:     text,
+				ToDevice: true,
+				Download: false,
+			},
+		}
+		sendMessage.SendMessage()
+	}
+	if oscEnabled {
+		sendMessage := SendMessageChannel.SendMessageStruct{
+			Type: "send_osc",
+			Value: struct {
+				Text string `json:"text"`
+			}{
+				Text: text,
+			},
+		}
+		sendMessage.SendMessage()
+	}
+}
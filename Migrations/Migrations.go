@@ -0,0 +1,222 @@
+package Migrations
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaVersionKey is the profile entry holding the schema version. Profiles without it have version 0.
+const SchemaVersionKey = "schema_version"
+
+const logFileName = "migrations.log"
+
+var ErrNewerVersion = errors.New("profile was saved by a newer version")
+
+// Options control how profiles are migrated.
+type Options struct {
+	// BackupDir receives a copy of each profile before it is migrated, as well as the migration log.
+	// Must not be the profiles directory, since every yaml file there is listed as profile.
+	BackupDir string
+	// DryRun only reports the changes without writing any files.
+	DryRun bool
+}
+
+// Result describes the migration of a single profile.
+type Result struct {
+	File        string
+	FromVersion int
+	ToVersion   int
+	Changes     []Change
+	BackupFile  string
+}
+
+// Migrated returns true if the profile was (or in a dry run would be) changed.
+func (r Result) Migrated() bool {
+	return r.FromVersion != r.ToVersion
+}
+
+// MigrateData migrates the content of a profile file and returns the migrated content.
+func MigrateData(data []byte) ([]byte, Result, error) {
+	result := Result{}
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, result, err
+	}
+	if document.Kind == 0 {
+		document = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	if document.Kind != yaml.DocumentNode || len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return nil, result, errors.New("profile is not a yaml mapping")
+	}
+	profile := &Profile{mapping: document.Content[0]}
+
+	if versionNode, ok := profile.Node(SchemaVersionKey); ok {
+		if err := versionNode.Decode(&result.FromVersion); err != nil {
+			return nil, result, fmt.Errorf("invalid %s: %w", SchemaVersionKey, err)
+		}
+	}
+	result.ToVersion = LatestVersion()
+	if result.FromVersion > result.ToVersion {
+		return nil, result, fmt.Errorf("%w (version %d, supported up to %d)", ErrNewerVersion, result.FromVersion, result.ToVersion)
+	}
+	if result.FromVersion == result.ToVersion {
+		return data, result, nil
+	}
+
+	steps := append([]Step{}, Steps...)
+	sort.SliceStable(steps, func(i, j int) bool {
+		return steps[i].Version < steps[j].Version
+	})
+	for _, step := range steps {
+		if step.Version <= result.FromVersion {
+			continue
+		}
+		if err := step.Apply(profile); err != nil {
+			return nil, result, fmt.Errorf("migration %d (%s): %w", step.Version, step.Description, err)
+		}
+	}
+	if err := profile.Set(SchemaVersionKey, result.ToVersion); err != nil {
+		return nil, result, err
+	}
+	result.Changes = profile.changes
+
+	migratedData, err := yaml.Marshal(&document)
+	if err != nil {
+		return nil, result, err
+	}
+	return migratedData, result, nil
+}
+
+// MigrateFile migrates a single profile file to the latest schema version.
+func MigrateFile(fileName string, options Options) (Result, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return Result{File: fileName}, err
+	}
+	migratedData, result, err := MigrateData(data)
+	result.File = fileName
+	if err != nil || !result.Migrated() || options.DryRun {
+		return result, err
+	}
+
+	if options.BackupDir != "" {
+		if err = os.MkdirAll(options.BackupDir, 0755); err != nil {
+			return result, err
+		}
+		result.BackupFile = filepath.Join(options.BackupDir, fmt.Sprintf("%s.v%d.%s.bak", filepath.Base(fileName), result.FromVersion, time.Now().Format("20060102-150405")))
+		if err = os.WriteFile(result.BackupFile, data, 0644); err != nil {
+			return result, fmt.Errorf("backup failed, profile not migrated: %w", err)
+		}
+	}
+
+	tmpFile := fileName + ".tmp"
+	if err = os.WriteFile(tmpFile, migratedData, 0644); err != nil {
+		return result, err
+	}
+	if err = os.Rename(tmpFile, fileName); err != nil {
+		_ = os.Remove(tmpFile)
+		return result, err
+	}
+	logResult(result, options.BackupDir)
+	return result, nil
+}
+
+// MigrateProfiles migrates all profiles in the profiles directory.
+// Profiles from old versions that were stored in the working directory are moved into it first.
+func MigrateProfiles(profilesDir string, options Options) ([]Result, error) {
+	if !options.DryRun {
+		moveLegacyProfiles(".", profilesDir)
+	}
+
+	files, err := os.ReadDir(profilesDir)
+	if err != nil {
+		return nil, err
+	}
+	var results []Result
+	var errs []error
+	for _, file := range files {
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") || !(strings.HasSuffix(file.Name(), ".yaml") || strings.HasSuffix(file.Name(), ".yml")) {
+			continue
+		}
+		result, err := MigrateFile(filepath.Join(profilesDir, file.Name()), options)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", file.Name(), err))
+			continue
+		}
+		if result.Migrated() {
+			results = append(results, result)
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+func logResult(result Result, logDir string) {
+	var lines []string
+	lines = append(lines, fmt.Sprintf("%s migrated %s from version %d to %d", time.Now().Format(time.RFC3339), result.File, result.FromVersion, result.ToVersion))
+	if result.BackupFile != "" {
+		lines = append(lines, "  backup: "+result.BackupFile)
+	}
+	for _, change := range result.Changes {
+		lines = append(lines, "  "+change.String())
+	}
+	for _, line := range lines {
+		log.Println(line)
+	}
+	if logDir == "" {
+		return
+	}
+	logFile, err := os.OpenFile(filepath.Join(logDir, logFileName), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Printf("error writing migration log: %v", err)
+		return
+	}
+	defer logFile.Close()
+	_, _ = logFile.WriteString(strings.Join(lines, "\n") + "\n")
+}
+
+// moveLegacyProfiles moves profiles from legacyDir into an empty profiles directory.
+func moveLegacyProfiles(legacyDir, profilesDir string) {
+	// Create the Profiles directory if it does not exist
+	if err := os.MkdirAll(profilesDir, 0755); err != nil {
+		log.Println("Error creating Profiles directory:", err)
+		return
+	}
+
+	// Check if Profiles directory is empty, ignoring .gitkeep
+	files, err := os.ReadDir(profilesDir)
+	if err != nil {
+		log.Println("Error reading Profiles directory:", err)
+		return
+	}
+	for _, file := range files {
+		if file.Name() != ".gitkeep" {
+			return
+		}
+	}
+
+	// Move .yml and .yaml files
+	files, err = os.ReadDir(legacyDir)
+	if err != nil {
+		log.Println("Error reading legacy profiles directory:", err)
+		return
+	}
+	for _, file := range files {
+		if !file.IsDir() && !strings.HasPrefix(file.Name(), ".") &&
+			(strings.HasSuffix(file.Name(), ".yaml") || strings.HasSuffix(file.Name(), ".yml")) {
+			newPath := filepath.Join(profilesDir, file.Name())
+			if err := os.Rename(filepath.Join(legacyDir, file.Name()), newPath); err != nil {
+				log.Printf("Error moving %s to Profiles: %s\n", file.Name(), err)
+			} else {
+				log.Printf("Moved %s to Profiles\n", file.Name())
+			}
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package Migrations
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrateData(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		wantFrom    int
+		wantContain []string
+		wantErr     error
+		wantAnyErr  bool
+	}{
+		{name: "tts_model as string", data: "tts_model: silero\n", wantContain: []string{"- silero", "schema_version: 1"}},
+		{name: "empty tts_model", data: "tts_model: \"\"\n", wantContain: []string{"tts_model: []"}},
+		{name: "empty file", data: "", wantContain: []string{"schema_version: 1"}},
+		{name: "current version is unchanged", data: "schema_version: 1\ntts_model: silero\n", wantFrom: 1, wantContain: []string{"tts_model: silero"}},
+		{name: "newer version", data: "schema_version: 99\n", wantFrom: 99, wantErr: ErrNewerVersion},
+		{name: "invalid version", data: "schema_version: abc\n", wantAnyErr: true},
+		{name: "not a mapping", data: "- a\n- b\n", wantAnyErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrated, result, err := MigrateData([]byte(tt.data))
+			if tt.wantErr != nil || tt.wantAnyErr {
+				if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+					t.Fatalf("MigrateData() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("MigrateData() error = %v", err)
+			}
+			if result.FromVersion != tt.wantFrom || result.ToVersion != LatestVersion() {
+				t.Errorf("MigrateData() versions = %d -> %d, want %d -> %d", result.FromVersion, result.ToVersion, tt.wantFrom, LatestVersion())
+			}
+			for _, want := range tt.wantContain {
+				if !strings.Contains(string(migrated), want) {
+					t.Errorf("migrated profile %q does not contain %q", migrated, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMigrateFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		options    func(dir string) Options
+		wantData   string
+		wantBackup bool
+	}{
+		{name: "with backup", options: func(dir string) Options { return Options{BackupDir: filepath.Join(dir, "backup")} }, wantData: "- silero", wantBackup: true},
+		{name: "without backup", options: func(dir string) Options { return Options{} }, wantData: "- silero"},
+		{name: "dry run", options: func(dir string) Options { return Options{BackupDir: filepath.Join(dir, "backup"), DryRun: true} }, wantData: "tts_model: silero\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			fileName := filepath.Join(dir, "profile.yaml")
+			if err := os.WriteFile(fileName, []byte("tts_model: silero\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			options := tt.options(dir)
+			result, err := MigrateFile(fileName, options)
+			if err != nil {
+				t.Fatalf("MigrateFile() error = %v", err)
+			}
+			if !result.Migrated() {
+				t.Errorf("MigrateFile() did not migrate the profile")
+			}
+			data, _ := os.ReadFile(fileName)
+			if !strings.Contains(string(data), tt.wantData) {
+				t.Errorf("profile = %q, want it to contain %q", data, tt.wantData)
+			}
+			if (result.BackupFile != "") != tt.wantBackup {
+				t.Fatalf("BackupFile = %q, want backup %v", result.BackupFile, tt.wantBackup)
+			}
+			if tt.wantBackup {
+				backup, err := os.ReadFile(result.BackupFile)
+				if err != nil || string(backup) != "tts_model: silero\n" {
+					t.Errorf("backup = %q, %v, want the original profile", backup, err)
+				}
+				if _, err := os.Stat(filepath.Join(options.BackupDir, logFileName)); err != nil {
+					t.Errorf("migration log was not written: %v", err)
+				}
+			}
+			if _, err := os.Stat(fileName + ".tmp"); !os.IsNotExist(err) {
+				t.Errorf("temporary file was left behind")
+			}
+		})
+	}
+}
+
+func TestMoveLegacyProfiles(t *testing.T) {
+	tests := []struct {
+		name          string
+		existing      []string
+		wantMoved     bool
+		wantProfiles  []string
+		wantRemaining []string
+	}{
+		{name: "empty profiles directory", wantMoved: true, wantProfiles: []string{"a.yaml", "b.yml"}, wantRemaining: []string{".hidden.yaml", "notes.txt"}},
+		{name: "only .gitkeep", existing: []string{".gitkeep"}, wantMoved: true, wantProfiles: []string{"a.yaml", "b.yml"}},
+		{name: "profiles directory in use", existing: []string{"c.yaml"}, wantProfiles: []string{"c.yaml"}, wantRemaining: []string{"a.yaml", "b.yml"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			legacyDir := t.TempDir()
+			// the profiles directory is not a child of the legacy directory, like the profiles next to the executable
+			profilesDir := filepath.Join(t.TempDir(), "Profiles")
+			for _, name := range []string{"a.yaml", "b.yml", ".hidden.yaml", "notes.txt"} {
+				if err := os.WriteFile(filepath.Join(legacyDir, name), []byte("x: 1\n"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := os.MkdirAll(profilesDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range tt.existing {
+				if err := os.WriteFile(filepath.Join(profilesDir, name), nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			moveLegacyProfiles(legacyDir, profilesDir)
+
+			for _, name := range tt.wantProfiles {
+				if _, err := os.Stat(filepath.Join(profilesDir, name)); err != nil {
+					t.Errorf("%s is not in the profiles directory", name)
+				}
+			}
+			for _, name := range tt.wantRemaining {
+				if _, err := os.Stat(filepath.Join(legacyDir, name)); err != nil {
+					t.Errorf("%s was moved, want it to stay", name)
+				}
+			}
+			_, err := os.Stat(filepath.Join(legacyDir, "a.yaml"))
+			if moved := os.IsNotExist(err); moved != tt.wantMoved {
+				t.Errorf("a.yaml moved = %v, want %v", moved, tt.wantMoved)
+			}
+		})
+	}
+}
+
+func TestProfileEdits(t *testing.T) {
+	_, result, err := MigrateData([]byte("tts_model: silero\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Changes) == 0 || result.Changes[0].Key != "tts_model" {
+		t.Errorf("Changes = %v, want the tts_model change first", result.Changes)
+	}
+
+	steps := Steps
+	defer func() { Steps = steps }()
+	Steps = append(append([]Step{}, steps...), Step{Version: LatestVersion() + 1, Description: "test", Apply: func(p *Profile) error {
+		p.Rename("old_key", "new_key")
+		p.Rename("duplicate", "existing")
+		p.Delete("removed")
+		p.ReplaceValue("stt_type", map[string]string{"old_name": "new_name"})
+		return nil
+	}})
+	migrated, result, err := MigrateData([]byte("old_key: 1\nduplicate: 2\nexisting: 3\nremoved: 4\nstt_type: old_name\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"new_key: 1", "existing: 3", "stt_type: new_name"} {
+		if !strings.Contains(string(migrated), want) {
+			t.Errorf("migrated profile %q does not contain %q", migrated, want)
+		}
+	}
+	for _, unwanted := range []string{"old_key", "duplicate", "removed"} {
+		if strings.Contains(string(migrated), unwanted) {
+			t.Errorf("migrated profile %q still contains %q", migrated, unwanted)
+		}
+	}
+	if result.ToVersion != LatestVersion() {
+		t.Errorf("ToVersion = %d, want %d", result.ToVersion, LatestVersion())
+	}
+}
@@ -0,0 +1,113 @@
+package Migrations
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Change describes a single modification of a profile made by a migration step.
+type Change struct {
+	Key         string
+	Description string
+}
+
+func (c Change) String() string {
+	return c.Key + ": " + c.Description
+}
+
+// Profile gives migration steps access to the top level entries of a profile file.
+// All modifications are recorded as changes.
+type Profile struct {
+	mapping *yaml.Node
+	changes []Change
+}
+
+func (p *Profile) find(key string) int {
+	for i := 0; i+1 < len(p.mapping.Content); i += 2 {
+		if p.mapping.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func (p *Profile) record(key, format string, args ...interface{}) {
+	p.changes = append(p.changes, Change{Key: key, Description: fmt.Sprintf(format, args...)})
+}
+
+// Has returns true if the profile contains the key.
+func (p *Profile) Has(key string) bool {
+	return p.find(key) >= 0
+}
+
+// Node returns the value node of a key.
+func (p *Profile) Node(key string) (*yaml.Node, bool) {
+	index := p.find(key)
+	if index < 0 {
+		return nil, false
+	}
+	return p.mapping.Content[index+1], true
+}
+
+// Value returns the value of a scalar entry, or "" if the key does not exist or is not a scalar.
+func (p *Profile) Value(key string) string {
+	node, ok := p.Node(key)
+	if !ok || node.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return node.Value
+}
+
+// Set adds or replaces an entry.
+func (p *Profile) Set(key string, value interface{}) error {
+	valueNode := &yaml.Node{}
+	if err := valueNode.Encode(value); err != nil {
+		return err
+	}
+	if index := p.find(key); index >= 0 {
+		p.mapping.Content[index+1] = valueNode
+		p.record(key, "set to %v", value)
+		return nil
+	}
+	p.mapping.Content = append(p.mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, valueNode)
+	p.record(key, "added with %v", value)
+	return nil
+}
+
+// Rename renames a key. If the new key already exists, the old entry is removed and the new one kept.
+func (p *Profile) Rename(oldKey, newKey string) {
+	index := p.find(oldKey)
+	if index < 0 {
+		return
+	}
+	if p.Has(newKey) {
+		p.Delete(oldKey)
+		return
+	}
+	p.mapping.Content[index].Value = newKey
+	p.record(oldKey, "renamed to %s", newKey)
+}
+
+// Delete removes an entry.
+func (p *Profile) Delete(key string) {
+	index := p.find(key)
+	if index < 0 {
+		return
+	}
+	p.mapping.Content = append(p.mapping.Content[:index], p.mapping.Content[index+2:]...)
+	p.record(key, "removed")
+}
+
+// ReplaceValue replaces the value of a scalar entry using the given old to new value mapping,
+// for example when STT types or model names are renamed.
+func (p *Profile) ReplaceValue(key string, replacements map[string]string) {
+	node, ok := p.Node(key)
+	if !ok || node.Kind != yaml.ScalarNode {
+		return
+	}
+	if newValue, ok := replacements[node.Value]; ok && newValue != node.Value {
+		p.record(key, "changed from %s to %s", node.Value, newValue)
+		node.Value = newValue
+	}
+}
@@ -0,0 +1,47 @@
+package Migrations
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+// Step migrates a profile from Version-1 to Version.
+type Step struct {
+	Version     int
+	Description string
+	Apply       func(p *Profile) error
+}
+
+// Steps are applied in order of their version. Never change or remove a released step, add a new one instead.
+//
+// Example for a renamed STT type:
+//
+//	{Version: 2, Description: "Rename STT type", Apply: func(p *Profile) error {
+//		p.ReplaceValue("stt_type", map[string]string{"old_name": "new_name"})
+//		return nil
+//	}},
+var Steps = []Step{
+	{
+		Version:     1,
+		Description: "Store tts_model as list",
+		Apply: func(p *Profile) error {
+			node, ok := p.Node("tts_model")
+			if !ok || node.Kind != yaml.ScalarNode || node.Tag == "!!null" {
+				return nil
+			}
+			var models []string
+			if node.Value != "" {
+				models = []string{node.Value}
+			}
+			return p.Set("tts_model", models)
+		},
+	},
+}
+
+// LatestVersion returns the schema version of profiles after all steps are applied.
+func LatestVersion() int {
+	latestVersion := 0
+	for _, step := range Steps {
+		latestVersion = max(latestVersion, step.Version)
+	}
+	return latestVersion
+}
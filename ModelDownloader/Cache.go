@@ -0,0 +1,341 @@
+package ModelDownloader
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"whispering-tiger-ui/ModelRegistry"
+	"whispering-tiger-ui/Settings"
+	"whispering-tiger-ui/Updater"
+	"whispering-tiger-ui/Utilities/Hardwareinfo"
+)
+
+// ErrNothingToVerify is returned for models whose downloaded files were removed after extracting, or that have no checksum.
+var ErrNothingToVerify = errors.New("no downloaded file with checksum found")
+
+// precisionSuffixes are the precisions that model names of the registry end with, e.g. small.en_float16.
+var precisionSuffixes = []string{"float16", "float32", "bfloat16", "int8", "int8_float16"}
+
+// CacheDir returns the directory of the model cache.
+func CacheDir() string {
+	return rootCacheFolder
+}
+
+// CacheEntry is a model of the registry found in the cache, or a file or directory of the cache that is not in the registry.
+type CacheEntry struct {
+	// Family and Model are the registry names, empty for unknown entries.
+	Family    string
+	Model     string
+	ModelSize string
+	Precision string
+	// Dir is the directory of the entry relative to the cache directory, "" for the cache directory itself.
+	Dir string
+	// Paths are the files and directories of the entry relative to the cache directory.
+	Paths []string
+	// Size is the disk usage in bytes.
+	Size int64
+	// Files are the registry files of the model, used to verify their checksums.
+	Files []ModelRegistry.File
+	// ReferencedBy are the profile files that use the model.
+	ReferencedBy []string
+}
+
+// Known returns true if the entry is a model of the registry.
+func (e CacheEntry) Known() bool {
+	return e.Family != ""
+}
+
+// Name returns the model name of the registry, or the path of unknown entries.
+func (e CacheEntry) Name() string {
+	if e.Known() {
+		return e.Family + " " + e.Model
+	}
+	return filepath.ToSlash(e.Paths[0])
+}
+
+// CacheInfo is the content of the model cache.
+type CacheInfo struct {
+	Dir     string
+	Entries []CacheEntry
+	// Size is the disk usage of all entries in bytes.
+	Size int64
+	// FreeSpace is the free space of the drive of the cache in bytes.
+	FreeSpace uint64
+}
+
+// splitModelName splits a registry model name like small.en_float16 into size and precision.
+func splitModelName(name string) (size, precision string) {
+	for _, suffix := range precisionSuffixes {
+		if strings.HasSuffix(name, "_"+suffix) && len(name) > len(suffix)+1 {
+			return strings.TrimSuffix(name, "_"+suffix), suffix
+		}
+	}
+	return name, ""
+}
+
+// cacheNames returns the names a downloaded file can have in the cache: the file, its finished marker and the extracted directory.
+func cacheNames(file ModelRegistry.File) []string {
+	fileName := file.FileName()
+	names := []string{fileName, fileName + ".finished"}
+	for _, ext := range []string{".zip", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(strings.ToLower(fileName), ext) {
+			names = append(names, fileName[:len(fileName)-len(ext)])
+		}
+	}
+	return names
+}
+
+// modelReference is a model type with size and precision used by a profile.
+type modelReference struct {
+	category  string
+	modelType string
+	size      string
+	precision string
+}
+
+// profileModelReferences returns the models a profile loads, including the second whisper model of the realtime transcription.
+func profileModelReferences(profile Settings.Conf) []modelReference {
+	var references []modelReference
+	if profile.Stt_type != "" {
+		references = append(references, modelReference{ModelRegistry.CategorySTT, profile.Stt_type, profile.Model, profile.Whisper_precision})
+		if profile.Realtime && profile.Realtime_whisper_model != "" {
+			precision := profile.Realtime_whisper_precision
+			if precision == "" {
+				precision = profile.Whisper_precision
+			}
+			references = append(references, modelReference{ModelRegistry.CategorySTT, profile.Stt_type, profile.Realtime_whisper_model, precision})
+		}
+	}
+	if profile.Txt_translator != "" {
+		references = append(references, modelReference{ModelRegistry.CategoryTextTranslator, profile.Txt_translator, profile.Txt_translator_size, profile.Txt_translator_precision})
+	}
+	if profile.Tts_type != "" {
+		// tts_model is the language and the model name
+		ttsModel := ""
+		if len(profile.Tts_model) > 1 {
+			ttsModel = profile.Tts_model[1]
+		}
+		references = append(references, modelReference{ModelRegistry.CategoryTTS, profile.Tts_type, ttsModel, ""})
+	}
+	if profile.Ocr_type != "" {
+		references = append(references, modelReference{ModelRegistry.CategoryOCR, profile.Ocr_type, "", profile.Ocr_precision})
+	}
+	return references
+}
+
+// references returns true if a model of a family is needed by a profile model.
+// Families without a model of the size (like tokenizers) are needed for all sizes,
+// and all precisions of a size are needed if the precision of the profile is converted when loading.
+// Without a size, all models of the family with the precision are needed.
+func (r modelReference) references(family ModelRegistry.Family, model ModelRegistry.Model) bool {
+	if !family.IsUsedBy(r.category, r.modelType) {
+		return false
+	}
+	sizeFound, precisionFound := false, false
+	for _, familyModel := range family.Models {
+		size, precision := splitModelName(familyModel.Name)
+		if r.size == "" || size == r.size {
+			sizeFound = true
+			precisionFound = precisionFound || (r.precision != "" && precision == r.precision)
+		}
+	}
+	if !sizeFound {
+		return true
+	}
+	size, precision := splitModelName(model.Name)
+	if r.size != "" && size != r.size {
+		return false
+	}
+	return !precisionFound || precision == r.precision
+}
+
+// LoadProfiles reads the profiles of a directory with their parent profiles, keyed by file name.
+// Profiles that can not be read are skipped.
+func LoadProfiles(profilesDir string) map[string]Settings.Conf {
+	profiles := map[string]Settings.Conf{}
+	files, err := os.ReadDir(profilesDir)
+	if err != nil {
+		return profiles
+	}
+	for _, file := range files {
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") || (!strings.HasSuffix(file.Name(), ".yaml") && !strings.HasSuffix(file.Name(), ".yml")) {
+			continue
+		}
+		layers, _ := Settings.LoadProfileLayers(Settings.DefaultConf, filepath.Join(profilesDir, file.Name()))
+		if layers != nil {
+			profiles[file.Name()] = layers.Effective()
+		}
+	}
+	return profiles
+}
+
+func diskUsage(path string) int64 {
+	var size int64
+	_ = filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil && !entry.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// ScanCache lists the models in the cache directory and the profiles that use them.
+// Files and directories are mapped to the registry by the file names of the downloads below the cache path of their family.
+// Everything else is listed as unknown entry per file or directory below the first level of the cache.
+func ScanCache(cacheDir string, registry *ModelRegistry.Manifest, profiles map[string]Settings.Conf) (CacheInfo, error) {
+	info := CacheInfo{Dir: cacheDir}
+	if _, err := os.Stat(cacheDir); os.IsNotExist(err) {
+		return info, nil
+	}
+	if freeSpace, err := Hardwareinfo.GetFreeSpace(cacheDir); err == nil {
+		info.FreeSpace = freeSpace
+	}
+
+	type registryModel struct {
+		family ModelRegistry.Family
+		model  ModelRegistry.Model
+	}
+	// registry models by directory and file name
+	registryFiles := map[string]map[string]registryModel{}
+	for _, family := range registry.Downloads {
+		dir := filepath.Clean(filepath.FromSlash(family.CachePath))
+		if registryFiles[dir] == nil {
+			registryFiles[dir] = map[string]registryModel{}
+		}
+		for _, model := range family.Models {
+			for _, file := range model.Files {
+				for _, name := range cacheNames(file) {
+					registryFiles[dir][strings.ToLower(name)] = registryModel{family: family, model: model}
+				}
+			}
+		}
+	}
+
+	entryIndex := map[string]int{}
+	addPath := func(dir, name string) {
+		relativePath := filepath.Join(dir, name)
+		size := diskUsage(filepath.Join(cacheDir, relativePath))
+		info.Size += size
+		match, ok := registryFiles[filepath.Clean(dir)][strings.ToLower(name)]
+		if !ok {
+			info.Entries = append(info.Entries, CacheEntry{Dir: dir, Paths: []string{relativePath}, Size: size})
+			return
+		}
+		key := match.family.Name + "\x00" + match.model.Name
+		index, exists := entryIndex[key]
+		if !exists {
+			modelSize, precision := splitModelName(match.model.Name)
+			info.Entries = append(info.Entries, CacheEntry{
+				Family:    match.family.Name,
+				Model:     match.model.Name,
+				ModelSize: modelSize,
+				Precision: precision,
+				Dir:       dir,
+				Files:     match.model.Files,
+			})
+			index = len(info.Entries) - 1
+			entryIndex[key] = index
+		}
+		info.Entries[index].Paths = append(info.Entries[index].Paths, relativePath)
+		info.Entries[index].Size += size
+	}
+
+	rootEntries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		return info, err
+	}
+	for _, rootEntry := range rootEntries {
+		_, isCachePath := registryFiles[rootEntry.Name()]
+		_, isRootModel := registryFiles["."][strings.ToLower(rootEntry.Name())]
+		if !rootEntry.IsDir() || (isRootModel && !isCachePath) {
+			addPath("", rootEntry.Name())
+			continue
+		}
+		children, err := os.ReadDir(filepath.Join(cacheDir, rootEntry.Name()))
+		if err != nil {
+			return info, err
+		}
+		for _, child := range children {
+			addPath(rootEntry.Name(), child.Name())
+		}
+	}
+
+	profileFiles := make([]string, 0, len(profiles))
+	for profileFile := range profiles {
+		profileFiles = append(profileFiles, profileFile)
+	}
+	slices.Sort(profileFiles)
+	for i := range info.Entries {
+		entry := &info.Entries[i]
+		if !entry.Known() {
+			continue
+		}
+		family, _ := registry.Family(entry.Family)
+		model, _ := family.Model(entry.Model)
+		for _, profileFile := range profileFiles {
+			for _, reference := range profileModelReferences(profiles[profileFile]) {
+				if reference.references(family, model) {
+					entry.ReferencedBy = append(entry.ReferencedBy, profileFile)
+					break
+				}
+			}
+		}
+	}
+
+	slices.SortStableFunc(info.Entries, func(a, b CacheEntry) int {
+		if a.Known() != b.Known() {
+			if a.Known() {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(strings.ToLower(a.Name()), strings.ToLower(b.Name()))
+	})
+	return info, nil
+}
+
+// VerifyCacheEntry checks the downloaded files of a model against the checksums of the registry.
+// Returns ErrNothingToVerify if no downloaded file with checksum is left in the cache.
+func VerifyCacheEntry(cacheDir string, entry CacheEntry) error {
+	verified := 0
+	var errs []error
+	for _, file := range entry.Files {
+		if file.Checksum == "" {
+			continue
+		}
+		fileName := filepath.Join(cacheDir, entry.Dir, file.FileName())
+		if stat, err := os.Stat(fileName); err != nil || stat.IsDir() {
+			continue
+		}
+		verified++
+		if err := Updater.CheckFileHash(fileName, file.Checksum); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", file.FileName(), err))
+		}
+	}
+	if verified == 0 {
+		return ErrNothingToVerify
+	}
+	return errors.Join(errs...)
+}
+
+// DeleteCacheEntry removes the files and directories of an entry from the cache.
+func DeleteCacheEntry(cacheDir string, entry CacheEntry) error {
+	var errs []error
+	for _, relativePath := range entry.Paths {
+		if relativePath == "" || relativePath == "." || strings.HasPrefix(filepath.Clean(relativePath), "..") {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(cacheDir, relativePath)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package ModelDownloader
+
+import "os"
+
+// createDirLink links a directory to a target directory.
+func createDirLink(target, link string) error {
+	return os.Symlink(target, link)
+}
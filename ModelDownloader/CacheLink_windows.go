@@ -0,0 +1,20 @@
+//go:build windows
+
+package ModelDownloader
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"whispering-tiger-ui/Utilities"
+)
+
+// createDirLink links a directory to a target directory. Junctions work across drives and do not need administrator rights.
+func createDirLink(target, link string) error {
+	cmd := exec.Command("cmd", "/c", "mklink", "/J", link, target)
+	Utilities.ProcessHideWindowAttr(cmd)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("creating junction %s: %w: %s", link, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
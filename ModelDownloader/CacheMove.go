@@ -0,0 +1,235 @@
+package ModelDownloader
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"whispering-tiger-ui/Utilities/Hardwareinfo"
+)
+
+// ErrDownloadsRunning is returned when the cache is moved while downloads write into it.
+var ErrDownloadsRunning = errors.New("downloads are running, pause or wait for them before moving the cache")
+
+// Busy returns true while a download is running.
+func (m *Manager) Busy() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, job := range m.jobs {
+		job.mutex.Lock()
+		running := job.running
+		job.mutex.Unlock()
+		if running {
+			return true
+		}
+	}
+	return false
+}
+
+// existingParent returns the path or its first existing parent directory, used to check the free space of a new directory.
+func existingParent(path string) string {
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
+
+func isEmptyDir(path string) bool {
+	entries, err := os.ReadDir(path)
+	return err == nil && len(entries) == 0
+}
+
+// linkDir and renameDir create the link of the cache directory and move it on the same drive, replaced in tests.
+var (
+	linkDir   = createDirLink
+	renameDir = os.Rename
+)
+
+// copyDir copies a directory tree. Links are copied as links, other files that are not regular files
+// (like named pipes or devices) are reported as error. onProgress is called with the copied and total bytes after every file.
+func copyDir(source, target string, total int64, onProgress func(copied, total int64)) error {
+	var copied int64
+	return filepath.WalkDir(source, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relativePath, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		targetPath := filepath.Join(target, relativePath)
+		switch {
+		case entry.IsDir():
+			return os.MkdirAll(targetPath, 0755)
+		case entry.Type()&fs.ModeSymlink != 0:
+			// links of the cache (e.g. of the Hugging Face cache) are relative and keep working in the new directory
+			linkTarget, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(linkTarget, targetPath)
+		case !entry.Type().IsRegular():
+			return fmt.Errorf("%s can not be copied, it is not a regular file (%s)", path, entry.Type())
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.OpenFile(targetPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		written, err := io.Copy(out, in)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+		copied += written
+		if onProgress != nil {
+			onProgress(copied, total)
+		}
+		return nil
+	})
+}
+
+// sameDir returns true if both paths resolve to the same existing directory.
+func sameDir(a, b string) bool {
+	aInfo, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bInfo, err := os.Stat(b)
+	return err == nil && os.SameFile(aInfo, bInfo)
+}
+
+// MoveCache moves the model cache to another directory, for example on another drive.
+// The cache directory is replaced by a link to the new directory, so the backend and the downloads keep using the same path.
+// The target directory must not exist or be empty. onProgress is called while files are copied to another drive.
+//
+// The link is created and verified before the old cache directory is deleted. If anything fails before,
+// the cache is restored as it was.
+func MoveCache(targetDir string, onProgress func(copied, total int64)) error {
+	if Default.Busy() {
+		return ErrDownloadsRunning
+	}
+	targetDir, err := filepath.Abs(targetDir)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(targetDir); err == nil && !isEmptyDir(targetDir) {
+		return fmt.Errorf("the directory %s is not empty", targetDir)
+	}
+	cacheLink, err := filepath.Abs(rootCacheFolder)
+	if err != nil {
+		return err
+	}
+
+	currentDir := ""
+	if _, err := os.Stat(cacheLink); err == nil {
+		if currentDir, err = filepath.EvalSymlinks(cacheLink); err != nil {
+			return err
+		}
+		if currentDir, err = filepath.Abs(currentDir); err != nil {
+			return err
+		}
+	}
+	// renamed is set if the cache directory was renamed instead of copied, rollback renames it back
+	renamed := false
+	rollback := func() {}
+	if currentDir != "" {
+		if strings.EqualFold(currentDir, targetDir) {
+			return nil
+		}
+		if relativePath, err := filepath.Rel(currentDir, targetDir); err == nil && !strings.HasPrefix(relativePath, "..") {
+			return errors.New("the cache can not be moved into itself")
+		}
+
+		size := diskUsage(currentDir)
+		freeSpace, err := Hardwareinfo.GetFreeSpace(existingParent(targetDir))
+		if err == nil && freeSpace < uint64(size) {
+			return fmt.Errorf("not enough free space in %s: %d bytes needed, %d bytes free", targetDir, size, freeSpace)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(targetDir), 0755); err != nil {
+			return err
+		}
+		// an empty target directory is removed, the target is created by the rename or copy
+		_ = os.Remove(targetDir)
+		if renameDir(currentDir, targetDir) == nil {
+			renamed = true
+		} else if err := copyDir(currentDir, targetDir, size, onProgress); err != nil {
+			// renaming fails across drives, the files are copied instead and a partial copy is removed
+			_ = os.RemoveAll(targetDir)
+			return err
+		}
+		rollback = func() {
+			if renamed {
+				_ = os.Rename(targetDir, currentDir)
+			} else {
+				_ = os.RemoveAll(targetDir)
+			}
+		}
+	} else if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return err
+	}
+
+	// the cache directory, or the link of an earlier move, is kept until the new link works
+	oldCache := ""
+	if _, err := os.Lstat(cacheLink); err == nil {
+		oldCache = fmt.Sprintf("%s.old-%d", cacheLink, time.Now().UnixNano())
+		if err := os.Rename(cacheLink, oldCache); err != nil {
+			rollback()
+			return err
+		}
+	}
+	err = linkDir(targetDir, cacheLink)
+	if err == nil && !sameDir(cacheLink, targetDir) {
+		err = fmt.Errorf("the link %s does not point to %s", cacheLink, targetDir)
+	}
+	if err != nil {
+		if _, statErr := os.Lstat(cacheLink); statErr == nil {
+			_ = os.Remove(cacheLink)
+		}
+		if oldCache != "" {
+			_ = os.Rename(oldCache, cacheLink)
+		}
+		rollback()
+		return err
+	}
+
+	// the cache works with the new directory, the old one is deleted last
+	var errs []error
+	if oldCache != "" {
+		if currentDir == cacheLink {
+			// the copied cache directory
+			errs = append(errs, os.RemoveAll(oldCache))
+		} else {
+			// only the link of an earlier move
+			errs = append(errs, os.Remove(oldCache))
+		}
+	}
+	if currentDir != "" && currentDir != cacheLink && !renamed {
+		errs = append(errs, os.RemoveAll(currentDir))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("the cache was moved, but the old cache could not be deleted: %w", err)
+	}
+	return nil
+}
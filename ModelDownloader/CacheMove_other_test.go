@@ -0,0 +1,21 @@
+//go:build !windows
+
+package ModelDownloader
+
+import (
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestCopyDirSpecialFile(t *testing.T) {
+	source := t.TempDir()
+	if err := syscall.Mkfifo(filepath.Join(source, "pipe"), 0644); err != nil {
+		t.Skip("mkfifo not supported:", err)
+	}
+	err := copyDir(source, filepath.Join(t.TempDir(), "target"), 0, nil)
+	if err == nil || !strings.Contains(err.Error(), "not a regular file") {
+		t.Errorf("copyDir() error = %v, want not a regular file", err)
+	}
+}
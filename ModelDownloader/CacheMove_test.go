@@ -0,0 +1,156 @@
+package ModelDownloader
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writeTestCache creates a cache directory with a model file and a relative link like the Hugging Face cache.
+func writeTestCache(t *testing.T, dir string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(dir, "family", "blobs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "family", "blobs", "model.bin"), []byte("weights"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" {
+		if err := os.Symlink(filepath.Join("blobs", "model.bin"), filepath.Join(dir, "family", "model.bin")); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func checkTestCache(t *testing.T, dir string) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, "family", "blobs", "model.bin"))
+	if err != nil || string(data) != "weights" {
+		t.Fatalf("model file in %s = %q, %v", dir, data, err)
+	}
+	if runtime.GOOS != "windows" {
+		linkTarget, err := os.Readlink(filepath.Join(dir, "family", "model.bin"))
+		if err != nil || linkTarget != filepath.Join("blobs", "model.bin") {
+			t.Fatalf("link in %s = %q, %v", dir, linkTarget, err)
+		}
+	}
+}
+
+func TestCopyDir(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "source")
+	writeTestCache(t, source)
+	target := filepath.Join(t.TempDir(), "target")
+
+	var progress []int64
+	if err := copyDir(source, target, 7, func(copied, total int64) { progress = append(progress, copied) }); err != nil {
+		t.Fatalf("copyDir() error = %v", err)
+	}
+	checkTestCache(t, target)
+	if len(progress) != 1 || progress[0] != 7 {
+		t.Errorf("progress = %v, want [7]", progress)
+	}
+}
+
+func TestMoveCache(t *testing.T) {
+	tests := []struct {
+		name string
+		// linked moves the cache into a directory first, so the cache directory is a link of an earlier move
+		linked  bool
+		noCache bool
+		// copied moves the cache like to another drive, where renaming fails
+		copied   bool
+		linkErr  error
+		wantErr  bool
+		wantMove bool
+	}{
+		{name: "cache directory", wantMove: true},
+		{name: "linked cache", linked: true, wantMove: true},
+		{name: "no cache yet", noCache: true, wantMove: true},
+		{name: "copied cache directory", copied: true, wantMove: true},
+		{name: "copied linked cache", linked: true, copied: true, wantMove: true},
+		{name: "link fails", linkErr: errors.New("link failed"), wantErr: true},
+		{name: "link of linked cache fails", linked: true, linkErr: errors.New("link failed"), wantErr: true},
+		{name: "link of copied cache fails", copied: true, linkErr: errors.New("link failed"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.linked && runtime.GOOS == "windows" {
+				t.Skip("directory links need junctions on windows")
+			}
+			workDir := t.TempDir()
+			t.Chdir(workDir)
+			cacheDir := filepath.Join(workDir, rootCacheFolder)
+			if !tt.noCache {
+				writeTestCache(t, cacheDir)
+			}
+			if tt.linked {
+				if err := MoveCache(filepath.Join(workDir, "first"), nil); err != nil {
+					t.Fatalf("first MoveCache() error = %v", err)
+				}
+			}
+			if tt.copied {
+				renameDir = func(oldPath, newPath string) error { return errors.New("different drive") }
+				t.Cleanup(func() { renameDir = os.Rename })
+			}
+			if tt.linkErr != nil {
+				linkDir = func(target, link string) error { return tt.linkErr }
+				t.Cleanup(func() { linkDir = createDirLink })
+			}
+
+			target := filepath.Join(t.TempDir(), "moved")
+			err := MoveCache(target, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MoveCache() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			resolved, err := filepath.EvalSymlinks(cacheDir)
+			if err != nil {
+				t.Fatalf("cache directory missing after MoveCache(): %v", err)
+			}
+			if !tt.noCache {
+				checkTestCache(t, cacheDir)
+			}
+			if tt.wantMove {
+				if !sameDir(resolved, target) {
+					t.Errorf("cache resolves to %s, want %s", resolved, target)
+				}
+			} else if _, err := os.Stat(target); err == nil && !isEmptyDir(target) {
+				t.Errorf("target %s was not cleaned up", target)
+			}
+			if tt.linked && tt.wantMove {
+				if _, err := os.Stat(filepath.Join(workDir, "first")); !os.IsNotExist(err) {
+					t.Errorf("old cache directory still exists: %v", err)
+				}
+			}
+			entries, _ := os.ReadDir(workDir)
+			for _, entry := range entries {
+				if strings.HasPrefix(entry.Name(), rootCacheFolder+".old-") {
+					t.Errorf("old cache %s was not removed", entry.Name())
+				}
+			}
+		})
+	}
+}
+
+func TestMoveCacheChecks(t *testing.T) {
+	workDir := t.TempDir()
+	t.Chdir(workDir)
+	writeTestCache(t, rootCacheFolder)
+
+	notEmpty := filepath.Join(workDir, "not-empty")
+	if err := os.MkdirAll(notEmpty, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(notEmpty, "file"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, target := range []string{notEmpty, filepath.Join(rootCacheFolder, "inside")} {
+		if err := MoveCache(target, nil); err == nil {
+			t.Errorf("MoveCache(%s) error = nil", target)
+		}
+	}
+	checkTestCache(t, rootCacheFolder)
+}
@@ -0,0 +1,173 @@
+package ModelDownloader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"whispering-tiger-ui/ModelRegistry"
+	"whispering-tiger-ui/Settings"
+)
+
+func checksum(data string) string {
+	hash := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(hash[:])
+}
+
+// testRegistry has a whisper family with two sizes in two precisions, a tokenizer without sizes,
+// a tts family and an ocr family with two precisions. The content of every file is its name.
+func testRegistry() *ModelRegistry.Manifest {
+	model := func(name string, files ...string) ModelRegistry.Model {
+		model := ModelRegistry.Model{Name: name}
+		for _, file := range files {
+			model.Files = append(model.Files, ModelRegistry.File{Name: file, Mirrors: []string{"https://example.com/" + file}, Checksum: checksum(file)})
+		}
+		return model
+	}
+	return &ModelRegistry.Manifest{Downloads: []ModelRegistry.Family{
+		{
+			Name: "whisper", CachePath: "whisper",
+			UsedBy: []ModelRegistry.ModelTypeRef{{Category: ModelRegistry.CategorySTT, Type: "faster_whisper"}},
+			Models: []ModelRegistry.Model{
+				model("tiny_float16", "tiny-float16.bin"), model("tiny_float32", "tiny-float32.bin"),
+				model("small_float16", "small-float16.bin"), model("small_float32", "small-float32.bin"),
+			},
+		},
+		{
+			Name: "tokenizer", CachePath: "whisper",
+			UsedBy: []ModelRegistry.ModelTypeRef{{Category: ModelRegistry.CategorySTT, Type: "faster_whisper"}},
+			Models: []ModelRegistry.Model{model("default", "tokenizer.json")},
+		},
+		{
+			Name: "silero", CachePath: "tts",
+			UsedBy: []ModelRegistry.ModelTypeRef{{Category: ModelRegistry.CategoryTTS, Type: "silero"}},
+			Models: []ModelRegistry.Model{model("v3_en", "v3_en.pt"), model("v3_de", "v3_de.pt")},
+		},
+		{
+			Name: "easyocr", CachePath: "ocr",
+			UsedBy: []ModelRegistry.ModelTypeRef{{Category: ModelRegistry.CategoryOCR, Type: "easyocr"}},
+			Models: []ModelRegistry.Model{model("default_float16", "ocr-float16.bin"), model("default_float32", "ocr-float32.bin")},
+		},
+	}}
+}
+
+func TestProfileModelReferences(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile Settings.Conf
+		want    []string
+	}{
+		{
+			name:    "stt model",
+			profile: Settings.Conf{Stt_type: "faster_whisper", Model: "small", Whisper_precision: "float16"},
+			want:    []string{"whisper small_float16", "tokenizer default"},
+		},
+		{
+			name:    "converted precision",
+			profile: Settings.Conf{Stt_type: "faster_whisper", Model: "small", Whisper_precision: "int8"},
+			want:    []string{"whisper small_float16", "whisper small_float32", "tokenizer default"},
+		},
+		{
+			name:    "realtime model",
+			profile: Settings.Conf{Stt_type: "faster_whisper", Model: "small", Whisper_precision: "float16", Realtime: true, Realtime_whisper_model: "tiny", Realtime_whisper_precision: "float32"},
+			want:    []string{"whisper tiny_float32", "whisper small_float16", "tokenizer default"},
+		},
+		{
+			name:    "realtime model with the precision of the main model",
+			profile: Settings.Conf{Stt_type: "faster_whisper", Model: "small", Whisper_precision: "float16", Realtime: true, Realtime_whisper_model: "tiny"},
+			want:    []string{"whisper tiny_float16", "whisper small_float16", "tokenizer default"},
+		},
+		{
+			name:    "realtime disabled",
+			profile: Settings.Conf{Stt_type: "faster_whisper", Model: "small", Whisper_precision: "float16", Realtime_whisper_model: "tiny", Realtime_whisper_precision: "float32"},
+			want:    []string{"whisper small_float16", "tokenizer default"},
+		},
+		{
+			name:    "tts model",
+			profile: Settings.Conf{Tts_type: "silero", Tts_model: []string{"en", "v3_en"}},
+			want:    []string{"silero v3_en"},
+		},
+		{
+			name:    "tts without model",
+			profile: Settings.Conf{Tts_type: "silero"},
+			want:    []string{"silero v3_en", "silero v3_de"},
+		},
+		{
+			name:    "ocr precision",
+			profile: Settings.Conf{Ocr_type: "easyocr", Ocr_precision: "float16"},
+			want:    []string{"easyocr default_float16"},
+		},
+		{
+			name:    "ocr converted precision",
+			profile: Settings.Conf{Ocr_type: "easyocr", Ocr_precision: "int8"},
+			want:    []string{"easyocr default_float16", "easyocr default_float32"},
+		},
+	}
+	registry := testRegistry()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, family := range registry.Downloads {
+				for _, model := range family.Models {
+					for _, reference := range profileModelReferences(tt.profile) {
+						if reference.references(family, model) {
+							got = append(got, family.Name+" "+model.Name)
+							break
+						}
+					}
+				}
+			}
+			slices.Sort(got)
+			slices.Sort(tt.want)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("referenced models = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	for _, name := range []string{"whisper/tiny-float32.bin", "whisper/tiny-float32.bin.finished", "whisper/small-float16.bin", "whisper/unknown.bin", "other.txt"} {
+		fileName := filepath.Join(cacheDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fileName, []byte(filepath.Base(name)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	profiles := map[string]Settings.Conf{
+		"main.yaml":     {Stt_type: "faster_whisper", Model: "small", Whisper_precision: "float16"},
+		"realtime.yaml": {Stt_type: "faster_whisper", Model: "small", Whisper_precision: "float16", Realtime: true, Realtime_whisper_model: "tiny", Realtime_whisper_precision: "float32"},
+	}
+
+	info, err := ScanCache(cacheDir, testRegistry(), profiles)
+	if err != nil {
+		t.Fatalf("ScanCache() error = %v", err)
+	}
+	want := map[string][]string{
+		"whisper small_float16": {"main.yaml", "realtime.yaml"},
+		"whisper tiny_float32":  {"realtime.yaml"},
+		"whisper/unknown.bin":   nil,
+		"other.txt":             nil,
+	}
+	if len(info.Entries) != len(want) {
+		t.Fatalf("ScanCache() entries = %+v, want %v", info.Entries, want)
+	}
+	for _, entry := range info.Entries {
+		referencedBy, ok := want[entry.Name()]
+		if !ok {
+			t.Errorf("unexpected entry %s", entry.Name())
+			continue
+		}
+		if !slices.Equal(entry.ReferencedBy, referencedBy) {
+			t.Errorf("%s referenced by %v, want %v", entry.Name(), entry.ReferencedBy, referencedBy)
+		}
+	}
+	if tiny := info.Entries[1]; tiny.Name() != "whisper tiny_float32" || len(tiny.Paths) != 2 {
+		t.Errorf("tiny entry = %+v, want the file and its finished marker", tiny)
+	}
+}
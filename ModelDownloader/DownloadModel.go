@@ -1,285 +1,62 @@
 package ModelDownloader
 
 import (
+	"errors"
 	"fmt"
 	"fyne.io/fyne/v2"
-	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
-	"fyne.io/fyne/v2/widget"
-	"github.com/dustin/go-humanize"
-	"math/rand"
-	"os"
 	"path/filepath"
-	"strings"
-	"sync"
-	"time"
-	"whispering-tiger-ui/Logging"
-	"whispering-tiger-ui/Updater"
+	"whispering-tiger-ui/ModelRegistry"
 	"whispering-tiger-ui/Utilities"
 )
 
 const rootCacheFolder = ".cache"
 
-// Global variables to track active downloads.
-var (
-	activeDownloads      = []string{}
-	activeDownloadsMutex sync.Mutex
-)
-
-func isDownloading(target string) bool {
-	activeDownloadsMutex.Lock()
-	defer activeDownloadsMutex.Unlock()
-	for _, v := range activeDownloads {
-		if v == target {
-			return true
-		}
+// DownloadFile queues a download in the download manager and waits until it is finished.
+// A download of the same target that is already queued is not started again, but waited for.
+func DownloadFile(urls []string, targetDir string, checksum string, title string, extractFormat string) error {
+	job, err := Default.Add(urls, targetDir, checksum, title, extractFormat)
+	if err != nil {
+		return err
 	}
-	return false
-}
-
-func addDownload(target string) {
-	activeDownloadsMutex.Lock()
-	defer activeDownloadsMutex.Unlock()
-	activeDownloads = append(activeDownloads, target)
+	return job.Wait()
 }
 
-func removeDownload(target string) {
-	activeDownloadsMutex.Lock()
-	defer activeDownloadsMutex.Unlock()
-	for i, v := range activeDownloads {
-		if v == target {
-			activeDownloads = append(activeDownloads[:i], activeDownloads[i+1:]...)
-			break
-		}
+// DownloadModel downloads the files of a model of the model registry into the cache path of its family.
+func DownloadModel(familyName string, modelName string) error {
+	family, ok := ModelRegistry.Current().Family(familyName)
+	if !ok {
+		return fmt.Errorf("unknown model family: %s", familyName)
 	}
-}
-
-func DownloadFile(urls []string, targetDir string, checksum string, title string, extractFormat string) error {
-	// If the file is already being downloaded, skip and return.
-	if isDownloading(targetDir) {
-		return fmt.Errorf("File is already being downloaded: %s", targetDir)
+	model, ok := family.Model(modelName)
+	if !ok {
+		return fmt.Errorf("unknown model %s of %s", modelName, familyName)
 	}
-	addDownload(targetDir)
-	// Ensure removal on exit.
-	defer removeDownload(targetDir)
 
 	// find active window
-	window, _ := Utilities.GetCurrentMainWindow("Downloading " + title)
-
-	// select download url
-	randomUrlIndex := rand.Int() % len(urls)
-	downloadUrl := urls[randomUrlIndex]
-
-	// get file name from download url
-	filename := downloadUrl[strings.LastIndex(downloadUrl, "/")+1:]
-
-	// create downloader
-	downloader := Updater.Download{
-		Url:                 downloadUrl,
-		FallbackUrls:        urls,
-		Filepath:            targetDir,
-		ConcurrentDownloads: 4,
-		ChunkSize:           15 * 1024 * 1024, // 15 MB
-	}
-
-	// create download dialog
-	statusBar := widget.NewProgressBar()
-	statusBarContainer := container.NewVBox(statusBar)
+	window, _ := Utilities.GetCurrentMainWindow("Downloading " + familyName + " " + modelName)
 
-	dialogTitlePart := filename
-	if title != "" {
-		dialogTitlePart = title + " [" + filename + "]"
-	}
-	downloadDialog := dialog.NewCustom("Downloading "+dialogTitlePart, "Hide (Download will continue)", statusBarContainer, window)
-	fyne.Do(func() {
-		downloadDialog.Show()
-	})
-	downloadingLabel := widget.NewLabel("Downloading... ")
-
-	reOpenAfterHide := false
-	downloadDialog.SetOnClosed(func() {
-		reOpenAfterHide = true
-	})
-
-	// is filename a zip file?
-	needsExtract := false
-	extractType := ""
-	if strings.HasSuffix(filename, ".zip") {
-		needsExtract = true
-		extractType = "zip"
-	} else if strings.HasSuffix(filename, ".tar.gz") {
-		needsExtract = true
-		extractType = "tar.gz"
-	}
-	if extractFormat == "none" {
-		needsExtract = false
-		extractType = ""
-	} else if extractFormat != "" {
-		needsExtract = true
-		extractType = extractFormat
-	}
-
-	// get subdomain from download url
-	subdomain := downloadUrl[strings.Index(downloadUrl, "://")+3 : strings.Index(downloadUrl, ".")]
-
-	//appExec, _ := os.Executable()
-
-	downloadTargetDir := filepath.Dir(targetDir)
-	os.MkdirAll(downloadTargetDir, 0755)
-	//downloadTargetFile := filepath.Join(downloadTargetDir, filename)
-
-	// guard for adding the infinite bar only once
-	var infiniteOnce sync.Once
-
-	downloader.WriteCounter.OnProgress = func(progress, total uint64, speed float64) {
-		if int64(total) == -1 {
-			infiniteOnce.Do(func() {
-				fyne.Do(func() {
-					statusBarContainer.Remove(statusBar)
-					statusBarContainer.Add(widget.NewProgressBarInfinite())
-					statusBarContainer.Refresh()
-				})
-			})
-		} else {
-			fyne.Do(func() {
-				if total < 0 {
-					total = 0
-				}
-				statusBar.Max = float64(total)
-				if progress < 0 {
-					progress = 0
-				}
-				statusBar.SetValue(float64(progress))
-			})
-			resumeStatusText := ""
-			if downloader.IsResuming() {
-				resumeStatusText = " (Resume)"
-			}
-
-			speedStr := ""
-			if speed < 1024 {
-				speedStr = fmt.Sprintf("%.2f B/s", speed)
-			} else if speed < 1024*1024 {
-				speedStr = fmt.Sprintf("%.2f KiB/s", speed/1024)
-			} else {
-				speedStr = fmt.Sprintf("%.2f MiB/s", speed/(1024*1024))
-			}
-			fyne.Do(func() {
-				downloadingLabel.SetText("Downloading from " + subdomain + "... " + humanize.Bytes(total) + " (" + speedStr + ") " + resumeStatusText)
-			})
+	// queue all files first, so they are downloaded in parallel
+	var jobs []*Job
+	for _, file := range model.Files {
+		targetFile := filepath.Join(rootCacheFolder, family.CachePath, file.FileName())
+		job, err := Default.Add(file.Mirrors, targetFile, file.Checksum, familyName+" "+modelName, file.Extract)
+		if err != nil {
+			return err
 		}
+		jobs = append(jobs, job)
 	}
 
-	fyne.Do(func() {
-		statusBarContainer.Add(downloadingLabel)
-		statusBarContainer.Refresh()
-	})
-	err := downloader.DownloadFile(3)
-	if err != nil {
-		Logging.CaptureException(err)
-		fyne.Do(func() {
-			dialog.ShowError(err, window)
-		})
-		return err
-	}
-
-	// check if the file has the correct hash
-	if checksum != "" {
-		fyne.Do(func() {
-			statusBarContainer.Add(widget.NewLabel("Checking checksum..."))
-		})
-		if err := Updater.CheckFileHash(targetDir, checksum); err != nil {
-			fmt.Printf("Error: %s\n", err.Error())
-			fyne.Do(func() {
-				dialog.ShowError(err, window)
-				statusBarContainer.Add(widget.NewLabel("Checksum check failed. Please delete temporary file and download again. If it still fails, please contact support."))
-			})
-			return err
+	var downloadErr error
+	for _, job := range jobs {
+		if err := job.Wait(); err != nil && downloadErr == nil {
+			downloadErr = err
 		}
 	}
-
-	// wait a bit before trying to extract
-	if needsExtract {
-		time.Sleep(1 * time.Second)
+	if downloadErr != nil && !errors.Is(downloadErr, ErrCancelled) {
 		fyne.Do(func() {
-			statusBarContainer.Add(widget.NewLabel("Extracting..."))
-			statusBarContainer.Refresh()
+			dialog.ShowError(downloadErr, window)
 		})
-		if extractType == "zip" {
-			err = Updater.Unzip(targetDir, downloadTargetDir)
-		} else if extractType == "tar.gz" {
-			err = Updater.Untar(targetDir, downloadTargetDir)
-		}
-		if err != nil {
-			fyne.Do(func() {
-				dialog.ShowError(err, window)
-			})
-			return err
-		}
-		err = downloader.CreateFinishedFile(".finished", 5, 3*time.Second)
-		if err != nil {
-			fyne.Do(func() {
-				dialog.ShowError(err, window)
-			})
-			return err
-		}
-
-		//err = os.Rename(targetDir, targetDir+".finished")
-		//if err != nil {
-		//	dialog.ShowError(err, window)
-		//	return err
-		//}
-
-		//err = os.Remove(downloadTargetFile)
-		//if err != nil {
-		//	dialog.ShowError(err, window)
-		//	return err
-		//}
-	} else {
-		//err = os.Rename(targetDir, targetDir+".finished")
-		err = downloader.CreateFinishedFile(".finished", 5, 3*time.Second)
-		if err != nil {
-			fyne.Do(func() {
-				dialog.ShowError(err, window)
-			})
-			return err
-		}
-	}
-
-	fyne.Do(func() {
-		statusBarContainer.Add(widget.NewLabel("Finished."))
-		downloadDialog.SetDismissText("Close")
-		downloadDialog.Refresh()
-		if reOpenAfterHide {
-			downloadDialog.Show()
-		} else {
-			downloadDialog.Hide()
-		}
-	})
-
-	fyne.Do(func() {
-		statusBarContainer.Refresh()
-	})
-
-	return nil
-}
-
-func (c *modelNameLinksMap) DownloadModel(modelName string, modelType string) error {
-	// get model links from map
-	modelLinksMap := (*c)[modelName].modelLink
-	modelCachePath := (*c)[modelName].cachePath
-
-	modelLinks := modelLinksMap[modelType]
-	modelChecksum := modelLinks.checksum
-
-	// find active window
-	window, _ := Utilities.GetCurrentMainWindow("Downloading " + modelName + " " + modelType)
-
-	err := DownloadFile(modelLinks.urls, modelCachePath, modelChecksum, modelName+" "+modelType, "")
-	if err != nil {
-		Logging.CaptureException(err)
-		dialog.ShowError(err, window)
 	}
-
-	return err
+	return downloadErr
 }
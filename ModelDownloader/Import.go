@@ -0,0 +1,320 @@
+package ModelDownloader
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"whispering-tiger-ui/ModelRegistry"
+	"whispering-tiger-ui/Updater"
+	"whispering-tiger-ui/Utilities/Hardwareinfo"
+)
+
+// ErrNothingToImport is returned if no file of the import source is a file of the model registry.
+var ErrNothingToImport = errors.New("no file of the model registry found")
+
+// ImportedFile is a downloaded file of a model that was imported into the cache.
+type ImportedFile struct {
+	Family string
+	Model  string
+	Name   string
+}
+
+// importSource is a file to import, either a file on disk or an entry of a model package.
+type importSource struct {
+	name    string
+	size    int64
+	path    string
+	zipFile *zip.File
+	// family and model are set for files of a model package.
+	family string
+	model  string
+}
+
+func (s importSource) open() (io.ReadCloser, error) {
+	if s.zipFile != nil {
+		return s.zipFile.Open()
+	}
+	return os.Open(s.path)
+}
+
+// importMatch is an import source with the registry file it was matched to.
+type importMatch struct {
+	source importSource
+	family ModelRegistry.Family
+	model  ModelRegistry.Model
+	file   ModelRegistry.File
+}
+
+func (m importMatch) target(cacheDir string) string {
+	return modelFilePath(cacheDir, m.family, m.file)
+}
+
+// packageSources returns the files of a model package manifest. fileSource resolves the file inside the package.
+func packageSources(manifest PackageManifest, fileSource func(packageFile PackageFile) (importSource, error)) ([]importSource, error) {
+	var sources []importSource
+	for _, packageFile := range manifest.Files {
+		source, err := fileSource(packageFile)
+		if err != nil {
+			return nil, err
+		}
+		source.name = packageFile.Name
+		source.family = manifest.Family
+		source.model = manifest.Model
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+// zipPackageSources returns the files of a model package zip file, or ErrNoModelPackage for other zip files.
+func zipPackageSources(reader *zip.Reader) ([]importSource, error) {
+	files := map[string]*zip.File{}
+	for _, file := range reader.File {
+		files[file.Name] = file
+	}
+	manifestFile, ok := files[packageManifestName]
+	if !ok {
+		return nil, ErrNoModelPackage
+	}
+	manifestReader, err := manifestFile.Open()
+	if err != nil {
+		return nil, err
+	}
+	manifestData, err := io.ReadAll(manifestReader)
+	manifestReader.Close()
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := readPackageManifest(manifestData)
+	if err != nil {
+		return nil, err
+	}
+	return packageSources(manifest, func(packageFile PackageFile) (importSource, error) {
+		file, ok := files[packageFile.File]
+		if !ok {
+			return importSource{}, fmt.Errorf("%s is missing in the model package", packageFile.File)
+		}
+		return importSource{size: int64(file.UncompressedSize64), zipFile: file}, nil
+	})
+}
+
+// dirSources returns the files of an extracted model package directory, or all files of any other directory.
+func dirSources(dir string) ([]importSource, error) {
+	if manifestData, err := os.ReadFile(filepath.Join(dir, packageManifestName)); err == nil {
+		manifest, err := readPackageManifest(manifestData)
+		if err != nil {
+			return nil, err
+		}
+		return packageSources(manifest, func(packageFile PackageFile) (importSource, error) {
+			fileName := filepath.Join(dir, filepath.FromSlash(packageFile.File))
+			if relativePath, err := filepath.Rel(dir, fileName); err != nil || strings.HasPrefix(relativePath, "..") {
+				return importSource{}, fmt.Errorf("invalid file path in model package: %s", packageFile.File)
+			}
+			stat, err := os.Stat(fileName)
+			if err != nil {
+				return importSource{}, fmt.Errorf("%s is missing in the model package", packageFile.File)
+			}
+			return importSource{size: stat.Size(), path: fileName}, nil
+		})
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var sources []importSource
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, importSource{name: entry.Name(), size: info.Size(), path: filepath.Join(dir, entry.Name())})
+	}
+	return sources, nil
+}
+
+// matchSource finds the registry file of an import source.
+// Files of a model package are looked up by their model, other files by their file name and verified by their checksum,
+// since different models can have files with the same name.
+func matchSource(registry *ModelRegistry.Manifest, source importSource) (importMatch, error) {
+	if source.family != "" {
+		family, model, err := registryModel(registry, source.family, source.model)
+		if err != nil {
+			return importMatch{}, err
+		}
+		for _, file := range model.Files {
+			if strings.EqualFold(file.FileName(), source.name) {
+				return importMatch{source: source, family: family, model: model, file: file}, nil
+			}
+		}
+		return importMatch{}, fmt.Errorf("%s is not a file of %s %s", source.name, source.family, source.model)
+	}
+
+	var candidates []importMatch
+	for _, family := range registry.Downloads {
+		for _, model := range family.Models {
+			for _, file := range model.Files {
+				if strings.EqualFold(file.FileName(), source.name) {
+					candidates = append(candidates, importMatch{source: source, family: family, model: model, file: file})
+				}
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return importMatch{}, ErrNothingToImport
+	}
+	var checksumErr error
+	for _, candidate := range candidates {
+		if candidate.file.Checksum == "" {
+			continue
+		}
+		if checksumErr = Updater.CheckFileHash(source.path, candidate.file.Checksum); checksumErr == nil {
+			return candidate, nil
+		}
+	}
+	if checksumErr == nil {
+		return importMatch{}, fmt.Errorf("%s: the model registry has no checksum to verify the file", source.name)
+	}
+	return importMatch{}, fmt.Errorf("%s: %w", source.name, checksumErr)
+}
+
+// copySource writes an import source to a temporary file next to the target.
+func copySource(source importSource, tempFile string) error {
+	in, err := source.open()
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(tempFile)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tempFile)
+	}
+	return err
+}
+
+// importFile copies a matched file into the cache, verifies it against the registry checksum,
+// extracts it like a download and creates the finished marker, so the model is used like a downloaded one.
+func importFile(cacheDir string, match importMatch) error {
+	target := match.target(cacheDir)
+	if Default.HasUnfinished(target) {
+		return fmt.Errorf("%s is currently downloaded", match.file.FileName())
+	}
+	if match.file.Checksum == "" {
+		return fmt.Errorf("%s: the model registry has no checksum to verify the file", match.file.FileName())
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if freeSpace, err := Hardwareinfo.GetFreeSpace(filepath.Dir(target)); err == nil && freeSpace < uint64(match.source.size) {
+		return fmt.Errorf("not enough free space for %s: %d bytes needed, %d bytes free", match.file.FileName(), match.source.size, freeSpace)
+	}
+
+	sameFile := false
+	if match.source.path != "" {
+		if sourceStat, err := os.Stat(match.source.path); err == nil {
+			if targetStat, err := os.Stat(target); err == nil {
+				sameFile = os.SameFile(sourceStat, targetStat)
+			}
+		}
+	}
+	if sameFile {
+		if err := Updater.CheckFileHash(target, match.file.Checksum); err != nil {
+			return fmt.Errorf("%s: %w", match.file.FileName(), err)
+		}
+	} else {
+		tempFile := target + ".import"
+		if err := copySource(match.source, tempFile); err != nil {
+			return err
+		}
+		if err := Updater.CheckFileHash(tempFile, match.file.Checksum); err != nil {
+			_ = os.Remove(tempFile)
+			return fmt.Errorf("%s: %w", match.file.FileName(), err)
+		}
+		_ = os.Remove(target + ".finished")
+		_ = os.Remove(target)
+		if err := os.Rename(tempFile, target); err != nil {
+			_ = os.Remove(tempFile)
+			return err
+		}
+	}
+
+	if format := archiveFormat(target, match.file.Extract); format != "" {
+		if err := extractArchive(target, format, nil); err != nil {
+			return fmt.Errorf("%s: %w", match.file.FileName(), err)
+		}
+	}
+	finishedFile := Updater.Download{Filepath: target}
+	return finishedFile.CreateFinishedFile(".finished", 5, 3*time.Second)
+}
+
+// ImportModel imports the downloaded files of models from a model package, a directory or a single downloaded file.
+// Directories can be extracted model packages or contain downloaded files, files that are not in the registry are skipped.
+// Every file is verified against the checksum of the registry before it is extracted into the cache path of its family.
+// onProgress is called with the name of each file before it is imported.
+func ImportModel(source, cacheDir string, registry *ModelRegistry.Manifest, onProgress func(fileName string)) ([]ImportedFile, error) {
+	stat, err := os.Stat(source)
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []importSource
+	fromPackage := false
+	if stat.IsDir() {
+		if sources, err = dirSources(source); err != nil {
+			return nil, err
+		}
+		_, err = os.Stat(filepath.Join(source, packageManifestName))
+		fromPackage = err == nil
+	} else {
+		sources = []importSource{{name: stat.Name(), size: stat.Size(), path: source}}
+		if zipReader, err := zip.OpenReader(source); err == nil {
+			defer zipReader.Close()
+			packageSources, err := zipPackageSources(&zipReader.Reader)
+			if err == nil {
+				sources = packageSources
+				fromPackage = true
+			} else if !errors.Is(err, ErrNoModelPackage) {
+				return nil, err
+			}
+		}
+	}
+
+	var imported []ImportedFile
+	var errs []error
+	for _, fileSource := range sources {
+		match, err := matchSource(registry, fileSource)
+		if errors.Is(err, ErrNothingToImport) && stat.IsDir() && !fromPackage {
+			// other files of the directory
+			continue
+		}
+		if err == nil {
+			if onProgress != nil {
+				onProgress(match.file.FileName())
+			}
+			err = importFile(cacheDir, match)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		imported = append(imported, ImportedFile{Family: match.family.Name, Model: match.model.Name, Name: match.file.FileName()})
+	}
+	if len(imported) == 0 && len(errs) == 0 {
+		return nil, ErrNothingToImport
+	}
+	return imported, errors.Join(errs...)
+}
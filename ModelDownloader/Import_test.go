@@ -0,0 +1,216 @@
+package ModelDownloader
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeTestPackageDir writes an extracted model package with the given manifest and files.
+func writeTestPackageDir(t *testing.T, manifest PackageManifest, files map[string]string) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "package")
+	manifestData, _ := json.Marshal(manifest)
+	files[packageManifestName] = string(manifestData)
+	for name, content := range files {
+		fileName := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fileName, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestImportModel(t *testing.T) {
+	tinyPackage := PackageManifest{FormatVersion: PackageFormatVersion, Family: "whisper", Model: "tiny_float32", Files: []PackageFile{{Name: "tiny-float32.bin", File: "files/tiny-float32.bin"}}}
+	withFiles := func(manifest PackageManifest, files ...PackageFile) PackageManifest {
+		manifest.Files = files
+		return manifest
+	}
+	tests := []struct {
+		name string
+		// source returns the file or directory to import
+		source    func(t *testing.T) string
+		want      []string
+		wantErr   string
+		wantFiles []string
+	}{
+		{
+			name: "package directory",
+			source: func(t *testing.T) string {
+				return writeTestPackageDir(t, tinyPackage, map[string]string{"files/tiny-float32.bin": "tiny-float32.bin"})
+			},
+			want:      []string{"whisper tiny_float32 tiny-float32.bin"},
+			wantFiles: []string{"whisper/tiny-float32.bin", "whisper/tiny-float32.bin.finished"},
+		},
+		{
+			name: "directory with downloaded files",
+			source: func(t *testing.T) string {
+				dir := t.TempDir()
+				for _, name := range []string{"small-float16.bin", "tokenizer.json", "readme.txt"} {
+					if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+						t.Fatal(err)
+					}
+				}
+				return dir
+			},
+			want:      []string{"tokenizer default tokenizer.json", "whisper small_float16 small-float16.bin"},
+			wantFiles: []string{"whisper/small-float16.bin", "whisper/small-float16.bin.finished", "whisper/tokenizer.json", "whisper/tokenizer.json.finished"},
+		},
+		{
+			name: "single file with wrong content",
+			source: func(t *testing.T) string {
+				fileName := filepath.Join(t.TempDir(), "tiny-float16.bin")
+				if err := os.WriteFile(fileName, []byte("broken"), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return fileName
+			},
+			wantErr: "hash does not match",
+		},
+		{
+			name: "package file with wrong content",
+			source: func(t *testing.T) string {
+				return writeTestPackageDir(t, tinyPackage, map[string]string{"files/tiny-float32.bin": "broken"})
+			},
+			wantErr: "hash does not match",
+		},
+		{
+			name: "package file outside the package",
+			source: func(t *testing.T) string {
+				manifest := withFiles(tinyPackage, PackageFile{Name: "tiny-float32.bin", File: "../tiny-float32.bin"})
+				dir := writeTestPackageDir(t, manifest, map[string]string{})
+				if err := os.WriteFile(filepath.Join(filepath.Dir(dir), "tiny-float32.bin"), []byte("tiny-float32.bin"), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return dir
+			},
+			wantErr: "invalid file path",
+		},
+		{
+			name: "package file name with path",
+			source: func(t *testing.T) string {
+				manifest := withFiles(tinyPackage, PackageFile{Name: "../tiny-float32.bin", File: "files/tiny-float32.bin"})
+				return writeTestPackageDir(t, manifest, map[string]string{"files/tiny-float32.bin": "tiny-float32.bin"})
+			},
+			wantErr: "invalid file name",
+		},
+		{
+			name: "package file name with backslash",
+			source: func(t *testing.T) string {
+				manifest := withFiles(tinyPackage, PackageFile{Name: `..\tiny-float32.bin`, File: "files/tiny-float32.bin"})
+				return writeTestPackageDir(t, manifest, map[string]string{"files/tiny-float32.bin": "tiny-float32.bin"})
+			},
+			wantErr: "invalid file name",
+		},
+		{
+			name: "file of another model",
+			source: func(t *testing.T) string {
+				manifest := withFiles(tinyPackage, PackageFile{Name: "small-float16.bin", File: "files/small-float16.bin"})
+				return writeTestPackageDir(t, manifest, map[string]string{"files/small-float16.bin": "small-float16.bin"})
+			},
+			wantErr: "is not a file of whisper tiny_float32",
+		},
+		{
+			name: "newer package format",
+			source: func(t *testing.T) string {
+				manifest := tinyPackage
+				manifest.FormatVersion = PackageFormatVersion + 1
+				return writeTestPackageDir(t, manifest, map[string]string{"files/tiny-float32.bin": "tiny-float32.bin"})
+			},
+			wantErr: ErrNewerPackageFormat.Error(),
+		},
+		{
+			name: "directory without model files",
+			source: func(t *testing.T) string {
+				dir := t.TempDir()
+				if err := os.WriteFile(filepath.Join(dir, "readme.txt"), nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return dir
+			},
+			wantErr: ErrNothingToImport.Error(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cacheDir := t.TempDir()
+			imported, err := ImportModel(tt.source(t), cacheDir, testRegistry(), nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ImportModel() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("ImportModel() error = %v", err)
+			}
+			var got []string
+			for _, file := range imported {
+				got = append(got, file.Family+" "+file.Model+" "+file.Name)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ImportModel() = %v, want %v", got, tt.want)
+			}
+
+			var files []string
+			_ = filepath.WalkDir(cacheDir, func(path string, entry os.DirEntry, err error) error {
+				if err == nil && !entry.IsDir() {
+					relativePath, _ := filepath.Rel(cacheDir, path)
+					files = append(files, filepath.ToSlash(relativePath))
+				}
+				return nil
+			})
+			if !slices.Equal(files, tt.wantFiles) {
+				t.Errorf("cache files = %v, want %v", files, tt.wantFiles)
+			}
+		})
+	}
+}
+
+func TestImportModelPackageZip(t *testing.T) {
+	registry := testRegistry()
+	sourceCache := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(sourceCache, "whisper"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sourceCache, "whisper", "tiny-float16.bin"), []byte("tiny-float16.bin"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	packageFile := filepath.Join(t.TempDir(), "tiny.zip")
+	if _, err := ExportModelPackageFile(packageFile, sourceCache, registry, "whisper", "tiny_float16", "test"); err != nil {
+		t.Fatalf("ExportModelPackageFile() error = %v", err)
+	}
+
+	cacheDir := t.TempDir()
+	imported, err := ImportModel(packageFile, cacheDir, registry, nil)
+	if err != nil || len(imported) != 1 || imported[0].Model != "tiny_float16" {
+		t.Fatalf("ImportModel() = %v, %v", imported, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(cacheDir, "whisper", "tiny-float16.bin")); err != nil || string(data) != "tiny-float16.bin" {
+		t.Errorf("imported file = %q, %v", data, err)
+	}
+
+	// a zip file without package manifest is imported as downloaded file, which is not in the registry
+	otherZip := filepath.Join(t.TempDir(), "other.zip")
+	file, err := os.Create(otherZip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zipWriter := zip.NewWriter(file)
+	if _, err := zipWriter.Create("model.bin"); err != nil {
+		t.Fatal(err)
+	}
+	_ = zipWriter.Close()
+	_ = file.Close()
+	if _, err := ImportModel(otherZip, t.TempDir(), registry, nil); !errors.Is(err, ErrNothingToImport) {
+		t.Errorf("ImportModel(other zip) error = %v, want %v", err, ErrNothingToImport)
+	}
+}
@@ -0,0 +1,603 @@
+package ModelDownloader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"whispering-tiger-ui/Logging"
+	"whispering-tiger-ui/Settings"
+	"whispering-tiger-ui/Updater"
+
+	"github.com/getsentry/sentry-go"
+)
+
+const downloadsFileName = "downloads.json"
+
+// DefaultMaxConcurrent is the number of downloads that run at the same time if not configured otherwise.
+const DefaultMaxConcurrent = 2
+
+type JobStatus string
+
+const (
+	StatusQueued      JobStatus = "queued"
+	StatusDownloading JobStatus = "downloading"
+	StatusVerifying   JobStatus = "verifying"
+	StatusExtracting  JobStatus = "extracting"
+	StatusPaused      JobStatus = "paused"
+	StatusDone        JobStatus = "done"
+	StatusFailed      JobStatus = "failed"
+	StatusCancelled   JobStatus = "cancelled"
+)
+
+// ErrCancelled is returned to everyone waiting for a download that was cancelled.
+var ErrCancelled = errors.New("download cancelled")
+
+// Job is a file download of the download queue.
+type Job struct {
+	ID   int
+	Urls []string
+	// Target is the path of the downloaded file. Archives are extracted into its directory.
+	Target        string
+	Checksum      string
+	Title         string
+	ExtractFormat string
+
+	mutex  sync.Mutex
+	status JobStatus
+	bytes  uint64
+	total  uint64
+	speed  float64
+	mirror string
+	err    error
+	// running is set while the download goroutine runs, which can be longer than the status is downloading after a pause.
+	running  bool
+	cancel   context.CancelFunc
+	finished chan struct{}
+	manager  *Manager
+}
+
+// JobState is a snapshot of the job state for displaying.
+type JobState struct {
+	Status JobStatus
+	Bytes  uint64
+	// Total is 0 until the file size is known and math.MaxUint64 if the server does not send it.
+	Total uint64
+	// Speed is the download speed in bytes per second.
+	Speed float64
+	// Mirror is the host name of the server the file is downloaded from.
+	Mirror string
+	Err    error
+}
+
+func (j *Job) State() JobState {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return JobState{Status: j.status, Bytes: j.bytes, Total: j.total, Speed: j.speed, Mirror: j.mirror, Err: j.err}
+}
+
+func (s JobState) sizeKnown() bool {
+	return s.Total > 0 && s.Total != math.MaxUint64
+}
+
+// Progress returns the progress between 0 and 1. Downloads without known size return 0 until they are done.
+func (s JobState) Progress() float64 {
+	if s.Status == StatusDone {
+		return 1
+	}
+	if !s.sizeKnown() {
+		return 0
+	}
+	return float64(s.Bytes) / float64(s.Total)
+}
+
+// ETA returns the estimated remaining download time. Returns false while the size or speed is unknown.
+func (s JobState) ETA() (time.Duration, bool) {
+	if s.Status != StatusDownloading || !s.sizeKnown() || s.Speed <= 0 || s.Bytes > s.Total {
+		return 0, false
+	}
+	return time.Duration(float64(s.Total-s.Bytes) / s.Speed * float64(time.Second)), true
+}
+
+// Finished returns true for jobs that will not change anymore.
+func (s JobState) Finished() bool {
+	return s.Status == StatusDone || s.Status == StatusFailed || s.Status == StatusCancelled
+}
+
+// update changes the job state. Status changes are persisted, progress updates are not.
+func (j *Job) update(statusChange bool, change func()) {
+	j.mutex.Lock()
+	change()
+	j.mutex.Unlock()
+	j.manager.jobChanged(statusChange)
+}
+
+// finish sets a final status and releases everyone waiting for the job. Must be called with the job mutex held.
+func (j *Job) finish(status JobStatus, err error) {
+	j.status = status
+	j.err = err
+	j.speed = 0
+	close(j.finished)
+}
+
+// Pause stops a queued or downloading job. The partial file is kept, so the download continues where it stopped.
+func (j *Job) Pause() {
+	j.update(true, func() {
+		if j.status != StatusQueued && j.status != StatusDownloading {
+			return
+		}
+		if j.cancel != nil {
+			j.cancel()
+		}
+		j.status = StatusPaused
+		j.speed = 0
+	})
+	j.manager.schedule()
+}
+
+// Resume queues a paused job again.
+func (j *Job) Resume() {
+	j.update(true, func() {
+		if j.status == StatusPaused {
+			j.status = StatusQueued
+		}
+	})
+	j.manager.schedule()
+}
+
+// Cancel stops the job and removes the partial file. Jobs that are verified or extracted can not be cancelled anymore.
+func (j *Job) Cancel() {
+	running := false
+	j.update(true, func() {
+		if j.status != StatusQueued && j.status != StatusDownloading && j.status != StatusPaused {
+			return
+		}
+		running = j.running
+		if j.cancel != nil {
+			j.cancel()
+		}
+		j.finish(StatusCancelled, ErrCancelled)
+	})
+	// a running download removes its file after it stopped writing
+	if !running {
+		j.removePartialFile()
+	}
+	j.manager.schedule()
+}
+
+// CanCancel returns true if Cancel stops the job.
+func (s JobState) CanCancel() bool {
+	return s.Status == StatusQueued || s.Status == StatusDownloading || s.Status == StatusPaused
+}
+
+// Wait blocks until the job is done, failed or cancelled and returns its error.
+func (j *Job) Wait() error {
+	<-j.finished
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.err
+}
+
+func (j *Job) removePartialFile() {
+	if err := os.Remove(j.Target); err != nil && !os.IsNotExist(err) {
+		log.Printf("Error removing cancelled download %s: %v", j.Target, err)
+	}
+}
+
+// archiveFormat returns the archive format of a downloaded file or "" if it is not extracted.
+// An empty extractFormat detects the format by the file extension.
+func archiveFormat(fileName, extractFormat string) string {
+	switch extractFormat {
+	case "none":
+		return ""
+	case "":
+		fileName = strings.ToLower(filepath.Base(fileName))
+		if strings.HasSuffix(fileName, ".zip") {
+			return Updater.ArchiveZip
+		} else if strings.HasSuffix(fileName, ".tar.gz") {
+			return Updater.ArchiveTarGz
+		}
+		return ""
+	}
+	return extractFormat
+}
+
+// urlFileName returns the file name of a download url.
+func urlFileName(downloadUrl string) string {
+	parsedUrl, err := url.Parse(downloadUrl)
+	if err != nil {
+		return downloadUrl[strings.LastIndex(downloadUrl, "/")+1:]
+	}
+	return path.Base(parsedUrl.Path)
+}
+
+// archiveFormat returns the archive format of the job. It is detected by the file name of the download url,
+// or by the target file name if the url has no archive extension.
+func (j *Job) archiveFormat(downloadUrl string) string {
+	if format := archiveFormat(urlFileName(downloadUrl), j.ExtractFormat); format != "" || j.ExtractFormat != "" {
+		return format
+	}
+	return archiveFormat(j.Target, j.ExtractFormat)
+}
+
+// extractArchive extracts a downloaded archive into its directory. onProgress can be nil.
+func extractArchive(fileName, format string, onProgress Updater.ExtractProgress) error {
+	return Updater.Extract(fileName, filepath.Dir(fileName), format, onProgress)
+}
+
+func mirrorName(mirrorUrl string) string {
+	parsedUrl, err := url.Parse(mirrorUrl)
+	if err != nil || parsedUrl.Hostname() == "" {
+		return mirrorUrl
+	}
+	return parsedUrl.Hostname()
+}
+
+func (j *Job) run(ctx context.Context) {
+	defer Logging.GoRoutineErrorHandler(func(scope *sentry.Scope) {
+		scope.SetTag("GoRoutine", "ModelDownloader\\Manager->run")
+	})
+
+	err := j.download(ctx)
+
+	cancelled := false
+	j.update(true, func() {
+		j.running = false
+		switch {
+		case j.status == StatusCancelled:
+			cancelled = true
+		case j.status == StatusPaused:
+			// stopped by Pause, the partial file is kept
+		case j.status == StatusQueued && ctx.Err() != nil:
+			// resumed before the paused download stopped, it is started again by schedule
+		case err != nil:
+			j.finish(StatusFailed, err)
+		default:
+			j.finish(StatusDone, nil)
+		}
+	})
+	if cancelled {
+		j.removePartialFile()
+	} else if err != nil && !errors.Is(err, context.Canceled) {
+		log.Printf("Download %d (%s) failed: %v", j.ID, j.Target, err)
+		Logging.CaptureException(err)
+	}
+	j.manager.schedule()
+}
+
+func (j *Job) download(ctx context.Context) error {
+	if err := os.MkdirAll(filepath.Dir(j.Target), 0755); err != nil {
+		return err
+	}
+
+	// start with the mirror with the best score, the others are used if it fails or gets slow
+	mirrorUrls := Updater.Mirrors.Rank(ctx, j.Urls)
+	downloader := Updater.Download{
+		Context:             ctx,
+		Url:                 mirrorUrls[0],
+		FallbackUrls:        mirrorUrls[1:],
+		Filepath:            j.Target,
+		ConcurrentDownloads: 4,
+		ChunkSize:           15 * 1024 * 1024, // 15 MB
+	}
+	downloader.WriteCounter.OnProgress = func(progress, total uint64, speed float64) {
+		j.update(false, func() {
+			j.bytes = progress
+			j.total = total
+			j.speed = speed
+			j.mirror = mirrorName(downloader.CurrentUrl())
+		})
+	}
+	j.update(false, func() {
+		j.mirror = mirrorName(downloader.CurrentUrl())
+	})
+	if err := downloader.DownloadFile(3); err != nil {
+		return err
+	}
+
+	// the download can not be paused anymore
+	setStatus := func(status JobStatus) error {
+		var err error
+		j.update(true, func() {
+			if j.status != StatusDownloading && j.status != StatusVerifying {
+				err = ctx.Err()
+				return
+			}
+			j.status = status
+			j.speed = 0
+		})
+		return err
+	}
+
+	if j.Checksum != "" {
+		if err := setStatus(StatusVerifying); err != nil {
+			return err
+		}
+		if err := Updater.CheckFileHash(j.Target, j.Checksum); err != nil {
+			// remove the broken file, so the next try downloads it again
+			_ = os.Remove(j.Target)
+			return err
+		}
+	}
+
+	if extractType := j.archiveFormat(downloader.CurrentUrl()); extractType != "" {
+		if err := setStatus(StatusExtracting); err != nil {
+			return err
+		}
+		// wait a bit before trying to extract
+		time.Sleep(1 * time.Second)
+		// the extraction progress is shown like the download progress
+		err := extractArchive(j.Target, extractType, func(extracted, total uint64) {
+			j.update(false, func() {
+				j.bytes = extracted
+				j.total = total
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return downloader.CreateFinishedFile(".finished", 5, 3*time.Second)
+}
+
+// Manager queues downloads and runs up to MaxConcurrent of them at the same time.
+// Unfinished downloads are saved, so they continue after a restart.
+type Manager struct {
+	mutex         sync.Mutex
+	jobs          []*Job
+	nextID        int
+	maxConcurrent int
+	onChange      func()
+	onAdded       func(job *Job)
+	// filePath is the downloads file, FilePath uses the UI data directory if empty.
+	filePath string
+}
+
+var Default = NewManager()
+
+func NewManager() *Manager {
+	return &Manager{maxConcurrent: DefaultMaxConcurrent}
+}
+
+// FilePath returns the file the unfinished downloads are saved to.
+func (m *Manager) FilePath() string {
+	if m.filePath != "" {
+		return m.filePath
+	}
+	return filepath.Join(Settings.GetUiDataDir(), downloadsFileName)
+}
+
+// SetOnChange sets the function called after jobs are added or change their state or progress.
+func (m *Manager) SetOnChange(onChange func()) {
+	m.mutex.Lock()
+	m.onChange = onChange
+	m.mutex.Unlock()
+}
+
+// SetOnAdded sets the function called after a new job is queued.
+func (m *Manager) SetOnAdded(onAdded func(job *Job)) {
+	m.mutex.Lock()
+	m.onAdded = onAdded
+	m.mutex.Unlock()
+}
+
+func (m *Manager) MaxConcurrent() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.maxConcurrent
+}
+
+// SetMaxConcurrent changes the number of parallel downloads. Running downloads above the limit are finished.
+func (m *Manager) SetMaxConcurrent(maxConcurrent int) {
+	m.mutex.Lock()
+	m.maxConcurrent = max(maxConcurrent, 1)
+	m.mutex.Unlock()
+	m.schedule()
+}
+
+func (m *Manager) jobChanged(statusChange bool) {
+	if statusChange {
+		if err := m.Save(); err != nil {
+			log.Printf("Error saving downloads: %v", err)
+		}
+	}
+	m.mutex.Lock()
+	onChange := m.onChange
+	m.mutex.Unlock()
+	if onChange != nil {
+		onChange()
+	}
+}
+
+// schedule starts queued jobs in the order they were added until the limit of parallel downloads is reached.
+func (m *Manager) schedule() {
+	m.mutex.Lock()
+	running := 0
+	var queued []*Job
+	for _, job := range m.jobs {
+		job.mutex.Lock()
+		if job.running {
+			running++
+		} else if job.status == StatusQueued {
+			queued = append(queued, job)
+		}
+		job.mutex.Unlock()
+	}
+	var started []*Job
+	for _, job := range queued {
+		if running >= m.maxConcurrent {
+			break
+		}
+		running++
+		ctx, cancel := context.WithCancel(context.Background())
+		job.mutex.Lock()
+		job.status = StatusDownloading
+		job.running = true
+		job.cancel = cancel
+		job.mutex.Unlock()
+		started = append(started, job)
+		go job.run(ctx)
+	}
+	m.mutex.Unlock()
+
+	if len(started) > 0 {
+		m.jobChanged(true)
+	}
+}
+
+func (m *Manager) newJob(urls []string, target, checksum, title, extractFormat string) *Job {
+	m.nextID++
+	return &Job{
+		ID:            m.nextID,
+		Urls:          urls,
+		Target:        target,
+		Checksum:      checksum,
+		Title:         title,
+		ExtractFormat: extractFormat,
+		status:        StatusQueued,
+		finished:      make(chan struct{}),
+		manager:       m,
+	}
+}
+
+// Add queues a download. If the target is already downloaded by an unfinished job, that job is returned.
+func (m *Manager) Add(urls []string, target, checksum, title, extractFormat string) (*Job, error) {
+	if len(urls) == 0 {
+		return nil, errors.New("no download url")
+	}
+	if target == "" {
+		return nil, errors.New("no download target")
+	}
+	target = filepath.Clean(target)
+
+	m.mutex.Lock()
+	for _, job := range m.jobs {
+		if job.Target == target && !job.State().Finished() {
+			m.mutex.Unlock()
+			return job, nil
+		}
+	}
+	job := m.newJob(urls, target, checksum, title, extractFormat)
+	m.jobs = append(m.jobs, job)
+	onAdded := m.onAdded
+	m.mutex.Unlock()
+
+	m.jobChanged(true)
+	if onAdded != nil {
+		onAdded(job)
+	}
+	m.schedule()
+	return job, nil
+}
+
+// HasUnfinished returns true if a job for the target is queued, running or paused.
+func (m *Manager) HasUnfinished(target string) bool {
+	target = filepath.Clean(target)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for _, job := range m.jobs {
+		if job.Target == target && !job.State().Finished() {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Manager) Jobs() []*Job {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]*Job{}, m.jobs...)
+}
+
+// RemoveFinished removes done, failed and cancelled jobs from the list.
+func (m *Manager) RemoveFinished() {
+	m.mutex.Lock()
+	var jobs []*Job
+	for _, job := range m.jobs {
+		if !job.State().Finished() {
+			jobs = append(jobs, job)
+		}
+	}
+	m.jobs = jobs
+	m.mutex.Unlock()
+	m.jobChanged(false)
+}
+
+// savedJob is a job in the downloads file.
+type savedJob struct {
+	Urls          []string `json:"urls"`
+	Target        string   `json:"target"`
+	Checksum      string   `json:"checksum,omitempty"`
+	Title         string   `json:"title,omitempty"`
+	ExtractFormat string   `json:"extract_format,omitempty"`
+	Paused        bool     `json:"paused,omitempty"`
+}
+
+// Save writes the unfinished jobs to disk.
+func (m *Manager) Save() error {
+	m.mutex.Lock()
+	saved := []savedJob{}
+	for _, job := range m.jobs {
+		state := job.State()
+		if state.Finished() {
+			continue
+		}
+		saved = append(saved, savedJob{
+			Urls:          job.Urls,
+			Target:        job.Target,
+			Checksum:      job.Checksum,
+			Title:         job.Title,
+			ExtractFormat: job.ExtractFormat,
+			Paused:        state.Status == StatusPaused,
+		})
+	}
+	m.mutex.Unlock()
+
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(m.FilePath(), data, 0644)
+}
+
+// Load restores the unfinished jobs of the last run and starts the ones that were not paused.
+// The partial files of the jobs are kept, so their download continues where it stopped.
+func (m *Manager) Load() error {
+	data, err := os.ReadFile(m.FilePath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var saved []savedJob
+	if err = json.Unmarshal(data, &saved); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	for _, savedJob := range saved {
+		if len(savedJob.Urls) == 0 || savedJob.Target == "" {
+			continue
+		}
+		job := m.newJob(savedJob.Urls, filepath.Clean(savedJob.Target), savedJob.Checksum, savedJob.Title, savedJob.ExtractFormat)
+		if savedJob.Paused {
+			job.status = StatusPaused
+		}
+		m.jobs = append(m.jobs, job)
+	}
+	m.mutex.Unlock()
+
+	m.jobChanged(false)
+	m.schedule()
+	return nil
+}
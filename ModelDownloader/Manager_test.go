@@ -0,0 +1,314 @@
+package ModelDownloader
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"whispering-tiger-ui/Updater"
+)
+
+// testDownloadServer serves files by path. Downloads of blocked files do not start until release is called.
+type testDownloadServer struct {
+	*httptest.Server
+	files map[string]string
+
+	mutex     sync.Mutex
+	blocked   map[string]chan struct{}
+	requested chan string
+}
+
+func newTestDownloadServer(t *testing.T, files map[string]string, blocked ...string) *testDownloadServer {
+	t.Helper()
+	server := &testDownloadServer{files: files, blocked: map[string]chan struct{}{}, requested: make(chan string, 10)}
+	for _, name := range blocked {
+		server.blocked[name] = make(chan struct{})
+	}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := server.files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		// the download starts with a HEAD request for the file size, mirror probes are range requests
+		if r.Method == http.MethodHead {
+			server.mutex.Lock()
+			release := server.blocked[r.URL.Path]
+			server.mutex.Unlock()
+			if release != nil {
+				server.requested <- r.URL.Path
+				select {
+				case <-release:
+				case <-r.Context().Done():
+					return
+				}
+			}
+		}
+		http.ServeContent(w, r, r.URL.Path, time.Time{}, strings.NewReader(content))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func (s *testDownloadServer) release(name string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if release := s.blocked[name]; release != nil {
+		close(release)
+		delete(s.blocked, name)
+	}
+}
+
+// waitRequested waits until the download of a blocked file was started.
+func (s *testDownloadServer) waitRequested(t *testing.T, name string) {
+	t.Helper()
+	select {
+	case requested := <-s.requested:
+		if requested != name {
+			t.Fatalf("download of %s started, want %s", requested, name)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("download of %s was not started", name)
+	}
+}
+
+func newTestManager(t *testing.T, filePath string) *Manager {
+	t.Helper()
+	m := NewManager()
+	if filePath == "" {
+		filePath = filepath.Join(t.TempDir(), downloadsFileName)
+	}
+	m.filePath = filePath
+	return m
+}
+
+func waitFinished(t *testing.T, job *Job) error {
+	t.Helper()
+	select {
+	case <-job.finished:
+		return job.Wait()
+	case <-time.After(20 * time.Second):
+		t.Fatalf("download %s did not finish: %+v", job.Title, job.State())
+		return nil
+	}
+}
+
+// waitStopped waits until the download goroutine of a job ended.
+func waitStopped(t *testing.T, job *Job) {
+	t.Helper()
+	for start := time.Now(); time.Since(start) < 10*time.Second; time.Sleep(10 * time.Millisecond) {
+		job.mutex.Lock()
+		running := job.running
+		job.mutex.Unlock()
+		if !running {
+			return
+		}
+	}
+	t.Fatalf("download %s did not stop", job.Title)
+}
+
+func TestManagerDownload(t *testing.T) {
+	server := newTestDownloadServer(t, map[string]string{"/model.bin": "model"})
+	tests := []struct {
+		name     string
+		checksum string
+		wantErr  string
+	}{
+		{name: "without checksum"},
+		{name: "checksum", checksum: checksum("model")},
+		{name: "wrong checksum", checksum: checksum("other"), wantErr: "hash does not match"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := filepath.Join(t.TempDir(), "model.bin")
+			job, err := newTestManager(t, "").Add([]string{server.URL + "/model.bin"}, target, tt.checksum, tt.name, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = waitFinished(t, job)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) || job.State().Status != StatusFailed {
+					t.Fatalf("download = %v, %v, want %q", job.State().Status, err, tt.wantErr)
+				}
+				if _, err := os.Stat(target); !os.IsNotExist(err) {
+					t.Errorf("broken download was kept: %v", err)
+				}
+				return
+			}
+			if err != nil || job.State().Status != StatusDone {
+				t.Fatalf("download = %v, %v", job.State().Status, err)
+			}
+			if data, _ := os.ReadFile(target); string(data) != "model" {
+				t.Errorf("downloaded file = %q", data)
+			}
+			if _, err := os.Stat(target + ".finished"); err != nil {
+				t.Errorf("finished file: %v", err)
+			}
+		})
+	}
+}
+
+func TestManagerQueue(t *testing.T) {
+	server := newTestDownloadServer(t, map[string]string{"/a.bin": "a", "/b.bin": "b"}, "/a.bin")
+	m := newTestManager(t, "")
+	m.SetMaxConcurrent(1)
+	dir := t.TempDir()
+	a, _ := m.Add([]string{server.URL + "/a.bin"}, filepath.Join(dir, "a.bin"), "", "a", "")
+	server.waitRequested(t, "/a.bin")
+	b, _ := m.Add([]string{server.URL + "/b.bin"}, filepath.Join(dir, "b.bin"), "", "b", "")
+
+	if again, _ := m.Add([]string{server.URL + "/a.bin"}, filepath.Join(dir, "a.bin"), "", "a", ""); again != a {
+		t.Error("Add() of a queued target created a new job")
+	}
+	if status := b.State().Status; status != StatusQueued {
+		t.Errorf("second download = %v while the first one runs, want %v", status, StatusQueued)
+	}
+	server.release("/a.bin")
+	for _, job := range []*Job{a, b} {
+		if err := waitFinished(t, job); err != nil {
+			t.Errorf("download %s error = %v", job.Title, err)
+		}
+	}
+	m.RemoveFinished()
+	if jobs := m.Jobs(); len(jobs) != 0 {
+		t.Errorf("Jobs() after RemoveFinished() = %d jobs", len(jobs))
+	}
+}
+
+func TestManagerPauseResume(t *testing.T) {
+	tests := []struct {
+		name string
+		// waitForPause waits until the paused download stopped before resuming it
+		waitForPause bool
+	}{
+		{name: "resume after the download stopped", waitForPause: true},
+		{name: "resume right after pausing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestDownloadServer(t, map[string]string{"/model.bin": "model"}, "/model.bin")
+			m := newTestManager(t, "")
+			job, _ := m.Add([]string{server.URL + "/model.bin"}, filepath.Join(t.TempDir(), "model.bin"), "", tt.name, "")
+			server.waitRequested(t, "/model.bin")
+
+			job.Pause()
+			if status := job.State().Status; status != StatusPaused {
+				t.Fatalf("paused download = %v", status)
+			}
+			if tt.waitForPause {
+				waitStopped(t, job)
+				if status := job.State().Status; status != StatusPaused {
+					t.Fatalf("stopped download = %v, want %v", status, StatusPaused)
+				}
+			}
+			job.Resume()
+			server.release("/model.bin")
+			if err := waitFinished(t, job); err != nil || job.State().Status != StatusDone {
+				t.Fatalf("resumed download = %v, %v", job.State().Status, err)
+			}
+		})
+	}
+}
+
+func TestManagerCancel(t *testing.T) {
+	server := newTestDownloadServer(t, map[string]string{"/model.bin": "model"}, "/model.bin")
+	m := newTestManager(t, "")
+	target := filepath.Join(t.TempDir(), "model.bin")
+	job, _ := m.Add([]string{server.URL + "/model.bin"}, target, "", "model", "")
+	server.waitRequested(t, "/model.bin")
+	if err := os.WriteFile(target, []byte("mo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	job.Cancel()
+	if err := waitFinished(t, job); !errors.Is(err, ErrCancelled) || job.State().Status != StatusCancelled {
+		t.Fatalf("cancelled download = %v, %v", job.State().Status, err)
+	}
+	waitStopped(t, job)
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Errorf("partial file of the cancelled download was kept: %v", err)
+	}
+	// a finished job is not changed anymore
+	job.Resume()
+	if status := job.State().Status; status != StatusCancelled {
+		t.Errorf("resumed cancelled download = %v", status)
+	}
+}
+
+func TestManagerPersistence(t *testing.T) {
+	server := newTestDownloadServer(t, map[string]string{"/a.bin": "a", "/b.bin": "b"})
+	filePath := filepath.Join(t.TempDir(), downloadsFileName)
+	dir := t.TempDir()
+
+	// the state of the last run, without starting the downloads
+	saved := newTestManager(t, filePath)
+	paused := saved.newJob([]string{server.URL + "/a.bin"}, filepath.Join(dir, "a.bin"), "", "a", "")
+	paused.status = StatusPaused
+	queued := saved.newJob([]string{server.URL + "/b.bin"}, filepath.Join(dir, "b.bin"), checksum("b"), "b", "none")
+	done := saved.newJob([]string{server.URL + "/c.bin"}, filepath.Join(dir, "c.bin"), "", "c", "")
+	done.status = StatusDone
+	saved.jobs = []*Job{paused, queued, done}
+	if err := saved.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	m := newTestManager(t, filePath)
+	if err := m.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	jobs := m.Jobs()
+	if len(jobs) != 2 {
+		t.Fatalf("Load() = %d jobs, want the 2 unfinished ones", len(jobs))
+	}
+	if jobs[0].Target != paused.Target || jobs[0].State().Status != StatusPaused {
+		t.Errorf("first job = %s %v, want the paused job", jobs[0].Target, jobs[0].State().Status)
+	}
+	restored := jobs[1]
+	if restored.Target != queued.Target || restored.Checksum != queued.Checksum || restored.ExtractFormat != queued.ExtractFormat {
+		t.Errorf("second job = %+v, want %+v", restored, queued)
+	}
+	// the restored job that was not paused is started
+	if err := waitFinished(t, restored); err != nil {
+		t.Fatalf("restored download error = %v", err)
+	}
+
+	// only the paused job is left in the downloads file
+	reloaded := newTestManager(t, filePath)
+	if err := reloaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if jobs := reloaded.Jobs(); len(jobs) != 1 || jobs[0].Target != paused.Target || jobs[0].State().Status != StatusPaused {
+		t.Errorf("downloads file after the download finished = %v", jobs)
+	}
+}
+
+func TestJobArchiveFormat(t *testing.T) {
+	tests := []struct {
+		name          string
+		url           string
+		target        string
+		extractFormat string
+		want          string
+	}{
+		{name: "zip url", url: "https://example.com/files/model.zip", target: "model", want: Updater.ArchiveZip},
+		{name: "tar.gz url with query", url: "https://example.com/model.tar.gz?download=1", target: "model", want: Updater.ArchiveTarGz},
+		{name: "target name", url: "https://example.com/download?id=1", target: "model.zip", want: Updater.ArchiveZip},
+		{name: "url before the target name", url: "https://example.com/model.tar.gz", target: "model.zip", want: Updater.ArchiveTarGz},
+		{name: "no archive", url: "https://example.com/model.bin", target: "model.bin"},
+		{name: "not extracted", url: "https://example.com/model.zip", target: "model.zip", extractFormat: "none"},
+		{name: "configured format", url: "https://example.com/model.bin", target: "model.bin", extractFormat: Updater.ArchiveZip, want: Updater.ArchiveZip},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := &Job{Target: tt.target, ExtractFormat: tt.extractFormat}
+			if got := job.archiveFormat(tt.url); got != tt.want {
+				t.Errorf("archiveFormat(%q) = %q, want %q", tt.url, got, tt.want)
+			}
+		})
+	}
+}
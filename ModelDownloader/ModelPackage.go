@@ -0,0 +1,187 @@
+package ModelDownloader
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+	"whispering-tiger-ui/ModelRegistry"
+	"whispering-tiger-ui/Updater"
+)
+
+// PackageFormatVersion is the version of the model package format written by ExportModelPackage. Packages with a newer version are rejected.
+const PackageFormatVersion = 1
+
+const (
+	packageManifestName = "model-package.json"
+	packageFilesDir     = "files/"
+)
+
+var (
+	ErrNoModelPackage     = errors.New("not a model package (" + packageManifestName + " missing)")
+	ErrNewerPackageFormat = errors.New("model package was created by a newer version")
+)
+
+// PackageManifest describes the content of a model package.
+// A model package is a zip file with the downloaded files of a model, so it can be imported on machines without internet access.
+type PackageManifest struct {
+	FormatVersion int           `json:"format_version"`
+	AppVersion    string        `json:"app_version,omitempty"`
+	Created       time.Time     `json:"created"`
+	Family        string        `json:"family"`
+	Model         string        `json:"model"`
+	Files         []PackageFile `json:"files"`
+}
+
+// PackageFile is a downloaded file of the model included in the package.
+type PackageFile struct {
+	// Name is the file name in the cache directory.
+	Name string `json:"name"`
+	// File is the path of the file inside the package.
+	File     string `json:"file"`
+	Size     int64  `json:"size"`
+	Checksum string `json:"checksum"`
+}
+
+// modelFilePath returns the path of a downloaded file of a model in the cache.
+func modelFilePath(cacheDir string, family ModelRegistry.Family, file ModelRegistry.File) string {
+	return filepath.Join(cacheDir, filepath.FromSlash(family.CachePath), file.FileName())
+}
+
+// registryModel returns a model of the registry by family and model name.
+func registryModel(registry *ModelRegistry.Manifest, familyName, modelName string) (ModelRegistry.Family, ModelRegistry.Model, error) {
+	family, ok := registry.Family(familyName)
+	if !ok {
+		return family, ModelRegistry.Model{}, fmt.Errorf("unknown model family: %s", familyName)
+	}
+	model, ok := family.Model(modelName)
+	if !ok {
+		return family, model, fmt.Errorf("unknown model %s of %s", modelName, familyName)
+	}
+	return family, model, nil
+}
+
+// PrepareModelFiles makes sure the downloaded files of a model are in the cache and match the checksums of the registry.
+// Missing or broken files are downloaded again with the download manager.
+func PrepareModelFiles(cacheDir string, registry *ModelRegistry.Manifest, familyName, modelName string) error {
+	family, model, err := registryModel(registry, familyName, modelName)
+	if err != nil {
+		return err
+	}
+	for _, file := range model.Files {
+		fileName := modelFilePath(cacheDir, family, file)
+		if Updater.CheckFileHash(fileName, file.Checksum) == nil {
+			continue
+		}
+		_ = os.Remove(fileName)
+		if err := DownloadFile(file.Mirrors, fileName, file.Checksum, familyName+" "+modelName, file.Extract); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportModelPackage writes the downloaded files of a model from the cache into a model package.
+// The files are stored without compression, since they are mostly archives or binary weights.
+func ExportModelPackage(writer io.Writer, cacheDir string, registry *ModelRegistry.Manifest, familyName, modelName, appVersion string) (PackageManifest, error) {
+	manifest := PackageManifest{
+		FormatVersion: PackageFormatVersion,
+		AppVersion:    appVersion,
+		Created:       time.Now().UTC(),
+		Family:        familyName,
+		Model:         modelName,
+	}
+	family, model, err := registryModel(registry, familyName, modelName)
+	if err != nil {
+		return manifest, err
+	}
+
+	zipWriter := zip.NewWriter(writer)
+	for _, file := range model.Files {
+		fileName := modelFilePath(cacheDir, family, file)
+		if err := Updater.CheckFileHash(fileName, file.Checksum); err != nil {
+			return manifest, fmt.Errorf("%s: %w", file.FileName(), err)
+		}
+		packageFile := PackageFile{Name: file.FileName(), File: packageFilesDir + file.FileName(), Checksum: file.Checksum}
+		if packageFile.Size, err = writeZipEntry(zipWriter, packageFile.File, fileName); err != nil {
+			return manifest, err
+		}
+		manifest.Files = append(manifest.Files, packageFile)
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return manifest, err
+	}
+	manifestWriter, err := zipWriter.Create(packageManifestName)
+	if err != nil {
+		return manifest, err
+	}
+	if _, err = manifestWriter.Write(manifestData); err != nil {
+		return manifest, err
+	}
+	return manifest, zipWriter.Close()
+}
+
+// ExportModelPackageFile writes a model package to a file.
+func ExportModelPackageFile(fileName, cacheDir string, registry *ModelRegistry.Manifest, familyName, modelName, appVersion string) (PackageManifest, error) {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return PackageManifest{}, err
+	}
+	manifest, err := ExportModelPackage(file, cacheDir, registry, familyName, modelName, appVersion)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(fileName)
+	}
+	return manifest, err
+}
+
+func writeZipEntry(zipWriter *zip.Writer, name, fileName string) (int64, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	header, err := zip.FileInfoHeader(stat)
+	if err != nil {
+		return 0, err
+	}
+	header.Name = name
+	header.Method = zip.Store
+	entryWriter, err := zipWriter.CreateHeader(header)
+	if err != nil {
+		return 0, err
+	}
+	return io.Copy(entryWriter, file)
+}
+
+// readPackageManifest reads and checks the manifest of a model package.
+func readPackageManifest(data []byte) (PackageManifest, error) {
+	var manifest PackageManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest, fmt.Errorf("invalid %s: %w", packageManifestName, err)
+	}
+	if manifest.FormatVersion > PackageFormatVersion {
+		return manifest, ErrNewerPackageFormat
+	}
+	for _, file := range manifest.Files {
+		// the files are written into the cache by their name, so it must not contain a path
+		if file.Name == "" || path.Base(file.Name) != file.Name || strings.ContainsAny(file.Name, `\:`) {
+			return manifest, fmt.Errorf("invalid file name in model package: %q", file.Name)
+		}
+	}
+	return manifest, nil
+}
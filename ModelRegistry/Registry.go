@@ -0,0 +1,301 @@
+package ModelRegistry
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sync"
+	"whispering-tiger-ui/Settings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestVersion is the newest manifest format this version can read.
+const ManifestVersion = 1
+
+// Categories of model types, matching the model groups of the profile settings.
+const (
+	CategorySTT            = "stt"
+	CategoryTextTranslator = "txt"
+	CategoryTTS            = "tts"
+	CategoryOCR            = "ocr"
+)
+
+//go:embed manifest.yaml
+var bundledManifest []byte
+
+// SizeOption is a model size offered in the profile settings.
+type SizeOption struct {
+	Value string `yaml:"value"`
+	Label string `yaml:"label"`
+}
+
+// MemoryEstimate is the memory usage of a model size in MiB with float32 precision.
+// Sizes are the canonical sizes (tiny, base, ..., large-distilled) for model types with size variants.
+type MemoryEstimate struct {
+	Size          string  `yaml:"size"`
+	MemoryFloat32 float64 `yaml:"memory_float32"`
+}
+
+// ModelType is a model type of the profile settings (e.g. the stt_type faster_whisper).
+type ModelType struct {
+	Category string `yaml:"category"`
+	Type     string `yaml:"type"`
+	Label    string `yaml:"label"`
+	// Hidden types are not offered for selection, but are still known for existing profiles.
+	Hidden bool `yaml:"hidden,omitempty"`
+	// DefaultSize is the index of the size selected when switching to the type.
+	DefaultSize int `yaml:"default_size,omitempty"`
+	// FixedSize types show their only size, but do not allow changing it.
+	FixedSize bool             `yaml:"fixed_size,omitempty"`
+	Sizes     []SizeOption     `yaml:"sizes,omitempty"`
+	Estimates []MemoryEstimate `yaml:"estimates,omitempty"`
+}
+
+// File is a file of a model that is downloaded from one of its mirrors.
+type File struct {
+	// Name is the file name in the cache directory. Defaults to the file name of the first mirror.
+	Name    string   `yaml:"name,omitempty"`
+	Mirrors []string `yaml:"mirrors"`
+	// Size is the file size in bytes, 0 if unknown.
+	Size     int64  `yaml:"size,omitempty"`
+	Checksum string `yaml:"checksum,omitempty"`
+	// Extract is the archive format (zip, tar.gz) or "none". Empty detects the format by the file extension.
+	Extract string `yaml:"extract,omitempty"`
+}
+
+// FileName returns the name of the file in the cache directory.
+func (f File) FileName() string {
+	if f.Name != "" {
+		return f.Name
+	}
+	if len(f.Mirrors) == 0 {
+		return ""
+	}
+	if mirrorUrl, err := url.Parse(f.Mirrors[0]); err == nil && mirrorUrl.Path != "" {
+		return path.Base(mirrorUrl.Path)
+	}
+	return path.Base(f.Mirrors[0])
+}
+
+// Model is a downloadable model of a family, e.g. the float16 variant of a size.
+type Model struct {
+	Name string `yaml:"name"`
+	// License overrides the license of the family.
+	License string `yaml:"license,omitempty"`
+	Files   []File `yaml:"files"`
+}
+
+// ModelTypeRef refers to a model type by category and type.
+type ModelTypeRef struct {
+	Category string `yaml:"category"`
+	Type     string `yaml:"type"`
+}
+
+// Family is a group of downloadable models that share a cache directory.
+type Family struct {
+	Name string `yaml:"name"`
+	// CachePath is the directory of the models below the .cache directory.
+	CachePath string `yaml:"cache_path,omitempty"`
+	License   string `yaml:"license,omitempty"`
+	// UsedBy are the model types that load the models of the family.
+	UsedBy []ModelTypeRef `yaml:"used_by,omitempty"`
+	Models []Model        `yaml:"models"`
+}
+
+// IsUsedBy returns true if the models of the family are loaded by a model type.
+func (f Family) IsUsedBy(category, modelType string) bool {
+	for _, usedBy := range f.UsedBy {
+		if usedBy.Category == category && usedBy.Type == modelType {
+			return true
+		}
+	}
+	return false
+}
+
+// Model returns a model of the family by name.
+func (f Family) Model(name string) (Model, bool) {
+	for _, model := range f.Models {
+		if model.Name == name {
+			return model, true
+		}
+	}
+	return Model{}, false
+}
+
+// ModelLicense returns the license of a model of the family.
+func (f Family) ModelLicense(model Model) string {
+	if model.License != "" {
+		return model.License
+	}
+	return f.License
+}
+
+// Manifest is the content of a model registry file.
+type Manifest struct {
+	Version    int         `yaml:"version"`
+	ModelTypes []ModelType `yaml:"model_types"`
+	Downloads  []Family    `yaml:"downloads"`
+}
+
+// ParseManifest reads a manifest and checks that its version is supported.
+func ParseManifest(data []byte) (*Manifest, error) {
+	manifest := &Manifest{}
+	if err := yaml.Unmarshal(data, manifest); err != nil {
+		return nil, err
+	}
+	if manifest.Version < 1 || manifest.Version > ManifestVersion {
+		return nil, fmt.Errorf("unsupported model registry version %d (supported up to %d)", manifest.Version, ManifestVersion)
+	}
+	for _, modelType := range manifest.ModelTypes {
+		if modelType.Category == "" || modelType.Type == "" {
+			return nil, errors.New("model type without category or type")
+		}
+	}
+	for _, family := range manifest.Downloads {
+		if family.Name == "" {
+			return nil, errors.New("download without name")
+		}
+	}
+	return manifest, nil
+}
+
+// Merge applies an override manifest. Model types with the same category and type are replaced.
+// Download families with the same name are merged: set fields replace the bundled ones and models replace the models with the same name.
+func (m *Manifest) Merge(override *Manifest) {
+	for _, modelType := range override.ModelTypes {
+		index := slices.IndexFunc(m.ModelTypes, func(existing ModelType) bool {
+			return existing.Category == modelType.Category && existing.Type == modelType.Type
+		})
+		if index >= 0 {
+			m.ModelTypes[index] = modelType
+		} else {
+			m.ModelTypes = append(m.ModelTypes, modelType)
+		}
+	}
+	for _, family := range override.Downloads {
+		index := slices.IndexFunc(m.Downloads, func(existing Family) bool {
+			return existing.Name == family.Name
+		})
+		if index < 0 {
+			m.Downloads = append(m.Downloads, family)
+			continue
+		}
+		existing := &m.Downloads[index]
+		if family.CachePath != "" {
+			existing.CachePath = family.CachePath
+		}
+		if family.License != "" {
+			existing.License = family.License
+		}
+		if len(family.UsedBy) > 0 {
+			existing.UsedBy = family.UsedBy
+		}
+		for _, model := range family.Models {
+			modelIndex := slices.IndexFunc(existing.Models, func(existingModel Model) bool {
+				return existingModel.Name == model.Name
+			})
+			if modelIndex >= 0 {
+				existing.Models[modelIndex] = model
+			} else {
+				existing.Models = append(existing.Models, model)
+			}
+		}
+	}
+}
+
+// ModelTypesOf returns the model types of a category in manifest order, including hidden types.
+func (m *Manifest) ModelTypesOf(category string) []ModelType {
+	var modelTypes []ModelType
+	for _, modelType := range m.ModelTypes {
+		if modelType.Category == category {
+			modelTypes = append(modelTypes, modelType)
+		}
+	}
+	return modelTypes
+}
+
+// ModelType returns a model type by category and type.
+func (m *Manifest) ModelType(category, modelType string) (ModelType, bool) {
+	for _, existing := range m.ModelTypes {
+		if existing.Category == category && existing.Type == modelType {
+			return existing, true
+		}
+	}
+	return ModelType{}, false
+}
+
+// Family returns a download family by name.
+func (m *Manifest) Family(name string) (Family, bool) {
+	for _, family := range m.Downloads {
+		if family.Name == name {
+			return family, true
+		}
+	}
+	return Family{}, false
+}
+
+// GetOverrideDir returns the directory with manifests that are applied on top of the bundled manifest.
+func GetOverrideDir() string {
+	return filepath.Join(Settings.GetUiDataDir(), "ModelRegistry")
+}
+
+// Load reads the bundled manifest and applies the *.yaml manifests of overrideDir in alphabetical order.
+// Invalid override files are skipped and returned as error after applying the valid ones.
+func Load(overrideDir string) (*Manifest, error) {
+	manifest, err := ParseManifest(bundledManifest)
+	if err != nil {
+		return nil, fmt.Errorf("bundled model registry: %w", err)
+	}
+	if overrideDir == "" {
+		return manifest, nil
+	}
+	files, err := filepath.Glob(filepath.Join(overrideDir, "*.yaml"))
+	if err != nil {
+		return manifest, err
+	}
+	slices.Sort(files)
+	var errs []error
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err == nil {
+			var override *Manifest
+			override, err = ParseManifest(data)
+			if err == nil {
+				manifest.Merge(override)
+				continue
+			}
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", filepath.Base(file), err))
+	}
+	return manifest, errors.Join(errs...)
+}
+
+var (
+	currentLock sync.Mutex
+	current     *Manifest
+)
+
+// Current returns the registry of the bundled manifest with the overrides of GetOverrideDir.
+// It is loaded on first use, changed override files are read after a restart.
+func Current() *Manifest {
+	currentLock.Lock()
+	defer currentLock.Unlock()
+	if current == nil {
+		manifest, err := Load(GetOverrideDir())
+		if err != nil {
+			log.Printf("Error loading model registry: %v", err)
+		}
+		if manifest == nil {
+			manifest = &Manifest{Version: ManifestVersion}
+		}
+		current = manifest
+	}
+	return current
+}
@@ -0,0 +1,344 @@
+package ModelRegistry
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// typeNames returns the types of model types, hidden types are marked with a trailing "*".
+func typeNames(modelTypes []ModelType) []string {
+	var names []string
+	for _, modelType := range modelTypes {
+		name := modelType.Type
+		if modelType.Hidden {
+			name += "*"
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func modelNames(family Family) []string {
+	var names []string
+	for _, model := range family.Models {
+		names = append(names, model.Name)
+	}
+	return names
+}
+
+func TestParseManifest(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{name: "current version", data: "version: 1\nmodel_types:\n  - {category: stt, type: a, label: A}\ndownloads:\n  - {name: A}\n"},
+		{name: "missing version", data: "model_types: []\n", wantErr: "unsupported model registry version 0"},
+		{name: "newer version", data: "version: 2\n", wantErr: "unsupported model registry version 2"},
+		{name: "malformed", data: "version: [1\n", wantErr: "yaml"},
+		{name: "wrong field type", data: "version: 1\nmodel_types: stt\n", wantErr: "yaml"},
+		{name: "model type without category", data: "version: 1\nmodel_types:\n  - {type: a}\n", wantErr: "model type without category or type"},
+		{name: "model type without type", data: "version: 1\nmodel_types:\n  - {category: stt}\n", wantErr: "model type without category or type"},
+		{name: "download without name", data: "version: 1\ndownloads:\n  - {cache_path: a}\n", wantErr: "download without name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifest, err := ParseManifest([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) || manifest != nil {
+					t.Fatalf("ParseManifest() = %v, %v, want error %q", manifest, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || manifest.Version != ManifestVersion {
+				t.Fatalf("ParseManifest() = %v, %v", manifest, err)
+			}
+		})
+	}
+}
+
+func TestManifestMerge(t *testing.T) {
+	base := `version: 1
+model_types:
+  - {category: stt, type: a, label: A}
+  - {category: stt, type: b, label: B}
+  - {category: txt, type: a, label: Text A}
+downloads:
+  - name: Family
+    cache_path: family
+    license: MIT
+    used_by: [{category: stt, type: a}]
+    models:
+      - {name: small, files: [{mirrors: [https://example.com/small.bin]}]}
+      - {name: large, files: [{mirrors: [https://example.com/large.bin]}]}
+`
+	tests := []struct {
+		name     string
+		override string
+		// wantTypes are the stt types in order, hidden types end with "*"
+		wantTypes    []string
+		wantLabel    string
+		wantCache    string
+		wantLicense  string
+		wantUsedBy   string
+		wantModels   []string
+		wantFamilies int
+	}{
+		{
+			name:      "empty override",
+			override:  "version: 1\n",
+			wantTypes: []string{"a", "b"}, wantLabel: "A",
+			wantCache: "family", wantLicense: "MIT", wantUsedBy: "a", wantModels: []string{"small", "large"}, wantFamilies: 1,
+		},
+		{
+			name:      "replaced model type keeps its position",
+			override:  "version: 1\nmodel_types:\n  - {category: stt, type: a, label: New A, hidden: true}\n",
+			wantTypes: []string{"a*", "b"}, wantLabel: "New A",
+			wantCache: "family", wantLicense: "MIT", wantUsedBy: "a", wantModels: []string{"small", "large"}, wantFamilies: 1,
+		},
+		{
+			name:      "new model type is appended",
+			override:  "version: 1\nmodel_types:\n  - {category: stt, type: c, label: C}\n",
+			wantTypes: []string{"a", "b", "c"}, wantLabel: "A",
+			wantCache: "family", wantLicense: "MIT", wantUsedBy: "a", wantModels: []string{"small", "large"}, wantFamilies: 1,
+		},
+		{
+			name:      "same type of another category",
+			override:  "version: 1\nmodel_types:\n  - {category: txt, type: b, label: Text B}\n",
+			wantTypes: []string{"a", "b"}, wantLabel: "A",
+			wantCache: "family", wantLicense: "MIT", wantUsedBy: "a", wantModels: []string{"small", "large"}, wantFamilies: 1,
+		},
+		{
+			name:      "family fields",
+			override:  "version: 1\ndownloads:\n  - {name: Family, cache_path: other, used_by: [{category: stt, type: b}]}\n",
+			wantTypes: []string{"a", "b"}, wantLabel: "A",
+			wantCache: "other", wantLicense: "MIT", wantUsedBy: "b", wantModels: []string{"small", "large"}, wantFamilies: 1,
+		},
+		{
+			name: "family models",
+			override: `version: 1
+downloads:
+  - name: Family
+    license: Apache-2.0
+    models:
+      - {name: large, files: [{mirrors: [https://mirror.example.com/large.bin]}]}
+      - {name: medium, files: [{mirrors: [https://example.com/medium.bin]}]}
+`,
+			wantTypes: []string{"a", "b"}, wantLabel: "A",
+			wantCache: "family", wantLicense: "Apache-2.0", wantUsedBy: "a", wantModels: []string{"small", "large", "medium"}, wantFamilies: 1,
+		},
+		{
+			name:      "new family",
+			override:  "version: 1\ndownloads:\n  - {name: Other, cache_path: other}\n",
+			wantTypes: []string{"a", "b"}, wantLabel: "A",
+			wantCache: "family", wantLicense: "MIT", wantUsedBy: "a", wantModels: []string{"small", "large"}, wantFamilies: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifest, err := ParseManifest([]byte(base))
+			if err != nil {
+				t.Fatal(err)
+			}
+			override, err := ParseManifest([]byte(tt.override))
+			if err != nil {
+				t.Fatal(err)
+			}
+			manifest.Merge(override)
+
+			if got := typeNames(manifest.ModelTypesOf(CategorySTT)); !slices.Equal(got, tt.wantTypes) {
+				t.Errorf("ModelTypesOf(stt) = %v, want %v", got, tt.wantTypes)
+			}
+			if modelType, ok := manifest.ModelType(CategorySTT, "a"); !ok || modelType.Label != tt.wantLabel {
+				t.Errorf("ModelType(stt, a) = %+v, %v, want label %q", modelType, ok, tt.wantLabel)
+			}
+			if modelType, ok := manifest.ModelType(CategoryTextTranslator, "a"); !ok || modelType.Label != "Text A" {
+				t.Errorf("ModelType(txt, a) = %+v, %v", modelType, ok)
+			}
+			family, ok := manifest.Family("Family")
+			if !ok {
+				t.Fatal("Family() not found")
+			}
+			if family.CachePath != tt.wantCache || family.License != tt.wantLicense {
+				t.Errorf("family = %s %s, want %s %s", family.CachePath, family.License, tt.wantCache, tt.wantLicense)
+			}
+			if !family.IsUsedBy(CategorySTT, tt.wantUsedBy) || len(family.UsedBy) != 1 {
+				t.Errorf("family used by %v, want %s", family.UsedBy, tt.wantUsedBy)
+			}
+			if got := modelNames(family); !slices.Equal(got, tt.wantModels) {
+				t.Errorf("family models = %v, want %v", got, tt.wantModels)
+			}
+			if len(manifest.Downloads) != tt.wantFamilies {
+				t.Errorf("%d families, want %d", len(manifest.Downloads), tt.wantFamilies)
+			}
+		})
+	}
+}
+
+func TestManifestMergeReplacesModelFiles(t *testing.T) {
+	manifest, _ := ParseManifest([]byte("version: 1\ndownloads:\n  - {name: F, models: [{name: m, files: [{mirrors: [https://example.com/a/model.bin]}]}]}\n"))
+	override, _ := ParseManifest([]byte("version: 1\ndownloads:\n  - {name: F, models: [{name: m, license: CC-BY-4.0, files: [{name: renamed.bin, mirrors: [https://mirror.example.com/model.bin]}]}]}\n"))
+	manifest.Merge(override)
+	family, _ := manifest.Family("F")
+	model, ok := family.Model("m")
+	if !ok || len(model.Files) != 1 {
+		t.Fatalf("Model(m) = %+v, %v", model, ok)
+	}
+	if model.Files[0].FileName() != "renamed.bin" || model.Files[0].Mirrors[0] != "https://mirror.example.com/model.bin" {
+		t.Errorf("model file = %+v", model.Files[0])
+	}
+	if family.ModelLicense(model) != "CC-BY-4.0" {
+		t.Errorf("ModelLicense() = %q", family.ModelLicense(model))
+	}
+}
+
+func TestFileName(t *testing.T) {
+	tests := []struct {
+		name string
+		file File
+		want string
+	}{
+		{name: "name", file: File{Name: "model.bin", Mirrors: []string{"https://example.com/other.bin"}}, want: "model.bin"},
+		{name: "first mirror", file: File{Mirrors: []string{"https://example.com/a/model.bin?download=1", "https://example.com/b.bin"}}, want: "model.bin"},
+		{name: "no mirrors"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.file.FileName(); got != tt.want {
+				t.Errorf("FileName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestBundledManifest checks the lookups of the profile settings and the memory estimates on the bundled manifest.
+func TestBundledManifest(t *testing.T) {
+	manifest, err := Load("")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	for _, category := range []string{CategorySTT, CategoryTextTranslator, CategoryTTS, CategoryOCR} {
+		if len(manifest.ModelTypesOf(category)) == 0 {
+			t.Errorf("no model types of category %s", category)
+		}
+	}
+	for _, modelType := range manifest.ModelTypes {
+		if !slices.Contains([]string{CategorySTT, CategoryTextTranslator, CategoryTTS, CategoryOCR}, modelType.Category) {
+			t.Errorf("model type %s has unknown category %s", modelType.Type, modelType.Category)
+		}
+		if modelType.DefaultSize < 0 || (len(modelType.Sizes) > 0 && modelType.DefaultSize >= len(modelType.Sizes)) {
+			t.Errorf("model type %s/%s default size %d of %d sizes", modelType.Category, modelType.Type, modelType.DefaultSize, len(modelType.Sizes))
+		}
+	}
+	if types := typeNames(manifest.ModelTypesOf(CategorySTT)); types[0] != "faster_whisper" || !slices.Contains(types, "medusa_whisper*") {
+		t.Errorf("ModelTypesOf(stt) = %v, want faster_whisper first and the hidden medusa_whisper", types)
+	}
+	if modelType, ok := manifest.ModelType(CategorySTT, "faster_whisper"); !ok || len(modelType.Sizes) == 0 || len(modelType.Estimates) == 0 {
+		t.Errorf("ModelType(stt, faster_whisper) = %+v, %v", modelType, ok)
+	}
+	if _, ok := manifest.ModelType(CategoryTextTranslator, "faster_whisper"); ok {
+		t.Error("ModelType() found a type of another category")
+	}
+	if _, ok := manifest.ModelType(CategorySTT, "unknown"); ok {
+		t.Error("ModelType() found an unknown type")
+	}
+}
+
+func TestLoadOverrides(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		// wantErrFiles are the override files named in the error
+		wantErrFiles []string
+		wantLabel    string
+		wantHidden   bool
+		wantSTTTypes int
+	}{
+		{name: "no override directory", wantLabel: "Faster Whisper"},
+		{name: "empty override directory", files: map[string]string{}, wantLabel: "Faster Whisper"},
+		{
+			name:      "override",
+			files:     map[string]string{"a.yaml": "version: 1\nmodel_types:\n  - {category: stt, type: faster_whisper, label: Custom, hidden: true}\n"},
+			wantLabel: "Custom", wantHidden: true,
+		},
+		{
+			name: "overrides in alphabetical order",
+			files: map[string]string{
+				"b.yaml": "version: 1\nmodel_types:\n  - {category: stt, type: faster_whisper, label: Second}\n",
+				"a.yaml": "version: 1\nmodel_types:\n  - {category: stt, type: faster_whisper, label: First}\n",
+			},
+			wantLabel: "Second",
+		},
+		{
+			name: "new model type",
+			files: map[string]string{
+				"a.yaml": "version: 1\nmodel_types:\n  - {category: stt, type: custom_whisper, label: Custom Whisper}\n",
+			},
+			wantLabel: "Faster Whisper", wantSTTTypes: 1,
+		},
+		{
+			name: "invalid overrides are skipped",
+			files: map[string]string{
+				"a.yaml": "version: [1\n",
+				"b.yaml": "version: 2\nmodel_types:\n  - {category: stt, type: faster_whisper, label: Newer}\n",
+				"c.yaml": "version: 1\nmodel_types:\n  - {category: stt, type: faster_whisper, label: Custom}\n",
+				"d.yaml": "version: 1\nmodel_types:\n  - {type: faster_whisper, label: No category}\n",
+			},
+			wantErrFiles: []string{"a.yaml", "b.yaml", "d.yaml"}, wantLabel: "Custom",
+		},
+		{
+			name:      "other files are ignored",
+			files:     map[string]string{"a.yml": "version: [1\n", "README.txt": "overrides"},
+			wantLabel: "Faster Whisper",
+		},
+	}
+	bundled, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundledSTTTypes := len(bundled.ModelTypesOf(CategorySTT))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := ""
+			if tt.files != nil {
+				dir = t.TempDir()
+				for name, content := range tt.files {
+					if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			manifest, err := Load(dir)
+			if manifest == nil {
+				t.Fatalf("Load() = nil, %v", err)
+			}
+			if len(tt.wantErrFiles) == 0 && err != nil {
+				t.Errorf("Load() error = %v", err)
+			}
+			for _, name := range tt.wantErrFiles {
+				if err == nil || !strings.Contains(err.Error(), name) {
+					t.Errorf("Load() error = %v, want an error of %s", err, name)
+				}
+			}
+			if err != nil && strings.Contains(err.Error(), "c.yaml") {
+				t.Errorf("Load() error = %v contains the valid override", err)
+			}
+
+			modelType, ok := manifest.ModelType(CategorySTT, "faster_whisper")
+			if !ok || modelType.Label != tt.wantLabel || modelType.Hidden != tt.wantHidden {
+				t.Errorf("ModelType(stt, faster_whisper) = %q hidden %v, want %q hidden %v", modelType.Label, modelType.Hidden, tt.wantLabel, tt.wantHidden)
+			}
+			sttTypes := manifest.ModelTypesOf(CategorySTT)
+			if len(sttTypes) != bundledSTTTypes+tt.wantSTTTypes {
+				t.Errorf("ModelTypesOf(stt) = %d types, want %d", len(sttTypes), bundledSTTTypes+tt.wantSTTTypes)
+			}
+			// overridden types keep their position in the selection
+			if sttTypes[0].Type != "faster_whisper" {
+				t.Errorf("first stt type = %s", sttTypes[0].Type)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package AdditionalTextTranslations
+
+import (
+	"strings"
+	"whispering-tiger-ui/Fields"
+	"whispering-tiger-ui/Logging"
+	"whispering-tiger-ui/SendMessageChannel"
+	"whispering-tiger-ui/Settings"
+	"whispering-tiger-ui/Websocket/Messages"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/data/binding"
+	dialog2 "fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/getsentry/sentry-go"
+)
+
+// LanguageTranslation is a single target language part of a translation result.
+type LanguageTranslation struct {
+	LanguageCode string
+	Text         string
+}
+
+// ActiveAdditionalLanguages returns the language codes of the enabled additional translations.
+func ActiveAdditionalLanguages() []string {
+	var languages []string
+	if !Settings.Config.Txt_second_translation_enabled {
+		return languages
+	}
+	for _, language := range strings.Split(Settings.Config.Txt_second_translation_languages, ",") {
+		if language != "" {
+			languages = append(languages, language)
+		}
+	}
+	return languages
+}
+
+// SplitTranslations splits a translation result that contains additional translations,
+// joined by Txt_second_translation_wrap, into one part per target language.
+// If the parts don't match the configured languages, the whole text is returned as the main translation.
+func SplitTranslations(text string, targetLanguage string) []LanguageTranslation {
+	languages := append([]string{targetLanguage}, ActiveAdditionalLanguages()...)
+	wrap := Settings.Config.Txt_second_translation_wrap
+	if len(languages) == 1 || wrap == "" {
+		return []LanguageTranslation{{LanguageCode: targetLanguage, Text: text}}
+	}
+
+	parts := strings.Split(text, wrap)
+	if len(parts) != len(languages) {
+		// the wrap might have been trimmed around line breaks
+		trimmedWrap := strings.TrimSpace(wrap)
+		if trimmedWrap != "" && trimmedWrap != wrap {
+			parts = strings.Split(text, trimmedWrap)
+		}
+	}
+	if len(parts) != len(languages) {
+		return []LanguageTranslation{{LanguageCode: targetLanguage, Text: text}}
+	}
+
+	translations := make([]LanguageTranslation, 0, len(parts))
+	for i, part := range parts {
+		translations = append(translations, LanguageTranslation{LanguageCode: languages[i], Text: strings.TrimSpace(part)})
+	}
+	return translations
+}
+
+func createTranslationCard(translation LanguageTranslation) fyne.CanvasObject {
+	languageName := Messages.InstalledLanguages.GetNameByCode(translation.LanguageCode)
+	if languageName == "" {
+		languageName = translation.LanguageCode
+	}
+
+	textLabel := widget.NewLabel(translation.Text)
+	textLabel.Wrapping = fyne.TextWrapWord
+	textLabel.Selectable = true
+
+	copyButton := widget.NewButtonWithIcon("", theme.ContentCopyIcon(), func() {
+		fyne.CurrentApp().Driver().AllWindows()[0].Clipboard().SetContent(translation.Text)
+	})
+	ttsButton := widget.NewButtonWithIcon(lang.L("TTS"), theme.MediaPlayIcon(), func() {
+		sendMessage := SendMessageChannel.SendMessageStruct{
+			Type: "tts_req",
+			Value: struct {
+				Text     string `json:"text"`
+				ToDevice bool   `json:"to_device"`
+				Download bool   `json:"download"`
+			}{
+				Text:     translation.Text,
+				ToDevice: true,
+				Download: false,
+			},
+		}
+		sendMessage.SendMessage()
+	})
+	oscButton := widget.NewButtonWithIcon(lang.L("OSC"), theme.MailSendIcon(), func() {
+		sendMessage := SendMessageChannel.SendMessageStruct{
+			Type: "send_osc",
+			Value: struct {
+				Text string `json:"text"`
+			}{
+				Text: translation.Text,
+			},
+		}
+		sendMessage.SendMessage()
+	})
+
+	return widget.NewCard(languageName, "", container.NewBorder(nil,
+		container.NewHBox(copyButton, ttsButton, oscButton),
+		nil, nil,
+		textLabel,
+	))
+}
+
+// CreateMultiTargetView shows every target language of the current translation result in its own card.
+// targetLanguage returns the code of the main target language.
+func CreateMultiTargetView(targetLanguage func() string) fyne.CanvasObject {
+	defer Logging.GoRoutineErrorHandler(func(scope *sentry.Scope) {
+		scope.SetTag("GoRoutine", "Pages\\AdditionalTextTranslations\\MultiTargetView->CreateMultiTargetView")
+	})
+
+	cardsContainer := container.NewGridWithColumns(1)
+	cardsScroll := container.NewVScroll(cardsContainer)
+
+	var languagesDialog *dialog2.CustomDialog
+	chooseLanguagesButton := widget.NewButtonWithIcon(lang.L("Additional Translation Languages")+Fields.AdditionalLanguagesCountString(" ", "()"), theme.ListIcon(), nil)
+
+	view := container.NewBorder(
+		container.NewHBox(widget.NewLabel(lang.L("All translations")), chooseLanguagesButton),
+		nil, nil, nil,
+		cardsScroll,
+	)
+
+	update := func() {
+		text, _ := Fields.DataBindings.TranscriptionTranslationInputBinding.Get()
+		translations := SplitTranslations(text, targetLanguage())
+		fyne.Do(func() {
+			cardsContainer.RemoveAll()
+			if strings.TrimSpace(text) == "" || len(translations) < 2 {
+				view.Hide()
+				return
+			}
+			cardsContainer.Layout = layout.NewGridLayoutWithColumns(min(len(translations), 3))
+			for _, translation := range translations {
+				cardsContainer.Add(createTranslationCard(translation))
+			}
+			view.Show()
+			cardsContainer.Refresh()
+		})
+	}
+
+	chooseLanguagesButton.OnTapped = func() {
+		if languagesDialog != nil {
+			languagesDialog.Hide()
+		}
+		languagesDialog = CreateLanguagesListWindow(chooseLanguagesButton)
+		languagesDialog.SetOnClosed(func() {
+			chooseLanguagesButton.SetText(lang.L("Additional Translation Languages") + Fields.AdditionalLanguagesCountString(" ", "()"))
+			update()
+		})
+	}
+
+	Fields.DataBindings.TranscriptionTranslationInputBinding.AddListener(binding.NewDataListener(update))
+
+	view.Hide()
+	return view
+}
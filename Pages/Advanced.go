@@ -3,11 +3,12 @@ package Pages
 import (
 	"io"
 	"net/url"
-	"path/filepath"
 	"strings"
+	"time"
 	"whispering-tiger-ui/CustomWidget"
 	"whispering-tiger-ui/Fields"
 	"whispering-tiger-ui/Logging"
+	"whispering-tiger-ui/Pages/SettingsMappings"
 	"whispering-tiger-ui/Resources"
 	"whispering-tiger-ui/RuntimeBackend"
 	"whispering-tiger-ui/Settings"
@@ -85,12 +86,50 @@ func buildAboutInfo() fyne.CanvasObject {
 	return container.NewVScroll(container.NewCenter(verticalLayout))
 }
 
+// advancedTabs are the tabs of the Advanced window, used to jump to a setting.
+var advancedTabs *container.AppTabs
+
+// ShowAdvancedSetting selects the Advanced Settings tab and scrolls to a setting.
+func ShowAdvancedSetting(name string) {
+	if advancedTabs == nil || SelectMainTab == nil {
+		return
+	}
+	var settingsTab *container.TabItem
+	for _, item := range advancedTabs.Items {
+		if item.Text == lang.L("Advanced Settings") {
+			settingsTab = item
+			break
+		}
+	}
+	if settingsTab == nil {
+		return
+	}
+	advancedTabs.Select(settingsTab)
+	SelectMainTab(lang.L("Advanced"))
+
+	go func() {
+		time.Sleep(settingsSearchLayoutDelay)
+		fyne.Do(func() {
+			settingsScroll, ok := settingsTab.Content.(*container.Scroll)
+			if !ok || Settings.Form == nil {
+				return
+			}
+			for _, formItem := range Settings.Form.Items {
+				if formItem.Text == name {
+					SettingsMappings.ScrollToObject(settingsScroll, formItem.Widget)
+					return
+				}
+			}
+		})
+	}()
+}
+
 func CreateAdvancedWindow() fyne.CanvasObject {
 	defer Logging.GoRoutineErrorHandler(func(scope *sentry.Scope) {
 		scope.SetTag("GoRoutine", "Pages\\Advanced->CreateAdvancedWindow")
 	})
 
-	Settings.Form = Settings.BuildSettingsForm(nil, filepath.Join(Settings.GetConfProfileDir(), Settings.Config.SettingsFilename)).(*widget.Form)
+	Settings.Form = Settings.BuildSettingsForm(nil, Settings.GetBackendSettingsFile()).(*widget.Form)
 
 	settingsTabContent := container.NewVScroll(Settings.Form)
 
@@ -111,6 +150,8 @@ func CreateAdvancedWindow() fyne.CanvasObject {
 
 	sendErrorReportButton := widget.NewButtonWithIcon(lang.L("Send error report"), theme.MailSendIcon(), func() { RuntimeBackend.ErrorReportWithLog(nil) })
 
+	downloadsButton := widget.NewButtonWithIcon(lang.L("Downloads"), theme.DownloadIcon(), ShowDownloadsWindow)
+
 	logToWindowButton := widget.NewButtonWithIcon("", theme.ViewFullScreenIcon(), nil)
 	logToWindowButton.OnTapped = func() {
 		logWindow := fyne.CurrentApp().NewWindow(lang.L("Logs"))
@@ -128,18 +169,21 @@ func CreateAdvancedWindow() fyne.CanvasObject {
 		logWindow.Show()
 	}
 
-	logTabContent := container.NewBorder(nil, container.NewBorder(nil, nil, nil, container.NewHBox(logToWindowButton), container.NewHBox(RestartBackendButton, writeLogFileCheckbox, copyLogButton, sendErrorReportButton)), nil, nil, container.NewScroll(Fields.Field.LogText))
+	logTabContent := container.NewBorder(nil, container.NewBorder(nil, nil, nil, container.NewHBox(logToWindowButton), container.NewHBox(RestartBackendButton, writeLogFileCheckbox, copyLogButton, sendErrorReportButton, downloadsButton)), nil, nil, container.NewScroll(Fields.Field.LogText))
 
 	tabs := container.NewAppTabs(
 		container.NewTabItem(lang.L("About Whispering Tiger"), buildAboutInfo()),
 		container.NewTabItem(lang.L("Advanced Settings"), settingsTabContent),
 		container.NewTabItem(lang.L("Logs"), logTabContent),
+		container.NewTabItem(lang.L("Settings History"), CreateSettingsHistoryWindow()),
+		container.NewTabItem(lang.L("Model Cache"), container.NewStack()),
 	)
 	tabs.SetTabLocation(container.TabLocationLeading)
+	advancedTabs = tabs
 
 	tabs.OnSelected = func(tab *container.TabItem) {
 		if tab.Text == lang.L("Advanced Settings") {
-			Settings.Form = Settings.BuildSettingsForm(nil, filepath.Join(Settings.GetConfProfileDir(), Settings.Config.SettingsFilename)).(*widget.Form)
+			Settings.Form = Settings.BuildSettingsForm(nil, Settings.GetBackendSettingsFile()).(*widget.Form)
 			tab.Content.(*container.Scroll).Content = Settings.Form
 			tab.Content.(*container.Scroll).Content.Refresh()
 			tab.Content.(*container.Scroll).Refresh()
@@ -149,6 +193,15 @@ func CreateAdvancedWindow() fyne.CanvasObject {
 				Fields.Field.LogText.SetText(strings.Join(RuntimeBackend.BackendsList[0].RecentLog, "\n") + "\n")
 			})
 		}
+		if tab.Text == lang.L("Settings History") {
+			tab.Content = CreateSettingsHistoryWindow()
+			tab.Content.Refresh()
+		}
+		if tab.Text == lang.L("Model Cache") {
+			// scanning the cache takes a while, it is only done when the tab is opened
+			tab.Content = CreateModelCacheWindow()
+			tab.Content.Refresh()
+		}
 	}
 
 	// Log logText updater thread
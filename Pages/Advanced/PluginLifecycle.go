@@ -0,0 +1,190 @@
+package Advanced
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"whispering-tiger-ui/SendMessageChannel"
+	"whispering-tiger-ui/Settings"
+	"whispering-tiger-ui/SettingsHistory"
+	"whispering-tiger-ui/UpdateUtility"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/widget"
+)
+
+// setPluginEnabled enables or disables a plugin in the loaded profile and sends it to the backend.
+func setPluginEnabled(pluginClassName string, enabled bool) {
+	if Settings.Config.Plugins == nil {
+		Settings.Config.Plugins = map[string]bool{}
+	}
+	SettingsHistory.Record(SettingsHistory.PluginEnabledName(pluginClassName), Settings.Config.Plugins[pluginClassName], enabled)
+	Settings.Config.Plugins[pluginClassName] = enabled
+	sendMessage := SendMessageChannel.SendMessageStruct{
+		Type:  "setting_change",
+		Name:  "plugins",
+		Value: Settings.Config.Plugins,
+	}
+	sendMessage.SendMessage()
+}
+
+// removePluginSettings removes the settings and the enabled state of plugins from the loaded profile.
+func removePluginSettings(pluginClassNames []string) {
+	pluginSettings := Settings.LoadPluginSettings()
+	for _, pluginClassName := range pluginClassNames {
+		delete(pluginSettings, pluginClassName)
+		delete(Settings.Config.Plugins, pluginClassName)
+	}
+	Settings.Config.Plugin_settings = pluginSettings
+
+	sendMessage := SendMessageChannel.SendMessageStruct{
+		Type:  "setting_change",
+		Name:  "plugin_settings",
+		Value: pluginSettings,
+	}
+	sendMessage.SendMessage()
+	sendMessage = SendMessageChannel.SendMessageStruct{
+		Type:  "setting_change",
+		Name:  "plugins",
+		Value: Settings.Config.Plugins,
+	}
+	sendMessage.SendMessage()
+}
+
+// orphanedPluginSettings returns the plugins that have settings or an enabled state in the loaded profile, but are not installed.
+func orphanedPluginSettings(localPluginFilesData []UpdateUtility.LocalPluginFilesData) []string {
+	var orphaned []string
+	addOrphaned := func(pluginClassName string) {
+		if UpdateUtility.FindLocalPluginFileByClass(localPluginFilesData, pluginClassName).Class == "" && !slices.Contains(orphaned, pluginClassName) {
+			orphaned = append(orphaned, pluginClassName)
+		}
+	}
+	for pluginClassName := range Settings.LoadPluginSettings() {
+		addOrphaned(pluginClassName)
+	}
+	for pluginClassName := range Settings.Config.Plugins {
+		addOrphaned(pluginClassName)
+	}
+	sort.Strings(orphaned)
+	return orphaned
+}
+
+// uninstallPlugin disables a plugin and removes its file. removeSettings also removes its settings from the loaded profile.
+func uninstallPlugin(pluginFile UpdateUtility.LocalPluginFilesData, removeSettings bool) error {
+	if Settings.Config.Plugins[pluginFile.Class] {
+		setPluginEnabled(pluginFile.Class, false)
+	}
+	if err := UpdateUtility.UninstallPlugin(pluginFile); err != nil {
+		return err
+	}
+	if removeSettings {
+		removePluginSettings([]string{pluginFile.Class})
+	}
+	return nil
+}
+
+// showUninstallPluginDialog asks to uninstall a plugin and shows the result.
+func showUninstallPluginDialog(pluginFile UpdateUtility.LocalPluginFilesData, window fyne.Window, onUninstalled func()) {
+	translationVarMap := map[string]interface{}{"Plugin": pluginFile.Class}
+	removeSettingsCheck := widget.NewCheck(lang.L("Also remove the settings of the Plugin"), nil)
+
+	dialog.ShowCustomConfirm(lang.L("Uninstall Plugin"), lang.L("Uninstall"), lang.L("Cancel"), container.NewVBox(
+		widget.NewLabel(lang.L("Are you sure you want to uninstall this Plugin?", translationVarMap)),
+		removeSettingsCheck,
+	), func(b bool) {
+		if !b {
+			return
+		}
+		if err := uninstallPlugin(pluginFile, removeSettingsCheck.Checked); err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		resultText := lang.L("The Plugin was uninstalled. Its settings are kept.", translationVarMap)
+		if removeSettingsCheck.Checked {
+			resultText = lang.L("The Plugin was uninstalled and its settings were removed.", translationVarMap)
+		}
+		if onUninstalled != nil {
+			onUninstalled()
+		}
+		dialog.ShowInformation(lang.L("Plugin uninstalled"), resultText, window)
+	}, window)
+}
+
+// pluginLifecycleMenu returns the actions for an installed plugin. onChanged is called after the plugin file or its state changed.
+func pluginLifecycleMenu(pluginFile UpdateUtility.LocalPluginFilesData, window fyne.Window, onChanged func()) *fyne.Menu {
+	translationVarMap := map[string]interface{}{"Plugin": pluginFile.Class, "Version": pluginFile.LocalVersion}
+	var items []*fyne.MenuItem
+
+	if Settings.Config.Plugins[pluginFile.Class] {
+		items = append(items, fyne.NewMenuItem(lang.L("Disable"), func() {
+			setPluginEnabled(pluginFile.Class, false)
+			dialog.ShowInformation(lang.L("Plugin disabled"), lang.L("The Plugin was disabled in the current profile.", translationVarMap), window)
+		}))
+	}
+
+	if pluginFile.PinnedVersion == "" {
+		items = append(items, fyne.NewMenuItem(lang.L("Pin version"), func() {
+			pinnedVersion, err := UpdateUtility.PinPlugin(pluginFile, true)
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			onChanged()
+			dialog.ShowInformation(lang.L("Plugin pinned"), lang.L("The Plugin is pinned to its version, updates are skipped until it is unpinned.", map[string]interface{}{"Plugin": pluginFile.Class, "Version": pinnedVersion}), window)
+		}))
+	} else {
+		items = append(items, fyne.NewMenuItem(lang.L("Unpin version"), func() {
+			if _, err := UpdateUtility.PinPlugin(pluginFile, false); err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			onChanged()
+			dialog.ShowInformation(lang.L("Plugin unpinned"), lang.L("The Plugin is not pinned anymore, updates are offered again.", translationVarMap), window)
+		}))
+	}
+
+	if pluginFile.HasPrevious {
+		items = append(items, fyne.NewMenuItem(lang.L("RollbackPluginMenuItem", map[string]interface{}{"Version": pluginFile.PreviousVersion}), func() {
+			restoredFile, err := UpdateUtility.RollbackPlugin(pluginFile)
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			// the backend loads the restored file on its next start
+			FreshInstalledPlugins = append(FreshInstalledPlugins, restoredFile.Class)
+			onChanged()
+			dialog.ShowInformation(lang.L("Plugin rolled back"), lang.L("The Plugin was rolled back to the previously installed file.", map[string]interface{}{"Plugin": restoredFile.Class, "Version": restoredFile.LocalVersion}), window)
+		}))
+	}
+
+	uninstallItem := fyne.NewMenuItem(lang.L("Uninstall"), func() {
+		showUninstallPluginDialog(pluginFile, window, onChanged)
+	})
+	items = append(items, fyne.NewMenuItemSeparator(), uninstallItem)
+
+	return fyne.NewMenu("", items...)
+}
+
+// showOrphanedPluginSettingsDialog reports the settings of plugins that are not installed and offers to remove them.
+func showOrphanedPluginSettingsDialog(localPluginFilesData []UpdateUtility.LocalPluginFilesData, window fyne.Window) {
+	orphaned := orphanedPluginSettings(localPluginFilesData)
+	if len(orphaned) == 0 {
+		dialog.ShowInformation(lang.L("Orphaned Plugin settings"), lang.L("No settings of uninstalled Plugins found in the current profile."), window)
+		return
+	}
+
+	orphanedList := widget.NewLabel(" - " + strings.Join(orphaned, "\n - "))
+	dialog.ShowCustomConfirm(lang.L("Orphaned Plugin settings"), lang.L("Remove settings"), lang.L("Close"), container.NewVBox(
+		widget.NewLabel(lang.L("The following Plugins are not installed, but have settings in the current profile:")),
+		orphanedList,
+	), func(b bool) {
+		if !b {
+			return
+		}
+		removePluginSettings(orphaned)
+		dialog.ShowInformation(lang.L("Orphaned Plugin settings"), lang.L("Removed the settings of the uninstalled Plugins.", map[string]interface{}{"Count": len(orphaned)}), window)
+	}, window)
+}
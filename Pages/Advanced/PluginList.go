@@ -12,9 +12,9 @@ import (
 	"fyne.io/fyne/v2/widget"
 	"github.com/getsentry/sentry-go"
 	"image/color"
-	"log"
-	"os"
+	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 	"whispering-tiger-ui/CustomWidget"
@@ -32,7 +32,7 @@ func CreatePluginListWindow(closeFunction func(), backendRunning bool) {
 		scope.SetTag("GoRoutine", "Pages\\Advanced\\PluginList->CreatePluginListWindow")
 	})
 
-	md, err := UpdateUtility.DownloadFile(UpdateUtility.PluginListUrl)
+	plugins, err := UpdateUtility.LoadPluginIndex()
 	if err != nil {
 		Logging.CaptureException(err)
 		print(err)
@@ -85,16 +85,24 @@ func CreatePluginListWindow(closeFunction func(), backendRunning bool) {
 
 	loadingBar := widget.NewProgressBarInfinite()
 
-	table := UpdateUtility.ExtractTable(md)
+	localPluginFilesData := UpdateUtility.ParseLocalPluginFiles()
 
-	tableData := UpdateUtility.ParseTableIntoStruct(table, UpdateUtility.PluginRelativeUrlPrefix)
+	pluginWidgets := make([]*UpdateUtility.TableDataWidgets, len(plugins))
 
-	localPluginFilesData := UpdateUtility.ParseLocalPluginFiles()
+	// refreshPlugin updates the widgets of a plugin after its local file or state was changed
+	refreshPlugin := func(pluginClassName string) {
+		localPluginFilesData = UpdateUtility.ParseLocalPluginFiles()
+		for i := range plugins {
+			if plugins[i].Id == pluginClassName && pluginWidgets[i] != nil {
+				UpdateUtility.PluginsUpdateWidgetsRefresh(&plugins[i], pluginWidgets[i], localPluginFilesData)
+			}
+		}
+	}
 
 	checkAllButton := widget.NewButton(lang.L("Check all Plugins for Updates"), func() {
 		loadingBar.Show()
-		for _, row := range tableData {
-			UpdateUtility.PluginsUpdateWidgetsRefresh(&row, localPluginFilesData)
+		for i := range plugins {
+			UpdateUtility.PluginsUpdateWidgetsRefresh(&plugins[i], pluginWidgets[i], localPluginFilesData)
 		}
 		loadingBar.Hide()
 	})
@@ -102,11 +110,15 @@ func CreatePluginListWindow(closeFunction func(), backendRunning bool) {
 	// hide button as we already update on window open
 	checkAllButton.Hide()
 
+	orphanedSettingsButton := widget.NewButtonWithIcon(lang.L("Orphaned Plugin settings"), theme.SearchIcon(), func() {
+		showOrphanedPluginSettingsDialog(localPluginFilesData, pluginListWindow)
+	})
+
 	grid := container.New(layout.NewFormLayout())
 
 	// Set the content of the window to the table container
 	scrollContainer := container.NewVScroll(grid)
-	verticalContent := container.NewBorder(container.NewVBox(checkAllButton, loadingBar), nil, nil, nil, scrollContainer)
+	verticalContent := container.NewBorder(container.NewVBox(checkAllButton, container.NewBorder(nil, nil, nil, orphanedSettingsButton), loadingBar), nil, nil, nil, scrollContainer)
 	pluginListWindow.SetContent(verticalContent)
 
 	// Show and run the application
@@ -114,10 +126,57 @@ func CreatePluginListWindow(closeFunction func(), backendRunning bool) {
 
 	loadingBar.Start()
 
-	// iterate over the table data and create a new widget for each row
-	for _, row := range tableData {
+	// installPlugins installs a plugin together with its dependencies, which are first in the order
+	installPlugins := func(order []UpdateUtility.PluginIndexEntry) {
+		var installedClasses []string
+		for _, plugin := range order {
+			pluginFile, err := UpdateUtility.InstallPlugin(plugin, localPluginFilesData)
+			if err != nil {
+				Logging.CaptureException(err)
+				dialog.ShowError(err, pluginListWindow)
+				break
+			}
+			localPluginFilesData = slices.DeleteFunc(localPluginFilesData, func(file UpdateUtility.LocalPluginFilesData) bool {
+				return file.Class == pluginFile.Class
+			})
+			localPluginFilesData = append(localPluginFilesData, pluginFile)
+
+			for i := range plugins {
+				if plugins[i].Id != pluginFile.Class || pluginWidgets[i] == nil {
+					continue
+				}
+				pluginWidgets[i].CurrentVersion.Color = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+				pluginWidgets[i].CurrentVersion.Text = "  " + lang.L("Current V") + ": " + pluginFile.LocalVersion
+				pluginWidgets[i].CurrentVersion.Refresh()
 
-		title := row.Title
+				pluginWidgets[i].UpdateButton.Importance = widget.LowImportance
+				pluginWidgets[i].UpdateButton.SetText(lang.L("Installed"))
+			}
+
+			sendMessage := SendMessageChannel.SendMessageStruct{
+				Type:  "plugin_install",
+				Name:  "plugin",
+				Value: map[string]string{"name": pluginFile.Class, "file": filepath.Base(pluginFile.FilePath)},
+			}
+			sendMessage.SendMessage()
+
+			// add to FreshInstalledPlugins list
+			FreshInstalledPlugins = append(FreshInstalledPlugins, pluginFile.Class)
+			installedClasses = append(installedClasses, pluginFile.Class)
+		}
+
+		// show success installed dialog
+		if len(installedClasses) > 0 {
+			dialog.ShowInformation(lang.L("Plugin Installed"), lang.L("Plugin has been installed. The Plugin is disabled by default.", map[string]interface{}{"Plugin": strings.Join(installedClasses, ", ")})+"\n",
+				pluginListWindow)
+		}
+	}
+
+	// iterate over the plugin list and create a new widget for each plugin
+	for pluginIndex := range plugins {
+		row := &plugins[pluginIndex]
+
+		title := row.Name
 
 		titleLabel := canvas.NewText(title, color.RGBA{255, 255, 255, 255})
 		titleLabel.TextSize = theme.TextSubHeadingSize()
@@ -130,56 +189,40 @@ func CreatePluginListWindow(closeFunction func(), backendRunning bool) {
 		author := row.Author
 		authorLabel := widget.NewLabel(lang.L("Author") + ":\n" + author)
 
-		row.Widgets.RemoteVersion = remoteVersionLabel
-		row.Widgets.CurrentVersion = currentVersionLabel
-
-		titleLink := row.TitleLink
+		titleLink := row.Homepage
+		if titleLink == "" {
+			titleLink = row.Url
+		}
 
 		titleButton := widget.NewButtonWithIcon(lang.L("Update")+" / "+lang.L("Install"), theme.DownloadIcon(), nil)
 		titleButton.OnTapped = func() {
-			version, class, _, fileContent := UpdateUtility.FetchAndAnalyzePluginUrl(titleLink)
-
-			pluginFileName := Utilities.CamelToSnake(class) + ".py"
-			pluginFileDir := UpdateUtility.PluginDir + pluginFileName
-
-			localPluginFile := UpdateUtility.FindLocalPluginFileByClass(localPluginFilesData, class)
-			if localPluginFile.Class != "" {
-				pluginFileDir = localPluginFile.FilePath
-			}
-
-			// write the file to disk
-			err := os.WriteFile(pluginFileDir, fileContent, 0644)
+			order, err := UpdateUtility.PluginInstallOrder(plugins, *row, localPluginFilesData)
 			if err != nil {
-				window, _ := Utilities.GetCurrentMainWindow("Error writing file")
-				dialog.ShowError(err, window)
-				log.Fatalf("Error writing file: %v", err)
+				Logging.CaptureException(err)
+				dialog.ShowError(err, pluginListWindow)
+				return
 			}
-
-			//currentVersionLabel.SetText("Current V: " + version)
-			currentVersionLabel.Color = color.RGBA{R: 255, G: 255, B: 255, A: 255}
-			currentVersionLabel.Text = "  " + lang.L("Current V") + ": " + version
-			currentVersionLabel.Refresh()
-
-			titleButton.Importance = widget.LowImportance
-			titleButton.SetText(lang.L("Installed"))
-
-			// show success installed dialog
-
-			dialog.ShowInformation(lang.L("Plugin Installed"), lang.L("Plugin has been installed. The Plugin is disabled by default.", map[string]interface{}{"Plugin": class})+"\n",
-				pluginListWindow)
-
-			sendMessage := SendMessageChannel.SendMessageStruct{
-				Type:  "plugin_install",
-				Name:  "plugin",
-				Value: map[string]string{"name": class, "file": pluginFileName},
+			if len(order) <= 1 {
+				installPlugins([]UpdateUtility.PluginIndexEntry{*row})
+				return
 			}
-			sendMessage.SendMessage()
-
-			// add to FreshInstalledPlugins list
-			FreshInstalledPlugins = append(FreshInstalledPlugins, class)
+			var dependencyNames []string
+			for _, dependency := range order[:len(order)-1] {
+				dependencyNames = append(dependencyNames, " - "+dependency.Name)
+			}
+			dialog.ShowConfirm(lang.L("Install Plugin dependencies"), lang.L("The Plugin requires other Plugins. Install them as well?", map[string]interface{}{"Plugin": row.Name})+"\n\n"+strings.Join(dependencyNames, "\n"), func(b bool) {
+				if b {
+					installPlugins(order)
+				}
+			}, pluginListWindow)
 		}
 
-		row.Widgets.UpdateButton = titleButton
+		pluginWidgets[pluginIndex] = &UpdateUtility.TableDataWidgets{
+			UpdateButton:   titleButton,
+			CurrentVersion: currentVersionLabel,
+			RemoteVersion:  remoteVersionLabel,
+		}
+		rowWidgets := pluginWidgets[pluginIndex]
 
 		descriptionText := strings.ReplaceAll(row.Description, "<sub>", "\n<sub>")
 		descriptionText = strings.ReplaceAll(descriptionText, "</sub>", "</sub>")
@@ -199,7 +242,7 @@ func CreatePluginListWindow(closeFunction func(), backendRunning bool) {
 		descriptionLabel := widget.NewRichTextFromMarkdown(descriptionText)
 		descriptionLabel.Wrapping = fyne.TextWrapWord
 
-		grid.Add(container.NewVBox(row.Widgets.UpdateButton, row.Widgets.RemoteVersion, row.Widgets.CurrentVersion))
+		grid.Add(container.NewVBox(rowWidgets.UpdateButton, rowWidgets.RemoteVersion, rowWidgets.CurrentVersion))
 
 		openPageButton := widget.NewButton(lang.L("Open Webpage"), func() {
 			err := fyne.CurrentApp().OpenURL(parseURL(titleLink))
@@ -209,9 +252,22 @@ func CreatePluginListWindow(closeFunction func(), backendRunning bool) {
 			}
 		})
 
-		rightColumn := container.NewBorder(authorLabel, nil, nil, nil, openPageButton)
+		manageButton := widget.NewButtonWithIcon(lang.L("Manage"), theme.SettingsIcon(), nil)
+		manageButton.OnTapped = func() {
+			pluginFile := UpdateUtility.FindLocalPluginFileByClass(localPluginFilesData, row.Id)
+			if row.Id == "" || pluginFile.Class == "" {
+				dialog.ShowInformation(lang.L("Manage"), lang.L("The Plugin is not installed."), pluginListWindow)
+				return
+			}
+			menu := pluginLifecycleMenu(pluginFile, pluginListWindow, func() {
+				refreshPlugin(pluginFile.Class)
+			})
+			widget.ShowPopUpMenuAtRelativePosition(menu, pluginListWindow.Canvas(), fyne.NewPos(0, manageButton.Size().Height), manageButton)
+		}
+
+		rightColumn := container.NewBorder(authorLabel, manageButton, nil, nil, openPageButton)
 
-		previewLink := row.PreviewLink
+		previewLink := row.Preview
 
 		previewImageContainer := container.NewStack()
 		previewBorder := CustomWidget.NewHoverableBorder(container.NewBorder(container.NewPadded(titleLabel), nil, nil, previewImageContainer, descriptionLabel),
@@ -28,6 +28,7 @@ import (
 	"whispering-tiger-ui/Logging"
 	"whispering-tiger-ui/SendMessageChannel"
 	"whispering-tiger-ui/Settings"
+	"whispering-tiger-ui/SettingsHistory"
 	"whispering-tiger-ui/Utilities"
 )
 
@@ -152,56 +153,58 @@ func RebuildSinglePluginSettings(pluginClassName string, pluginAccordionItem *wi
 	}
 }
 
+// OpenPluginSettingsWindow shows the settings of a plugin in a separate window.
+// pluginAccordionItem is used to guess the window size and can be nil.
+func OpenPluginSettingsWindow(pluginClassName string, pluginAccordionItem *widget.AccordionItem) {
+	pluginWindow := fyne.CurrentApp().NewWindow(pluginClassName + " " + lang.L("Settings"))
+	reloadButton := widget.NewButtonWithIcon(lang.L("Reload"), theme.ViewRefreshIcon(), nil)
+
+	pluginContentWin := BuildSinglePluginSettings(pluginClassName, nil, nil, reloadButton, pluginWindow)
+	pluginWindowContainer := container.NewVScroll(pluginContentWin)
+
+	reloadButton.OnTapped = func() {
+		pluginContentWin = BuildSinglePluginSettings(pluginClassName, nil, nil, reloadButton, pluginWindow)
+		pluginWindowContainer.Content = pluginContentWin
+		pluginWindowContainer.Refresh()
+		pluginWindow.Content().Refresh()
+	}
+	reloadButton.Importance = widget.MediumImportance
+
+	pluginWindow.SetContent(container.NewBorder(container.NewBorder(nil, nil, nil, reloadButton, layout.NewSpacer()), nil, nil, nil, pluginWindowContainer))
+
+	// guess the size
+	windowHeight := pluginContentWin.Size().Height + reloadButton.Size().Height + 20
+	windowWidth := pluginContentWin.Size().Width
+	if windowHeight >= fyne.CurrentApp().Driver().AllWindows()[0].Canvas().Size().Height {
+		windowHeight = fyne.CurrentApp().Driver().AllWindows()[0].Canvas().Size().Height
+	}
+	if pluginAccordionItem != nil {
+		windowHeight = pluginAccordionItem.Detail.Size().Height + reloadButton.Size().Height + 20
+		windowWidth = pluginAccordionItem.Detail.Size().Width
+		if windowHeight >= fyne.CurrentApp().Driver().AllWindows()[0].Canvas().Size().Height {
+			windowHeight = fyne.CurrentApp().Driver().AllWindows()[0].Canvas().Size().Height
+		}
+		if windowWidth >= 1400 {
+			windowWidth = windowWidth / 2
+		}
+	}
+	pluginWindow.Resize(fyne.NewSize(windowWidth, windowHeight))
+	pluginWindow.CenterOnScreen()
+	pluginWindow.Show()
+}
+
 func BuildSinglePluginSettings(pluginClassName string, pluginAccordionItem *widget.AccordionItem, pluginAccordion *widget.Accordion, reloadButtonRef *widget.Button, window fyne.Window) fyne.CanvasObject {
 	defer Logging.GoRoutineErrorHandler(func(scope *sentry.Scope) {
 		scope.SetTag("GoRoutine", "Pages\\Advanced\\PluginSettings->BuildSinglePluginSettings")
 	})
 
 	// load settings file for plugin settings
-	SettingsFile := Settings.Conf{}
-	err := SettingsFile.LoadYamlSettings(filepath.Join(Settings.GetConfProfileDir(), Settings.Config.SettingsFilename))
-	if err != nil {
-		SettingsFile = Settings.Config
-	}
+	SettingsFile := Settings.LoadBackendSettings()
 
 	// plugin to window button
 	pluginToWindowButton := widget.NewButtonWithIcon("", theme.ViewFullScreenIcon(), nil)
 	pluginToWindowButton.OnTapped = func() {
-		pluginWindow := fyne.CurrentApp().NewWindow(pluginClassName + " " + lang.L("Settings"))
-		reloadButton := widget.NewButtonWithIcon(lang.L("Reload"), theme.ViewRefreshIcon(), nil)
-
-		pluginContentWin := BuildSinglePluginSettings(pluginClassName, nil, nil, reloadButton, pluginWindow)
-		pluginWindowContainer := container.NewVScroll(pluginContentWin)
-
-		reloadButton.OnTapped = func() {
-			pluginContentWin = BuildSinglePluginSettings(pluginClassName, nil, nil, reloadButton, pluginWindow)
-			pluginWindowContainer.Content = pluginContentWin
-			pluginWindowContainer.Refresh()
-			pluginWindow.Content().Refresh()
-		}
-		reloadButton.Importance = widget.MediumImportance
-
-		pluginWindow.SetContent(container.NewBorder(container.NewBorder(nil, nil, nil, reloadButton, layout.NewSpacer()), nil, nil, nil, pluginWindowContainer))
-
-		// guess the size
-		windowHeight := pluginContentWin.Size().Height + reloadButton.Size().Height + 20
-		windowWidth := pluginContentWin.Size().Width
-		if windowHeight >= fyne.CurrentApp().Driver().AllWindows()[0].Canvas().Size().Height {
-			windowHeight = fyne.CurrentApp().Driver().AllWindows()[0].Canvas().Size().Height
-		}
-		if pluginAccordionItem != nil {
-			windowHeight = pluginAccordionItem.Detail.Size().Height + reloadButton.Size().Height + 20
-			windowWidth = pluginAccordionItem.Detail.Size().Width
-			if windowHeight >= fyne.CurrentApp().Driver().AllWindows()[0].Canvas().Size().Height {
-				windowHeight = fyne.CurrentApp().Driver().AllWindows()[0].Canvas().Size().Height
-			}
-			if windowWidth >= 1400 {
-				windowWidth = windowWidth / 2
-			}
-		}
-		pluginWindow.Resize(fyne.NewSize(windowWidth, windowHeight))
-		pluginWindow.CenterOnScreen()
-		pluginWindow.Show()
+		OpenPluginSettingsWindow(pluginClassName, pluginAccordionItem)
 	}
 	if pluginAccordionItem == nil || pluginAccordion == nil {
 		pluginToWindowButton.Hide()
@@ -253,13 +256,7 @@ func BuildSinglePluginSettings(pluginClassName string, pluginAccordionItem *widg
 
 	// plugin enabled checkbox
 	pluginEnabledCheckbox := widget.NewCheck(lang.L("pluginClass enabled", map[string]interface{}{"PluginClass": pluginClassName}), func(enabled bool) {
-		Settings.Config.Plugins[pluginClassName] = enabled
-		sendMessage := SendMessageChannel.SendMessageStruct{
-			Type:  "setting_change",
-			Name:  "plugins",
-			Value: Settings.Config.Plugins,
-		}
-		sendMessage.SendMessage()
+		setPluginEnabled(pluginClassName, enabled)
 
 		if pluginAccordionItem != nil && pluginAccordion != nil {
 			pluginAccordionItem.Title = pluginClassName + getPluginStatusString(pluginClassName)
@@ -283,6 +280,7 @@ func BuildSinglePluginSettings(pluginClassName string, pluginAccordionItem *widg
 			}
 		}
 	}
+	SettingsHistory.TrackPluginSettings(pluginClassName, pluginSettings)
 
 	// check if settings_groups exists
 	if groupData, exists := pluginSettings["settings_groups"]; exists && groupData != nil {
@@ -1111,6 +1109,7 @@ func createSettingsFields(pluginSettings map[string]interface{}, settingName str
 // Helper function to update settings
 func updateSettings(SettingsFile Settings.Conf, pluginClassName string, pluginSettings map[string]interface{}) {
 	SettingsFile.Plugin_settings.(map[string]interface{})[pluginClassName] = pluginSettings
+	SettingsHistory.RecordPluginSettings(pluginClassName, pluginSettings)
 	sendMessage := SendMessageChannel.SendMessageStruct{
 		Type:  "setting_change",
 		Name:  "plugin_settings",
@@ -0,0 +1,237 @@
+package Pages
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"whispering-tiger-ui/BatchJobs"
+	"whispering-tiger-ui/Fields"
+	"whispering-tiger-ui/Logging"
+	"whispering-tiger-ui/Websocket/Messages"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/getsentry/sentry-go"
+)
+
+var batchJobsWindow fyne.Window
+
+func batchJobStatusText(job *BatchJobs.Job, state BatchJobs.JobState) string {
+	statusText := lang.L("BatchJobStatus_" + string(state.Status))
+	if state.Total > 0 && (state.Status == BatchJobs.StatusRunning || state.Status == BatchJobs.StatusPaused) {
+		statusText += " (" + lang.L("BatchJobProgress", map[string]interface{}{"Done": state.Done, "Total": state.Total}) + ")"
+	}
+	if state.Err != nil {
+		statusText += ": " + state.Err.Error()
+	}
+	if state.Status == BatchJobs.StatusDone && job.OutputFile != "" {
+		statusText += " → " + job.OutputFile
+	}
+	return statusText
+}
+
+func ShowBatchJobsWindow() {
+	defer Logging.GoRoutineErrorHandler(func(scope *sentry.Scope) {
+		scope.SetTag("GoRoutine", "Pages\\BatchJobsWindow->ShowBatchJobsWindow")
+	})
+
+	if batchJobsWindow != nil {
+		batchJobsWindow.RequestFocus()
+		return
+	}
+
+	batchJobsWindow = fyne.CurrentApp().NewWindow(lang.L("Batch Jobs"))
+	window := batchJobsWindow
+
+	var sourceLanguageOptions []string
+	var targetLanguageOptions []string
+	for _, language := range Messages.InstalledLanguages.Languages {
+		if language.Code == "" || language.Code == "auto" {
+			continue
+		}
+		sourceLanguageOptions = append(sourceLanguageOptions, language.Name)
+		targetLanguageOptions = append(targetLanguageOptions, language.Name)
+	}
+	sourceLanguageOptions = append([]string{"Auto"}, sourceLanguageOptions...)
+
+	sourceLanguageSelect := widget.NewSelect(sourceLanguageOptions, nil)
+	sourceLanguageSelect.SetSelected(Fields.Field.SourceLanguageCombo.Text)
+	if sourceLanguageSelect.Selected == "" {
+		sourceLanguageSelect.SetSelected("Auto")
+	}
+	targetLanguageSelect := widget.NewSelect(targetLanguageOptions, nil)
+	targetLanguageSelect.SetSelected(Fields.Field.TargetLanguageCombo.Text)
+
+	outputDir := fyne.CurrentApp().Preferences().StringWithFallback("BatchJobsOutputDir", "")
+	outputDirLabel := widget.NewLabel("")
+	updateOutputDirLabel := func() {
+		if outputDir == "" {
+			outputDirLabel.SetText(lang.L("Same directory as input file"))
+		} else {
+			outputDirLabel.SetText(outputDir)
+		}
+	}
+	updateOutputDirLabel()
+	outputDirButton := widget.NewButtonWithIcon(lang.L("Choose"), theme.FolderOpenIcon(), func() {
+		folderDialog := dialog.NewFolderOpen(func(uri fyne.ListableURI, err error) {
+			if err != nil || uri == nil {
+				return
+			}
+			outputDir = uri.Path()
+			fyne.CurrentApp().Preferences().SetString("BatchJobsOutputDir", outputDir)
+			updateOutputDirLabel()
+		}, window)
+		if outputDir != "" {
+			if _, err := os.Stat(outputDir); err == nil {
+				fileLister, _ := storage.ListerForURI(storage.NewFileURI(outputDir))
+				folderDialog.SetLocation(fileLister)
+			}
+		}
+		folderDialog.Resize(window.Canvas().Size())
+		folderDialog.Show()
+	})
+	outputDirResetButton := widget.NewButtonWithIcon("", theme.ContentClearIcon(), func() {
+		outputDir = ""
+		fyne.CurrentApp().Preferences().SetString("BatchJobsOutputDir", outputDir)
+		updateOutputDirLabel()
+	})
+
+	addFile := func(fileName string) {
+		fromLang := Messages.InstalledLanguages.GetCodeByName(sourceLanguageSelect.Selected)
+		if fromLang == "" {
+			fromLang = "auto"
+		}
+		toLang := Messages.InstalledLanguages.GetCodeByName(targetLanguageSelect.Selected)
+		if _, err := BatchJobs.Default.Add(fileName, outputDir, fromLang, toLang); err != nil {
+			dialog.ShowError(err, window)
+		}
+	}
+
+	addFilesButton := widget.NewButtonWithIcon(lang.L("Add file"), theme.ContentAddIcon(), func() {
+		fileDialog := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil || reader == nil {
+				return
+			}
+			fileName := reader.URI().Path()
+			_ = reader.Close()
+			fyne.CurrentApp().Preferences().SetString("BatchJobsLastInputDir", filepath.Dir(fileName))
+			addFile(fileName)
+		}, window)
+		fileDialog.SetFilter(storage.NewExtensionFileFilter(BatchJobs.SupportedExtensions()))
+		lastInputDir := fyne.CurrentApp().Preferences().StringWithFallback("BatchJobsLastInputDir", "")
+		if lastInputDir != "" {
+			if _, err := os.Stat(lastInputDir); err == nil {
+				fileLister, _ := storage.ListerForURI(storage.NewFileURI(lastInputDir))
+				fileDialog.SetLocation(fileLister)
+			}
+		}
+		fileDialog.Resize(window.Canvas().Size())
+		fileDialog.Show()
+	})
+	addFilesButton.Importance = widget.HighImportance
+
+	window.SetOnDropped(func(position fyne.Position, uris []fyne.URI) {
+		for _, uri := range uris {
+			addFile(uri.Path())
+		}
+	})
+
+	jobsList := widget.NewList(
+		func() int {
+			return len(BatchJobs.Default.Jobs())
+		},
+		func() fyne.CanvasObject {
+			fileLabel := widget.NewLabel("")
+			fileLabel.Truncation = fyne.TextTruncateEllipsis
+			statusLabel := widget.NewLabel("")
+			statusLabel.Truncation = fyne.TextTruncateEllipsis
+			statusLabel.SizeName = theme.SizeNameCaptionText
+			return container.NewBorder(nil, nil, nil,
+				container.NewHBox(
+					widget.NewButtonWithIcon("", theme.MediaPauseIcon(), nil),
+					widget.NewButtonWithIcon("", theme.CancelIcon(), nil),
+				),
+				container.NewVBox(fileLabel, widget.NewProgressBar(), statusLabel),
+			)
+		},
+		func(id widget.ListItemID, object fyne.CanvasObject) {
+			jobs := BatchJobs.Default.Jobs()
+			if id >= len(jobs) {
+				return
+			}
+			job := jobs[id]
+			state := job.State()
+
+			row := object.(*fyne.Container)
+			infoBox := row.Objects[0].(*fyne.Container)
+			buttons := row.Objects[1].(*fyne.Container)
+			fileLabel := infoBox.Objects[0].(*widget.Label)
+			progressBar := infoBox.Objects[1].(*widget.ProgressBar)
+			statusLabel := infoBox.Objects[2].(*widget.Label)
+			pauseButton := buttons.Objects[0].(*widget.Button)
+			cancelButton := buttons.Objects[1].(*widget.Button)
+
+			jobDescription := filepath.Base(job.InputFile)
+			if job.Type == BatchJobs.JobTypeTranslate {
+				jobDescription += "  [" + job.FromLang + " → " + job.ToLang + "]"
+			} else {
+				jobDescription += "  [" + lang.L("Transcription") + "]"
+			}
+			fileLabel.SetText(jobDescription)
+			progressBar.SetValue(state.Progress())
+			statusLabel.SetText(batchJobStatusText(job, state))
+
+			finished := state.Status == BatchJobs.StatusDone || state.Status == BatchJobs.StatusFailed || state.Status == BatchJobs.StatusCancelled
+			if state.Status == BatchJobs.StatusPaused {
+				pauseButton.SetIcon(theme.MediaPlayIcon())
+				pauseButton.OnTapped = job.Resume
+			} else {
+				pauseButton.SetIcon(theme.MediaPauseIcon())
+				pauseButton.OnTapped = job.Pause
+			}
+			cancelButton.OnTapped = job.Cancel
+			if finished {
+				pauseButton.Disable()
+				cancelButton.Disable()
+			} else {
+				pauseButton.Enable()
+				cancelButton.Enable()
+			}
+		},
+	)
+
+	BatchJobs.Default.SetOnChange(func() {
+		fyne.Do(func() {
+			jobsList.Refresh()
+		})
+	})
+
+	removeFinishedButton := widget.NewButtonWithIcon(lang.L("Remove finished"), theme.DeleteIcon(), BatchJobs.Default.RemoveFinished)
+
+	settingsForm := widget.NewForm(
+		widget.NewFormItem(lang.L("Source Language"), sourceLanguageSelect),
+		widget.NewFormItem(lang.L("Target Language"), targetLanguageSelect),
+		widget.NewFormItem(lang.L("Output directory"), container.NewBorder(nil, nil, nil, container.NewHBox(outputDirButton, outputDirResetButton), outputDirLabel)),
+	)
+
+	hintLabel := widget.NewLabel(lang.L("BatchJobsHint", map[string]interface{}{"Extensions": strings.Join(BatchJobs.SupportedExtensions(), ", ")}))
+	hintLabel.Wrapping = fyne.TextWrapWord
+
+	window.SetContent(container.NewBorder(
+		container.NewVBox(settingsForm, hintLabel, container.NewHBox(addFilesButton)),
+		container.NewHBox(removeFinishedButton),
+		nil, nil,
+		jobsList,
+	))
+	window.SetOnClosed(func() {
+		BatchJobs.Default.SetOnChange(nil)
+		batchJobsWindow = nil
+	})
+	window.Resize(fyne.NewSize(700, 500))
+	window.Show()
+}
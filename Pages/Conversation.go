@@ -0,0 +1,195 @@
+package Pages
+
+import (
+	"strings"
+	"whispering-tiger-ui/Conversation"
+	"whispering-tiger-ui/CustomWidget"
+	"whispering-tiger-ui/Fields"
+	"whispering-tiger-ui/Logging"
+	"whispering-tiger-ui/Websocket/Messages"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/getsentry/sentry-go"
+)
+
+// refreshConversationOptions reloads the language and voice options, which are received from the backend after the page is created.
+var refreshConversationOptions func()
+
+// OnOpenConversationWindow is called when the conversation tab is selected.
+func OnOpenConversationWindow() {
+	if refreshConversationOptions != nil {
+		refreshConversationOptions()
+	}
+}
+
+func voiceOptions() []CustomWidget.TextValueOption {
+	var options []CustomWidget.TextValueOption
+	for _, option := range Fields.Field.TtsVoiceCombo.Options {
+		if option.Value == "" || option.Value == "last" || strings.HasPrefix(option.Value, "open_dir:") {
+			continue
+		}
+		options = append(options, option)
+	}
+	return options
+}
+
+func createConversationMessage(message Conversation.Message, lane Conversation.Lane) fyne.CanvasObject {
+	headerLabel := widget.NewLabel(lane.Name + " · " + message.Time.Format("15:04:05"))
+	headerLabel.SizeName = theme.SizeNameCaptionText
+	textLabel := widget.NewLabel(message.Text)
+	textLabel.Wrapping = fyne.TextWrapWord
+	textLabel.Selectable = true
+
+	translationText := message.Translation
+	if message.Err != nil {
+		translationText = lang.L("Translation failed") + ": " + message.Err.Error()
+	} else if translationText == "" {
+		translationText = "…"
+	}
+	translationLabel := widget.NewLabel(translationText)
+	translationLabel.Wrapping = fyne.TextWrapWord
+	translationLabel.Selectable = true
+	translationLabel.TextStyle = fyne.TextStyle{Italic: true}
+	if message.Err != nil {
+		translationLabel.Importance = widget.DangerImportance
+	}
+
+	bubble := widget.NewCard("", "", container.NewVBox(headerLabel, textLabel, widget.NewSeparator(), translationLabel))
+	if message.Lane == 0 {
+		return container.NewGridWithColumns(2, bubble, widget.NewLabel(""))
+	}
+	return container.NewGridWithColumns(2, widget.NewLabel(""), bubble)
+}
+
+func CreateConversationWindow() fyne.CanvasObject {
+	defer Logging.GoRoutineErrorHandler(func(scope *sentry.Scope) {
+		scope.SetTag("GoRoutine", "Pages\\Conversation->CreateConversationWindow")
+	})
+
+	conf := Conversation.GetConfig()
+
+	saveConfig := func() {
+		Conversation.SetConfig(conf)
+		if err := Conversation.Save(); err != nil {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+		}
+	}
+
+	var refreshFunctions []func()
+	createLaneForm := func(laneIndex int) fyne.CanvasObject {
+		nameEntry := widget.NewEntry()
+		nameEntry.SetText(conf.Lanes[laneIndex].Name)
+		nameEntry.OnChanged = func(s string) {
+			conf.Lanes[laneIndex].Name = s
+			saveConfig()
+		}
+
+		languageSelect := widget.NewSelect(nil, func(s string) {
+			code := Messages.InstalledLanguages.GetCodeByName(s)
+			if code == conf.Lanes[laneIndex].Language {
+				return
+			}
+			conf.Lanes[laneIndex].Language = code
+			saveConfig()
+		})
+		voiceSelect := widget.NewSelect(nil, func(s string) {
+			for _, option := range voiceOptions() {
+				if option.Text == s && option.Value != conf.Lanes[laneIndex].Voice {
+					conf.Lanes[laneIndex].Voice = option.Value
+					saveConfig()
+				}
+			}
+		})
+
+		refresh := func() {
+			var languageNames []string
+			for _, language := range Messages.InstalledLanguages.Languages {
+				if language.Code == "" || language.Code == "auto" {
+					continue
+				}
+				languageNames = append(languageNames, language.Name)
+			}
+			languageSelect.SetOptions(languageNames)
+			if conf.Lanes[laneIndex].Language != "" {
+				languageSelect.SetSelected(Messages.InstalledLanguages.GetNameByCode(conf.Lanes[laneIndex].Language))
+			}
+
+			var voiceNames []string
+			for _, option := range voiceOptions() {
+				voiceNames = append(voiceNames, option.Text)
+				if option.Value == conf.Lanes[laneIndex].Voice {
+					defer voiceSelect.SetSelected(option.Text)
+				}
+			}
+			voiceSelect.SetOptions(voiceNames)
+		}
+		refreshFunctions = append(refreshFunctions, refresh)
+		refresh()
+
+		return widget.NewCard(lang.L("Person")+" "+string(rune('A'+laneIndex)), "", widget.NewForm(
+			widget.NewFormItem(lang.L("Name"), nameEntry),
+			widget.NewFormItem(lang.L("Spoken Language"), languageSelect),
+			widget.NewFormItem(lang.L("Voice"), voiceSelect),
+		))
+	}
+
+	lanesRow := container.NewGridWithColumns(2, createLaneForm(0), createLaneForm(1))
+	refreshConversationOptions = func() {
+		for _, refresh := range refreshFunctions {
+			refresh()
+		}
+	}
+
+	enabledCheck := widget.NewCheck(lang.L("Enable conversation mode"), func(b bool) {
+		Conversation.SetEnabled(b)
+		if b {
+			refreshConversationOptions()
+		}
+	})
+	enabledCheck.Checked = Conversation.IsEnabled()
+	speakCheck := widget.NewCheck(lang.L("Speak translations"), func(b bool) {
+		conf.SpeakTranslations = b
+		saveConfig()
+	})
+	speakCheck.Checked = conf.SpeakTranslations
+	oscCheck := widget.NewCheck(lang.L("Send translations to OSC"), func(b bool) {
+		conf.SendOsc = b
+		saveConfig()
+	})
+	oscCheck.Checked = conf.SendOsc
+	clearButton := widget.NewButtonWithIcon(lang.L("Clear"), theme.DeleteIcon(), Conversation.ClearMessages)
+
+	hintLabel := widget.NewLabel(lang.L("ConversationModeHint"))
+	hintLabel.Wrapping = fyne.TextWrapWord
+	hintLabel.Importance = widget.LowImportance
+
+	messagesContainer := container.NewVBox()
+	messagesScroll := container.NewVScroll(messagesContainer)
+	Conversation.SetOnChange(func() {
+		fyne.Do(func() {
+			currentConf := Conversation.GetConfig()
+			messagesContainer.RemoveAll()
+			for _, message := range Conversation.Messages() {
+				messagesContainer.Add(createConversationMessage(message, currentConf.Lanes[message.Lane]))
+			}
+			messagesContainer.Refresh()
+			messagesScroll.ScrollToBottom()
+		})
+	})
+
+	return container.NewBorder(
+		container.NewVBox(
+			container.NewHBox(enabledCheck, speakCheck, oscCheck),
+			hintLabel,
+			lanesRow,
+		),
+		container.NewHBox(clearButton),
+		nil, nil,
+		messagesScroll,
+	)
+}
@@ -0,0 +1,180 @@
+package Pages
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"time"
+	"whispering-tiger-ui/Logging"
+	"whispering-tiger-ui/ModelDownloader"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/dustin/go-humanize"
+	"github.com/getsentry/sentry-go"
+)
+
+var downloadsWindow fyne.Window
+
+func downloadSpeedText(speed float64) string {
+	if speed < 1024 {
+		return fmt.Sprintf("%.2f B/s", speed)
+	} else if speed < 1024*1024 {
+		return fmt.Sprintf("%.2f KiB/s", speed/1024)
+	}
+	return fmt.Sprintf("%.2f MiB/s", speed/(1024*1024))
+}
+
+func downloadStatusText(state ModelDownloader.JobState) string {
+	statusText := lang.L("DownloadStatus_" + string(state.Status))
+	if state.Bytes > 0 && (state.Status == ModelDownloader.StatusDownloading || state.Status == ModelDownloader.StatusPaused || state.Status == ModelDownloader.StatusExtracting) {
+		if state.Progress() > 0 {
+			statusText += " (" + humanize.IBytes(state.Bytes) + " / " + humanize.IBytes(state.Total) + ")"
+		} else {
+			statusText += " (" + humanize.IBytes(state.Bytes) + ")"
+		}
+	}
+	if state.Status == ModelDownloader.StatusDownloading {
+		if state.Speed > 0 {
+			statusText += "  " + downloadSpeedText(state.Speed)
+		}
+		if eta, ok := state.ETA(); ok {
+			statusText += "  " + lang.L("DownloadETA", map[string]interface{}{"ETA": eta.Round(time.Second).String()})
+		}
+	}
+	if state.Mirror != "" && !state.Finished() {
+		statusText += "  " + lang.L("DownloadMirror", map[string]interface{}{"Mirror": state.Mirror})
+	}
+	if state.Err != nil && state.Status == ModelDownloader.StatusFailed {
+		statusText += ": " + state.Err.Error()
+	}
+	return statusText
+}
+
+// ShowDownloadsWindow shows the queue of the download manager.
+func ShowDownloadsWindow() {
+	defer Logging.GoRoutineErrorHandler(func(scope *sentry.Scope) {
+		scope.SetTag("GoRoutine", "Pages\\DownloadsWindow->ShowDownloadsWindow")
+	})
+
+	if downloadsWindow != nil {
+		downloadsWindow.RequestFocus()
+		return
+	}
+
+	downloadsWindow = fyne.CurrentApp().NewWindow(lang.L("Downloads"))
+	window := downloadsWindow
+
+	jobsList := widget.NewList(
+		func() int {
+			return len(ModelDownloader.Default.Jobs())
+		},
+		func() fyne.CanvasObject {
+			titleLabel := widget.NewLabel("")
+			titleLabel.Truncation = fyne.TextTruncateEllipsis
+			statusLabel := widget.NewLabel("")
+			statusLabel.Truncation = fyne.TextTruncateEllipsis
+			statusLabel.SizeName = theme.SizeNameCaptionText
+			return container.NewBorder(nil, nil, nil,
+				container.NewHBox(
+					widget.NewButtonWithIcon("", theme.MediaPauseIcon(), nil),
+					widget.NewButtonWithIcon("", theme.CancelIcon(), nil),
+				),
+				container.NewVBox(titleLabel, widget.NewProgressBar(), statusLabel),
+			)
+		},
+		func(id widget.ListItemID, object fyne.CanvasObject) {
+			jobs := ModelDownloader.Default.Jobs()
+			if id >= len(jobs) {
+				return
+			}
+			job := jobs[id]
+			state := job.State()
+
+			row := object.(*fyne.Container)
+			infoBox := row.Objects[0].(*fyne.Container)
+			buttons := row.Objects[1].(*fyne.Container)
+			titleLabel := infoBox.Objects[0].(*widget.Label)
+			progressBar := infoBox.Objects[1].(*widget.ProgressBar)
+			statusLabel := infoBox.Objects[2].(*widget.Label)
+			pauseButton := buttons.Objects[0].(*widget.Button)
+			cancelButton := buttons.Objects[1].(*widget.Button)
+
+			jobDescription := filepath.Base(job.Target)
+			if job.Title != "" {
+				jobDescription = job.Title + "  [" + jobDescription + "]"
+			}
+			titleLabel.SetText(jobDescription)
+			progressBar.SetValue(state.Progress())
+			statusLabel.SetText(downloadStatusText(state))
+
+			if state.Status == ModelDownloader.StatusPaused {
+				pauseButton.SetIcon(theme.MediaPlayIcon())
+				pauseButton.OnTapped = job.Resume
+			} else {
+				pauseButton.SetIcon(theme.MediaPauseIcon())
+				pauseButton.OnTapped = job.Pause
+			}
+			cancelButton.OnTapped = job.Cancel
+			if state.Status == ModelDownloader.StatusQueued || state.Status == ModelDownloader.StatusDownloading || state.Status == ModelDownloader.StatusPaused {
+				pauseButton.Enable()
+			} else {
+				pauseButton.Disable()
+			}
+			if state.CanCancel() {
+				cancelButton.Enable()
+			} else {
+				cancelButton.Disable()
+			}
+		},
+	)
+
+	// progress updates arrive every second per download, refresh at most a few times per second
+	var refreshPending bool
+	ModelDownloader.Default.SetOnChange(func() {
+		fyne.Do(func() {
+			if refreshPending {
+				return
+			}
+			refreshPending = true
+			time.AfterFunc(250*time.Millisecond, func() {
+				fyne.Do(func() {
+					refreshPending = false
+					jobsList.Refresh()
+				})
+			})
+		})
+	})
+
+	var concurrentOptions []string
+	for i := 1; i <= 4; i++ {
+		concurrentOptions = append(concurrentOptions, strconv.Itoa(i))
+	}
+	concurrentSelect := widget.NewSelect(concurrentOptions, func(value string) {
+		maxConcurrent, err := strconv.Atoi(value)
+		if err != nil {
+			return
+		}
+		fyne.CurrentApp().Preferences().SetInt("MaxConcurrentDownloads", maxConcurrent)
+		ModelDownloader.Default.SetMaxConcurrent(maxConcurrent)
+	})
+	concurrentSelect.SetSelected(strconv.Itoa(ModelDownloader.Default.MaxConcurrent()))
+
+	removeFinishedButton := widget.NewButtonWithIcon(lang.L("Remove finished"), theme.DeleteIcon(), ModelDownloader.Default.RemoveFinished)
+
+	window.SetContent(container.NewBorder(
+		widget.NewForm(widget.NewFormItem(lang.L("Parallel downloads"), concurrentSelect)),
+		container.NewHBox(removeFinishedButton),
+		nil, nil,
+		jobsList,
+	))
+	window.SetOnClosed(func() {
+		ModelDownloader.Default.SetOnChange(nil)
+		downloadsWindow = nil
+	})
+	window.Resize(fyne.NewSize(700, 400))
+	window.Show()
+}
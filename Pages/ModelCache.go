@@ -0,0 +1,406 @@
+package Pages
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"whispering-tiger-ui/Logging"
+	"whispering-tiger-ui/ModelDownloader"
+	"whispering-tiger-ui/ModelRegistry"
+	"whispering-tiger-ui/Settings"
+	"whispering-tiger-ui/Utilities"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/dustin/go-humanize"
+	"github.com/getsentry/sentry-go"
+)
+
+// movedCacheDirName is the directory created for the cache if the chosen directory is not empty.
+const movedCacheDirName = "whispering-tiger-cache"
+
+func cacheEntryDetailText(entry ModelDownloader.CacheEntry) string {
+	details := []string{humanize.IBytes(uint64(entry.Size))}
+	if entry.Known() {
+		if entry.Precision != "" {
+			details = append(details, entry.Precision)
+		}
+		if len(entry.ReferencedBy) > 0 {
+			details = append(details, lang.L("CacheUsedBy", map[string]interface{}{"Profiles": strings.Join(entry.ReferencedBy, ", ")}))
+		} else {
+			details = append(details, lang.L("Not used by any profile"))
+		}
+	} else {
+		details = append(details, lang.L("Not in the model registry"))
+	}
+	return strings.Join(details, "  •  ")
+}
+
+// CreateModelCacheWindow lists the models in the cache with their disk usage and the profiles using them.
+// The cache is scanned in the background, the content is replaced when the scan is done.
+func CreateModelCacheWindow() fyne.CanvasObject {
+	defer Logging.GoRoutineErrorHandler(func(scope *sentry.Scope) {
+		scope.SetTag("GoRoutine", "Pages\\ModelCache->CreateModelCacheWindow")
+	})
+
+	content := container.NewStack(container.NewCenter(container.NewVBox(
+		widget.NewLabel(lang.L("Scanning model cache...")),
+		widget.NewProgressBarInfinite(),
+	)))
+
+	var rescan func()
+	rescan = func() {
+		go func() {
+			defer Logging.GoRoutineErrorHandler(func(scope *sentry.Scope) {
+				scope.SetTag("GoRoutine", "Pages\\ModelCache->ScanCache")
+			})
+			profiles := ModelDownloader.LoadProfiles(Settings.GetConfProfileDir())
+			cacheInfo, err := ModelDownloader.ScanCache(ModelDownloader.CacheDir(), ModelRegistry.Current(), profiles)
+			fyne.Do(func() {
+				content.Objects = []fyne.CanvasObject{buildModelCacheContent(cacheInfo, err, rescan)}
+				content.Refresh()
+			})
+		}()
+	}
+	rescan()
+
+	return content
+}
+
+func buildModelCacheContent(cacheInfo ModelDownloader.CacheInfo, scanErr error, rescan func()) fyne.CanvasObject {
+	window := fyne.CurrentApp().Driver().AllWindows()[0]
+
+	cacheDir := cacheInfo.Dir
+	if absoluteDir, err := filepath.EvalSymlinks(cacheDir); err == nil {
+		cacheDir = absoluteDir
+	}
+	if absoluteDir, err := filepath.Abs(cacheDir); err == nil {
+		cacheDir = absoluteDir
+	}
+	usageLabel := widget.NewLabel(lang.L("CacheUsage", map[string]interface{}{
+		"Used": humanize.IBytes(uint64(cacheInfo.Size)),
+		"Free": humanize.IBytes(cacheInfo.FreeSpace),
+	}))
+	directoryLabel := widget.NewLabel(cacheDir)
+	directoryLabel.Truncation = fyne.TextTruncateEllipsis
+	if scanErr != nil {
+		usageLabel.SetText(scanErr.Error())
+	}
+
+	entries := cacheInfo.Entries
+	deleteEntries := func(toDelete []ModelDownloader.CacheEntry, message string) {
+		dialog.ShowConfirm(lang.L("Delete"), message, func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			var errs []error
+			for _, entry := range toDelete {
+				if err := ModelDownloader.DeleteCacheEntry(cacheInfo.Dir, entry); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			if err := errors.Join(errs...); err != nil {
+				dialog.ShowError(err, window)
+			}
+			rescan()
+		}, window)
+	}
+
+	entriesList := widget.NewList(
+		func() int {
+			return len(entries)
+		},
+		func() fyne.CanvasObject {
+			nameLabel := widget.NewLabel("")
+			nameLabel.Truncation = fyne.TextTruncateEllipsis
+			detailLabel := widget.NewLabel("")
+			detailLabel.Truncation = fyne.TextTruncateEllipsis
+			detailLabel.SizeName = theme.SizeNameCaptionText
+			return container.NewBorder(nil, nil, nil,
+				container.NewHBox(
+					widget.NewButtonWithIcon(lang.L("Verify"), theme.ConfirmIcon(), nil),
+					widget.NewButtonWithIcon("", theme.DeleteIcon(), nil),
+				),
+				container.NewVBox(nameLabel, detailLabel),
+			)
+		},
+		func(id widget.ListItemID, object fyne.CanvasObject) {
+			if id >= len(entries) {
+				return
+			}
+			entry := entries[id]
+
+			row := object.(*fyne.Container)
+			infoBox := row.Objects[0].(*fyne.Container)
+			buttons := row.Objects[1].(*fyne.Container)
+			nameLabel := infoBox.Objects[0].(*widget.Label)
+			detailLabel := infoBox.Objects[1].(*widget.Label)
+			verifyButton := buttons.Objects[0].(*widget.Button)
+			deleteButton := buttons.Objects[1].(*widget.Button)
+
+			nameLabel.SetText(entry.Name())
+			detailLabel.SetText(cacheEntryDetailText(entry))
+
+			verifyButton.OnTapped = func() {
+				verifyButton.Disable()
+				go func() {
+					defer Logging.GoRoutineErrorHandler(func(scope *sentry.Scope) {
+						scope.SetTag("GoRoutine", "Pages\\ModelCache->VerifyCacheEntry")
+					})
+					err := ModelDownloader.VerifyCacheEntry(cacheInfo.Dir, entry)
+					fyne.Do(func() {
+						verifyButton.Enable()
+						switch {
+						case errors.Is(err, ModelDownloader.ErrNothingToVerify):
+							dialog.ShowInformation(lang.L("Verify"), lang.L("The downloaded files of this model were removed, the checksum can not be verified."), window)
+						case err != nil:
+							dialog.ShowError(err, window)
+						default:
+							dialog.ShowInformation(lang.L("Verify"), lang.L("CacheVerified", map[string]interface{}{"Model": entry.Name()}), window)
+						}
+					})
+				}()
+			}
+			if entry.Known() {
+				verifyButton.Enable()
+			} else {
+				verifyButton.Disable()
+			}
+
+			deleteButton.OnTapped = func() {
+				message := lang.L("CacheDeleteConfirm", map[string]interface{}{"Model": entry.Name(), "Size": humanize.IBytes(uint64(entry.Size))})
+				if !entry.Known() {
+					message += "\n" + lang.L("This entry is not in the model registry, it might be used by the backend.")
+				}
+				deleteEntries([]ModelDownloader.CacheEntry{entry}, message)
+			}
+			if len(entry.ReferencedBy) > 0 {
+				deleteButton.Disable()
+			} else {
+				deleteButton.Enable()
+			}
+		},
+	)
+
+	refreshButton := widget.NewButtonWithIcon(lang.L("Refresh"), theme.ViewRefreshIcon(), rescan)
+
+	deleteUnusedButton := widget.NewButtonWithIcon(lang.L("Delete unused models"), theme.DeleteIcon(), func() {
+		var unused []ModelDownloader.CacheEntry
+		var unusedSize int64
+		for _, entry := range entries {
+			if entry.Known() && len(entry.ReferencedBy) == 0 {
+				unused = append(unused, entry)
+				unusedSize += entry.Size
+			}
+		}
+		if len(unused) == 0 {
+			dialog.ShowInformation(lang.L("Delete unused models"), lang.L("All models in the cache are used by a profile."), window)
+			return
+		}
+		deleteEntries(unused, lang.L("CacheDeleteUnusedConfirm", map[string]interface{}{"Count": len(unused), "Size": humanize.IBytes(uint64(unusedSize))}))
+	})
+
+	moveButton := widget.NewButtonWithIcon(lang.L("Move cache"), theme.FolderOpenIcon(), func() {
+		folderDialog := dialog.NewFolderOpen(func(uri fyne.ListableURI, err error) {
+			if err != nil || uri == nil {
+				return
+			}
+			targetDir := uri.Path()
+			if entries, err := os.ReadDir(targetDir); err == nil && len(entries) > 0 {
+				targetDir = filepath.Join(targetDir, movedCacheDirName)
+			}
+			dialog.ShowConfirm(lang.L("Move cache"), lang.L("CacheMoveConfirm", map[string]interface{}{"Dir": targetDir}), func(confirmed bool) {
+				if confirmed {
+					moveModelCache(targetDir, window, rescan)
+				}
+			}, window)
+		}, window)
+		folderDialog.Resize(window.Canvas().Size())
+		folderDialog.Show()
+	})
+
+	importButton := widget.NewButtonWithIcon(lang.L("Import model"), theme.UploadIcon(), func() {
+		fileDialog := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil || reader == nil {
+				return
+			}
+			sourcePath := reader.URI().Path()
+			reader.Close()
+			importModel(sourcePath, window, rescan)
+		}, window)
+		fileDialog.Resize(window.Canvas().Size())
+		fileDialog.Show()
+	})
+	importFolderButton := widget.NewButtonWithIcon(lang.L("Import folder"), theme.FolderOpenIcon(), func() {
+		folderDialog := dialog.NewFolderOpen(func(uri fyne.ListableURI, err error) {
+			if err != nil || uri == nil {
+				return
+			}
+			importModel(uri.Path(), window, rescan)
+		}, window)
+		folderDialog.Resize(window.Canvas().Size())
+		folderDialog.Show()
+	})
+	exportButton := widget.NewButtonWithIcon(lang.L("Export model package"), theme.DownloadIcon(), func() {
+		showModelExportDialog(window, rescan)
+	})
+
+	return container.NewBorder(
+		container.NewVBox(
+			widget.NewForm(
+				widget.NewFormItem(lang.L("Cache directory"), directoryLabel),
+				widget.NewFormItem(lang.L("Disk usage"), usageLabel),
+			),
+		),
+		container.NewVBox(
+			container.NewHBox(refreshButton, deleteUnusedButton, moveButton),
+			container.NewHBox(importButton, importFolderButton, exportButton),
+		),
+		nil, nil,
+		entriesList,
+	)
+}
+
+func moveModelCache(targetDir string, window fyne.Window, onMoved func()) {
+	progressBar := widget.NewProgressBar()
+	progressDialog := dialog.NewCustomWithoutButtons(lang.L("Moving cache..."), progressBar, window)
+	progressDialog.Show()
+
+	go func() {
+		defer Logging.GoRoutineErrorHandler(func(scope *sentry.Scope) {
+			scope.SetTag("GoRoutine", "Pages\\ModelCache->MoveCache")
+		})
+		err := ModelDownloader.MoveCache(targetDir, func(copied, total int64) {
+			if total <= 0 {
+				return
+			}
+			fyne.Do(func() {
+				progressBar.SetValue(float64(copied) / float64(total))
+			})
+		})
+		fyne.Do(func() {
+			progressDialog.Hide()
+			if err != nil {
+				Logging.CaptureException(err)
+				dialog.ShowError(err, window)
+			}
+			onMoved()
+		})
+	}()
+}
+
+// importModel imports a model package, a downloaded model file or a directory with downloaded files into the cache.
+func importModel(sourcePath string, window fyne.Window, onImported func()) {
+	statusLabel := widget.NewLabel(lang.L("Verifying files..."))
+	progressDialog := dialog.NewCustomWithoutButtons(lang.L("Import model"), container.NewVBox(statusLabel, widget.NewProgressBarInfinite()), window)
+	progressDialog.Show()
+
+	go func() {
+		defer Logging.GoRoutineErrorHandler(func(scope *sentry.Scope) {
+			scope.SetTag("GoRoutine", "Pages\\ModelCache->ImportModel")
+		})
+		imported, err := ModelDownloader.ImportModel(sourcePath, ModelDownloader.CacheDir(), ModelRegistry.Current(), func(fileName string) {
+			fyne.Do(func() {
+				statusLabel.SetText(lang.L("ImportingModelFile", map[string]interface{}{"File": fileName}))
+			})
+		})
+		fyne.Do(func() {
+			progressDialog.Hide()
+			if len(imported) > 0 {
+				var importedFiles []string
+				for _, file := range imported {
+					importedFiles = append(importedFiles, file.Family+" "+file.Model+" ("+file.Name+")")
+				}
+				message := lang.L("ModelsImported", map[string]interface{}{"Files": strings.Join(importedFiles, "\n")})
+				if err != nil {
+					message += "\n\n" + err.Error()
+				}
+				dialog.ShowInformation(lang.L("Import model"), message, window)
+			} else if err != nil {
+				dialog.ShowError(err, window)
+			}
+			onImported()
+		})
+	}()
+}
+
+// showModelExportDialog exports the files of a model of the registry as model package, that can be imported on machines without internet access.
+// Missing files are downloaded first.
+func showModelExportDialog(window fyne.Window, onExported func()) {
+	registry := ModelRegistry.Current()
+	var familyNames []string
+	for _, family := range registry.Downloads {
+		familyNames = append(familyNames, family.Name)
+	}
+	modelSelect := widget.NewSelect(nil, nil)
+	familySelect := widget.NewSelect(familyNames, func(familyName string) {
+		var modelNames []string
+		if family, ok := registry.Family(familyName); ok {
+			for _, model := range family.Models {
+				modelNames = append(modelNames, model.Name)
+			}
+		}
+		modelSelect.SetOptions(modelNames)
+		modelSelect.ClearSelected()
+	})
+
+	dialog.ShowForm(lang.L("Export model package"), lang.L("Export"), lang.L("Cancel"), []*widget.FormItem{
+		widget.NewFormItem(lang.L("Model family"), familySelect),
+		widget.NewFormItem(lang.L("Model"), modelSelect),
+	}, func(confirmed bool) {
+		if !confirmed || familySelect.Selected == "" || modelSelect.Selected == "" {
+			return
+		}
+		familyName, modelName := familySelect.Selected, modelSelect.Selected
+		fileDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil || writer == nil {
+				return
+			}
+			fileName := writer.URI().Path()
+			writer.Close()
+			exportModelPackage(fileName, familyName, modelName, window, onExported)
+		}, window)
+		fileDialog.SetFileName(familyName + "_" + modelName + ".zip")
+		fileDialog.SetFilter(storage.NewExtensionFileFilter([]string{".zip"}))
+		fileDialog.Resize(window.Canvas().Size())
+		fileDialog.Show()
+	}, window)
+}
+
+func exportModelPackage(fileName, familyName, modelName string, window fyne.Window, onExported func()) {
+	statusLabel := widget.NewLabel(lang.L("Downloading missing files..."))
+	progressDialog := dialog.NewCustomWithoutButtons(lang.L("Export model package"), container.NewVBox(statusLabel, widget.NewProgressBarInfinite()), window)
+	progressDialog.Show()
+
+	go func() {
+		defer Logging.GoRoutineErrorHandler(func(scope *sentry.Scope) {
+			scope.SetTag("GoRoutine", "Pages\\ModelCache->ExportModelPackage")
+		})
+		registry := ModelRegistry.Current()
+		err := ModelDownloader.PrepareModelFiles(ModelDownloader.CacheDir(), registry, familyName, modelName)
+		if err == nil {
+			fyne.Do(func() {
+				statusLabel.SetText(lang.L("Writing model package..."))
+			})
+			_, err = ModelDownloader.ExportModelPackageFile(fileName, ModelDownloader.CacheDir(), registry, familyName, modelName, Utilities.AppVersion+"."+Utilities.AppBuild)
+		}
+		fyne.Do(func() {
+			progressDialog.Hide()
+			switch {
+			case errors.Is(err, ModelDownloader.ErrCancelled):
+			case err != nil:
+				Logging.CaptureException(err)
+				dialog.ShowError(err, window)
+			default:
+				dialog.ShowInformation(lang.L("Export model package"), lang.L("ModelPackageExported", map[string]interface{}{"File": fileName}), window)
+			}
+			onExported()
+		})
+	}()
+}
@@ -0,0 +1,220 @@
+package Pages
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"whispering-tiger-ui/Logging"
+	"whispering-tiger-ui/Migrations"
+	"whispering-tiger-ui/ProfileBundle"
+	"whispering-tiger-ui/Settings"
+	"whispering-tiger-ui/UpdateUtility"
+	"whispering-tiger-ui/Utilities"
+	"whispering-tiger-ui/Utilities/AudioAPI"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+	"github.com/gen2brain/malgo"
+	"gopkg.in/yaml.v3"
+)
+
+func installedBundlePlugins() []ProfileBundle.Plugin {
+	var plugins []ProfileBundle.Plugin
+	for _, pluginFile := range UpdateUtility.ParseLocalPluginFiles() {
+		plugins = append(plugins, ProfileBundle.Plugin{Class: pluginFile.Class, Version: pluginFile.LocalVersion, SHA256: pluginFile.SHA256})
+	}
+	return plugins
+}
+
+func bundleDevices(devices []Utilities.AudioDevice) []ProfileBundle.Device {
+	var bundleDevices []ProfileBundle.Device
+	for _, device := range devices {
+		// the profile stores the index of the device option, which is shifted by the "Default" option
+		bundleDevices = append(bundleDevices, ProfileBundle.Device{Name: device.Name, Index: device.Index + 1, IsDefault: device.IsDefault})
+	}
+	return bundleDevices
+}
+
+func profileBundleFileDialogSize(window fyne.Window) fyne.Size {
+	dialogSize := window.Canvas().Size()
+	dialogSize.Height = dialogSize.Height - 50
+	dialogSize.Width = dialogSize.Width - 50
+	return dialogSize
+}
+
+// showProfileExportDialog exports the resolved settings of a profile as profile bundle.
+func showProfileExportDialog(profileFileName string, profileSettings Settings.Conf, window fyne.Window) {
+	profileName := strings.TrimSuffix(profileFileName, filepath.Ext(profileFileName))
+	includeVoicesCheck := widget.NewCheck(lang.L("Include voice files"), nil)
+	includeVoicesCheck.Checked = true
+
+	dialog.ShowCustomConfirm(lang.L("Export Profile"), lang.L("Export"), lang.L("Cancel"), container.NewVBox(
+		widget.NewLabel(lang.L("ExportProfileHint")),
+		includeVoicesCheck,
+	), func(b bool) {
+		if !b {
+			return
+		}
+		fileDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil || writer == nil {
+				return
+			}
+			defer writer.Close()
+			profileSettings.Extends = ""
+			if profileSettings.Schema_version == 0 {
+				profileSettings.Schema_version = Migrations.LatestVersion()
+			}
+			profileData, err := yaml.Marshal(profileSettings)
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			manifest, err := ProfileBundle.Export(writer, profileData, ProfileBundle.ExportOptions{
+				ProfileName:      profileName,
+				AppVersion:       Utilities.AppVersion + "." + Utilities.AppBuild,
+				InstalledPlugins: installedBundlePlugins(),
+				IncludeVoices:    includeVoicesCheck.Checked,
+			})
+			if err != nil {
+				Logging.CaptureException(err)
+				dialog.ShowError(err, window)
+				return
+			}
+			message := lang.L("Profile exported")
+			if len(manifest.StrippedSecrets) > 0 {
+				message += "\n\n" + lang.L("ExportStrippedSecrets", map[string]interface{}{"Settings": strings.Join(manifest.StrippedSecrets, ", ")})
+			}
+			dialog.ShowInformation(lang.L("Export Profile"), message, window)
+		}, window)
+		fileDialog.SetFileName(profileName + ".zip")
+		fileDialog.SetFilter(storage.NewExtensionFileFilter([]string{".zip"}))
+		fileDialog.Resize(profileBundleFileDialogSize(window))
+		fileDialog.Show()
+	}, window)
+}
+
+// showProfileImportDialog imports a profile bundle into the profiles directory.
+// onImported is called with the file name of the imported profile.
+func showProfileImportDialog(profilesDir string, window fyne.Window, onImported func(profileFileName string)) {
+	fileDialog := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil || reader == nil {
+			return
+		}
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		bundle, err := ProfileBundle.Open(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+
+		// use the audio API of the bundle if it is available on this platform, otherwise the default API
+		audioBackend := AudioAPI.GetAudioBackendByName(bundle.ProfileValue("audio_api"))
+		_, inputDevices, _ := GetAudioDevices(audioBackend.Backend, []malgo.DeviceType{malgo.Capture, malgo.Loopback}, 0, "", "")
+		_, outputDevices, _ := GetAudioDevices(audioBackend.Backend, []malgo.DeviceType{malgo.Playback}, len(inputDevices)+1, "", "")
+		importOptions := ProfileBundle.ImportOptions{
+			ProfileName:      bundle.Manifest.ProfileName,
+			AudioAPI:         audioBackend.Name,
+			InputDevices:     bundleDevices(inputDevices),
+			OutputDevices:    bundleDevices(outputDevices),
+			InstalledPlugins: installedBundlePlugins(),
+			VoiceDir:         filepath.Join(Settings.GetUiDataDir(), "Voices"),
+		}
+		if importOptions.ProfileName == "" {
+			importOptions.ProfileName = strings.TrimSuffix(reader.URI().Name(), reader.URI().Extension())
+		}
+
+		reportLabel := widget.NewLabel("")
+		reportLabel.Wrapping = fyne.TextWrapWord
+		overwriteCheck := widget.NewCheck(lang.L("Overwrite existing profile"), nil)
+		nameEntry := widget.NewEntry()
+		nameEntry.SetText(importOptions.ProfileName)
+		updateReport := func() {
+			importOptions.ProfileName = nameEntry.Text
+			report, err := bundle.Check(profilesDir, importOptions)
+			if err != nil {
+				reportLabel.SetText(err.Error())
+				return
+			}
+			reportLabel.SetText(profileImportReportText(report))
+			if report.ProfileExists {
+				overwriteCheck.Show()
+			} else {
+				overwriteCheck.Hide()
+			}
+		}
+		nameEntry.OnChanged = func(string) {
+			updateReport()
+		}
+		updateReport()
+
+		importDialog := dialog.NewCustomConfirm(lang.L("Import Profile"), lang.L("Import"), lang.L("Cancel"), container.NewVBox(
+			widget.NewForm(widget.NewFormItem(lang.L("Profile Name"), nameEntry)),
+			overwriteCheck,
+			container.NewVScroll(reportLabel),
+		), func(b bool) {
+			if !b {
+				return
+			}
+			importOptions.ProfileName = nameEntry.Text
+			importOptions.Overwrite = overwriteCheck.Checked
+			report, err := bundle.Import(profilesDir, importOptions)
+			if err != nil {
+				if !errors.Is(err, ProfileBundle.ErrProfileExists) {
+					Logging.CaptureException(err)
+				}
+				dialog.ShowError(err, window)
+				return
+			}
+			onImported(filepath.Base(report.ProfileFile))
+		}, window)
+		importDialog.Resize(fyne.NewSize(600, 450))
+		importDialog.Show()
+	}, window)
+	fileDialog.SetFilter(storage.NewExtensionFileFilter([]string{".zip"}))
+	fileDialog.Resize(profileBundleFileDialogSize(window))
+	fileDialog.Show()
+}
+
+func profileImportReportText(report ProfileBundle.ImportReport) string {
+	var lines []string
+	if report.ProfileExists {
+		lines = append(lines, lang.L("ImportProfileExists", map[string]interface{}{"Name": filepath.Base(report.ProfileFile)}))
+	}
+	if report.Migrated {
+		lines = append(lines, lang.L("ImportProfileMigrated", map[string]interface{}{"Version": report.MigratedFrom}))
+	}
+	if len(report.MissingPlugins) > 0 {
+		lines = append(lines, lang.L("ImportMissingPlugins", map[string]interface{}{"Plugins": strings.Join(report.MissingPlugins, ", ")}))
+	}
+	if len(report.ChangedPlugins) > 0 {
+		lines = append(lines, lang.L("ImportChangedPlugins", map[string]interface{}{"Plugins": strings.Join(report.ChangedPlugins, ", ")}))
+	}
+	for _, change := range report.DeviceChanges {
+		if change.To == "" {
+			lines = append(lines, lang.L("ImportDeviceNotFound", map[string]interface{}{"Setting": change.Setting, "From": change.From}))
+		} else {
+			lines = append(lines, lang.L("ImportDeviceChanged", map[string]interface{}{"Setting": change.Setting, "From": change.From, "To": change.To}))
+		}
+	}
+	if len(report.StrippedSecrets) > 0 {
+		lines = append(lines, lang.L("ImportStrippedSecrets", map[string]interface{}{"Settings": strings.Join(report.StrippedSecrets, ", ")}))
+	}
+	if len(report.VoiceFiles) > 0 {
+		lines = append(lines, lang.L("ImportVoiceFiles", map[string]interface{}{"Count": len(report.VoiceFiles)}))
+	}
+	if len(lines) == 0 {
+		return lang.L("No conflicts found.")
+	}
+	return strings.Join(lines, "\n")
+}
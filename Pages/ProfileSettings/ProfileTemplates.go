@@ -0,0 +1,344 @@
+package ProfileSettings
+
+import (
+	"strings"
+	"whispering-tiger-ui/Settings"
+	"whispering-tiger-ui/Utilities"
+	"whispering-tiger-ui/Utilities/Hardwareinfo"
+)
+
+// UseCase is what a profile created by the setup wizard is used for.
+type UseCase string
+
+const (
+	UseCaseVRChat            UseCase = "vrchat"
+	UseCaseStreamingCaptions UseCase = "streaming_captions"
+	UseCaseTranslation       UseCase = "translation"
+)
+
+// ProfileTemplate is the starting point of a profile created by the setup wizard.
+type ProfileTemplate struct {
+	UseCase     UseCase
+	Title       string
+	Description string
+	// Realtime templates prefer faster models over more accurate ones.
+	Realtime bool
+	// Translate loads a text translator if the target language differs from the spoken language.
+	Translate bool
+	// TextToSpeech loads a text-to-speech model.
+	TextToSpeech bool
+	// apply sets the settings of the use case that do not depend on the hardware.
+	apply func(profile *Settings.Conf)
+}
+
+// Templates are the use cases offered by the setup wizard.
+var Templates = []ProfileTemplate{
+	{
+		UseCase:      UseCaseVRChat,
+		Title:        "VR Chat",
+		Description:  "Transcribe and translate your voice and send it to the VRChat chatbox using OSC. Text-to-Speech can speak typed text.",
+		Translate:    true,
+		TextToSpeech: true,
+		apply: func(profile *Settings.Conf) {
+			profile.Osc_auto_processing_enabled = true
+			profile.Osc_type_transfer = "translation_result"
+			profile.Pause = 1.0
+			profile.Phrase_time_limit = 15
+		},
+	},
+	{
+		UseCase:     UseCaseStreamingCaptions,
+		Title:       "Streaming Captions",
+		Description: "Show live captions of your voice while streaming, optimized for low latency.",
+		Realtime:    true,
+		Translate:   true,
+		apply: func(profile *Settings.Conf) {
+			profile.Realtime = true
+			profile.Txt_translate_realtime = true
+			profile.Osc_auto_processing_enabled = false
+			profile.Pause = 0.8
+			profile.Phrase_time_limit = 10
+		},
+	},
+	{
+		UseCase:      UseCaseTranslation,
+		Title:        "Translation",
+		Description:  "Translate speech and text as accurately as possible, for example to talk with people speaking another language.",
+		Translate:    true,
+		TextToSpeech: true,
+		apply: func(profile *Settings.Conf) {
+			profile.Osc_auto_processing_enabled = false
+			profile.Condition_on_previous_text = true
+			profile.Pause = 1.4
+			profile.Phrase_time_limit = 30
+		},
+	},
+}
+
+// TemplateByUseCase returns the template of a use case.
+func TemplateByUseCase(useCase UseCase) (ProfileTemplate, bool) {
+	for _, template := range Templates {
+		if template.UseCase == useCase {
+			return template, true
+		}
+	}
+	return ProfileTemplate{}, false
+}
+
+// Hardware is the memory available for AI models in MiB.
+type Hardware struct {
+	// CUDA is set if an NVIDIA GPU is found, models can only run on the GPU with CUDA.
+	CUDA         bool
+	GPUMemoryMiB int64
+	CPUMemoryMiB int64
+}
+
+// DetectHardware reads the memory of the NVIDIA GPU and the system memory.
+func DetectHardware() Hardware {
+	hardware := Hardware{CPUMemoryMiB: Hardwareinfo.GetCPUMemory()}
+	if !Hardwareinfo.IsNVIDIACard(Hardwareinfo.GetGPUCard()) {
+		return hardware
+	}
+	hardware.CUDA = true
+	_, hardware.GPUMemoryMiB = Hardwareinfo.GetGPUMemory()
+	if hardware.GPUMemoryMiB <= 0 {
+		// fall back to registry reading of Video Memory
+		foundGPU, _ := Hardwareinfo.FindDedicatedGPUByVendor([]string{"nvidia"})
+		if len(foundGPU) > 0 {
+			hardware.GPUMemoryMiB = foundGPU[0].MemoryMB
+		}
+	}
+	return hardware
+}
+
+// Part of the memory that is used for models, the rest is left for the system, other applications and the audio processing.
+const (
+	usableGPUMemory = 0.85
+	usableCPUMemory = 0.5
+)
+
+// ProfileLanguages are the languages chosen in the setup wizard.
+type ProfileLanguages struct {
+	// Spoken is the ISO1 code of the spoken language, "" detects the language.
+	Spoken string
+	// Target is the NLLB code (e.g. eng_Latn) of the language to translate to, "" disables translation.
+	Target string
+}
+
+// ModelChoice is a model picked for a profile.
+type ModelChoice struct {
+	// Type is the model type (e.g. faster_whisper), "" if the model is disabled.
+	Type      string
+	Size      string
+	Precision string
+	Device    string
+	// MemoryMiB is the estimated memory usage of the model.
+	MemoryMiB float64
+}
+
+// Recommendation is a profile created from a template for the detected hardware.
+type Recommendation struct {
+	Profile    Settings.Conf
+	STT        ModelChoice
+	Translator ModelChoice
+	TTS        ModelChoice
+	// GPUMemoryMiB and CPUMemoryMiB are the estimated memory usage of all models.
+	GPUMemoryMiB float64
+	CPUMemoryMiB float64
+}
+
+// modelCandidate is a model size with the size used for its memory estimate in Hardwareinfo.Models.
+type modelCandidate struct {
+	size         string
+	estimateSize string
+	// gpuOnly models fit into the system memory, but are too slow on the CPU.
+	gpuOnly bool
+}
+
+var accurateSTTCandidates = []modelCandidate{
+	{size: "large-v3", estimateSize: "large", gpuOnly: true},
+	{size: "medium", estimateSize: "medium"},
+	{size: "small", estimateSize: "small"},
+	{size: "base", estimateSize: "base"},
+	{size: "tiny", estimateSize: "tiny"},
+}
+
+var realtimeSTTCandidates = []modelCandidate{
+	{size: "large-v3-turbo", estimateSize: "large-distilled", gpuOnly: true},
+	{size: "small", estimateSize: "small"},
+	{size: "base", estimateSize: "base"},
+	{size: "tiny", estimateSize: "tiny"},
+}
+
+var translatorCandidates = []modelCandidate{
+	{size: "large", estimateSize: "large", gpuOnly: true},
+	{size: "medium", estimateSize: "medium"},
+	{size: "small", estimateSize: "small"},
+}
+
+var ttsCandidates = []modelCandidate{
+	{size: "silero"},
+	{size: "kokoro"},
+}
+
+// Precisions tried per device, in order of preference.
+var (
+	gpuPrecisions = []string{"float16", "int8_float16"}
+	cpuPrecisions = []string{"int8"}
+)
+
+// precisionFactor returns the bytes per weight of a precision, used to scale the float32 memory estimates.
+func precisionFactor(precision string) float64 {
+	switch precision {
+	case "float16", "bfloat16", "int16":
+		return Hardwareinfo.Float16
+	case "int8", "int8_float16", "int8_bfloat16", "8bit":
+		return Hardwareinfo.Int8
+	case "4bit":
+		return Hardwareinfo.Bit4
+	default:
+		return Hardwareinfo.Float32
+	}
+}
+
+// float32MemoryUsage looks up the estimate of a model in Hardwareinfo.Models.
+func float32MemoryUsage(baseName, modelType, modelSize string) (float64, bool) {
+	for _, model := range Hardwareinfo.Models {
+		if model.BaseName == baseName && model.ModelType == modelType && model.ModelSize == modelSize {
+			return model.Float32PrecisionMemoryUsage, true
+		}
+	}
+	return 0, false
+}
+
+// memoryBudget is the remaining memory for models in MiB.
+type memoryBudget struct {
+	cuda bool
+	gpu  float64
+	cpu  float64
+}
+
+// pickModel returns the first candidate that fits into the remaining memory, preferring the GPU.
+// Candidates are ordered from the most accurate to the smallest model.
+// Precision is only chosen for models that support it, the memory of the others is estimated with float32.
+// The memory of the picked model is subtracted from the budget.
+func (b *memoryBudget) pickModel(baseName, modelType string, candidates []modelCandidate, withPrecision bool) (ModelChoice, bool) {
+	type modelDevice struct {
+		name       string
+		free       *float64
+		precisions []string
+	}
+	var devices []modelDevice
+	if b.cuda {
+		devices = append(devices, modelDevice{name: "cuda", free: &b.gpu, precisions: gpuPrecisions})
+	}
+	devices = append(devices, modelDevice{name: "cpu", free: &b.cpu, precisions: cpuPrecisions})
+
+	for _, candidate := range candidates {
+		estimateType, estimateSize := modelType, candidate.estimateSize
+		if modelType == "" {
+			// model types without size, like the text-to-speech models
+			estimateType, estimateSize = candidate.size, ""
+		}
+		float32Usage, ok := float32MemoryUsage(baseName, estimateType, estimateSize)
+		if !ok {
+			continue
+		}
+		for _, device := range devices {
+			if candidate.gpuOnly && device.name != "cuda" {
+				continue
+			}
+			precisions := device.precisions
+			if !withPrecision {
+				precisions = []string{"float32"}
+			}
+			for _, precision := range precisions {
+				usage := Hardwareinfo.EstimateMemoryUsage(float32Usage, precisionFactor(precision))
+				if usage > *device.free {
+					continue
+				}
+				*device.free -= usage
+				choice := ModelChoice{Type: modelType, Size: candidate.size, Precision: precision, Device: device.name, MemoryMiB: usage}
+				if modelType == "" {
+					choice.Type, choice.Size = candidate.size, ""
+				}
+				return choice, true
+			}
+		}
+	}
+	return ModelChoice{}, false
+}
+
+// Recommend creates a profile from a template with the most accurate models that fit into the memory of the hardware.
+// Speech-to-Text is picked first, so the translator and text-to-speech models get the remaining memory.
+func Recommend(template ProfileTemplate, languages ProfileLanguages, hardware Hardware) Recommendation {
+	profile := Settings.DefaultConf
+	if template.apply != nil {
+		template.apply(&profile)
+	}
+
+	budget := memoryBudget{
+		cuda: hardware.CUDA && hardware.GPUMemoryMiB > 0,
+		gpu:  float64(hardware.GPUMemoryMiB) * usableGPUMemory,
+		cpu:  float64(hardware.CPUMemoryMiB) * usableCPUMemory,
+	}
+	recommendation := Recommendation{}
+
+	sttCandidates := accurateSTTCandidates
+	if template.Realtime {
+		sttCandidates = realtimeSTTCandidates
+	}
+	recommendation.STT, _ = budget.pickModel("Whisper", "faster_whisper", sttCandidates, true)
+
+	spokenTarget := ""
+	if languages.Spoken != "" {
+		if iso3 := Utilities.LanguageMapList.GetISO3(languages.Spoken); len(iso3) > 0 {
+			spokenTarget = iso3[0]
+		}
+	}
+	if template.Translate && languages.Target != "" && !strings.EqualFold(languages.Target, spokenTarget) {
+		recommendation.Translator, _ = budget.pickModel("TxtTranslator", "NLLB200_CT2", translatorCandidates, true)
+	}
+	if template.TextToSpeech {
+		recommendation.TTS, _ = budget.pickModel("ttsType", "", ttsCandidates, false)
+	}
+
+	profile.Stt_type = recommendation.STT.Type
+	if recommendation.STT.Type != "" {
+		profile.Model = recommendation.STT.Size
+		profile.Whisper_precision = recommendation.STT.Precision
+		profile.Ai_device = recommendation.STT.Device
+		if template.Realtime {
+			profile.Beam_size = 1
+		}
+	}
+	profile.Current_language = languages.Spoken
+
+	profile.Src_lang = spokenTarget
+	profile.Trg_lang = languages.Target
+	profile.Txt_translate = recommendation.Translator.Type != ""
+	profile.Txt_translator = recommendation.Translator.Type
+	if recommendation.Translator.Type != "" {
+		profile.Txt_translator_size = recommendation.Translator.Size
+		profile.Txt_translator_precision = recommendation.Translator.Precision
+		profile.Txt_translator_device = recommendation.Translator.Device
+	} else {
+		profile.Txt_translate_realtime = false
+	}
+
+	profile.Tts_type = recommendation.TTS.Type
+	if recommendation.TTS.Type != "" {
+		profile.Tts_ai_device = recommendation.TTS.Device
+	}
+
+	for _, choice := range []ModelChoice{recommendation.STT, recommendation.Translator, recommendation.TTS} {
+		switch choice.Device {
+		case "cuda":
+			recommendation.GPUMemoryMiB += choice.MemoryMiB
+		case "cpu":
+			recommendation.CPUMemoryMiB += choice.MemoryMiB
+		}
+	}
+	recommendation.Profile = profile
+	return recommendation
+}
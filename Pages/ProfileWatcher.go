@@ -0,0 +1,103 @@
+package Pages
+
+import (
+	"fmt"
+	"log"
+	"whispering-tiger-ui/Settings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/widget"
+)
+
+// StartProfileWatcher watches the layer files of the loaded profile and asks to reload external changes.
+func StartProfileWatcher(window fyne.Window) {
+	dialogOpen := false
+	err := Settings.StartProfileWatcher(func(diffs []Settings.SettingDiff) {
+		fyne.Do(func() {
+			if dialogOpen {
+				return
+			}
+			dialogOpen = true
+			showProfileChangesDialog(lang.L("Profile changed"), lang.L("ProfileChangedExternallyHint"), lang.L("Keep current settings"), false, window, func(reload bool) {
+				dialogOpen = false
+				if reload {
+					return
+				}
+				// write the loaded settings so the profile matches them again
+				if err := Settings.WriteActiveProfile(); err != nil {
+					dialog.ShowError(err, window)
+				}
+			})
+		})
+	})
+	if err != nil {
+		log.Printf("error watching profile files: %v", err)
+		return
+	}
+	// the settings form asks before it overwrites pending changes
+	Settings.OnPendingProfileChanges = func(overwrite func()) {
+		showProfileChangesDialog(lang.L("Profile changed"), lang.L("ProfileConflictHint"), lang.L("Overwrite"), true, fyne.CurrentApp().Driver().AllWindows()[0], func(reload bool) {
+			if !reload {
+				overwrite()
+			}
+		})
+	}
+}
+
+// showProfileChangesDialog shows the pending external changes of the profile files and lets the user reload them
+// or keep the loaded settings. onClosed is called with true if the changes were reloaded,
+// with false if the loaded settings are kept. It is not called if the dialog is cancelled.
+func showProfileChangesDialog(title, message, keepButtonText string, cancelable bool, window fyne.Window, onClosed func(reload bool)) {
+	profileWatcher := Settings.ActiveProfileWatcher
+	if profileWatcher == nil {
+		return
+	}
+	diffs := profileWatcher.Pending()
+
+	diffGrid := container.NewGridWithColumns(3,
+		widget.NewLabelWithStyle(lang.L("Setting"), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewLabelWithStyle(lang.L("Current value"), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewLabelWithStyle(lang.L("Value in file"), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+	)
+	for _, diff := range diffs {
+		settingName := diff.Name
+		if diff.Layer != Settings.LayerProfile {
+			settingName = fmt.Sprintf("%s (%s)", diff.Name, lang.L(diff.Layer.String()))
+		}
+		currentLabel := widget.NewLabel(fmt.Sprint(diff.Current))
+		currentLabel.Wrapping = fyne.TextWrapWord
+		fileLabel := widget.NewLabel(fmt.Sprint(diff.File))
+		fileLabel.Wrapping = fyne.TextWrapWord
+		diffGrid.Add(widget.NewLabel(settingName))
+		diffGrid.Add(currentLabel)
+		diffGrid.Add(fileLabel)
+	}
+
+	messageLabel := widget.NewLabel(message)
+	messageLabel.Wrapping = fyne.TextWrapWord
+	changesDialog := dialog.NewCustomWithoutButtons(title, container.NewBorder(messageLabel, nil, nil, nil, container.NewVScroll(diffGrid)), window)
+
+	reloadButton := widget.NewButton(lang.L("Reload"), func() {
+		changesDialog.Hide()
+		profileWatcher.Reload()
+		onClosed(true)
+	})
+	reloadButton.Importance = widget.HighImportance
+	keepButton := widget.NewButton(keepButtonText, func() {
+		changesDialog.Hide()
+		profileWatcher.Dismiss()
+		onClosed(false)
+	})
+	buttons := []fyne.CanvasObject{keepButton, reloadButton}
+	if cancelable {
+		buttons = append([]fyne.CanvasObject{widget.NewButton(lang.L("Cancel"), func() {
+			changesDialog.Hide()
+		})}, buttons...)
+	}
+	changesDialog.SetButtons(buttons)
+	changesDialog.Resize(fyne.NewSize(700, 400))
+	changesDialog.Show()
+}
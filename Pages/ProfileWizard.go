@@ -0,0 +1,242 @@
+package Pages
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"whispering-tiger-ui/CustomWidget"
+	"whispering-tiger-ui/Logging"
+	"whispering-tiger-ui/Pages/ProfileSettings"
+	"whispering-tiger-ui/Utilities"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/getsentry/sentry-go"
+)
+
+// wizardLanguageOptions returns the languages of the language mapping sorted by name.
+// The first option has an empty value and the given text.
+func wizardLanguageOptions(emptyText string, value func(languageMap Utilities.LanguageMap) string) []CustomWidget.TextValueOption {
+	options := []CustomWidget.TextValueOption{{Text: emptyText, Value: ""}}
+	var languageOptions []CustomWidget.TextValueOption
+	for _, languageMap := range Utilities.LanguageMapList.LanguageMappings {
+		languageValue := value(languageMap)
+		if languageValue == "" {
+			continue
+		}
+		languageOptions = append(languageOptions, CustomWidget.TextValueOption{Text: languageMap.Name, Value: languageValue})
+	}
+	slices.SortFunc(languageOptions, func(a, b CustomWidget.TextValueOption) int {
+		return strings.Compare(a.Text, b.Text)
+	})
+	return append(options, languageOptions...)
+}
+
+func wizardModelText(choice ProfileSettings.ModelChoice) string {
+	if choice.Type == "" {
+		return lang.L("Disabled")
+	}
+	name := choice.Type
+	if choice.Size != "" {
+		name += " " + choice.Size
+	}
+	return lang.L("WizardModelChoice", map[string]interface{}{
+		"Model":     name,
+		"Precision": choice.Precision,
+		"Device":    strings.ToUpper(choice.Device),
+		"Memory":    fmt.Sprintf("%.0f", choice.MemoryMiB),
+	})
+}
+
+func wizardSummaryText(hardware ProfileSettings.Hardware, recommendation ProfileSettings.Recommendation) string {
+	var lines []string
+	if hardware.CUDA {
+		lines = append(lines, lang.L("WizardGPUMemory", map[string]interface{}{"Memory": hardware.GPUMemoryMiB}))
+	} else {
+		lines = append(lines, lang.L("No NVIDIA GPU found, the models run on the CPU."))
+	}
+	lines = append(lines,
+		lang.L("WizardCPUMemory", map[string]interface{}{"Memory": hardware.CPUMemoryMiB}),
+		"",
+		lang.L("Speech-to-Text")+": "+wizardModelText(recommendation.STT),
+		lang.L("Text-Translate")+": "+wizardModelText(recommendation.Translator),
+		lang.L("Text-to-Speech")+": "+wizardModelText(recommendation.TTS),
+		"",
+		lang.L("WizardEstimatedMemory", map[string]interface{}{
+			"GPU": fmt.Sprintf("%.0f", recommendation.GPUMemoryMiB),
+			"CPU": fmt.Sprintf("%.0f", recommendation.CPUMemoryMiB),
+		}),
+	)
+	return strings.Join(lines, "\n")
+}
+
+// showProfileWizard guides through the creation of a profile by use case, languages and the detected hardware.
+// nameValidator checks the entered profile name. onCreated is called with the file name of the created profile.
+func showProfileWizard(profilesDir string, nameValidator fyne.StringValidator, window fyne.Window, onCreated func(profileFileName string)) {
+	defer Logging.GoRoutineErrorHandler(func(scope *sentry.Scope) {
+		scope.SetTag("GoRoutine", "Pages\\ProfileWizard->showProfileWizard")
+	})
+
+	// step 1: use case
+	templateDescription := widget.NewLabel("")
+	templateDescription.Wrapping = fyne.TextWrapWord
+	var templateTitles []string
+	for _, template := range ProfileSettings.Templates {
+		templateTitles = append(templateTitles, lang.L(template.Title))
+	}
+	selectedTemplate := ProfileSettings.Templates[0]
+	templateRadio := widget.NewRadioGroup(templateTitles, func(title string) {
+		for i, template := range ProfileSettings.Templates {
+			if templateTitles[i] == title {
+				selectedTemplate = template
+				templateDescription.SetText(lang.L(template.Description))
+			}
+		}
+	})
+	templateRadio.Required = true
+	templateRadio.SetSelected(templateTitles[0])
+	useCaseStep := container.NewVBox(
+		widget.NewLabelWithStyle(lang.L("What do you want to use Whispering Tiger for?"), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		templateRadio,
+		templateDescription,
+	)
+
+	// step 2: languages
+	spokenLanguageSelect := CustomWidget.NewTextValueSelect("spoken_language", wizardLanguageOptions(lang.L("Auto detect"), func(languageMap Utilities.LanguageMap) string {
+		// speech-to-text only supports languages with ISO1 code
+		if len(languageMap.ISO1) != 2 {
+			return ""
+		}
+		return languageMap.ISO1
+	}), nil, 0)
+	targetLanguageSelect := CustomWidget.NewTextValueSelect("target_language", wizardLanguageOptions(lang.L("No translation"), func(languageMap Utilities.LanguageMap) string {
+		if len(languageMap.ISO3) == 0 {
+			return ""
+		}
+		return languageMap.ISO3[0]
+	}), nil, 0)
+	targetLanguageSelect.SetSelected("eng_Latn")
+	languagesStep := container.NewVBox(
+		widget.NewLabelWithStyle(lang.L("Which languages do you need?"), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewForm(
+			widget.NewFormItem(lang.L("Spoken language"), spokenLanguageSelect),
+			widget.NewFormItem(lang.L("Translate to"), targetLanguageSelect),
+		),
+	)
+
+	// step 3: hardware and profile name
+	summaryLabel := widget.NewLabel(lang.L("Detecting hardware..."))
+	summaryLabel.Wrapping = fyne.TextWrapWord
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder(lang.L("New Profile Name"))
+	nameEntry.Validator = nameValidator
+	hardwareStep := container.NewVBox(
+		widget.NewLabelWithStyle(lang.L("Models recommended for your hardware"), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		summaryLabel,
+		widget.NewForm(widget.NewFormItem(lang.L("Profile Name"), nameEntry)),
+	)
+
+	var hardware *ProfileSettings.Hardware
+	var recommendation ProfileSettings.Recommendation
+	updateRecommendation := func() {
+		if hardware == nil {
+			return
+		}
+		recommendation = ProfileSettings.Recommend(selectedTemplate, ProfileSettings.ProfileLanguages{
+			Spoken: spokenLanguageSelect.GetSelected().Value,
+			Target: targetLanguageSelect.GetSelected().Value,
+		}, *hardware)
+		summaryLabel.SetText(wizardSummaryText(*hardware, recommendation))
+	}
+
+	steps := []fyne.CanvasObject{useCaseStep, languagesStep, hardwareStep}
+	currentStep := 0
+	stepLabel := widget.NewLabel("")
+	stepContent := container.NewStack(steps...)
+
+	var wizardDialog *dialog.CustomDialog
+	backButton := widget.NewButtonWithIcon(lang.L("Back"), theme.NavigateBackIcon(), nil)
+	nextButton := widget.NewButtonWithIcon(lang.L("Next"), theme.NavigateNextIcon(), nil)
+	createButton := widget.NewButtonWithIcon(lang.L("Create Profile"), theme.ConfirmIcon(), nil)
+	createButton.Importance = widget.HighImportance
+	cancelButton := widget.NewButtonWithIcon(lang.L("Cancel"), theme.CancelIcon(), func() {
+		wizardDialog.Hide()
+	})
+
+	showStep := func(step int) {
+		currentStep = step
+		for i, stepObject := range steps {
+			if i == step {
+				stepObject.Show()
+			} else {
+				stepObject.Hide()
+			}
+		}
+		stepLabel.SetText(lang.L("WizardStep", map[string]interface{}{"Step": step + 1, "Steps": len(steps)}))
+		if step == 0 {
+			backButton.Disable()
+		} else {
+			backButton.Enable()
+		}
+		if step == len(steps)-1 {
+			nextButton.Hide()
+			createButton.Show()
+			if hardware == nil {
+				createButton.Disable()
+			}
+			if nameEntry.Text == "" {
+				nameEntry.SetText(lang.L(selectedTemplate.Title))
+			}
+			updateRecommendation()
+		} else {
+			nextButton.Show()
+			createButton.Hide()
+		}
+	}
+	backButton.OnTapped = func() {
+		showStep(currentStep - 1)
+	}
+	nextButton.OnTapped = func() {
+		showStep(currentStep + 1)
+	}
+	createButton.OnTapped = func() {
+		if err := nameEntry.Validate(); err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		profileFileName := strings.TrimSpace(nameEntry.Text) + ".yaml"
+		profile := recommendation.Profile
+		if err := profile.WriteYamlSettings(filepath.Join(profilesDir, profileFileName)); err != nil {
+			Logging.CaptureException(err)
+			dialog.ShowError(err, window)
+			return
+		}
+		wizardDialog.Hide()
+		onCreated(profileFileName)
+	}
+
+	content := container.NewBorder(stepLabel, nil, nil, nil, container.NewVScroll(stepContent))
+	wizardDialog = dialog.NewCustomWithoutButtons(lang.L("Profile Setup Wizard"), content, window)
+	wizardDialog.SetButtons([]fyne.CanvasObject{cancelButton, backButton, nextButton, createButton})
+	showStep(0)
+	wizardDialog.Resize(fyne.NewSize(600, 480))
+	wizardDialog.Show()
+
+	// detecting the GPU memory calls nvidia-smi, which can take a moment
+	go func() {
+		defer Logging.GoRoutineErrorHandler(func(scope *sentry.Scope) {
+			scope.SetTag("GoRoutine", "Pages\\ProfileWizard->DetectHardware")
+		})
+		detectedHardware := ProfileSettings.DetectHardware()
+		fyne.Do(func() {
+			hardware = &detectedHardware
+			createButton.Enable()
+			updateRecommendation()
+		})
+	}()
+}
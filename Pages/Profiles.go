@@ -7,20 +7,22 @@ import (
 	"fmt"
 	"image/color"
 	"io"
+	"log"
 	"math"
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 	"whispering-tiger-ui/CustomWidget"
 	"whispering-tiger-ui/Logging"
+	"whispering-tiger-ui/Migrations"
 	"whispering-tiger-ui/Pages/Advanced"
 	"whispering-tiger-ui/Pages/ProfileSettings"
 	PF "whispering-tiger-ui/ProfileForm"
-	"whispering-tiger-ui/Profiles"
 	"whispering-tiger-ui/Resources"
 	"whispering-tiger-ui/Settings"
 	"whispering-tiger-ui/UpdateUtility"
@@ -1000,8 +1002,29 @@ func CreateProfileWindow(onClose func()) fyne.CanvasObject {
 		formSubmitFunction(false)
 	}
 
+	saveAsBaseFunction := func() {}
+	saveAsBaseButton := widget.NewButtonWithIcon(lang.L("Save as Base Profile"), theme.ContentCopyIcon(), func() {
+		dialog.ShowConfirm(lang.L("Save as Base Profile"), lang.L("SaveAsBaseProfileHint"), func(b bool) {
+			if b {
+				saveAsBaseFunction()
+			}
+		}, fyne.CurrentApp().Driver().AllWindows()[1])
+	})
+	saveAsBaseButton.Importance = widget.LowImportance
+
+	exportFunction := func() {}
+	exportButton := widget.NewButtonWithIcon(lang.L("Export Profile"), theme.UploadIcon(), func() {
+		exportFunction()
+	})
+	exportButton.Importance = widget.LowImportance
+
+	noParentOption := lang.L("None")
+	extendsSelect := widget.NewSelect([]string{noParentOption}, nil)
+	extendsItem := widget.NewFormItem(lang.L("Extends Profile"), extendsSelect)
+	extendsItem.HintText = lang.L("ExtendsProfileHint")
+
 	profileListContent := container.NewBorder(
-		nil, container.NewGridWithColumns(2, saveOnlyButton, submitButton), nil, nil,
+		widget.NewForm(extendsItem), container.NewBorder(nil, nil, container.NewHBox(saveAsBaseButton, exportButton), nil, container.NewGridWithColumns(2, saveOnlyButton, submitButton)), nil, nil,
 		container.NewVScroll(profileFormBuild),
 	)
 
@@ -1056,11 +1079,14 @@ func CreateProfileWindow(onClose func()) fyne.CanvasObject {
 	)
 	beginLine.Resize(fyne.NewSize(profileHelpTextContent.Size().Width, 2))
 
-	// Run migrations
-	Utilities.MigrateProfileSettingsLocation1704429446()
-
 	// build profile list
 	profilesDir := Settings.GetConfProfileDir()
+
+	// Run migrations
+	if _, err := Migrations.MigrateProfiles(profilesDir, Migrations.Options{BackupDir: Settings.GetProfileBackupDir()}); err != nil {
+		log.Printf("Error migrating profiles: %v", err)
+		Logging.CaptureException(err)
+	}
 	var settingsFiles []string
 	files, err := os.ReadDir(profilesDir)
 	if err != nil {
@@ -1072,6 +1098,18 @@ func CreateProfileWindow(onClose func()) fyne.CanvasObject {
 		}
 	}
 
+	// order the profiles so that profiles extending another one are listed below their parent
+	profileEntries := map[string]Settings.ProfileEntry{}
+	updateProfileHierarchy := func() {
+		hierarchy := Settings.ProfileHierarchy(profilesDir, settingsFiles)
+		settingsFiles = settingsFiles[:0]
+		for _, entry := range hierarchy {
+			settingsFiles = append(settingsFiles, entry.File)
+			profileEntries[entry.File] = entry
+		}
+	}
+	updateProfileHierarchy()
+
 	profileList := widget.NewList(
 		func() int {
 			return len(settingsFiles)
@@ -1080,7 +1118,17 @@ func CreateProfileWindow(onClose func()) fyne.CanvasObject {
 			return widget.NewLabel("template")
 		},
 		func(i widget.ListItemID, o fyne.CanvasObject) {
-			o.(*widget.Label).SetText(settingsFiles[i])
+			label := o.(*widget.Label)
+			entry := profileEntries[settingsFiles[i]]
+			text := settingsFiles[i]
+			if entry.Depth > 0 {
+				text = strings.Repeat("    ", entry.Depth-1) + "└ " + text
+			}
+			label.Importance = widget.MediumImportance
+			if entry.Err != nil && Utilities.FileExists(filepath.Join(profilesDir, settingsFiles[i])) {
+				label.Importance = widget.WarningImportance
+			}
+			label.SetText(text)
 		},
 	)
 
@@ -1090,20 +1138,56 @@ func CreateProfileWindow(onClose func()) fyne.CanvasObject {
 		profileListContent.Show()
 		submitButton.Hide()
 
-		profileSettings := ProfileSettings.Presets[createProfilePresetSelect.GetSelected().Value]
-		profileSettings.SettingsFilename = settingsFiles[id]
+		profileFileName := settingsFiles[id]
 
-		if Utilities.FileExists(filepath.Join(profilesDir, settingsFiles[id])) {
-			err = profileSettings.LoadYamlSettings(filepath.Join(profilesDir, settingsFiles[id]))
-			if err != nil {
-				Logging.CaptureException(err)
-				dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[1])
-			}
+		// the selected preset is the default layer, the base profile, parent profiles and the profile file are applied on top
+		profileLayers, err := Settings.LoadProfileLayers(ProfileSettings.Presets[createProfilePresetSelect.GetSelected().Value], filepath.Join(profilesDir, profileFileName))
+		if err != nil {
+			Logging.CaptureException(err)
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[1])
 		}
-		profileSettings.SettingsFilename = settingsFiles[id]
+		profileSettings := profileLayers.Effective()
+		profileSettings.SettingsFilename = profileFileName
 		// Generic load of all registered controls
 		engine.LoadFromSettings(&profileSettings)
 
+		extendsSelect.OnChanged = nil
+		parentOptions := []string{noParentOption}
+		for _, file := range settingsFiles {
+			if file != profileFileName && Utilities.FileExists(filepath.Join(profilesDir, file)) {
+				parentOptions = append(parentOptions, file)
+			}
+		}
+		extendsSelect.SetOptions(parentOptions)
+		if profileLayers.Extends() != "" {
+			extendsSelect.SetSelected(profileLayers.Extends())
+		} else {
+			extendsSelect.SetSelected(noParentOption)
+		}
+		extendsSelect.OnChanged = func(parent string) {
+			if parent == noParentOption {
+				parent = ""
+			}
+			if parent == profileLayers.Extends() {
+				return
+			}
+			if err := profileLayers.SetExtends(parent); err != nil {
+				dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[1])
+				selectedParent := profileLayers.Extends()
+				if selectedParent == "" {
+					selectedParent = noParentOption
+				}
+				extendsSelect.SetSelected(selectedParent)
+				return
+			}
+			// show the values inherited from the new parent
+			isLoadingSettingsFile = true
+			profileSettings = profileLayers.Effective()
+			profileSettings.SettingsFilename = profileFileName
+			engine.LoadFromSettings(&profileSettings)
+			isLoadingSettingsFile = false
+		}
+
 		// After loading: actively apply the profile's Audio API because onAudioAPIChanged is suppressed during loading.
 		if profileSettings.Audio_api != "" {
 			backend := AudioAPI.GetAudioBackendByName(profileSettings.Audio_api)
@@ -1147,6 +1231,26 @@ func CreateProfileWindow(onClose func()) fyne.CanvasObject {
 			}
 		}
 
+		saveAsBaseFunction = func() {
+			baseSettings := profileSettings
+			if err := engine.SaveToSettings(&baseSettings); err != nil {
+				dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[1])
+				return
+			}
+			if err := Settings.WriteBaseProfile(baseSettings); err != nil {
+				dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[1])
+			}
+		}
+
+		exportFunction = func() {
+			exportSettings := profileSettings
+			if err := engine.SaveToSettings(&exportSettings); err != nil {
+				dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[1])
+				return
+			}
+			showProfileExportDialog(profileFileName, exportSettings, fyne.CurrentApp().Driver().AllWindows()[1])
+		}
+
 		formSubmitFunction = func(load bool) {
 			if load {
 				loadingDialog := dialog.NewCustomWithoutButtons(lang.L("Loading..."), widget.NewProgressBarInfinite(), fyne.CurrentApp().Driver().AllWindows()[1])
@@ -1159,58 +1263,46 @@ func CreateProfileWindow(onClose func()) fyne.CanvasObject {
 			}
 
 			// Generic save of all registered controls
-			engine.SaveToSettings(&profileSettings)
+			if err := engine.SaveToSettings(&profileSettings); err != nil {
+				fyne.Do(func() {
+					dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[1])
+				})
+				return
+			}
 
-			// update existing settings or create new one if it does not exist yet
-			if Utilities.FileExists(filepath.Join(profilesDir, settingsFiles[id])) {
-				profileSettings.WriteYamlSettings(filepath.Join(profilesDir, settingsFiles[id]))
-			} else {
-				newProfileEntry := Profiles.Profile{
-					SettingsFilename: settingsFiles[id],
-					Websocket_ip:     profileSettings.Websocket_ip,
-					Websocket_port:   profileSettings.Websocket_port,
-					Run_Backend:      profileSettings.Run_backend,
-
-					Audio_api:           profileSettings.Audio_api,
-					Device_index:        profileSettings.Device_index,
-					Audio_input_device:  profileSettings.Audio_input_device,
-					Device_out_index:    profileSettings.Device_out_index,
-					Audio_output_device: profileSettings.Audio_output_device,
-
-					Vad_enabled:              profileSettings.Vad_enabled,
-					Realtime:                 profileSettings.Realtime,
-					Vad_confidence_threshold: profileSettings.Vad_confidence_threshold,
-
-					Energy:            profileSettings.Energy,
-					Pause:             profileSettings.Pause,
-					Phrase_time_limit: profileSettings.Phrase_time_limit,
-
-					Ai_device:         profileSettings.Ai_device,
-					Model:             profileSettings.Model,
-					Whisper_precision: profileSettings.Whisper_precision,
-					Stt_type:          profileSettings.Stt_type,
-
-					Denoise_audio: profileSettings.Denoise_audio,
-
-					Txt_translator_device:    profileSettings.Txt_translator_device,
-					Txt_translator_size:      profileSettings.Txt_translator_size,
-					Txt_translator_precision: profileSettings.Txt_translator_precision,
-					Txt_translator:           profileSettings.Txt_translator,
-
-					Tts_type:      profileSettings.Tts_type,
-					Tts_ai_device: profileSettings.Tts_ai_device,
-
-					Osc_ip:        profileSettings.Osc_ip,
-					Osc_port:      profileSettings.Osc_port,
-					Ocr_type:      profileSettings.Ocr_type,
-					Ocr_ai_device: profileSettings.Ocr_ai_device,
-					Ocr_precision: profileSettings.Ocr_precision,
+			// store the setting changes of the loaded profile before it is replaced
+			if Settings.Layers != nil {
+				if err := Settings.Layers.FlushSettingChanges(); err != nil {
+					log.Printf("Error storing setting changes in profile: %v", err)
 				}
-				newProfileEntry.Save(filepath.Join(profilesDir, settingsFiles[id]))
 			}
+
+			// update existing settings or create new one if it does not exist yet.
+			// profiles extending another profile only store the values that differ from their parent.
+			if err := profileLayers.WriteProfile(profileSettings); err != nil {
+				fyne.Do(func() {
+					dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[1])
+				})
+				return
+			}
+			profileLayers.ClearLayer(Settings.LayerRuntime)
+			profileSettings.Extends = profileLayers.Extends()
+			Settings.Layers = profileLayers
 			Settings.Config = profileSettings
 
 			if !load {
+				if profileEntries[profileFileName].Parent != profileLayers.Extends() || profileEntries[profileFileName].Err != nil {
+					fyne.Do(func() {
+						updateProfileHierarchy()
+						profileList.Refresh()
+						for i, file := range settingsFiles {
+							if file == profileFileName {
+								profileList.Select(i)
+								break
+							}
+						}
+					})
+				}
 				return
 			}
 			statusBar := widget.NewProgressBarInfinite()
@@ -1272,9 +1364,9 @@ func CreateProfileWindow(onClose func()) fyne.CanvasObject {
 
 		// go through all profiles in the list and check if the file exists. if not, remove it from the list
 		filteredFiles := make([]string, 0, len(settingsFiles))
-		for i, filename := range settingsFiles {
+		for _, filename := range settingsFiles {
 			// skip the currently selected file
-			if i == id {
+			if filename == profileFileName {
 				filteredFiles = append(filteredFiles, filename)
 				continue
 			}
@@ -1315,7 +1407,32 @@ func CreateProfileWindow(onClose func()) fyne.CanvasObject {
 		return nil
 	}
 
-	newProfileRow := container.NewBorder(nil, nil, nil, widget.NewButtonWithIcon(lang.L("New"), theme.DocumentCreateIcon(), func() {
+	selectCreatedProfile := func(profileFileName string) {
+		if !slices.Contains(settingsFiles, profileFileName) {
+			settingsFiles = append(settingsFiles, profileFileName)
+		}
+		updateProfileHierarchy()
+		profileList.Refresh()
+		profileList.UnselectAll()
+		profileList.Select(slices.Index(settingsFiles, profileFileName))
+	}
+
+	importButton := widget.NewButtonWithIcon(lang.L("Import"), theme.DownloadIcon(), func() {
+		showProfileImportDialog(profilesDir, fyne.CurrentApp().Driver().AllWindows()[1], selectCreatedProfile)
+	})
+
+	wizardButton := widget.NewButtonWithIcon(lang.L("Setup Wizard"), theme.ComputerIcon(), func() {
+		showProfileWizard(profilesDir, newProfileEntry.Validator, fyne.CurrentApp().Driver().AllWindows()[1], selectCreatedProfile)
+	})
+
+	// guide new users through the creation of their first profile, once the profile window is shown
+	if len(settingsFiles) == 0 {
+		go fyne.Do(func() {
+			showProfileWizard(profilesDir, newProfileEntry.Validator, fyne.CurrentApp().Driver().AllWindows()[1], selectCreatedProfile)
+		})
+	}
+
+	newProfileRow := container.NewBorder(nil, nil, nil, container.NewHBox(wizardButton, importButton, widget.NewButtonWithIcon(lang.L("New"), theme.DocumentCreateIcon(), func() {
 		validationError := newProfileEntry.Validate()
 		if validationError != nil {
 			dialog.ShowError(validationError, fyne.CurrentApp().Driver().AllWindows()[1])
@@ -1327,7 +1444,7 @@ func CreateProfileWindow(onClose func()) fyne.CanvasObject {
 		settingsFiles = append(settingsFiles, newEntryName)
 		profileList.Select(len(settingsFiles) - 1)
 		profileList.Refresh()
-	}), container.NewAdaptiveGrid(2, createProfilePresetSelect, newProfileEntry))
+	})), container.NewAdaptiveGrid(2, createProfilePresetSelect, newProfileEntry))
 
 	memoryArea := container.NewVBox(
 		CPUMemoryBar,
@@ -14,15 +14,12 @@ func CreateSettingsWindow() fyne.CanvasObject {
 		scope.SetTag("GoRoutine", "Pages\\Settings->CreateSettingsWindow")
 	})
 
-	settingsFormTabs := container.NewAppTabs(
-		container.NewTabItem(lang.L("Application Options"), SettingsMappings.CreateSettingsFormByMapping(SettingsMappings.ApplicationSettingsMapping)),
-		container.NewTabItem(lang.L("Speech-to-Text Options"), SettingsMappings.CreateSettingsFormByMapping(SettingsMappings.SpeechToTextSettingsMapping)),
-		container.NewTabItem(lang.L("Text-Translate Options"), SettingsMappings.CreateSettingsFormByMapping(SettingsMappings.TextTranslateSettingsMapping)),
-		container.NewTabItem(lang.L("Text-to-Speech Options"), SettingsMappings.CreateSettingsFormByMapping(SettingsMappings.TextToSpeechSettingsMapping)),
-		container.NewTabItem(lang.L("OSC (VRChat) Options"), SettingsMappings.CreateSettingsFormByMapping(SettingsMappings.OSCSettingsMapping)),
-		container.NewTabItem(lang.L("Experimental Options"), SettingsMappings.CreateSettingsFormByMapping(SettingsMappings.ExperimentalSettingsMapping)),
-	)
+	settingsFormTabs := container.NewAppTabs()
+	for _, page := range SettingsMappings.SettingsPagesWithSchema() {
+		settingsFormTabs.Append(container.NewTabItem(lang.L(page.Title), SettingsMappings.CreateSettingsFormByMapping(*page.Mapping)))
+	}
+	settingsFormTabs.Append(container.NewTabItem(lang.L("Text Processing"), CreateTextProcessingSettingsWindow()))
 	settingsFormTabs.SetTabLocation(container.TabLocationLeading)
 
-	return settingsFormTabs
+	return createSettingsSearch(settingsFormTabs)
 }
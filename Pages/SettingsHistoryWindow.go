@@ -0,0 +1,178 @@
+package Pages
+
+import (
+	"fmt"
+	"whispering-tiger-ui/Logging"
+	"whispering-tiger-ui/Settings"
+	"whispering-tiger-ui/SettingsHistory"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/getsentry/sentry-go"
+)
+
+func historyValueText(value interface{}) string {
+	if value == nil {
+		return "-"
+	}
+	return fmt.Sprint(value)
+}
+
+// CreateSettingsHistoryWindow shows the setting changes of the loaded profile with undo, redo and revert,
+// and the settings that differ from the defaults.
+func CreateSettingsHistoryWindow() fyne.CanvasObject {
+	defer Logging.GoRoutineErrorHandler(func(scope *sentry.Scope) {
+		scope.SetTag("GoRoutine", "Pages\\SettingsHistoryWindow->CreateSettingsHistoryWindow")
+	})
+
+	journal := SettingsHistory.Active
+	if journal == nil {
+		return container.NewCenter(widget.NewLabel(lang.L("No profile loaded")))
+	}
+
+	entries := journal.Entries()
+	position := journal.Position()
+	selectedEntry := -1
+
+	undoButton := widget.NewButtonWithIcon(lang.L("Undo"), theme.ContentUndoIcon(), func() {
+		go journal.Undo()
+	})
+	redoButton := widget.NewButtonWithIcon(lang.L("Redo"), theme.ContentRedoIcon(), func() {
+		go journal.Redo()
+	})
+	revertButton := widget.NewButtonWithIcon(lang.L("Revert to selected change"), theme.HistoryIcon(), nil)
+	clearButton := widget.NewButtonWithIcon(lang.L("Clear history"), theme.DeleteIcon(), func() {
+		dialog.ShowConfirm(lang.L("Clear history"), lang.L("ClearSettingsHistoryHint"), func(b bool) {
+			if b {
+				journal.Clear()
+			}
+		}, fyne.CurrentApp().Driver().AllWindows()[0])
+	})
+	clearButton.Importance = widget.LowImportance
+
+	historyList := widget.NewList(
+		func() int {
+			return len(entries)
+		},
+		func() fyne.CanvasObject {
+			timeLabel := widget.NewLabel("2006-01-02 00:00:00")
+			changeLabel := widget.NewLabel("")
+			changeLabel.Truncation = fyne.TextTruncateEllipsis
+			return container.NewBorder(nil, nil, timeLabel, nil, changeLabel)
+		},
+		func(id widget.ListItemID, object fyne.CanvasObject) {
+			entry := entries[id]
+			row := object.(*fyne.Container)
+			changeLabel := row.Objects[0].(*widget.Label)
+			timeLabel := row.Objects[1].(*widget.Label)
+			timeLabel.SetText(entry.Time.Local().Format("2006-01-02 15:04:05"))
+			changeLabel.SetText(entry.Name + ": " + historyValueText(entry.OldValue) + " → " + historyValueText(entry.NewValue))
+			// undone changes can be redone
+			if id >= position {
+				changeLabel.Importance = widget.LowImportance
+				timeLabel.Importance = widget.LowImportance
+			} else {
+				changeLabel.Importance = widget.MediumImportance
+				timeLabel.Importance = widget.MediumImportance
+			}
+			changeLabel.Refresh()
+			timeLabel.Refresh()
+		},
+	)
+
+	updateButtons := func() {
+		if position > 0 {
+			undoButton.Enable()
+		} else {
+			undoButton.Disable()
+		}
+		if position < len(entries) {
+			redoButton.Enable()
+		} else {
+			redoButton.Disable()
+		}
+		if selectedEntry >= 0 && selectedEntry < len(entries) && selectedEntry+1 != position {
+			revertButton.Enable()
+		} else {
+			revertButton.Disable()
+		}
+		if len(entries) > 0 {
+			clearButton.Enable()
+		} else {
+			clearButton.Disable()
+		}
+	}
+	historyList.OnSelected = func(id widget.ListItemID) {
+		selectedEntry = id
+		updateButtons()
+	}
+	historyList.OnUnselected = func(id widget.ListItemID) {
+		selectedEntry = -1
+		updateButtons()
+	}
+	revertButton.OnTapped = func() {
+		if selectedEntry < 0 {
+			return
+		}
+		// revert to the state right after the selected change
+		go journal.RevertTo(selectedEntry + 1)
+	}
+
+	defaultsGrid := container.NewGridWithColumns(3)
+	updateDefaults := func() {
+		defaultsGrid.RemoveAll()
+		defaultsGrid.Add(widget.NewLabelWithStyle(lang.L("Setting"), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+		defaultsGrid.Add(widget.NewLabelWithStyle(lang.L("Default value"), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+		defaultsGrid.Add(widget.NewLabelWithStyle(lang.L("Current value"), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+		for _, change := range Settings.ChangedFromDefaults(Settings.Config) {
+			defaultLabel := widget.NewLabel(historyValueText(change.Default))
+			defaultLabel.Wrapping = fyne.TextWrapWord
+			valueLabel := widget.NewLabel(historyValueText(change.Value))
+			valueLabel.Wrapping = fyne.TextWrapWord
+			defaultsGrid.Add(widget.NewLabel(change.Name))
+			defaultsGrid.Add(defaultLabel)
+			defaultsGrid.Add(valueLabel)
+		}
+	}
+
+	update := func() {
+		entries = journal.Entries()
+		position = journal.Position()
+		if selectedEntry >= len(entries) {
+			selectedEntry = -1
+			historyList.UnselectAll()
+		}
+		historyList.Refresh()
+		if len(entries) > 0 {
+			historyList.ScrollTo(max(position-1, 0))
+		}
+		updateButtons()
+		updateDefaults()
+	}
+	journal.OnChanged = func() {
+		fyne.Do(update)
+	}
+	update()
+
+	historyContent := container.NewBorder(
+		container.NewBorder(nil, nil, container.NewHBox(undoButton, redoButton, revertButton), clearButton), nil, nil, nil,
+		historyList,
+	)
+	defaultsContent := container.NewBorder(
+		widget.NewLabel(lang.L("ChangedFromDefaultsHint")), nil, nil, nil,
+		container.NewVScroll(defaultsGrid),
+	)
+
+	tabs := container.NewAppTabs(
+		container.NewTabItem(lang.L("Change History"), historyContent),
+		container.NewTabItem(lang.L("Changed from defaults"), defaultsContent),
+	)
+	tabs.OnSelected = func(tab *container.TabItem) {
+		update()
+	}
+	return tabs
+}
@@ -1,6 +1,9 @@
 package SettingsMappings
 
 import (
+	"errors"
+	"strconv"
+
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
@@ -9,6 +12,7 @@ import (
 	"whispering-tiger-ui/Logging"
 	"whispering-tiger-ui/Pages/Advanced"
 	"whispering-tiger-ui/UpdateUtility"
+	"whispering-tiger-ui/Updater"
 )
 
 var ApplicationSettingsMapping = SettingsMapping{
@@ -27,6 +31,59 @@ var ApplicationSettingsMapping = SettingsMapping{
 				return widgetCheckbox
 			},
 		},
+		{
+			SettingsName:         "Download bandwidth limit",
+			SettingsInternalName: "",
+			SettingsDescription:  "Limits the combined speed of all downloads in KiB/s, so they do not saturate the connection during a live session. 0 is unlimited.",
+			DoNotSendToBackend:   true,
+			_widget: func() fyne.CanvasObject {
+				widgetEntry := widget.NewEntry()
+				widgetEntry.SetPlaceHolder("0")
+				widgetEntry.SetText(strconv.Itoa(fyne.CurrentApp().Preferences().IntWithFallback("DownloadBandwidthLimit", 0)))
+				widgetEntry.Validator = func(s string) error {
+					if value, err := strconv.Atoi(s); s != "" && (err != nil || value < 0) {
+						return errors.New(lang.L("Enter a number of KiB/s"))
+					}
+					return nil
+				}
+				widgetEntry.OnChanged = func(s string) {
+					limit, err := strconv.Atoi(s)
+					if s == "" {
+						limit, err = 0, nil
+					}
+					if err != nil || limit < 0 {
+						return
+					}
+					fyne.CurrentApp().Preferences().SetInt("DownloadBandwidthLimit", limit)
+					Updater.SetBandwidthLimit(int64(limit) * 1024)
+				}
+
+				return container.NewBorder(nil, nil, nil, widget.NewLabel("KiB/s"), widgetEntry)
+			},
+		},
+		{
+			SettingsName:         "HTTP proxy for downloads",
+			SettingsInternalName: "",
+			SettingsDescription:  "Proxy used for model, update and plugin downloads, like http://host:port. Empty uses the proxy of the system environment.",
+			DoNotSendToBackend:   true,
+			_widget: func() fyne.CanvasObject {
+				widgetEntry := widget.NewEntry()
+				widgetEntry.SetPlaceHolder("http://host:port")
+				widgetEntry.SetText(fyne.CurrentApp().Preferences().StringWithFallback("DownloadProxy", ""))
+				widgetEntry.Validator = func(s string) error {
+					_, err := Updater.ParseProxy(s)
+					return err
+				}
+				widgetEntry.OnChanged = func(s string) {
+					if err := Updater.SetProxy(s); err != nil {
+						return
+					}
+					fyne.CurrentApp().Preferences().SetString("DownloadProxy", s)
+				}
+
+				return widgetEntry
+			},
+		},
 		{
 			SettingsName:         "Run Python backend with UTF-8 encoding. (Recommended)",
 			SettingsInternalName: "",
@@ -87,6 +144,87 @@ var ApplicationSettingsMapping = SettingsMapping{
 				return container.NewHBox(widgetCheckbox, checkForUpdatesButton)
 			},
 		},
+		{
+			SettingsName:         "Update channel",
+			SettingsInternalName: "",
+			SettingsDescription:  "Beta and nightly updates are released earlier, but can be less stable.",
+			DoNotSendToBackend:   true,
+			_widget: func() fyne.CanvasObject {
+				channelNames := map[string]string{
+					Updater.ChannelStable:  lang.L("Stable"),
+					Updater.ChannelBeta:    lang.L("Beta"),
+					Updater.ChannelNightly: lang.L("Nightly"),
+				}
+				var options []string
+				for _, channel := range Updater.Channels {
+					options = append(options, channelNames[channel])
+				}
+				widgetSelect := widget.NewSelect(options, func(s string) {
+					for channel, name := range channelNames {
+						if name == s {
+							fyne.CurrentApp().Preferences().SetString("UpdateChannel", channel)
+						}
+					}
+				})
+				widgetSelect.Selected = channelNames[UpdateUtility.UpdateChannel()]
+
+				return widgetSelect
+			},
+		},
+		{
+			SettingsName:         "Keep previous versions for successful starts",
+			SettingsInternalName: "",
+			SettingsDescription:  "After an update the previous versions are kept for a rollback, until the backend started successfully this many times.",
+			DoNotSendToBackend:   true,
+			_widget: func() fyne.CanvasObject {
+				widgetEntry := widget.NewEntry()
+				widgetEntry.SetText(strconv.Itoa(fyne.CurrentApp().Preferences().IntWithFallback("KeepPreviousVersionStarts", UpdateUtility.DefaultKeepPreviousVersionStarts)))
+				widgetEntry.Validator = func(s string) error {
+					if value, err := strconv.Atoi(s); err != nil || value < 1 {
+						return errors.New(lang.L("Enter a number of at least 1"))
+					}
+					return nil
+				}
+				widgetEntry.OnChanged = func(s string) {
+					if value, err := strconv.Atoi(s); err == nil && value >= 1 {
+						fyne.CurrentApp().Preferences().SetInt("KeepPreviousVersionStarts", value)
+					}
+				}
+
+				return widgetEntry
+			},
+		},
+		{
+			SettingsName:         "Roll back to the previous version",
+			SettingsInternalName: "",
+			SettingsDescription:  "Switches back to the version that was installed before the last update.",
+			DoNotSendToBackend:   true,
+			_widget: func() fyne.CanvasObject {
+				window := fyne.CurrentApp().Driver().AllWindows()[0]
+				rollbackPlatformButton := widget.NewButton(lang.L("No previous Platform version"), nil)
+				rollbackPlatformButton.Disable()
+				if version, ok := UpdateUtility.PreviousVersion("ai_platform"); ok {
+					rollbackPlatformButton.SetText(lang.L("RollbackPlatformButton", map[string]interface{}{"Version": version}))
+					rollbackPlatformButton.OnTapped = func() {
+						rollbackPlatformButton.Disable()
+						go UpdateUtility.RollbackPlatform(window)
+					}
+					rollbackPlatformButton.Enable()
+				}
+				rollbackAppButton := widget.NewButton(lang.L("No previous App version"), nil)
+				rollbackAppButton.Disable()
+				if version, ok := UpdateUtility.PreviousVersion("app"); ok {
+					rollbackAppButton.SetText(lang.L("RollbackAppButton", map[string]interface{}{"Version": version}))
+					rollbackAppButton.OnTapped = func() {
+						rollbackAppButton.Disable()
+						UpdateUtility.RollbackApp(window)
+					}
+					rollbackAppButton.Enable()
+				}
+
+				return container.NewHBox(rollbackPlatformButton, rollbackAppButton)
+			},
+		},
 		{
 			SettingsName:         "Check for Plugin updates at startup",
 			SettingsInternalName: "",
@@ -0,0 +1,157 @@
+package SettingsMappings
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"whispering-tiger-ui/CustomWidget"
+	"whispering-tiger-ui/Settings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// SettingsPage is a tab of the settings window that is built from a mapping.
+type SettingsPage struct {
+	Title string
+	// Group is the group of the backend schema whose options are added to the page.
+	Group   string
+	Mapping *SettingsMapping
+}
+
+// SettingsPages are the tabs of the settings window built from hand-written mappings, in the order they are shown.
+var SettingsPages = []SettingsPage{
+	{Title: "Application Options", Group: "application", Mapping: &ApplicationSettingsMapping},
+	{Title: "Speech-to-Text Options", Group: "speech_to_text", Mapping: &SpeechToTextSettingsMapping},
+	{Title: "Text-Translate Options", Group: "text_translate", Mapping: &TextTranslateSettingsMapping},
+	{Title: "Text-to-Speech Options", Group: "text_to_speech", Mapping: &TextToSpeechSettingsMapping},
+	{Title: "OSC (VRChat) Options", Group: "osc", Mapping: &OSCSettingsMapping},
+	{Title: "Experimental Options", Group: "experimental", Mapping: &ExperimentalSettingsMapping},
+}
+
+// defaultSchemaGroup receives backend options without group.
+const defaultSchemaGroup = "Backend Options"
+
+// schemaGroup normalizes the group names of the backend schema, so "Speech-to-Text" matches "speech_to_text".
+func schemaGroup(group string) string {
+	return strings.NewReplacer("-", "_", " ", "_").Replace(strings.ToLower(strings.TrimSpace(group)))
+}
+
+// SettingsPagesWithSchema returns the settings pages with widgets generated for the options of the backend schema.
+// Options with a hand-written mapping keep it, so mappings override the generated widgets.
+// Options of groups without settings page are shown on a new page per group.
+func SettingsPagesWithSchema() []SettingsPage {
+	mapped := map[string]bool{}
+	pages := make([]SettingsPage, 0, len(SettingsPages))
+	for _, page := range SettingsPages {
+		for _, mapping := range page.Mapping.Mappings {
+			if mapping.SettingsInternalName != "" {
+				mapped[mapping.SettingsInternalName] = true
+			}
+		}
+		// copy the mapping, the generated widgets must not be added to the hand-written mappings
+		page.Mapping = &SettingsMapping{Mappings: append([]SettingMapping{}, page.Mapping.Mappings...)}
+		pages = append(pages, page)
+	}
+
+	for _, option := range Settings.BackendSchema() {
+		if mapped[option.Name] {
+			continue
+		}
+		mapping, ok := MappingFromBackendOption(option)
+		if !ok {
+			continue
+		}
+		mapped[option.Name] = true
+
+		group := option.Group
+		if strings.TrimSpace(group) == "" {
+			group = defaultSchemaGroup
+		}
+		pageIndex := -1
+		for i, page := range pages {
+			if schemaGroup(page.Group) == schemaGroup(group) || schemaGroup(page.Title) == schemaGroup(group) {
+				pageIndex = i
+				break
+			}
+		}
+		if pageIndex < 0 {
+			pages = append(pages, SettingsPage{Title: group, Group: schemaGroup(group), Mapping: &SettingsMapping{}})
+			pageIndex = len(pages) - 1
+		}
+		pages[pageIndex].Mapping.Mappings = append(pages[pageIndex].Mapping.Mappings, mapping)
+	}
+	return pages
+}
+
+// sliderPrecision returns the number of decimals needed to show the values of a slider step.
+func sliderPrecision(step float64) int {
+	precision := 0
+	for precision < 6 && math.Abs(step-math.Round(step)) > 1e-9 {
+		step *= 10
+		precision++
+	}
+	return precision
+}
+
+// MappingFromBackendOption generates the setting mapping of a backend option.
+// Numbers with range become sliders, strings with choices become selects.
+// Returns false for option types without generated widget, they stay available in the Advanced Settings.
+func MappingFromBackendOption(option Settings.BackendOption) (SettingMapping, bool) {
+	mapping := SettingMapping{
+		SettingsName:         option.DisplayLabel(),
+		SettingsInternalName: option.Name,
+		SettingsDescription:  option.Description,
+		initialValue:         option.CurrentValue(),
+	}
+
+	switch option.Type {
+	case Settings.OptionTypeBool:
+		mapping._widget = func() fyne.CanvasObject {
+			return widget.NewCheck("", func(b bool) {})
+		}
+	case Settings.OptionTypeInt, Settings.OptionTypeFloat:
+		if option.Min == nil || option.Max == nil {
+			mapping._widget = newGeneratedEntry
+			break
+		}
+		step := 1.0
+		if option.Step != nil && *option.Step > 0 {
+			step = *option.Step
+		} else if option.Type == Settings.OptionTypeFloat {
+			step = (*option.Max - *option.Min) / 100
+		}
+		precision := sliderPrecision(step)
+		mapping._widget = func() fyne.CanvasObject {
+			sliderWidget := widget.NewSlider(*option.Min, *option.Max)
+			sliderState := widget.NewLabel(strconv.FormatFloat(sliderWidget.Min, 'f', precision, 64))
+			sliderWidget.Step = step
+			sliderWidget.OnChanged = func(value float64) {
+				sliderState.SetText(strconv.FormatFloat(value, 'f', precision, 64))
+			}
+			return container.NewBorder(nil, nil, nil, sliderState, sliderWidget)
+		}
+	case Settings.OptionTypeString:
+		if len(option.Choices) == 0 {
+			mapping._widget = newGeneratedEntry
+			break
+		}
+		mapping._widget = func() fyne.CanvasObject {
+			var selectOptions []CustomWidget.TextValueOption
+			for _, choice := range option.Choices {
+				selectOptions = append(selectOptions, CustomWidget.TextValueOption{Text: choice.ChoiceLabel(), Value: choice.Value})
+			}
+			return CustomWidget.NewTextValueSelect(option.Name, selectOptions, func(s CustomWidget.TextValueOption) {}, 0)
+		}
+	default:
+		return mapping, false
+	}
+	return mapping, true
+}
+
+func newGeneratedEntry() fyne.CanvasObject {
+	entryWidget := widget.NewEntry()
+	entryWidget.OnChanged = func(s string) {}
+	return entryWidget
+}
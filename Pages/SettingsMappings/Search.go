@@ -0,0 +1,228 @@
+package SettingsMappings
+
+import (
+	"slices"
+	"strings"
+	"whispering-tiger-ui/Settings"
+	"whispering-tiger-ui/Utilities"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// SearchSource tells where a setting is shown.
+type SearchSource int
+
+const (
+	// SourceSettingsPage settings are shown on one of the SettingsPages.
+	SourceSettingsPage SearchSource = iota
+	// SourcePlugin settings are shown in the settings of a plugin.
+	SourcePlugin
+	// SourceAdvancedSettings are the profile settings without mapping, shown in the Advanced Settings form.
+	SourceAdvancedSettings
+)
+
+// SearchEntry is a setting in the search index.
+type SearchEntry struct {
+	Source SearchSource
+	// Page is the title of the settings tab, or the plugin class of plugin settings.
+	Page string
+	// Key identifies the setting on its page.
+	Key          string
+	Name         string
+	InternalName string
+	Description  string
+	// Modified is set if the value of the loaded profile differs from the default value.
+	Modified        bool
+	RequiresRestart bool
+
+	searchText string
+}
+
+// SearchFilter limits the search results. An empty filter matches all settings.
+type SearchFilter struct {
+	// Query contains words that must all be part of the name, internal name, description or page of a setting.
+	Query           string
+	Modified        bool
+	RequiresRestart bool
+}
+
+// Empty returns true if the filter matches all settings.
+func (f SearchFilter) Empty() bool {
+	return strings.TrimSpace(f.Query) == "" && !f.Modified && !f.RequiresRestart
+}
+
+// searchKey identifies a setting on its settings page.
+func (s *SettingMapping) searchKey() string {
+	if s.SettingsInternalName != "" {
+		return s.SettingsInternalName
+	}
+	return s.SettingsName
+}
+
+// BuildSearchIndex indexes the settings of all settings pages, the plugin settings and the remaining profile settings.
+func BuildSearchIndex() []SearchEntry {
+	modified := map[string]bool{}
+	for _, change := range Settings.ChangedFromDefaults(Settings.Config) {
+		modified[change.Name] = true
+	}
+	indexed := map[string]bool{}
+	var index []SearchEntry
+
+	for _, page := range SettingsPagesWithSchema() {
+		for _, mapping := range page.Mapping.Mappings {
+			name, description := mapping.translatedNameAndDescription()
+			entry := SearchEntry{
+				Source:      SourceSettingsPage,
+				Page:        page.Title,
+				Key:         mapping.searchKey(),
+				Name:        name,
+				Description: description,
+			}
+			if !mapping.DoNotSendToBackend && mapping.SettingsInternalName != "" {
+				entry.InternalName = mapping.SettingsInternalName
+				entry.Modified = modified[mapping.SettingsInternalName]
+				entry.RequiresRestart = Settings.RequiresRestart(mapping.SettingsInternalName)
+				indexed[mapping.SettingsInternalName] = true
+			}
+			index = append(index, entry)
+		}
+	}
+
+	allPluginSettings := Settings.LoadPluginSettings()
+	pluginClasses := make([]string, 0, len(allPluginSettings))
+	for pluginClass := range allPluginSettings {
+		pluginClasses = append(pluginClasses, pluginClass)
+	}
+	slices.Sort(pluginClasses)
+	for _, pluginClass := range pluginClasses {
+		pluginSettings, ok := allPluginSettings[pluginClass].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		settingNames := make([]string, 0, len(pluginSettings))
+		for settingName := range pluginSettings {
+			// settings_groups only describes the layout of the plugin settings
+			if settingName != "settings_groups" {
+				settingNames = append(settingNames, settingName)
+			}
+		}
+		slices.Sort(settingNames)
+		for _, settingName := range settingNames {
+			index = append(index, SearchEntry{
+				Source:       SourcePlugin,
+				Page:         pluginClass,
+				Key:          settingName,
+				Name:         settingName,
+				InternalName: settingName,
+			})
+		}
+	}
+
+	for _, fieldSchema := range Settings.Schema() {
+		if indexed[fieldSchema.Name] || Utilities.Contains(Settings.ExcludeConfigFields, fieldSchema.Name) {
+			continue
+		}
+		index = append(index, SearchEntry{
+			Source:          SourceAdvancedSettings,
+			Page:            "Advanced Settings",
+			Key:             fieldSchema.Name,
+			Name:            fieldSchema.Name,
+			InternalName:    fieldSchema.Name,
+			Modified:        modified[fieldSchema.Name],
+			RequiresRestart: Settings.RequiresRestart(fieldSchema.Name),
+		})
+	}
+
+	for i := range index {
+		index[i].searchText = strings.ToLower(strings.Join([]string{index[i].Name, index[i].InternalName, index[i].Description, index[i].Page}, "\n"))
+	}
+	return index
+}
+
+// Search returns the entries of the index that match the filter.
+func Search(index []SearchEntry, filter SearchFilter) []SearchEntry {
+	terms := strings.Fields(strings.ToLower(filter.Query))
+	var results []SearchEntry
+	for _, entry := range index {
+		if filter.Modified && !entry.Modified {
+			continue
+		}
+		if filter.RequiresRestart && !entry.RequiresRestart {
+			continue
+		}
+		matches := true
+		for _, term := range terms {
+			if !strings.Contains(entry.searchText, term) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			results = append(results, entry)
+		}
+	}
+	return results
+}
+
+type settingLocation struct {
+	widget fyne.CanvasObject
+	scroll *container.Scroll
+}
+
+// settingLocations are the widgets of the settings pages by search key, used to jump to a setting.
+var settingLocations = map[string]settingLocation{}
+
+func registerSettingLocation(key string, settingWidget fyne.CanvasObject, scroll *container.Scroll) {
+	if key == "" {
+		return
+	}
+	settingLocations[key] = settingLocation{widget: settingWidget, scroll: scroll}
+}
+
+// ShowSetting scrolls the settings page to a setting and focuses it.
+// The tab of the setting must be selected. Returns false if the setting is not shown.
+func ShowSetting(key string) bool {
+	location, ok := settingLocations[key]
+	if !ok {
+		return false
+	}
+	ScrollToObject(location.scroll, location.widget)
+	return true
+}
+
+// ScrollToObject scrolls a scroll container to show an object of its content at the top and focuses the object.
+func ScrollToObject(scroll *container.Scroll, object fyne.CanvasObject) {
+	driver := fyne.CurrentApp().Driver()
+	offset := driver.AbsolutePositionForObject(object).Y - driver.AbsolutePositionForObject(scroll.Content).Y
+	scroll.Offset = fyne.NewPos(scroll.Offset.X, max(offset-theme.Padding(), 0))
+	scroll.Refresh()
+
+	if canvas := driver.CanvasForObject(object); canvas != nil {
+		if focusable := findFocusable(object); focusable != nil {
+			canvas.Focus(focusable)
+		}
+	}
+}
+
+// findFocusable returns the first input of a setting widget, skipping buttons like the info button.
+func findFocusable(object fyne.CanvasObject) fyne.Focusable {
+	if _, isButton := object.(*widget.Button); isButton {
+		return nil
+	}
+	if focusable, ok := object.(fyne.Focusable); ok {
+		if disableable, ok := object.(fyne.Disableable); !ok || !disableable.Disabled() {
+			return focusable
+		}
+	}
+	if settingContainer, ok := object.(*fyne.Container); ok {
+		for _, child := range settingContainer.Objects {
+			if focusable := findFocusable(child); focusable != nil {
+				return focusable
+			}
+		}
+	}
+	return nil
+}
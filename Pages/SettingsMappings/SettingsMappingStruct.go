@@ -1,6 +1,7 @@
 package SettingsMappings
 
 import (
+	"errors"
 	"fmt"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
@@ -12,6 +13,7 @@ import (
 	"fyne.io/fyne/v2/widget"
 	"github.com/getsentry/sentry-go"
 	"image/color"
+	"log"
 	"reflect"
 	"strconv"
 	"strings"
@@ -21,6 +23,7 @@ import (
 	"whispering-tiger-ui/Logging"
 	"whispering-tiger-ui/SendMessageChannel"
 	"whispering-tiger-ui/Settings"
+	"whispering-tiger-ui/SettingsHistory"
 )
 
 var timerLock sync.Mutex
@@ -41,6 +44,14 @@ type SettingMapping struct {
 	_widget              func() fyne.CanvasObject
 	Widget               fyne.CanvasObject
 	DoNotSendToBackend   bool
+
+	// suppressUpdates prevents sending values while the widget is updated programmatically
+	suppressUpdates bool
+	onValueChanged  func()
+	// onValidationError shows the validation error of the last value on the form item, nil clears it
+	onValidationError func(err error)
+	// initialValue is used for settings that are not part of the profile settings, like generated backend options
+	initialValue interface{}
 }
 
 func (s *SettingsMapping) FindSettingByInternalName(internalName string) (*SettingMapping, error) {
@@ -53,19 +64,25 @@ func (s *SettingsMapping) FindSettingByInternalName(internalName string) (*Setti
 }
 
 func (s *SettingMapping) SendUpdatedValue(value interface{}) {
+	if s.suppressUpdates {
+		return
+	}
+	// settings that are not part of the profile (unknown to the schema) are passed to the backend unchecked
+	if _, err := Settings.ValidateOption(s.SettingsInternalName, value); err != nil && !errors.Is(err, Settings.ErrUnknownSetting) {
+		log.Printf("not sending invalid value of %s: %v", s.SettingsInternalName, err)
+		s.rejectValue(err)
+		return
+	}
+	if s.onValidationError != nil {
+		s.onValidationError(nil)
+	}
+
 	timerLock.Lock()
 	defer timerLock.Unlock()
 
 	// If the value is a boolean, send the update immediately
-	if v, ok := value.(bool); ok {
-		sendMessage := SendMessageChannel.SendMessageStruct{
-			Type:  "setting_change",
-			Name:  s.SettingsInternalName,
-			Value: v,
-		}
-		sendMessage.SendMessage()
-		Settings.Config.SetOption(s.SettingsInternalName, v)
-		fmt.Println("sent message with value" + fmt.Sprintf("%v", v))
+	if _, ok := value.(bool); ok {
+		s.sendValue(value)
 		return
 	}
 
@@ -76,15 +93,176 @@ func (s *SettingMapping) SendUpdatedValue(value interface{}) {
 
 	// Set up a new timer that calls the actual message sending after debounceDuration
 	debounceTimers[s.SettingsInternalName] = time.AfterFunc(debounceDuration, func() {
+		s.sendValue(value)
+	})
+}
+
+// rejectValue shows the validation error of an invalid value and resets the widget to the loaded value.
+// Entries keep the typed text, so typing is not interrupted, their validator marks the invalid text.
+func (s *SettingMapping) rejectValue(err error) {
+	if s.onValidationError != nil {
+		s.onValidationError(err)
+	}
+	if hasEntry(s.Widget) {
+		return
+	}
+	if value, optionErr := Settings.Config.GetOption(s.SettingsInternalName); optionErr == nil {
+		s.setWidgetValue(value, s.Widget)
+	}
+}
+
+// hasEntry returns true if a setting widget is or contains an entry.
+func hasEntry(settingWidget fyne.CanvasObject) bool {
+	switch w := settingWidget.(type) {
+	case *widget.Entry:
+		return true
+	case *fyne.Container:
+		for _, settingChild := range w.Objects {
+			if hasEntry(settingChild) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sendValue sends the value to the backend, stores it as runtime change and records it in the settings history
+func (s *SettingMapping) sendValue(value interface{}) {
+	oldValue, optionErr := Settings.Config.GetOption(s.SettingsInternalName)
+	sendMessage := SendMessageChannel.SendMessageStruct{
+		Type:  "setting_change",
+		Name:  s.SettingsInternalName,
+		Value: value,
+	}
+	sendMessage.SendMessage()
+	_ = Settings.SetRuntimeOption(s.SettingsInternalName, value)
+	// settings that are not part of the profile have no previous value to restore
+	if optionErr == nil {
+		SettingsHistory.Record(s.SettingsInternalName, oldValue, value)
+	}
+	fmt.Println("sent message with value" + fmt.Sprintf("%v", value))
+	if s.onValueChanged != nil {
+		s.onValueChanged()
+	}
+}
+
+// setWidgetValue updates the widgets of a setting without sending the value to the backend
+func (s *SettingMapping) setWidgetValue(value interface{}, settingWidget interface{}) {
+	s.suppressUpdates = true
+	defer func() { s.suppressUpdates = false }()
+
+	switch w := settingWidget.(type) {
+	case *widget.Check:
+		if v, ok := value.(bool); ok {
+			w.SetChecked(v)
+		}
+	case *widget.Slider:
+		switch v := value.(type) {
+		case float64:
+			w.SetValue(v)
+		case int:
+			w.SetValue(float64(v))
+		}
+	case *widget.Entry:
+		switch v := value.(type) {
+		case float64:
+			w.SetText(fmt.Sprintf("%f", v))
+		case int:
+			w.SetText(fmt.Sprintf("%d", v))
+		case string:
+			w.SetText(v)
+		}
+	case *CustomWidget.TextValueSelect:
+		if v, ok := value.(string); ok {
+			w.SetSelected(v)
+		}
+	case *fyne.Container:
+		for _, settingChild := range w.Objects {
+			s.setWidgetValue(value, settingChild)
+		}
+	}
+}
+
+// addSourceIndicator shows which layer (default, base profile, profile or runtime change) the value comes from,
+// with a button to reset it to the inherited value
+func (s *SettingMapping) addSourceIndicator(settingWidget fyne.CanvasObject) fyne.CanvasObject {
+	sourceLabel := widget.NewLabel("")
+	sourceLabel.SizeName = theme.SizeNameCaptionText
+	sourceLabel.Importance = widget.LowImportance
+	resetButton := widget.NewButtonWithIcon("", theme.ContentUndoIcon(), nil)
+	resetButton.Importance = widget.LowImportance
+
+	update := func() {
+		if Settings.Layers == nil {
+			sourceLabel.Hide()
+			resetButton.Hide()
+			return
+		}
+		source := Settings.Layers.Source(s.SettingsInternalName)
+		sourceLabel.SetText(lang.L(source.String()))
+		if source >= Settings.LayerProfile {
+			resetButton.Enable()
+		} else {
+			resetButton.Disable()
+		}
+	}
+	resetButton.OnTapped = func() {
+		// drop pending changes, they would overwrite the reset value
+		timerLock.Lock()
+		if timer, exists := debounceTimers[s.SettingsInternalName]; exists {
+			timer.Stop()
+		}
+		timerLock.Unlock()
+
+		oldValue, _ := Settings.Config.GetOption(s.SettingsInternalName)
+		value, err := Settings.ResetOption(s.SettingsInternalName)
+		if err != nil {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		SettingsHistory.Record(s.SettingsInternalName, oldValue, value)
+		s.setWidgetValue(value, settingWidget)
 		sendMessage := SendMessageChannel.SendMessageStruct{
 			Type:  "setting_change",
 			Name:  s.SettingsInternalName,
 			Value: value,
 		}
 		sendMessage.SendMessage()
-		Settings.Config.SetOption(s.SettingsInternalName, value)
-		fmt.Println("sent message with value" + fmt.Sprintf("%v", value))
-	})
+		update()
+	}
+	s.onValueChanged = func() {
+		fyne.Do(update)
+	}
+	update()
+
+	return container.NewBorder(nil, nil, nil, container.NewHBox(sourceLabel, resetButton), settingWidget)
+}
+
+// translatedNameAndDescription returns the translated name and description of a setting.
+// Settings with an internal name are translated by "<internal name>.Name" and "<internal name>.Description".
+func (s *SettingMapping) translatedNameAndDescription() (string, string) {
+	settingsName := s.SettingsName
+	settingsDescription := s.SettingsDescription
+	if s.SettingsInternalName != "" {
+		settingsTranslateName := lang.L(s.SettingsInternalName + ".Name")
+		if settingsTranslateName != "" && settingsTranslateName != s.SettingsInternalName+".Name" {
+			settingsName = settingsTranslateName
+		}
+		settingsTranslateDescription := lang.L(s.SettingsInternalName + ".Description")
+		if settingsTranslateDescription != "" && settingsTranslateDescription != s.SettingsInternalName+".Description" {
+			settingsDescription = settingsTranslateDescription
+		}
+	} else {
+		settingsTranslateName := lang.L(settingsName)
+		if settingsTranslateName != "" && settingsTranslateName != settingsName {
+			settingsName = settingsTranslateName
+		}
+		settingsTranslateDescription := lang.L(settingsDescription)
+		if settingsTranslateDescription != "" && settingsTranslateDescription != settingsDescription {
+			settingsDescription = settingsTranslateDescription
+		}
+	}
+	return settingsName, settingsDescription
 }
 
 // processWidget processes the widgets of each setting
@@ -171,6 +349,25 @@ func (s *SettingMapping) processWidget(settingsValue interface{}, settingWidget
 		}
 		println("setting entry value to " + value)
 		settingWidget.(*widget.Entry).SetText(value)
+		if !s.DoNotSendToBackend {
+			// mark invalid input, invalid values are not sent
+			settingWidget.(*widget.Entry).Validator = func(value string) error {
+				switch originalType.(type) {
+				case float64:
+					if _, err := strconv.ParseFloat(value, 64); err != nil {
+						return err
+					}
+				case int:
+					if _, err := strconv.Atoi(value); err != nil {
+						return err
+					}
+				}
+				if _, err := Settings.ValidateOption(s.SettingsInternalName, value); err != nil && !errors.Is(err, Settings.ErrUnknownSetting) {
+					return err
+				}
+				return nil
+			}
+		}
 		onChange := settingWidget.(*widget.Entry).OnChanged
 		settingWidget.(*widget.Entry).OnChanged = func(value string) {
 			onChange(value)
@@ -178,10 +375,16 @@ func (s *SettingMapping) processWidget(settingsValue interface{}, settingWidget
 				// Convert value back to its original type
 				switch originalType.(type) {
 				case float64:
-					convertedValue, _ := strconv.ParseFloat(value, 64)
+					convertedValue, err := strconv.ParseFloat(value, 64)
+					if err != nil {
+						return
+					}
 					s.SendUpdatedValue(convertedValue)
 				case int:
-					convertedValue, _ := strconv.Atoi(value)
+					convertedValue, err := strconv.Atoi(value)
+					if err != nil {
+						return
+					}
 					s.SendUpdatedValue(convertedValue)
 				case string:
 					s.SendUpdatedValue(value)
@@ -209,27 +412,7 @@ func (s *SettingMapping) processWidget(settingsValue interface{}, settingWidget
 	}
 
 	// find translations for settings name and description
-	settingsName := s.SettingsName
-	SettingsDescription := s.SettingsDescription
-	if s.SettingsInternalName != "" {
-		settingsTranslateName := lang.L(s.SettingsInternalName + ".Name")
-		if settingsTranslateName != "" && settingsTranslateName != s.SettingsInternalName+".Name" {
-			settingsName = settingsTranslateName
-		}
-		settingsTranslateDescription := lang.L(s.SettingsInternalName + ".Description")
-		if settingsTranslateDescription != "" && settingsTranslateDescription != s.SettingsInternalName+".Description" {
-			SettingsDescription = settingsTranslateDescription
-		}
-	} else {
-		settingsTranslateName := lang.L(settingsName)
-		if settingsTranslateName != "" && settingsTranslateName != settingsName {
-			settingsName = settingsTranslateName
-		}
-		settingsTranslateDescription := lang.L(SettingsDescription)
-		if settingsTranslateDescription != "" && settingsTranslateDescription != SettingsDescription {
-			SettingsDescription = settingsTranslateDescription
-		}
-	}
+	settingsName, SettingsDescription := s.translatedNameAndDescription()
 
 	if _topMost && SettingsDescription != "" {
 		originalWidget := settingWidget.(fyne.CanvasObject)
@@ -252,6 +435,7 @@ func CreateSettingsFormByMapping(mappings SettingsMapping) *container.Scroll {
 	})
 
 	settingsForm := widget.NewForm()
+	settingsScroll := container.NewVScroll(settingsForm)
 
 	// initialize widgets
 	for _, mapping := range mappings.Mappings {
@@ -263,6 +447,7 @@ func CreateSettingsFormByMapping(mappings SettingsMapping) *container.Scroll {
 		singleMapping := mapping
 
 		if !mapping.DoNotSendToBackend {
+			processed := false
 			settingsFields := reflect.ValueOf(Settings.Config)
 			for i := 0; i < settingsFields.NumField(); i++ {
 				if settingsFields.Field(i).CanInterface() && strings.ToLower(settingsFields.Type().Field(i).Name) == singleMapping.SettingsInternalName {
@@ -274,31 +459,48 @@ func CreateSettingsFormByMapping(mappings SettingsMapping) *container.Scroll {
 					}
 					settingsValue := settingsFields.Field(i).Interface()
 					singleMapping.processWidget(settingsValue, singleMapping.Widget, true)
+					processed = true
 					break
 				}
 			}
+			if !processed && singleMapping.initialValue != nil {
+				singleMapping.processWidget(singleMapping.initialValue, singleMapping.Widget, true)
+			}
 		} else {
 			singleMapping.processWidget(nil, singleMapping.Widget, true)
 		}
 
+		if singleMapping.Widget != nil && !singleMapping.DoNotSendToBackend {
+			if _, known := Settings.SchemaForField(singleMapping.SettingsInternalName); known {
+				singleMapping.Widget = singleMapping.addSourceIndicator(singleMapping.Widget)
+			}
+		}
+
 		// add widget to form
 		if singleMapping.Widget != nil {
 			// find translations for settings name
-			settingsName := singleMapping.SettingsName
-			if singleMapping.SettingsInternalName != "" {
-				settingsTranslateName := lang.L(singleMapping.SettingsInternalName + ".Name")
-				if settingsTranslateName != "" && settingsTranslateName != singleMapping.SettingsInternalName+".Name" {
-					settingsName = settingsTranslateName
+			settingsName, _ := singleMapping.translatedNameAndDescription()
+			formItem := widget.NewFormItem(settingsName, singleMapping.Widget)
+			// mark settings with invalid values in the current profile
+			if !singleMapping.DoNotSendToBackend && singleMapping.SettingsInternalName != "" {
+				if validationError := Settings.Config.ValidateField(singleMapping.SettingsInternalName); validationError != nil {
+					formItem.HintText = lang.L("Invalid value") + ": " + validationError.Error()
 				}
-			} else {
-				settingsTranslateName := lang.L(settingsName)
-				if settingsTranslateName != "" && settingsTranslateName != settingsName {
-					settingsName = settingsTranslateName
+				singleMapping.onValidationError = func(err error) {
+					hintText := ""
+					if err != nil {
+						hintText = lang.L("Invalid value") + ": " + err.Error()
+					}
+					if formItem.HintText != hintText {
+						formItem.HintText = hintText
+						settingsForm.Refresh()
+					}
 				}
 			}
-			settingsForm.Append(settingsName, singleMapping.Widget)
+			settingsForm.AppendItem(formItem)
+			registerSettingLocation(singleMapping.searchKey(), singleMapping.Widget, settingsScroll)
 		}
 	}
 
-	return container.NewVScroll(settingsForm)
+	return settingsScroll
 }
@@ -0,0 +1,145 @@
+package Pages
+
+import (
+	"strings"
+	"time"
+	"whispering-tiger-ui/Pages/Advanced"
+	"whispering-tiger-ui/Pages/SettingsMappings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// SelectMainTab selects a tab of the main window by its title. Set when the main window is created.
+var SelectMainTab func(title string)
+
+// settingsSearchLayoutDelay waits for the selected tab to be laid out before scrolling to a setting.
+const settingsSearchLayoutDelay = 100 * time.Millisecond
+
+func searchResultDetails(entry SettingsMappings.SearchEntry) string {
+	var details []string
+	switch entry.Source {
+	case SettingsMappings.SourcePlugin:
+		details = append(details, lang.L("Plugin")+": "+entry.Page)
+	default:
+		details = append(details, lang.L(entry.Page))
+	}
+	if entry.InternalName != "" && entry.InternalName != entry.Name {
+		details = append(details, entry.InternalName)
+	}
+	if entry.Modified {
+		details = append(details, lang.L("Modified from default"))
+	}
+	if entry.RequiresRestart {
+		details = append(details, lang.L("Requires restart"))
+	}
+	return strings.Join(details, " · ")
+}
+
+// createSettingsSearch adds a search over all settings above the settings tabs.
+// Search results replace the tabs until the search is cleared.
+func createSettingsSearch(settingsFormTabs *container.AppTabs) fyne.CanvasObject {
+	var index []SettingsMappings.SearchEntry
+	var results []SettingsMappings.SearchEntry
+
+	searchEntry := widget.NewEntry()
+	searchEntry.SetPlaceHolder(lang.L("Search settings"))
+	modifiedCheck := widget.NewCheck(lang.L("Modified from default"), nil)
+	requiresRestartCheck := widget.NewCheck(lang.L("Requires restart"), nil)
+	resultCountLabel := widget.NewLabel("")
+
+	resultList := widget.NewList(
+		func() int {
+			return len(results)
+		},
+		func() fyne.CanvasObject {
+			nameLabel := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+			nameLabel.Truncation = fyne.TextTruncateEllipsis
+			detailsLabel := widget.NewLabel("")
+			detailsLabel.SizeName = theme.SizeNameCaptionText
+			detailsLabel.Importance = widget.LowImportance
+			detailsLabel.Truncation = fyne.TextTruncateEllipsis
+			return container.NewVBox(nameLabel, detailsLabel)
+		},
+		func(id widget.ListItemID, object fyne.CanvasObject) {
+			entry := results[id]
+			row := object.(*fyne.Container)
+			row.Objects[0].(*widget.Label).SetText(entry.Name)
+			row.Objects[1].(*widget.Label).SetText(searchResultDetails(entry))
+		},
+	)
+	resultsContent := container.NewBorder(resultCountLabel, nil, nil, nil, resultList)
+	resultsContent.Hide()
+
+	clearSearch := func() {
+		searchEntry.SetText("")
+		modifiedCheck.SetChecked(false)
+		requiresRestartCheck.SetChecked(false)
+	}
+
+	update := func() {
+		filter := SettingsMappings.SearchFilter{
+			Query:           searchEntry.Text,
+			Modified:        modifiedCheck.Checked,
+			RequiresRestart: requiresRestartCheck.Checked,
+		}
+		if filter.Empty() {
+			// rebuild the index with the next search, settings might have changed in the meantime
+			index = nil
+			results = nil
+			resultsContent.Hide()
+			settingsFormTabs.Show()
+			return
+		}
+		if index == nil {
+			index = SettingsMappings.BuildSearchIndex()
+		}
+		results = SettingsMappings.Search(index, filter)
+		resultCountLabel.SetText(lang.L("SettingsSearchResults", map[string]interface{}{"Count": len(results)}))
+		resultList.UnselectAll()
+		resultList.ScrollToTop()
+		resultList.Refresh()
+		settingsFormTabs.Hide()
+		resultsContent.Show()
+	}
+	searchEntry.OnChanged = func(string) {
+		update()
+	}
+	modifiedCheck.OnChanged = func(bool) {
+		update()
+	}
+	requiresRestartCheck.OnChanged = func(bool) {
+		update()
+	}
+
+	resultList.OnSelected = func(id widget.ListItemID) {
+		entry := results[id]
+		resultList.UnselectAll()
+		switch entry.Source {
+		case SettingsMappings.SourceSettingsPage:
+			clearSearch()
+			for _, item := range settingsFormTabs.Items {
+				if item.Text == lang.L(entry.Page) {
+					settingsFormTabs.Select(item)
+					break
+				}
+			}
+			go func() {
+				time.Sleep(settingsSearchLayoutDelay)
+				fyne.Do(func() {
+					SettingsMappings.ShowSetting(entry.Key)
+				})
+			}()
+		case SettingsMappings.SourcePlugin:
+			Advanced.OpenPluginSettingsWindow(entry.Page, nil)
+		case SettingsMappings.SourceAdvancedSettings:
+			ShowAdvancedSetting(entry.Key)
+		}
+	}
+
+	searchBar := container.NewBorder(nil, nil, widget.NewIcon(theme.SearchIcon()), container.NewHBox(modifiedCheck, requiresRestartCheck), searchEntry)
+	return container.NewBorder(searchBar, nil, nil, nil, container.NewStack(settingsFormTabs, resultsContent))
+}
@@ -0,0 +1,290 @@
+package Pages
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"whispering-tiger-ui/Fields"
+	"whispering-tiger-ui/Logging"
+	"whispering-tiger-ui/TextProcessing"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/getsentry/sentry-go"
+)
+
+// formatGlossaryTranslations converts a translations map into "code=term, code=term".
+func formatGlossaryTranslations(translations map[string]string) string {
+	var keys []string
+	for key := range translations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, key := range keys {
+		parts = append(parts, key+"="+translations[key])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// parseGlossaryTranslations parses "code=term, code=term" into a translations map.
+func parseGlossaryTranslations(text string) map[string]string {
+	translations := map[string]string{}
+	for _, part := range strings.Split(text, ",") {
+		key, value, found := strings.Cut(part, "=")
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		if !found || key == "" || value == "" {
+			continue
+		}
+		translations[key] = value
+	}
+	return translations
+}
+
+func CreateTextProcessingSettingsWindow() fyne.CanvasObject {
+	defer Logging.GoRoutineErrorHandler(func(scope *sentry.Scope) {
+		scope.SetTag("GoRoutine", "Pages\\TextProcessingSettings->CreateTextProcessingSettingsWindow")
+	})
+
+	conf := TextProcessing.GetConfig()
+
+	// preview pane
+	previewInput := widget.NewMultiLineEntry()
+	previewInput.SetPlaceHolder(lang.L("Enter text to test the rules..."))
+	previewInput.Wrapping = fyne.TextWrapWord
+	previewSourceLanguage := widget.NewEntry()
+	previewSourceLanguage.SetPlaceHolder(lang.L("Source language code"))
+	previewTargetLanguage := widget.NewEntry()
+	previewTargetLanguage.SetPlaceHolder(lang.L("Target language code"))
+	previewProtectedLabel := widget.NewLabel("")
+	previewProtectedLabel.Wrapping = fyne.TextWrapWord
+	previewResultLabel := widget.NewLabel("")
+	previewResultLabel.Wrapping = fyne.TextWrapWord
+	validationLabel := widget.NewLabel("")
+	validationLabel.Wrapping = fyne.TextWrapWord
+	validationLabel.Importance = widget.DangerImportance
+
+	updatePreview := func() {
+		protectedText, result, errs := TextProcessing.Preview(conf, previewInput.Text, previewSourceLanguage.Text, previewTargetLanguage.Text)
+		previewProtectedLabel.SetText(protectedText)
+		previewResultLabel.SetText(result)
+		var errorTexts []string
+		for _, err := range errs {
+			errorTexts = append(errorTexts, err.Error())
+		}
+		validationLabel.SetText(strings.Join(errorTexts, "\n"))
+	}
+	previewInput.OnChanged = func(string) { updatePreview() }
+	previewSourceLanguage.OnChanged = func(string) { updatePreview() }
+	previewTargetLanguage.OnChanged = func(string) { updatePreview() }
+
+	// general options
+	enabledCheck := widget.NewCheck(lang.L("Enable text processing"), func(b bool) {
+		conf.Enabled = b
+		updatePreview()
+	})
+	enabledCheck.Checked = conf.Enabled
+	applyTranscriptsCheck := widget.NewCheck(lang.L("Transcriptions"), func(b bool) { conf.ApplyTranscripts = b })
+	applyTranscriptsCheck.Checked = conf.ApplyTranscripts
+	applyTranslationsCheck := widget.NewCheck(lang.L("Translations"), func(b bool) { conf.ApplyTranslations = b })
+	applyTranslationsCheck.Checked = conf.ApplyTranslations
+	applyTtsCheck := widget.NewCheck(lang.L("Text-to-Speech"), func(b bool) { conf.ApplyTts = b })
+	applyTtsCheck.Checked = conf.ApplyTts
+	applyOscCheck := widget.NewCheck(lang.L("OSC"), func(b bool) { conf.ApplyOsc = b })
+	applyOscCheck.Checked = conf.ApplyOsc
+	glossaryProtectionCheck := widget.NewCheck(lang.L("Protect glossary terms from translation"), func(b bool) {
+		conf.GlossaryProtection = b
+		updatePreview()
+	})
+	glossaryProtectionCheck.Checked = conf.GlossaryProtection
+
+	// replace rules
+	rulesContainer := container.NewVBox()
+	var buildRuleRows func()
+	buildRuleRows = func() {
+		rulesContainer.RemoveAll()
+		for i := range conf.ReplaceRules {
+			index := i
+			rule := conf.ReplaceRules[index]
+			ruleEnabled := widget.NewCheck("", func(b bool) {
+				conf.ReplaceRules[index].Enabled = b
+				updatePreview()
+			})
+			ruleEnabled.Checked = rule.Enabled
+			findEntry := widget.NewEntry()
+			findEntry.SetPlaceHolder(lang.L("Find"))
+			findEntry.SetText(rule.Find)
+			findEntry.OnChanged = func(s string) {
+				conf.ReplaceRules[index].Find = s
+				updatePreview()
+			}
+			replaceEntry := widget.NewEntry()
+			replaceEntry.SetPlaceHolder(lang.L("Replace"))
+			replaceEntry.SetText(rule.Replace)
+			replaceEntry.OnChanged = func(s string) {
+				conf.ReplaceRules[index].Replace = s
+				updatePreview()
+			}
+			regexCheck := widget.NewCheck(lang.L("Regex"), func(b bool) {
+				conf.ReplaceRules[index].Regex = b
+				updatePreview()
+			})
+			regexCheck.Checked = rule.Regex
+			caseSensitiveCheck := widget.NewCheck(lang.L("Case sensitive"), func(b bool) {
+				conf.ReplaceRules[index].CaseSensitive = b
+				updatePreview()
+			})
+			caseSensitiveCheck.Checked = rule.CaseSensitive
+			moveUpButton := widget.NewButtonWithIcon("", theme.MoveUpIcon(), func() {
+				if index > 0 {
+					conf.ReplaceRules[index-1], conf.ReplaceRules[index] = conf.ReplaceRules[index], conf.ReplaceRules[index-1]
+					buildRuleRows()
+					updatePreview()
+				}
+			})
+			moveDownButton := widget.NewButtonWithIcon("", theme.MoveDownIcon(), func() {
+				if index < len(conf.ReplaceRules)-1 {
+					conf.ReplaceRules[index+1], conf.ReplaceRules[index] = conf.ReplaceRules[index], conf.ReplaceRules[index+1]
+					buildRuleRows()
+					updatePreview()
+				}
+			})
+			removeButton := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+				conf.ReplaceRules = append(conf.ReplaceRules[:index], conf.ReplaceRules[index+1:]...)
+				buildRuleRows()
+				updatePreview()
+			})
+			rulesContainer.Add(container.NewBorder(nil, nil,
+				container.NewHBox(widget.NewLabel(fmt.Sprintf("%d.", index+1)), ruleEnabled),
+				container.NewHBox(regexCheck, caseSensitiveCheck, moveUpButton, moveDownButton, removeButton),
+				container.NewGridWithColumns(2, findEntry, replaceEntry),
+			))
+		}
+		rulesContainer.Refresh()
+	}
+	buildRuleRows()
+	addRuleButton := widget.NewButtonWithIcon(lang.L("Add replace rule"), theme.ContentAddIcon(), func() {
+		conf.ReplaceRules = append(conf.ReplaceRules, TextProcessing.ReplaceRule{Enabled: true})
+		buildRuleRows()
+	})
+
+	// glossary
+	glossaryContainer := container.NewVBox()
+	var buildGlossaryRows func()
+	buildGlossaryRows = func() {
+		glossaryContainer.RemoveAll()
+		for i := range conf.Glossary {
+			index := i
+			entry := conf.Glossary[index]
+			termEntry := widget.NewEntry()
+			termEntry.SetPlaceHolder(lang.L("Term"))
+			termEntry.SetText(entry.Term)
+			termEntry.OnChanged = func(s string) {
+				conf.Glossary[index].Term = s
+				updatePreview()
+			}
+			languageEntry := widget.NewEntry()
+			languageEntry.SetPlaceHolder(lang.L("Source language code (empty = all)"))
+			languageEntry.SetText(entry.Language)
+			languageEntry.OnChanged = func(s string) {
+				conf.Glossary[index].Language = strings.TrimSpace(s)
+				updatePreview()
+			}
+			translationsEntry := widget.NewEntry()
+			translationsEntry.SetPlaceHolder(lang.L("Translations (code=term, code=term)"))
+			translationsEntry.SetText(formatGlossaryTranslations(entry.Translations))
+			translationsEntry.OnChanged = func(s string) {
+				conf.Glossary[index].Translations = parseGlossaryTranslations(s)
+				updatePreview()
+			}
+			removeButton := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+				conf.Glossary = append(conf.Glossary[:index], conf.Glossary[index+1:]...)
+				buildGlossaryRows()
+				updatePreview()
+			})
+			glossaryContainer.Add(container.NewBorder(nil, nil, nil, removeButton,
+				container.NewGridWithColumns(3, termEntry, languageEntry, translationsEntry),
+			))
+		}
+		glossaryContainer.Refresh()
+	}
+	buildGlossaryRows()
+	addGlossaryButton := widget.NewButtonWithIcon(lang.L("Add glossary term"), theme.ContentAddIcon(), func() {
+		conf.Glossary = append(conf.Glossary, TextProcessing.GlossaryEntry{})
+		buildGlossaryRows()
+	})
+
+	// profanity filter
+	profanityEntry := widget.NewMultiLineEntry()
+	profanityEntry.SetPlaceHolder(lang.L("One word per line"))
+	profanityEntry.SetText(strings.Join(conf.ProfanityWords, "\n"))
+	profanityEntry.SetMinRowsVisible(4)
+	profanityEntry.OnChanged = func(s string) {
+		conf.ProfanityWords = nil
+		for _, word := range strings.Split(s, "\n") {
+			word = strings.TrimSpace(word)
+			if word != "" {
+				conf.ProfanityWords = append(conf.ProfanityWords, word)
+			}
+		}
+		updatePreview()
+	}
+	profanityMaskEntry := widget.NewEntry()
+	profanityMaskEntry.SetText(conf.ProfanityMask)
+	profanityMaskEntry.OnChanged = func(s string) {
+		conf.ProfanityMask = s
+		updatePreview()
+	}
+
+	saveButton := widget.NewButtonWithIcon(lang.L("Save"), theme.DocumentSaveIcon(), func() {
+		if errs := TextProcessing.ValidationErrors(conf); len(errs) > 0 {
+			dialog.ShowError(errs[0], fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		TextProcessing.SetConfig(conf)
+		go Fields.SendBackendOptions(Fields.HoldBackendOptions("text_processing", TextProcessing.OutputBackendOptions()))
+		if err := TextProcessing.Save(); err != nil {
+			dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+			return
+		}
+		conf = TextProcessing.GetConfig()
+	})
+	saveButton.Importance = widget.HighImportance
+
+	// the backend translates transcripts by itself if text translation is enabled, the UI can not protect these
+	glossaryProtectionItem := widget.NewFormItem("", glossaryProtectionCheck)
+	glossaryProtectionItem.HintText = lang.L("Only applies to translations requested by the UI, not to transcripts the backend translates automatically.")
+	optionsForm := widget.NewForm(
+		widget.NewFormItem("", enabledCheck),
+		widget.NewFormItem(lang.L("Apply to"), container.NewHBox(applyTranscriptsCheck, applyTranslationsCheck, applyTtsCheck, applyOscCheck)),
+		glossaryProtectionItem,
+	)
+
+	previewForm := widget.NewForm(
+		widget.NewFormItem(lang.L("Test text"), previewInput),
+		widget.NewFormItem(lang.L("Languages"), container.NewGridWithColumns(2, previewSourceLanguage, previewTargetLanguage)),
+		widget.NewFormItem(lang.L("Text sent to translator"), previewProtectedLabel),
+		widget.NewFormItem(lang.L("Result"), previewResultLabel),
+	)
+
+	updatePreview()
+
+	return container.NewBorder(nil, container.NewHBox(layout.NewSpacer(), saveButton), nil, nil,
+		container.NewVScroll(container.NewVBox(
+			optionsForm,
+			widget.NewCard(lang.L("Replace rules"), lang.L("Rules are applied in order from top to bottom."), container.NewVBox(rulesContainer, container.NewHBox(addRuleButton))),
+			widget.NewCard(lang.L("Glossary"), lang.L("Glossary terms are not translated, or replaced by the term for the target language."), container.NewVBox(glossaryContainer, container.NewHBox(addGlossaryButton))),
+			widget.NewCard(lang.L("Profanity filter"), "", widget.NewForm(
+				widget.NewFormItem(lang.L("Words"), profanityEntry),
+				widget.NewFormItem(lang.L("Mask"), profanityMaskEntry),
+			)),
+			widget.NewCard(lang.L("Preview"), "", container.NewVBox(previewForm, validationLabel)),
+		)),
+	)
+}
@@ -17,13 +17,32 @@ import (
 	"whispering-tiger-ui/Resources"
 	"whispering-tiger-ui/SendMessageChannel"
 	"whispering-tiger-ui/Settings"
+	"whispering-tiger-ui/TranslationMemory"
 	"whispering-tiger-ui/Utilities"
 	"whispering-tiger-ui/Websocket/Messages"
 )
 
 var additionalTranslationWindow dialog.Dialog
+var translationMemoryWindow dialog.Dialog
 
-func CreateTextTranslateWindow() fyne.CanvasObject {
+// translateFromMemory shows a known translation instead of sending a translate request.
+// Returns false if the memory is disabled or has no entry.
+func translateFromMemory(text, fromLang, toLang string, sendToOutputs bool) bool {
+	if !fyne.CurrentApp().Preferences().BoolWithFallback("TranslationMemoryEnabled", true) {
+		return false
+	}
+	entry, found := TranslationMemory.Default.Lookup(text, fromLang, toLang, TranslationMemory.CurrentModel())
+	if !found {
+		return false
+	}
+	Fields.DataBindings.TranscriptionTranslationInputBinding.Set(entry.Translation)
+	if sendToOutputs {
+		go Fields.SendTextToEnabledOutputs(entry.Translation)
+	}
+	return true
+}
+
+func CreateTextTranslateWindow(window fyne.Window) fyne.CanvasObject {
 	defer Logging.GoRoutineErrorHandler(func(scope *sentry.Scope) {
 		scope.SetTag("GoRoutine", "Pages\\TextTranslate->CreateTextTranslateWindow")
 	})
@@ -89,6 +108,9 @@ func CreateTextTranslateWindow() fyne.CanvasObject {
 		}
 		toLang := Messages.InstalledLanguages.GetCodeByName(Fields.Field.TargetLanguageCombo.Text)
 		text, _ := Fields.DataBindings.TranscriptionInputBinding.Get()
+		if translateFromMemory(text, fromLang, toLang, false) {
+			return
+		}
 		//goland:noinspection GoSnakeCaseUsage
 		sendMessage := SendMessageChannel.SendMessageStruct{
 			Type: "translate_req",
@@ -117,6 +139,9 @@ func CreateTextTranslateWindow() fyne.CanvasObject {
 		}
 		toLang := Messages.InstalledLanguages.GetCodeByName(Fields.Field.TargetLanguageCombo.Text)
 		text, _ := Fields.DataBindings.TranscriptionInputBinding.Get()
+		if translateFromMemory(text, fromLang, toLang, true) {
+			return
+		}
 		//goland:noinspection GoSnakeCaseUsage
 		sendMessage := SendMessageChannel.SendMessageStruct{
 			Type: "translate_req",
@@ -139,6 +164,27 @@ func CreateTextTranslateWindow() fyne.CanvasObject {
 	translateButton := widget.NewButtonWithIcon(lang.L("Translate (and send)[CTRL+Enter]"), theme.ConfirmIcon(), translateFunction)
 	translateButton.Importance = widget.HighImportance
 
+	saveCorrectionButton := widget.NewButtonWithIcon(lang.L("Save correction"), theme.DocumentSaveIcon(), func() {
+		fromLang := Messages.InstalledLanguages.GetCodeByName(Fields.Field.SourceLanguageCombo.Text)
+		if fromLang == "" {
+			fromLang = "auto"
+		}
+		toLang := Messages.InstalledLanguages.GetCodeByName(Fields.Field.TargetLanguageCombo.Text)
+		text, _ := Fields.DataBindings.TranscriptionInputBinding.Get()
+		translation, _ := Fields.DataBindings.TranscriptionTranslationInputBinding.Get()
+		TranslationMemory.Default.Correct(text, translation, fromLang, toLang, TranslationMemory.CurrentModel())
+		if err := TranslationMemory.Default.Save(); err != nil {
+			dialog.ShowError(err, window)
+		}
+	})
+	batchJobsButton := widget.NewButtonWithIcon(lang.L("Batch Jobs"), theme.DocumentIcon(), ShowBatchJobsWindow)
+	translationMemoryButton := widget.NewButtonWithIcon("", theme.StorageIcon(), func() {
+		if translationMemoryWindow != nil {
+			translationMemoryWindow.Hide()
+		}
+		translationMemoryWindow = CreateTranslationMemoryWindow(window)
+	})
+
 	// quick options row
 	quickOptionsRow := container.New(
 		layout.NewVBoxLayout(),
@@ -147,10 +193,17 @@ func CreateTextTranslateWindow() fyne.CanvasObject {
 	)
 
 	translateButtonRow := container.NewHBox(container.NewBorder(nil, nil, quickOptionsRow, nil), layout.NewSpacer(),
+		batchJobsButton,
+		translationMemoryButton,
+		saveCorrectionButton,
 		translateOnlyButton,
 		translateButton,
 	)
 
+	multiTargetView := AdditionalTextTranslations.CreateMultiTargetView(func() string {
+		return Messages.InstalledLanguages.GetCodeByName(Fields.Field.TargetLanguageCombo.Text)
+	})
+
 	mainContent := container.NewBorder(
 		container.New(layout.NewVBoxLayout(),
 			languageRow,
@@ -159,7 +212,7 @@ func CreateTextTranslateWindow() fyne.CanvasObject {
 		nil, nil, nil,
 		container.NewVSplit(
 			transcriptionRow,
-			container.New(layout.NewVBoxLayout(), translateButtonRow),
+			container.NewBorder(translateButtonRow, nil, nil, nil, multiTargetView),
 		),
 	)
 
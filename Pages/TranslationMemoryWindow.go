@@ -0,0 +1,140 @@
+package Pages
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"whispering-tiger-ui/Logging"
+	"whispering-tiger-ui/TranslationMemory"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/getsentry/sentry-go"
+)
+
+func translationMemoryFileDialogSize(window fyne.Window) fyne.Size {
+	dialogSize := window.Canvas().Size()
+	dialogSize.Height = dialogSize.Height - 80
+	dialogSize.Width = dialogSize.Width - 80
+	return dialogSize
+}
+
+func CreateTranslationMemoryWindow(window fyne.Window) dialog.Dialog {
+	defer Logging.GoRoutineErrorHandler(func(scope *sentry.Scope) {
+		scope.SetTag("GoRoutine", "Pages\\TranslationMemoryWindow->CreateTranslationMemoryWindow")
+	})
+
+	entriesLabel := widget.NewLabel("")
+	updateEntriesLabel := func() {
+		entriesLabel.SetText(lang.L("Entries") + ": " + strconv.Itoa(TranslationMemory.Default.Len()))
+	}
+	updateEntriesLabel()
+
+	enabledCheck := widget.NewCheck(lang.L("Use translation memory"), func(b bool) {
+		fyne.CurrentApp().Preferences().SetBool("TranslationMemoryEnabled", b)
+	})
+	enabledCheck.Checked = fyne.CurrentApp().Preferences().BoolWithFallback("TranslationMemoryEnabled", true)
+
+	maxEntriesEntry := widget.NewEntry()
+	maxEntriesEntry.SetText(strconv.Itoa(TranslationMemory.Default.MaxEntries()))
+	maxEntriesEntry.Validator = func(s string) error {
+		_, err := strconv.Atoi(strings.TrimSpace(s))
+		return err
+	}
+	maxEntriesEntry.OnSubmitted = func(s string) {
+		maxEntries, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return
+		}
+		TranslationMemory.Default.SetMaxEntries(maxEntries)
+		if err := TranslationMemory.Default.Save(); err != nil {
+			dialog.ShowError(err, window)
+		}
+		updateEntriesLabel()
+	}
+
+	importButton := widget.NewButtonWithIcon(lang.L("Import TMX / CSV"), theme.FolderOpenIcon(), func() {
+		fileDialog := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil || reader == nil {
+				return
+			}
+			defer reader.Close()
+			var entries []TranslationMemory.Entry
+			if strings.EqualFold(reader.URI().Extension(), ".csv") {
+				entries, err = TranslationMemory.ImportCSV(reader)
+			} else {
+				entries, err = TranslationMemory.ImportTMX(reader)
+			}
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			added := TranslationMemory.Default.AddEntries(entries)
+			if err = TranslationMemory.Default.Save(); err != nil {
+				dialog.ShowError(err, window)
+			}
+			updateEntriesLabel()
+			dialog.ShowInformation(lang.L("Import finished"), lang.L("Imported entries", map[string]interface{}{"Count": added}), window)
+		}, window)
+		fileDialog.SetFilter(storage.NewExtensionFileFilter([]string{".tmx", ".csv"}))
+		fileDialog.Resize(translationMemoryFileDialogSize(window))
+		fileDialog.Show()
+	})
+
+	exportButton := widget.NewButtonWithIcon(lang.L("Export TMX / CSV"), theme.DocumentSaveIcon(), func() {
+		fileDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+			if err != nil || writer == nil {
+				return
+			}
+			defer writer.Close()
+			entries := TranslationMemory.Default.Entries()
+			if strings.EqualFold(filepath.Ext(writer.URI().Path()), ".csv") {
+				err = TranslationMemory.ExportCSV(writer, entries)
+			} else {
+				err = TranslationMemory.ExportTMX(writer, entries)
+			}
+			if err != nil {
+				dialog.ShowError(err, window)
+			}
+		}, window)
+		fileDialog.SetFileName("translation_memory.tmx")
+		fileDialog.SetFilter(storage.NewExtensionFileFilter([]string{".tmx", ".csv"}))
+		fileDialog.Resize(translationMemoryFileDialogSize(window))
+		fileDialog.Show()
+	})
+
+	clearButton := widget.NewButtonWithIcon(lang.L("Clear"), theme.DeleteIcon(), func() {
+		dialog.ShowConfirm(lang.L("Clear translation memory"), lang.L("Are you sure you want to remove all entries from the translation memory?"), func(b bool) {
+			if !b {
+				return
+			}
+			TranslationMemory.Default.Clear()
+			if err := TranslationMemory.Default.Save(); err != nil {
+				dialog.ShowError(err, window)
+			}
+			updateEntriesLabel()
+		}, window)
+	})
+	clearButton.Importance = widget.DangerImportance
+
+	content := container.NewVBox(
+		enabledCheck,
+		widget.NewForm(
+			widget.NewFormItem(lang.L("Maximum entries (0 = unlimited)"), maxEntriesEntry),
+		),
+		entriesLabel,
+		container.NewHBox(importButton, exportButton, clearButton),
+	)
+
+	memoryDialog := dialog.NewCustom(lang.L("Translation Memory"), lang.L("Close"), content, window)
+	memoryDialog.SetOnClosed(func() {
+		maxEntriesEntry.OnSubmitted(maxEntriesEntry.Text)
+	})
+	memoryDialog.Show()
+	return memoryDialog
+}
@@ -0,0 +1,193 @@
+package ProfileBundle
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// profileOnlyKeys are removed from exported profiles, since they only make sense for the local profile file.
+var profileOnlyKeys = []string{"settingsfilename", "process_id", "extends"}
+
+// ExportOptions control what is written to a profile bundle.
+type ExportOptions struct {
+	ProfileName string
+	AppVersion  string
+	// InstalledPlugins are used to record the version of the plugins enabled in the profile.
+	InstalledPlugins []Plugin
+	// IncludeVoices adds the voice files referenced by the profile to the bundle.
+	IncludeVoices bool
+	// BaseDir is used to resolve relative voice file paths. Defaults to the working directory.
+	BaseDir string
+}
+
+// Export writes a profile bundle. profileData are the resolved settings of the profile,
+// so the bundle does not depend on the base profile or parent profiles of the exporting installation.
+// Settings containing secrets are not exported.
+func Export(writer io.Writer, profileData []byte, options ExportOptions) (Manifest, error) {
+	manifest := Manifest{
+		FormatVersion: FormatVersion,
+		AppVersion:    options.AppVersion,
+		Created:       time.Now().UTC(),
+		ProfileName:   options.ProfileName,
+	}
+	document, profile, err := parseProfile(profileData)
+	if err != nil {
+		return manifest, err
+	}
+	for _, key := range profileOnlyKeys {
+		deleteMappingValue(profile, key)
+	}
+	if versionNode := mappingValue(profile, "schema_version"); versionNode != nil {
+		manifest.SchemaVersion, _ = strconv.Atoi(versionNode.Value)
+	}
+	manifest.StrippedSecrets = stripSecrets(profile)
+	manifest.Plugins = enabledPlugins(profile, options.InstalledPlugins)
+
+	zipWriter := zip.NewWriter(writer)
+	if options.IncludeVoices {
+		if manifest.Voices, err = writeVoices(zipWriter, document, options.BaseDir); err != nil {
+			return manifest, err
+		}
+	}
+
+	profileData, err = yaml.Marshal(document)
+	if err != nil {
+		return manifest, err
+	}
+	if err = writeZipFile(zipWriter, profileFileName, profileData); err != nil {
+		return manifest, err
+	}
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return manifest, err
+	}
+	if err = writeZipFile(zipWriter, manifestFileName, manifestData); err != nil {
+		return manifest, err
+	}
+	return manifest, zipWriter.Close()
+}
+
+// ExportFile writes a profile bundle to a file.
+func ExportFile(fileName string, profileData []byte, options ExportOptions) (Manifest, error) {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return Manifest{}, err
+	}
+	manifest, err := Export(file, profileData, options)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(fileName)
+	}
+	return manifest, err
+}
+
+func enabledPlugins(profile *yaml.Node, installedPlugins []Plugin) []Plugin {
+	var plugins []Plugin
+	pluginsNode := mappingValue(profile, "plugins")
+	if pluginsNode == nil || pluginsNode.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(pluginsNode.Content); i += 2 {
+		var enabled bool
+		if err := pluginsNode.Content[i+1].Decode(&enabled); err != nil || !enabled {
+			continue
+		}
+		plugin := Plugin{Class: pluginsNode.Content[i].Value}
+		for _, installedPlugin := range installedPlugins {
+			if installedPlugin.Class == plugin.Class {
+				plugin = installedPlugin
+				break
+			}
+		}
+		plugins = append(plugins, plugin)
+	}
+	return plugins
+}
+
+// writeVoices adds all existing audio files referenced by the profile to the bundle.
+func writeVoices(zipWriter *zip.Writer, document *yaml.Node, baseDir string) ([]Voice, error) {
+	var voices []Voice
+	var references []string
+	usedNames := map[string]bool{}
+	stringValues(document, func(node *yaml.Node) {
+		if isAudioFile(node.Value) {
+			references = append(references, node.Value)
+		}
+	})
+	for _, reference := range references {
+		if containsVoice(voices, reference) {
+			continue
+		}
+		fileName := reference
+		if !filepath.IsAbs(fileName) && baseDir != "" {
+			fileName = filepath.Join(baseDir, fileName)
+		}
+		if info, err := os.Stat(fileName); err != nil || info.IsDir() {
+			continue
+		}
+
+		name := filepath.Base(fileName)
+		extension := path.Ext(name)
+		for i := 2; usedNames[strings.ToLower(name)]; i++ {
+			name = strings.TrimSuffix(filepath.Base(fileName), extension) + "_" + strconv.Itoa(i) + extension
+		}
+		usedNames[strings.ToLower(name)] = true
+
+		voice := Voice{Reference: reference, File: voicesDir + name}
+		hash, err := copyFileToZip(zipWriter, voice.File, fileName)
+		if err != nil {
+			return nil, err
+		}
+		voice.SHA256 = hash
+		voices = append(voices, voice)
+	}
+	return voices, nil
+}
+
+func containsVoice(voices []Voice, reference string) bool {
+	for _, voice := range voices {
+		if voice.Reference == reference {
+			return true
+		}
+	}
+	return false
+}
+
+func writeZipFile(zipWriter *zip.Writer, name string, data []byte) error {
+	fileWriter, err := zipWriter.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = fileWriter.Write(data)
+	return err
+}
+
+func copyFileToZip(zipWriter *zip.Writer, name string, fileName string) (string, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	fileWriter, err := zipWriter.Create(name)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	if _, err = io.Copy(io.MultiWriter(fileWriter, hash), file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
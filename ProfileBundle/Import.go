@@ -0,0 +1,372 @@
+package ProfileBundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"whispering-tiger-ui/Migrations"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Bundle is an opened profile bundle.
+type Bundle struct {
+	Manifest Manifest
+	profile  []byte
+	files    map[string]*zip.File
+}
+
+// Device is an audio device available on this computer.
+type Device struct {
+	Name      string
+	Index     int
+	IsDefault bool
+}
+
+// ImportOptions control how a profile bundle is imported.
+type ImportOptions struct {
+	// ProfileName is the name of the imported profile, without file extension.
+	ProfileName string
+	// Overwrite replaces an existing profile with the same name.
+	Overwrite bool
+	// AudioAPI replaces the audio API of the profile if set, for example if the API of the bundle is not available on this platform.
+	AudioAPI string
+	// InputDevices and OutputDevices are the available devices of the audio API, used to remap the devices of the profile.
+	InputDevices  []Device
+	OutputDevices []Device
+	// InstalledPlugins are used to report missing plugins or plugins with another version.
+	InstalledPlugins []Plugin
+	// VoiceDir receives the voice files of the bundle, in a sub directory named after the profile.
+	VoiceDir   string
+	SkipVoices bool
+}
+
+// DeviceChange describes a setting that was changed to match the local audio devices.
+type DeviceChange struct {
+	Setting string
+	From    string
+	To      string
+}
+
+// ImportReport describes the changes made when importing a bundle and the conflicts with the local installation.
+type ImportReport struct {
+	ProfileFile     string
+	ProfileExists   bool
+	Migrated        bool
+	MigratedFrom    int
+	MissingPlugins  []string
+	ChangedPlugins  []string
+	DeviceChanges   []DeviceChange
+	StrippedSecrets []string
+	VoiceFiles      []string
+}
+
+// OpenFile opens a profile bundle and reads its manifest.
+func OpenFile(fileName string) (*Bundle, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return Open(bytes.NewReader(data), int64(len(data)))
+}
+
+// Open reads the manifest and profile of a profile bundle.
+func Open(reader io.ReaderAt, size int64) (*Bundle, error) {
+	zipReader, err := zip.NewReader(reader, size)
+	if err != nil {
+		return nil, err
+	}
+	bundle := &Bundle{files: map[string]*zip.File{}}
+	for _, file := range zipReader.File {
+		bundle.files[file.Name] = file
+	}
+	manifestData, err := bundle.readFile(manifestFileName)
+	if err != nil {
+		return nil, ErrNoManifest
+	}
+	if err = json.Unmarshal(manifestData, &bundle.Manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if bundle.Manifest.FormatVersion > FormatVersion {
+		return nil, fmt.Errorf("%w (format %d, supported up to %d)", ErrNewerFormat, bundle.Manifest.FormatVersion, FormatVersion)
+	}
+	if bundle.profile, err = bundle.readFile(profileFileName); err != nil {
+		return nil, fmt.Errorf("invalid profile bundle: %w", err)
+	}
+	return bundle, nil
+}
+
+func (b *Bundle) readFile(name string) ([]byte, error) {
+	file, ok := b.files[name]
+	if !ok {
+		return nil, fmt.Errorf("%s missing", name)
+	}
+	fileReader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer fileReader.Close()
+	return io.ReadAll(fileReader)
+}
+
+// ProfileValue returns a scalar setting of the bundled profile, or "" if it is not set.
+func (b *Bundle) ProfileValue(key string) string {
+	_, profile, err := parseProfile(b.profile)
+	if err != nil {
+		return ""
+	}
+	if node := mappingValue(profile, key); node != nil && node.Kind == yaml.ScalarNode {
+		return node.Value
+	}
+	return ""
+}
+
+// Check returns the report of an import without writing any files.
+func (b *Bundle) Check(profilesDir string, options ImportOptions) (ImportReport, error) {
+	report, _, err := b.prepare(profilesDir, options)
+	return report, err
+}
+
+// Import writes the profile of the bundle into the profiles directory and extracts its voice files.
+// Returns ErrProfileExists if the profile exists and Overwrite is not set.
+func (b *Bundle) Import(profilesDir string, options ImportOptions) (ImportReport, error) {
+	report, document, err := b.prepare(profilesDir, options)
+	if err != nil {
+		return report, err
+	}
+	if report.ProfileExists && !options.Overwrite {
+		return report, ErrProfileExists
+	}
+	if !options.SkipVoices {
+		for i, voice := range b.Manifest.Voices {
+			if err = b.extractVoice(voice, report.VoiceFiles[i]); err != nil {
+				return report, err
+			}
+		}
+	}
+	profileData, err := yaml.Marshal(document)
+	if err != nil {
+		return report, err
+	}
+	tmpFile := report.ProfileFile + ".tmp"
+	if err = os.WriteFile(tmpFile, profileData, 0644); err != nil {
+		return report, err
+	}
+	if err = os.Rename(tmpFile, report.ProfileFile); err != nil {
+		_ = os.Remove(tmpFile)
+		return report, err
+	}
+	return report, nil
+}
+
+func (b *Bundle) prepare(profilesDir string, options ImportOptions) (ImportReport, *yaml.Node, error) {
+	report := ImportReport{}
+	profileName := strings.TrimSpace(options.ProfileName)
+	if profileName == "" || profileName == "." || profileName == ".." || strings.ContainsAny(profileName, `/\:`) {
+		return report, nil, fmt.Errorf("%w: %q", ErrInvalidProfileName, options.ProfileName)
+	}
+	if !strings.HasSuffix(profileName, ".yaml") && !strings.HasSuffix(profileName, ".yml") {
+		profileName += ".yaml"
+	}
+	report.ProfileFile = filepath.Join(profilesDir, profileName)
+	if _, err := os.Stat(report.ProfileFile); err == nil {
+		report.ProfileExists = true
+	}
+
+	profileData, migrationResult, err := Migrations.MigrateData(b.profile)
+	if err != nil {
+		return report, nil, err
+	}
+	report.Migrated = migrationResult.Migrated()
+	report.MigratedFrom = migrationResult.FromVersion
+	document, profile, err := parseProfile(profileData)
+	if err != nil {
+		return report, nil, err
+	}
+	for _, key := range profileOnlyKeys {
+		deleteMappingValue(profile, key)
+	}
+
+	// secrets are stripped on export, bundles created by hand may still contain them
+	report.StrippedSecrets = append(append(report.StrippedSecrets, b.Manifest.StrippedSecrets...), stripSecrets(profile)...)
+
+	for _, plugin := range enabledPlugins(profile, nil) {
+		installedPlugin, installed := findPlugin(options.InstalledPlugins, plugin.Class)
+		bundlePlugin, _ := findPlugin(b.Manifest.Plugins, plugin.Class)
+		if !installed {
+			report.MissingPlugins = append(report.MissingPlugins, plugin.Class)
+		} else if bundlePlugin.Version != "" && installedPlugin.Version != bundlePlugin.Version {
+			report.ChangedPlugins = append(report.ChangedPlugins, fmt.Sprintf("%s (%s -> %s)", plugin.Class, bundlePlugin.Version, installedPlugin.Version))
+		}
+	}
+
+	if options.AudioAPI != "" {
+		if audioAPI := mappingValue(profile, "audio_api"); audioAPI == nil || !strings.EqualFold(audioAPI.Value, options.AudioAPI) {
+			from := ""
+			if audioAPI != nil {
+				from = audioAPI.Value
+			}
+			if err = setMappingValue(profile, "audio_api", options.AudioAPI); err != nil {
+				return report, nil, err
+			}
+			report.DeviceChanges = append(report.DeviceChanges, DeviceChange{Setting: "audio_api", From: from, To: options.AudioAPI})
+		}
+	}
+	for _, deviceSetting := range []struct {
+		nameKey, indexKey string
+		devices           []Device
+	}{
+		{"audio_input_device", "device_index", options.InputDevices},
+		{"audio_output_device", "device_out_index", options.OutputDevices},
+	} {
+		change, err := remapDevice(profile, deviceSetting.nameKey, deviceSetting.indexKey, deviceSetting.devices)
+		if err != nil {
+			return report, nil, err
+		}
+		if change != nil {
+			report.DeviceChanges = append(report.DeviceChanges, *change)
+		}
+	}
+
+	if !options.SkipVoices {
+		voiceDir := filepath.Join(options.VoiceDir, strings.TrimSuffix(profileName, filepath.Ext(profileName)))
+		usedNames := map[string]string{}
+		for _, voice := range b.Manifest.Voices {
+			name, err := voiceFileName(voice)
+			if err != nil {
+				return report, nil, err
+			}
+			// names are compared ignoring case, since the file system of the target may do so
+			if otherFile, used := usedNames[strings.ToLower(name)]; used {
+				return report, nil, fmt.Errorf("%w: %s and %s would be extracted to the same file", ErrInvalidVoiceFile, otherFile, voice.File)
+			}
+			usedNames[strings.ToLower(name)] = voice.File
+			targetFile := filepath.Join(voiceDir, name)
+			report.VoiceFiles = append(report.VoiceFiles, targetFile)
+			stringValues(document, func(node *yaml.Node) {
+				if node.Value == voice.Reference {
+					node.Value = targetFile
+				}
+			})
+		}
+	}
+	return report, document, nil
+}
+
+// voiceFileName returns the name a voice file of the bundle is extracted as.
+// Only files directly in the voices directory of the bundle are accepted.
+func voiceFileName(voice Voice) (string, error) {
+	name := strings.TrimPrefix(voice.File, voicesDir)
+	if !strings.HasPrefix(voice.File, voicesDir) || name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\:`) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidVoiceFile, voice.File)
+	}
+	return name, nil
+}
+
+func (b *Bundle) extractVoice(voice Voice, targetFile string) error {
+	file, ok := b.files[voice.File]
+	if !ok || !strings.HasPrefix(voice.File, voicesDir) {
+		return fmt.Errorf("voice file %s missing in bundle", voice.File)
+	}
+	if err := os.MkdirAll(filepath.Dir(targetFile), 0755); err != nil {
+		return err
+	}
+	fileReader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer fileReader.Close()
+	tmpFile := targetFile + ".tmp"
+	out, err := os.Create(tmpFile)
+	if err != nil {
+		return err
+	}
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(out, hash), fileReader)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && voice.SHA256 != "" && !strings.EqualFold(hex.EncodeToString(hash.Sum(nil)), voice.SHA256) {
+		err = fmt.Errorf("checksum mismatch of voice file %s", voice.File)
+	}
+	if err == nil {
+		err = os.Rename(tmpFile, targetFile)
+	}
+	if err != nil {
+		_ = os.Remove(tmpFile)
+	}
+	return err
+}
+
+func findPlugin(plugins []Plugin, class string) (Plugin, bool) {
+	for _, plugin := range plugins {
+		if plugin.Class == class {
+			return plugin, true
+		}
+	}
+	return Plugin{}, false
+}
+
+// remapDevice changes the device of the profile to the matching local device.
+// Devices are matched by name, ignoring case and the text added by some audio APIs. Falls back to the default device.
+// Returns nil if the device did not change by name.
+func remapDevice(profile *yaml.Node, nameKey, indexKey string, devices []Device) (*DeviceChange, error) {
+	if len(devices) == 0 {
+		return nil, nil
+	}
+	nameNode := mappingValue(profile, nameKey)
+	if nameNode == nil || nameNode.Value == "" {
+		return nil, nil
+	}
+	device, found := matchDevice(nameNode.Value, devices)
+	if !found {
+		return &DeviceChange{Setting: nameKey, From: nameNode.Value}, nil
+	}
+	if err := setMappingValue(profile, indexKey, device.Index); err != nil {
+		return nil, err
+	}
+	if device.Name == nameNode.Value {
+		return nil, nil
+	}
+	change := &DeviceChange{Setting: nameKey, From: nameNode.Value, To: device.Name}
+	if err := setMappingValue(profile, nameKey, device.Name); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+func matchDevice(name string, devices []Device) (Device, bool) {
+	for _, device := range devices {
+		if device.Name == name {
+			return device, true
+		}
+	}
+	lowerName := strings.ToLower(strings.TrimSpace(name))
+	for _, device := range devices {
+		if strings.ToLower(strings.TrimSpace(device.Name)) == lowerName {
+			return device, true
+		}
+	}
+	for _, device := range devices {
+		lowerDeviceName := strings.ToLower(strings.TrimSpace(device.Name))
+		if lowerDeviceName == "" {
+			continue
+		}
+		if strings.Contains(lowerDeviceName, lowerName) || strings.Contains(lowerName, lowerDeviceName) {
+			return device, true
+		}
+	}
+	for _, device := range devices {
+		if device.IsDefault {
+			return device, true
+		}
+	}
+	return Device{}, false
+}
@@ -0,0 +1,215 @@
+package ProfileBundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testBundle creates a bundle with the given manifest voices and files.
+func testBundle(t *testing.T, profile string, voices []Voice, files map[string]string) *Bundle {
+	t.Helper()
+	var buffer bytes.Buffer
+	zipWriter := zip.NewWriter(&buffer)
+	manifestData, _ := json.Marshal(Manifest{FormatVersion: FormatVersion, ProfileName: "test", Voices: voices})
+	files[manifestFileName] = string(manifestData)
+	files[profileFileName] = profile
+	for name, content := range files {
+		if err := writeZipFile(zipWriter, name, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	bundle, err := Open(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	return bundle
+}
+
+func TestImportVoiceFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		voices  []Voice
+		files   map[string]string
+		wantErr error
+		want    []string
+	}{
+		{name: "valid voice", voices: []Voice{{Reference: "C:/voices/a.wav", File: "voices/a.wav"}}, files: map[string]string{"voices/a.wav": "a"}, want: []string{"a.wav"}},
+		{name: "two voices", voices: []Voice{{Reference: "x/a.wav", File: "voices/a.wav"}, {Reference: "y/a.wav", File: "voices/a_2.wav"}}, files: map[string]string{"voices/a.wav": "a", "voices/a_2.wav": "b"}, want: []string{"a.wav", "a_2.wav"}},
+		{name: "parent directory", voices: []Voice{{Reference: "a.wav", File: "voices/.."}}, wantErr: ErrInvalidVoiceFile},
+		{name: "current directory", voices: []Voice{{Reference: "a.wav", File: "voices/."}}, wantErr: ErrInvalidVoiceFile},
+		{name: "empty name", voices: []Voice{{Reference: "a.wav", File: "voices/"}}, wantErr: ErrInvalidVoiceFile},
+		{name: "outside the voices directory", voices: []Voice{{Reference: "a.wav", File: "profile.yaml"}}, wantErr: ErrInvalidVoiceFile},
+		{name: "traversal", voices: []Voice{{Reference: "a.wav", File: "voices/../../a.wav"}}, wantErr: ErrInvalidVoiceFile},
+		{name: "sub directory", voices: []Voice{{Reference: "a.wav", File: "voices/sub/a.wav"}}, wantErr: ErrInvalidVoiceFile},
+		{name: "backslash", voices: []Voice{{Reference: "a.wav", File: `voices/..\a.wav`}}, wantErr: ErrInvalidVoiceFile},
+		{name: "drive", voices: []Voice{{Reference: "a.wav", File: "voices/C:a.wav"}}, wantErr: ErrInvalidVoiceFile},
+		{name: "same file twice", voices: []Voice{{Reference: "x/a.wav", File: "voices/a.wav"}, {Reference: "y/a.wav", File: "voices/a.wav"}}, files: map[string]string{"voices/a.wav": "a"}, wantErr: ErrInvalidVoiceFile},
+		{name: "names differing in case", voices: []Voice{{Reference: "x/a.wav", File: "voices/a.wav"}, {Reference: "y/A.wav", File: "voices/A.wav"}}, files: map[string]string{"voices/a.wav": "a", "voices/A.wav": "b"}, wantErr: ErrInvalidVoiceFile},
+		{name: "checksum mismatch", voices: []Voice{{Reference: "a.wav", File: "voices/a.wav", SHA256: "00"}}, files: map[string]string{"voices/a.wav": "a"}, wantErr: nil, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.files == nil {
+				tt.files = map[string]string{}
+			}
+			var references []string
+			for _, voice := range tt.voices {
+				references = append(references, "- "+voice.Reference)
+			}
+			bundle := testBundle(t, "tts_voices:\n"+strings.Join(references, "\n")+"\n", tt.voices, tt.files)
+			profilesDir := t.TempDir()
+			voiceDir := t.TempDir()
+			report, err := bundle.Import(profilesDir, ImportOptions{ProfileName: "imported", VoiceDir: voiceDir})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Import() error = %v, want %v", err, tt.wantErr)
+				}
+				if _, statErr := os.Stat(filepath.Join(profilesDir, "imported.yaml")); !os.IsNotExist(statErr) {
+					t.Errorf("profile was imported with invalid voice files")
+				}
+				return
+			}
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("Import() succeeded, want a checksum error")
+				}
+				entries, _ := os.ReadDir(filepath.Join(voiceDir, "imported"))
+				if len(entries) > 0 {
+					t.Errorf("voice file with wrong checksum was extracted")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Import() error = %v", err)
+			}
+			profileData, _ := os.ReadFile(report.ProfileFile)
+			for i, name := range tt.want {
+				targetFile := filepath.Join(voiceDir, "imported", name)
+				if report.VoiceFiles[i] != targetFile {
+					t.Errorf("VoiceFiles[%d] = %s, want %s", i, report.VoiceFiles[i], targetFile)
+				}
+				if _, err := os.Stat(targetFile); err != nil {
+					t.Errorf("voice file %s was not extracted", name)
+				}
+				if !strings.Contains(string(profileData), targetFile) {
+					t.Errorf("profile %q does not reference %s", profileData, targetFile)
+				}
+			}
+		})
+	}
+}
+
+func TestImportProfileName(t *testing.T) {
+	tests := []struct {
+		name        string
+		profileName string
+		existing    bool
+		overwrite   bool
+		wantErr     error
+		wantFile    string
+	}{
+		{name: "name without extension", profileName: "imported", wantFile: "imported.yaml"},
+		{name: "name with extension", profileName: "imported.yml", wantFile: "imported.yml"},
+		{name: "empty", profileName: " ", wantErr: ErrInvalidProfileName},
+		{name: "parent directory", profileName: "..", wantErr: ErrInvalidProfileName},
+		{name: "path", profileName: "../imported", wantErr: ErrInvalidProfileName},
+		{name: "windows path", profileName: `..\imported`, wantErr: ErrInvalidProfileName},
+		{name: "existing profile", profileName: "imported", existing: true, wantErr: ErrProfileExists},
+		{name: "overwrite existing profile", profileName: "imported", existing: true, overwrite: true, wantFile: "imported.yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bundle := testBundle(t, "osc_port: 9001\n", nil, map[string]string{})
+			profilesDir := t.TempDir()
+			if tt.existing {
+				if err := os.WriteFile(filepath.Join(profilesDir, "imported.yaml"), []byte("osc_port: 9000\n"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			report, err := bundle.Import(profilesDir, ImportOptions{ProfileName: tt.profileName, Overwrite: tt.overwrite})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Import() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || report.ProfileFile != filepath.Join(profilesDir, tt.wantFile) {
+				t.Fatalf("Import() = %s, %v, want %s", report.ProfileFile, err, tt.wantFile)
+			}
+			data, _ := os.ReadFile(report.ProfileFile)
+			if !strings.Contains(string(data), "osc_port: 9001") {
+				t.Errorf("imported profile = %q", data)
+			}
+		})
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	sourceDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(sourceDir, "voice.wav"), []byte("voice"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	profile := strings.Join([]string{
+		"settingsfilename: source.yaml",
+		"extends: parent.yaml",
+		"schema_version: 1",
+		"audio_api: WASAPI",
+		"audio_input_device: Microphone (USB Audio)",
+		"device_index: 7",
+		"tts_voice: voice.wav",
+		"plugin_settings:",
+		"  Plugin:",
+		"    api_key: abc",
+		"    volume: 5",
+		"plugins:",
+		"  Plugin: true",
+		"",
+	}, "\n")
+	var buffer bytes.Buffer
+	manifest, err := Export(&buffer, []byte(profile), ExportOptions{ProfileName: "source", IncludeVoices: true, BaseDir: sourceDir})
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if len(manifest.Voices) != 1 || len(manifest.StrippedSecrets) != 1 || len(manifest.Plugins) != 1 {
+		t.Fatalf("manifest = %+v", manifest)
+	}
+
+	bundle, err := Open(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	profilesDir := t.TempDir()
+	voiceDir := t.TempDir()
+	report, err := bundle.Import(profilesDir, ImportOptions{
+		ProfileName:  "imported",
+		VoiceDir:     voiceDir,
+		AudioAPI:     "MME",
+		InputDevices: []Device{{Name: "Speakers", Index: 1, IsDefault: true}, {Name: "microphone (usb audio)", Index: 3}},
+	})
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if len(report.MissingPlugins) != 1 || len(report.DeviceChanges) != 2 {
+		t.Errorf("report = %+v", report)
+	}
+	data, _ := os.ReadFile(report.ProfileFile)
+	for _, want := range []string{"audio_api: MME", "audio_input_device: microphone (usb audio)", "device_index: 3", "volume: 5", filepath.Join(voiceDir, "imported", "voice.wav")} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("imported profile %q does not contain %q", data, want)
+		}
+	}
+	for _, unwanted := range []string{"api_key", "settingsfilename", "extends"} {
+		if strings.Contains(string(data), unwanted) {
+			t.Errorf("imported profile %q contains %q", data, unwanted)
+		}
+	}
+}
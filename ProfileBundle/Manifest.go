@@ -0,0 +1,55 @@
+package ProfileBundle
+
+import (
+	"errors"
+	"time"
+)
+
+// FormatVersion is the version of the bundle format written by Export. Bundles with a newer version are rejected.
+const FormatVersion = 1
+
+const (
+	manifestFileName = "manifest.json"
+	profileFileName  = "profile.yaml"
+	voicesDir        = "voices/"
+)
+
+var (
+	ErrNoManifest         = errors.New("not a profile bundle (manifest.json missing)")
+	ErrNewerFormat        = errors.New("profile bundle was created by a newer version")
+	ErrProfileExists      = errors.New("a profile with this name already exists")
+	ErrInvalidProfileName = errors.New("invalid profile name")
+	ErrInvalidVoiceFile   = errors.New("invalid voice file in profile bundle")
+)
+
+// Manifest describes the content of a profile bundle.
+type Manifest struct {
+	FormatVersion int       `json:"format_version"`
+	AppVersion    string    `json:"app_version,omitempty"`
+	Created       time.Time `json:"created"`
+	ProfileName   string    `json:"profile_name"`
+	SchemaVersion int       `json:"schema_version"`
+
+	// Plugins are the plugins enabled in the profile. Plugin files are not part of the bundle.
+	Plugins []Plugin `json:"plugins,omitempty"`
+	// Voices are the voice files referenced by the profile that are included in the bundle.
+	Voices []Voice `json:"voices,omitempty"`
+	// StrippedSecrets are the settings that were removed because they contain secrets, like API keys.
+	StrippedSecrets []string `json:"stripped_secrets,omitempty"`
+}
+
+// Plugin is a plugin enabled in the profile.
+type Plugin struct {
+	Class   string `json:"class"`
+	Version string `json:"version,omitempty"`
+	SHA256  string `json:"sha256,omitempty"`
+}
+
+// Voice is a voice file included in the bundle.
+type Voice struct {
+	// Reference is the value in the profile that pointed to the file.
+	Reference string `json:"reference"`
+	// File is the path of the file inside the bundle.
+	File   string `json:"file"`
+	SHA256 string `json:"sha256"`
+}
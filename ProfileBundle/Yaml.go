@@ -0,0 +1,128 @@
+package ProfileBundle
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// secretKeyPattern matches setting names that hold secrets. Such settings are never exported or imported.
+var secretKeyPattern = regexp.MustCompile(`(?i)(api[_-]?key|secret|token|password|passwd|credential|auth)`)
+
+// secretSections are the profile entries that contain settings of plugins and TTS engines.
+var secretSections = []string{"plugin_settings", "special_settings"}
+
+// audioExtensions are file extensions of voice files referenced by a profile.
+var audioExtensions = []string{".wav", ".mp3", ".flac", ".ogg", ".opus", ".m4a"}
+
+func parseProfile(data []byte) (*yaml.Node, *yaml.Node, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, nil, err
+	}
+	if document.Kind == 0 {
+		document = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	if document.Kind != yaml.DocumentNode || len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return nil, nil, errors.New("profile is not a yaml mapping")
+	}
+	return &document, document.Content[0], nil
+}
+
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func setMappingValue(mapping *yaml.Node, key string, value interface{}) error {
+	valueNode := &yaml.Node{}
+	if err := valueNode.Encode(value); err != nil {
+		return err
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = valueNode
+			return nil
+		}
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, valueNode)
+	return nil
+}
+
+func deleteMappingValue(mapping *yaml.Node, key string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+// stripSecrets removes all settings with secret names from the plugin and TTS engine settings
+// and returns their paths, for example "plugin_settings.DeepLPlugin.api_key".
+func stripSecrets(profile *yaml.Node) []string {
+	var stripped []string
+	var strip func(node *yaml.Node, path string)
+	strip = func(node *yaml.Node, path string) {
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); {
+				key := node.Content[i].Value
+				if secretKeyPattern.MatchString(key) && node.Content[i+1].Kind == yaml.ScalarNode {
+					stripped = append(stripped, path+"."+key)
+					node.Content = append(node.Content[:i], node.Content[i+2:]...)
+					continue
+				}
+				strip(node.Content[i+1], path+"."+key)
+				i += 2
+			}
+		case yaml.SequenceNode:
+			for _, item := range node.Content {
+				strip(item, path)
+			}
+		}
+	}
+	for _, section := range secretSections {
+		if node := mappingValue(profile, section); node != nil {
+			strip(node, section)
+		}
+	}
+	return stripped
+}
+
+// stringValues calls fn for every string value of the profile.
+func stringValues(node *yaml.Node, fn func(node *yaml.Node)) {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, item := range node.Content {
+			stringValues(item, fn)
+		}
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			stringValues(node.Content[i], fn)
+		}
+	case yaml.ScalarNode:
+		if node.Tag == "!!str" || node.Tag == "" {
+			fn(node)
+		}
+	}
+}
+
+func isAudioFile(value string) bool {
+	lowerValue := strings.ToLower(value)
+	for _, extension := range audioExtensions {
+		if strings.HasSuffix(lowerValue, extension) {
+			return true
+		}
+	}
+	return false
+}
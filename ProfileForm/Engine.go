@@ -1,6 +1,7 @@
 package ProfileForm
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"strconv"
@@ -222,43 +223,55 @@ func (e *FormEngine) getOptionByLowercase(conf *Settings.Conf, key string) any {
 }
 
 // SaveToSettings iterates all registered bindings and writes control values back to settings
-func (e *FormEngine) SaveToSettings(conf *Settings.Conf) {
+// Values that do not match the settings schema are not written and returned as ValidationErrors.
+func (e *FormEngine) SaveToSettings(conf *Settings.Conf) error {
 	if e == nil || conf == nil {
-		return
+		return nil
+	}
+	var validationErrors Settings.ValidationErrors
+	setOption := func(key string, value interface{}) {
+		var validationError *Settings.ValidationError
+		if err := conf.SetOption(key, value); errors.As(err, &validationError) {
+			validationErrors = append(validationErrors, validationError)
+		}
 	}
 	for key, ctrl := range e.Bindings {
 		switch c := ctrl.(type) {
 		case *widget.Entry:
 			Settings.Config.SetOption(key, c.Text)
-			conf.SetOption(key, c.Text)
+			setOption(key, c.Text)
 		case *widget.Check:
-			conf.SetOption(key, c.Checked)
+			setOption(key, c.Checked)
 		case *widget.Slider:
 			// value is float64; Conf.SetOption converts where needed
-			conf.SetOption(key, c.Value)
+			setOption(key, c.Value)
 		case *CustomWidget.TextValueSelect:
 			sel := c.GetSelected()
 			if sel == nil {
-				conf.SetOption(key, "")
+				setOption(key, "")
 				continue
 			}
 			// handle audio devices indexes specially (store int and name)
 			if key == "device_index" || key == "device_out_index" {
 				if iv, err := strconv.Atoi(sel.Value); err == nil {
-					conf.SetOption(key, iv)
+					setOption(key, iv)
 				} else {
-					conf.SetOption(key, sel.Value)
+					setOption(key, sel.Value)
 				}
 				if key == "device_index" {
-					conf.SetOption("audio_input_device", sel.Text)
+					setOption("audio_input_device", sel.Text)
 				} else {
-					conf.SetOption("audio_output_device", sel.Text)
+					setOption("audio_output_device", sel.Text)
 				}
 			} else {
-				conf.SetOption(key, sel.Value)
+				setOption(key, sel.Value)
 			}
 		case *CustomWidget.HotKeyEntry:
-			conf.SetOption(key, c.Text)
+			setOption(key, c.Text)
 		}
 	}
+	if len(validationErrors) > 0 {
+		return validationErrors
+	}
+	return nil
 }
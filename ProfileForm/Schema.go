@@ -2,6 +2,7 @@ package ProfileForm
 
 import (
 	"whispering-tiger-ui/CustomWidget"
+	"whispering-tiger-ui/ModelRegistry"
 
 	"fyne.io/fyne/v2/lang"
 )
@@ -55,51 +56,48 @@ func GenericOcrPrecisionOptions() []TVO {
 	}
 }
 
-// Base type-option providers for initial selects
+// Base type-option providers for initial selects, the model types are defined in the model registry
 func STTTypeOptions() []TVO {
-	return []TVO{{Text: "Faster Whisper", Value: "faster_whisper"}, {Text: "Original Whisper", Value: "original_whisper"}, {Text: "Transformer Whisper", Value: "transformer_whisper"}, {Text: "Seamless M4T", Value: "seamless_m4t"}, {Text: "MMS", Value: "mms"}, {Text: "Speech T5 (English only)", Value: "speech_t5"}, {Text: "Wav2Vec Bert 2.0", Value: "wav2vec_bert"}, {Text: "NeMo Canary", Value: "nemo_canary"}, {Text: "Phi-4", Value: "phi4"}, {Text: "Voxtral", Value: "voxtral"}, {Text: lang.L("Disabled"), Value: ""}}
+	return modelTypeOptions(ModelRegistry.CategorySTT)
 }
 
 func TXTTypeOptions() []TVO {
-	return []TVO{{Text: "Faster NLLB200 (200 languages)", Value: "NLLB200_CT2"}, {Text: "Original NLLB200 (200 languages)", Value: "NLLB200"}, {Text: "M2M100 (100 languages)", Value: "M2M100"}, {Text: "Seamless M4T (101 languages)", Value: "seamless_m4t"}, {Text: "Phi-4 (23 languages)", Value: "phi4"}, {Text: "Voxtral (13 languages)", Value: "voxtral"}, {Text: lang.L("Disabled"), Value: ""}}
+	return modelTypeOptions(ModelRegistry.CategoryTextTranslator)
 }
 
 func TTSTypeOptions() []TVO {
-	//return []TVO{{Text: "Silero", Value: "silero"}, {Text: "F5/E2", Value: "f5_e2"}, {Text: "Zonos", Value: "zonos"}, {Text: "Kokoro", Value: "kokoro"}, {Text: "Orpheus", Value: "orpheus"}, {Text: "Parler", Value: "parler"}, {Text: lang.L("Disabled"), Value: ""}}
-	return []TVO{{Text: "Silero", Value: "silero"}, {Text: "F5/E2", Value: "f5_e2"}, {Text: "Zonos", Value: "zonos"}, {Text: "Kokoro", Value: "kokoro"}, {Text: "Orpheus", Value: "orpheus"}, {Text: "Chatterbox", Value: "chatterbox"}, {Text: lang.L("Disabled"), Value: ""}}
+	return modelTypeOptions(ModelRegistry.CategoryTTS)
 }
 
 func OcrTypeOptions() []TVO {
-	return []TVO{{Text: "Easy OCR", Value: "easyocr"}, {Text: "GOT OCR 2.0", Value: "got_ocr_20"}, {Text: "Phi-4", Value: "phi4"}, {Text: lang.L("Disabled"), Value: ""}}
+	return modelTypeOptions(ModelRegistry.CategoryOCR)
 }
 
-func STTModelOptions(modelType string) (options []TVO, defaultIndex int, enableSize bool) {
-	enableSize = true
-	switch modelType {
-	case "faster_whisper":
-		return []TVO{{Text: "Tiny", Value: "tiny"}, {Text: "Tiny (English only)", Value: "tiny.en"}, {Text: "Base", Value: "base"}, {Text: "Base (English only)", Value: "base.en"}, {Text: "Small", Value: "small"}, {Text: "Small (English only)", Value: "small.en"}, {Text: "Medium", Value: "medium"}, {Text: "Medium (English only)", Value: "medium.en"}, {Text: "Large V1", Value: "large-v1"}, {Text: "Large V2", Value: "large-v2"}, {Text: "Large V3", Value: "large-v3"}, {Text: "Large V3 Turbo", Value: "large-v3-turbo"}, {Text: "Medium Distilled (English)", Value: "medium-distilled.en"}, {Text: "Large V2 Distilled (English)", Value: "large-distilled-v2.en"}, {Text: "Large V3 Distilled (English)", Value: "large-distilled-v3.en"}, {Text: "Large V3.5 Distilled (English)", Value: "large-distilled-v3.5.en"}, {Text: "Crisper", Value: "crisper"}, {Text: "Small (European finetune)", Value: "small.eu"}, {Text: "Medium (European finetune)", Value: "medium.eu"}, {Text: "Small (German finetune)", Value: "small.de"}, {Text: "Medium (German finetune)", Value: "medium.de"}, {Text: "Large V2 (German finetune)", Value: "large-v2.de2"}, {Text: "Large V3 Distilled (German finetune)", Value: "large-distilled-v3.de"}, {Text: "Small (German-Swiss finetune)", Value: "small.de-swiss"}, {Text: "Medium (Mix-Japanese-v2 finetune)", Value: "medium.mix-jpv2"}, {Text: "Large V2 (Mix-Japanese finetune)", Value: "large-v2.mix-jp"}, {Text: "Small (Japanese finetune)", Value: "small.jp"}, {Text: "Medium (Japanese finetune)", Value: "medium.jp"}, {Text: "Large V2 (Japanese finetune)", Value: "large-v2.jp"}, {Text: "Medium (Korean finetune)", Value: "medium.ko"}, {Text: "Large V2 (Korean finetune)", Value: "large-v2.ko"}, {Text: "Small (Chinese finetune)", Value: "small.zh"}, {Text: "Medium (Chinese finetune)", Value: "medium.zh"}, {Text: "Large V2 (Chinese finetune)", Value: "large-v2.zh"}, {Text: "Custom (Place in '.cache/whisper/custom-ct2' directory)", Value: "custom"}}, 0, true
-	case "original_whisper":
-		return []TVO{{Text: "Tiny", Value: "tiny"}, {Text: "Tiny (English only)", Value: "tiny.en"}, {Text: "Base", Value: "base"}, {Text: "Base (English only)", Value: "base.en"}, {Text: "Small", Value: "small"}, {Text: "Small (English only)", Value: "small.en"}, {Text: "Medium", Value: "medium"}, {Text: "Medium (English only)", Value: "medium.en"}, {Text: "Large V1", Value: "large-v1"}, {Text: "Large V2", Value: "large-v2"}, {Text: "Large V3", Value: "large-v3"}, {Text: "Large V3 Turbo", Value: "large-v3-turbo"}, {Text: "Custom (Place in '.cache/whisper/custom' directory)", Value: "custom"}}, 0, true
-	case "transformer_whisper":
-		return []TVO{{Text: "Tiny", Value: "tiny"}, {Text: "Tiny (English only)", Value: "tiny.en"}, {Text: "Base", Value: "base"}, {Text: "Base (English only)", Value: "base.en"}, {Text: "Small", Value: "small"}, {Text: "Small (English only)", Value: "small.en"}, {Text: "Medium", Value: "medium"}, {Text: "Medium (English only)", Value: "medium.en"}, {Text: "Large V1", Value: "large-v1"}, {Text: "Large V2", Value: "large-v2"}, {Text: "Large V3", Value: "large-v3"}, {Text: "Large V3 Turbo", Value: "large-v3-turbo"}, {Text: "Custom (Place in '.cache/whisper-transformer/custom' directory)", Value: "custom"}}, 0, true
-	case "medusa_whisper":
-		return []TVO{{Text: "V1", Value: "v1"}}, 0, true
-	case "seamless_m4t":
-		return []TVO{{Text: "Medium", Value: "medium"}, {Text: "Large", Value: "large"}, {Text: "Large V2", Value: "large-v2"}}, 1, true
-	case "mms":
-		return []TVO{{Text: "1b-fl102 (102 languages)", Value: "mms-1b-fl102"}, {Text: "1b-l1107 (1107 languages)", Value: "mms-1b-l1107"}, {Text: "1b-all (1162 languages)", Value: "1b-all"}}, 1, true
-	case "nemo_canary":
-		return []TVO{{Text: "Nemo Canary 1b v2", Value: "canary-1b-v2"}, {Text: "Nemo Canary 1b", Value: "canary-1b"}, {Text: "Nemo Canary 180m flash", Value: "canary-180m-flash"}, {Text: "Nemo Canary 1b flash", Value: "canary-1b-flash"}, {Text: "Parakeet TDT 0.6B V2 (English)", Value: "parakeet-tdt-0_6b-v2"}, {Text: "Parakeet TDT 0.6B V3 (Multilingual)", Value: "parakeet-tdt-0_6b-v3"}}, 0, true
-	case "phi4":
-		// Phi-4 has a fixed model size; provide option for display but disable the selector in Coordinator
-		return []TVO{{Text: "Large", Value: "large"}}, 0, false
-	case "voxtral":
-		return []TVO{{Text: "Voxtral-Mini-3B-2507", Value: "Voxtral-Mini-3B-2507"}}, 0, true
-	case "speech_t5":
-		return nil, 0, false
-	default:
+// modelTypeOptions returns the model types of a registry category that are not hidden, followed by the option to disable the category.
+func modelTypeOptions(category string) []TVO {
+	var options []TVO
+	for _, modelType := range ModelRegistry.Current().ModelTypesOf(category) {
+		if !modelType.Hidden {
+			options = append(options, TVO{Text: modelType.Label, Value: modelType.Type})
+		}
+	}
+	return append(options, TVO{Text: lang.L("Disabled"), Value: ""})
+}
+
+// modelSizeOptions returns the sizes of a model type in the registry. Types without sizes disable the size selector.
+func modelSizeOptions(category, modelType string) (options []TVO, defaultIndex int, enableSize bool) {
+	registryType, ok := ModelRegistry.Current().ModelType(category, modelType)
+	if !ok || len(registryType.Sizes) == 0 {
 		return nil, 0, false
 	}
+	for _, size := range registryType.Sizes {
+		options = append(options, TVO{Text: size.Label, Value: size.Value})
+	}
+	return options, registryType.DefaultSize, !registryType.FixedSize
+}
+
+func STTModelOptions(modelType string) (options []TVO, defaultIndex int, enableSize bool) {
+	return modelSizeOptions(ModelRegistry.CategorySTT, modelType)
 }
 
 func STTPrecisionOptions(modelType string) (options []TVO, enablePrecision bool) {
@@ -124,21 +122,7 @@ func STTPrecisionOptions(modelType string) (options []TVO, enablePrecision bool)
 }
 
 func TXTSizeOptions(modelType string) (options []TVO, defaultIndex int, enableSize bool) {
-	enableSize = true
-	switch modelType {
-	case "M2M100":
-		return []TVO{{Text: "Small", Value: "small"}, {Text: "Large", Value: "large"}}, 0, true
-	case "NLLB200_CT2", "NLLB200":
-		return []TVO{{Text: "Small", Value: "small"}, {Text: "Medium", Value: "medium"}, {Text: "Large", Value: "large"}}, 0, true
-	case "seamless_m4t":
-		return []TVO{{Text: "Medium", Value: "medium"}, {Text: "Large", Value: "large"}, {Text: "Large V2", Value: "large-v2"}}, 0, true
-	case "phi4":
-		return []TVO{{Text: "Large", Value: "large"}}, 0, true
-	case "voxtral":
-		return []TVO{{Text: "Voxtral-Mini-3B-2507", Value: "Voxtral-Mini-3B-2507"}}, 0, true
-	default:
-		return nil, 0, false
-	}
+	return modelSizeOptions(ModelRegistry.CategoryTextTranslator, modelType)
 }
 
 func TXTPrecisionOptions(modelType string) (options []TVO, enablePrecision bool) {
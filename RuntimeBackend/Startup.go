@@ -0,0 +1,48 @@
+package RuntimeBackend
+
+import (
+	"errors"
+	"sync"
+)
+
+// OnStartupResult is called once for every started backend process, with started true when the UI connected to it
+// or false and the error if the process exited before. Used to offer a rollback if an updated backend does not start.
+var OnStartupResult func(started bool, err error)
+
+var errExitedBeforeConnect = errors.New("the backend exited before the UI could connect to it")
+
+var startupMu sync.Mutex
+var startupPending bool
+
+func beginStartup() {
+	startupMu.Lock()
+	defer startupMu.Unlock()
+	startupPending = true
+}
+
+// cancelStartup ignores the result of a start, for backends that are stopped on purpose.
+func cancelStartup() {
+	startupMu.Lock()
+	defer startupMu.Unlock()
+	startupPending = false
+}
+
+func finishStartup(started bool, err error) {
+	startupMu.Lock()
+	if !startupPending {
+		startupMu.Unlock()
+		return
+	}
+	startupPending = false
+	onStartupResult := OnStartupResult
+	startupMu.Unlock()
+
+	if onStartupResult != nil {
+		go onStartupResult(started, err)
+	}
+}
+
+// BackendConnected is called by the websocket client when it connected to the backend.
+func BackendConnected() {
+	finishStartup(true, nil)
+}
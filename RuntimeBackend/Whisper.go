@@ -161,6 +161,7 @@ func (c *WhisperProcessConfig) Stop() {
 	if !running || proc == nil || proc.Process == nil {
 		return
 	}
+	cancelStartup()
 
 	timeout := 6 * time.Second
 	println("Terminating process")
@@ -549,6 +550,7 @@ func (c *WhisperProcessConfig) Start() {
 			cmdArguments = append(cmdArguments, "--ui_download")
 		}
 
+		beginStartup()
 		if Utilities.FileExists("audioWhisper.py") {
 			cmdArguments = append([]string{"-u", "audioWhisper.py"}, cmdArguments...)
 			err = c.RunWithStreams("python", cmdArguments, tmpReader, c.WriterBackend, c.WriterBackend)
@@ -567,6 +569,11 @@ func (c *WhisperProcessConfig) Start() {
 		} else {
 			err = errors.New("could not start audioWhisper")
 		}
+		if err != nil {
+			finishStartup(false, err)
+		} else {
+			finishStartup(false, errExitedBeforeConnect)
+		}
 
 		if err != nil {
 			_, _ = c.WriterBackend.Write([]byte("Error: " + err.Error()))
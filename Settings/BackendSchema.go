@@ -0,0 +1,197 @@
+package Settings
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Types of backend options. Options of other types are not shown in the generated forms.
+const (
+	OptionTypeBool   = "bool"
+	OptionTypeInt    = "int"
+	OptionTypeFloat  = "float"
+	OptionTypeString = "string"
+)
+
+// OptionChoice is an allowed value of a backend option with the label shown for it.
+type OptionChoice struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+}
+
+// UnmarshalJSON accepts plain values as well as objects with value and label.
+func (c *OptionChoice) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if _, isObject := value.(map[string]interface{}); isObject {
+		type optionChoice OptionChoice
+		return json.Unmarshal(data, (*optionChoice)(c))
+	}
+	c.Value = optionString(value)
+	return nil
+}
+
+// BackendOption describes a setting of the backend, sent with the settings_schema message.
+type BackendOption struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Label       string `json:"label"`
+	Description string `json:"description"`
+	// Group is the settings page the option is shown on.
+	Group string `json:"group"`
+	// Min, Max and Step limit numeric values. nil means no limit.
+	Min     *float64       `json:"min"`
+	Max     *float64       `json:"max"`
+	Step    *float64       `json:"step"`
+	Choices []OptionChoice `json:"choices"`
+	// RestartRequired marks options the backend only reads at startup.
+	RestartRequired bool        `json:"restart_required"`
+	Default         interface{} `json:"default"`
+	// Value is the current value of options that are not part of the profile settings.
+	Value interface{} `json:"value"`
+}
+
+// DisplayLabel returns the label of the option, or its name if the backend sent no label.
+func (o BackendOption) DisplayLabel() string {
+	if o.Label != "" {
+		return o.Label
+	}
+	return o.Name
+}
+
+// ChoiceLabel returns the label of an allowed value, or the value itself if it has no label.
+func (c OptionChoice) ChoiceLabel() string {
+	if c.Label != "" {
+		return c.Label
+	}
+	return c.Value
+}
+
+// CurrentValue returns the value of the option in the loaded profile, the value sent by the backend or its default.
+// Numbers are converted to the type of the option.
+func (o BackendOption) CurrentValue() interface{} {
+	if _, known := SchemaForField(o.Name); known {
+		if value, err := Config.GetOption(o.Name); err == nil {
+			return value
+		}
+	}
+	value := o.Value
+	if value == nil {
+		value = o.Default
+	}
+	switch o.Type {
+	case OptionTypeInt:
+		if floatValue, ok := value.(float64); ok {
+			return int(floatValue)
+		}
+		if value == nil {
+			return 0
+		}
+	case OptionTypeFloat:
+		if intValue, ok := value.(int); ok {
+			return float64(intValue)
+		}
+		if value == nil {
+			return 0.0
+		}
+	case OptionTypeBool:
+		if value == nil {
+			return false
+		}
+	case OptionTypeString:
+		if value == nil {
+			return ""
+		}
+		return optionString(value)
+	}
+	return value
+}
+
+func optionString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	data, _ := json.Marshal(value)
+	return strings.Trim(string(data), `"`)
+}
+
+// ParseBackendSchema reads the data of a settings_schema message.
+// The options are either a list or an object with the option names as keys.
+func ParseBackendSchema(data []byte) ([]BackendOption, error) {
+	var options []BackendOption
+	if err := json.Unmarshal(data, &options); err == nil {
+		return options, nil
+	}
+	var optionMap map[string]BackendOption
+	if err := json.Unmarshal(data, &optionMap); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(optionMap))
+	for name := range optionMap {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		option := optionMap[name]
+		if option.Name == "" {
+			option.Name = name
+		}
+		options = append(options, option)
+	}
+	return options, nil
+}
+
+var backendSchemaLock sync.RWMutex
+var backendSchema []BackendOption
+
+// SetBackendSchema replaces the options received from the backend. Options without name are dropped.
+func SetBackendSchema(options []BackendOption) {
+	var validOptions []BackendOption
+	for _, option := range options {
+		option.Name = strings.ToLower(strings.TrimSpace(option.Name))
+		if option.Name == "" {
+			continue
+		}
+		option.Type = strings.ToLower(option.Type)
+		validOptions = append(validOptions, option)
+	}
+	backendSchemaLock.Lock()
+	defer backendSchemaLock.Unlock()
+	backendSchema = validOptions
+}
+
+// BackendSchema returns the options received from the backend, in the order they were sent.
+func BackendSchema() []BackendOption {
+	backendSchemaLock.RLock()
+	defer backendSchemaLock.RUnlock()
+	return append([]BackendOption{}, backendSchema...)
+}
+
+// BackendOptionForField returns the backend option of a setting by name (case-insensitive).
+func BackendOptionForField(name string) (BackendOption, bool) {
+	name = strings.ToLower(name)
+	backendSchemaLock.RLock()
+	defer backendSchemaLock.RUnlock()
+	for _, option := range backendSchema {
+		if option.Name == name {
+			return option, true
+		}
+	}
+	return BackendOption{}, false
+}
+
+// RequiresRestart returns true if changing a setting only takes effect after restarting the backend.
+func RequiresRestart(name string) bool {
+	if fieldSchema, known := SchemaForField(name); known && fieldSchema.RequiresRestart {
+		return true
+	}
+	option, known := BackendOptionForField(name)
+	return known && option.RestartRequired
+}
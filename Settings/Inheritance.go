@@ -0,0 +1,152 @@
+package Settings
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	extendsKey         = "extends"
+	resolvedProfileDir = "ResolvedProfiles"
+)
+
+var (
+	ErrProfileCycle   = errors.New("profile inheritance cycle")
+	ErrParentNotFound = errors.New("parent profile not found")
+)
+
+// GetResolvedProfileFile returns the file with the resolved settings of a profile.
+// The backend reads and writes its settings file, so it gets all values instead of the settings stored in the profile.
+func GetResolvedProfileFile(profileFileName string) string {
+	return filepath.Join(GetUiDataDir(), resolvedProfileDir, filepath.Base(profileFileName))
+}
+
+// GetBackendSettingsFile returns the settings file used by the backend for the loaded profile.
+func GetBackendSettingsFile() string {
+	if Layers != nil && Layers.profileFile != "" {
+		return Layers.BackendSettingsFile()
+	}
+	return filepath.Join(GetConfProfileDir(), Config.SettingsFilename)
+}
+
+// ReadExtends returns the name of the parent profile of a profile file, or "" if it does not extend another profile.
+func ReadExtends(fileName string) (string, error) {
+	yamlFile, err := os.ReadFile(fileName)
+	if err != nil {
+		return "", err
+	}
+	var document yaml.Node
+	if err = yaml.Unmarshal(yamlFile, &document); err != nil {
+		return "", err
+	}
+	if document.Kind != yaml.DocumentNode || len(document.Content) == 0 {
+		return "", nil
+	}
+	mapping := document.Content[0]
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == extendsKey {
+			return strings.TrimSpace(mapping.Content[i+1].Value), nil
+		}
+	}
+	return "", nil
+}
+
+// findProfileFile returns the file name of a profile in the profiles directory. The file extension is optional.
+func findProfileFile(profilesDir, name string) (string, bool) {
+	candidates := []string{name}
+	if !strings.HasSuffix(name, ".yaml") && !strings.HasSuffix(name, ".yml") {
+		candidates = []string{name + ".yaml", name + ".yml"}
+	}
+	for _, candidate := range candidates {
+		if FileExists(filepath.Join(profilesDir, candidate)) {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// ProfileChain returns the file names of a profile and all its parents, starting with the topmost parent.
+// Returns ErrProfileCycle if a profile extends itself over any number of levels
+// and ErrParentNotFound if a parent profile does not exist.
+func ProfileChain(profilesDir, profileFileName string) ([]string, error) {
+	chain := []string{profileFileName}
+	visited := map[string]bool{strings.ToLower(profileFileName): true}
+	current := profileFileName
+	for {
+		parent, err := ReadExtends(filepath.Join(profilesDir, current))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", current, err)
+		}
+		if parent == "" {
+			break
+		}
+		parentFile, ok := findProfileFile(profilesDir, parent)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s (extended by %s)", ErrParentNotFound, parent, current)
+		}
+		if visited[strings.ToLower(parentFile)] {
+			path := make([]string, 0, len(chain)+1)
+			for i := len(chain) - 1; i >= 0; i-- {
+				path = append(path, chain[i])
+			}
+			return nil, fmt.Errorf("%w: %s", ErrProfileCycle, strings.Join(append(path, parentFile), " -> "))
+		}
+		visited[strings.ToLower(parentFile)] = true
+		chain = append([]string{parentFile}, chain...)
+		current = parentFile
+	}
+	return chain, nil
+}
+
+// ProfileEntry is a profile in the profile hierarchy.
+type ProfileEntry struct {
+	File   string
+	Parent string
+	Depth  int
+	// Err is set if the parents of the profile can not be resolved. Such profiles are listed at the top level.
+	Err error
+}
+
+// ProfileHierarchy orders profile files so that every profile is followed by the profiles extending it.
+// Profiles on the same level keep the order of files.
+func ProfileHierarchy(profilesDir string, files []string) []ProfileEntry {
+	entries := map[string]*ProfileEntry{}
+	children := map[string][]string{}
+	var roots []string
+	for _, file := range files {
+		entry := &ProfileEntry{File: file}
+		entries[file] = entry
+		chain, err := ProfileChain(profilesDir, file)
+		if err != nil {
+			entry.Err = err
+		} else if len(chain) > 1 {
+			entry.Parent = chain[len(chain)-2]
+		}
+	}
+	for _, file := range files {
+		entry := entries[file]
+		if _, listed := entries[entry.Parent]; entry.Parent == "" || !listed {
+			roots = append(roots, file)
+			continue
+		}
+		children[entry.Parent] = append(children[entry.Parent], file)
+	}
+
+	hierarchy := make([]ProfileEntry, 0, len(files))
+	var addEntries func(files []string, depth int)
+	addEntries = func(files []string, depth int) {
+		for _, file := range files {
+			entry := entries[file]
+			entry.Depth = depth
+			hierarchy = append(hierarchy, *entry)
+			addEntries(children[file], depth+1)
+		}
+	}
+	addEntries(roots, 0)
+	return hierarchy
+}
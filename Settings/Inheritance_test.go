@@ -0,0 +1,66 @@
+package Settings
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestProfileChain(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		profile string
+		want    []string
+		wantErr error
+	}{
+		{name: "no parent", files: map[string]string{"a.yaml": "osc_port: 9000\n"}, profile: "a.yaml", want: []string{"a.yaml"}},
+		{name: "parent without extension", files: map[string]string{"a.yaml": "extends: b\n", "b.yaml": ""}, profile: "a.yaml", want: []string{"b.yaml", "a.yaml"}},
+		{name: "two levels", files: map[string]string{"a.yaml": "extends: b.yaml\n", "b.yaml": "extends: c.yml\n", "c.yml": ""}, profile: "a.yaml", want: []string{"c.yml", "b.yaml", "a.yaml"}},
+		{name: "missing parent", files: map[string]string{"a.yaml": "extends: b.yaml\n"}, profile: "a.yaml", wantErr: ErrParentNotFound},
+		{name: "extends itself", files: map[string]string{"a.yaml": "extends: a.yaml\n"}, profile: "a.yaml", wantErr: ErrProfileCycle},
+		{name: "cycle over two levels", files: map[string]string{"a.yaml": "extends: b.yaml\n", "b.yaml": "extends: c.yaml\n", "c.yaml": "extends: a.yaml\n"}, profile: "a.yaml", wantErr: ErrProfileCycle},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTestFiles(t, dir, tt.files)
+			chain, err := ProfileChain(dir, tt.profile)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("ProfileChain() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(chain, tt.want) {
+				t.Errorf("ProfileChain() = %v, %v, want %v", chain, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestProfileHierarchy(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"child.yaml":  "extends: root.yaml\n",
+		"other.yaml":  "",
+		"root.yaml":   "",
+		"grand.yaml":  "extends: child.yaml\n",
+		"broken.yaml": "extends: missing.yaml\n",
+	})
+	hierarchy := ProfileHierarchy(dir, []string{"broken.yaml", "child.yaml", "grand.yaml", "other.yaml", "root.yaml"})
+	var got []string
+	var depths []int
+	for _, entry := range hierarchy {
+		got = append(got, entry.File)
+		depths = append(depths, entry.Depth)
+	}
+	want := []string{"broken.yaml", "other.yaml", "root.yaml", "child.yaml", "grand.yaml"}
+	wantDepths := []int{0, 0, 0, 1, 2}
+	if !reflect.DeepEqual(got, want) || !reflect.DeepEqual(depths, wantDepths) {
+		t.Errorf("ProfileHierarchy() = %v %v, want %v %v", got, depths, want, wantDepths)
+	}
+	if hierarchy[0].Err == nil {
+		t.Errorf("profile with missing parent has no error")
+	}
+}
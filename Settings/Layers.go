@@ -0,0 +1,745 @@
+package Settings
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"whispering-tiger-ui/Migrations"
+	"whispering-tiger-ui/Utilities"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Layer is a source of setting values. Higher layers override lower ones.
+type Layer int
+
+const (
+	// LayerDefault are the built-in defaults (or the selected preset).
+	LayerDefault Layer = iota
+	// LayerBase is the base profile shared by all profiles.
+	LayerBase
+	// LayerParent are the profiles the profile extends. Closer parents override the ones further up.
+	LayerParent
+	// LayerProfile is the profile file.
+	LayerProfile
+	// LayerRuntime are the changes made while the profile is loaded.
+	LayerRuntime
+
+	layerCount
+)
+
+func (l Layer) String() string {
+	switch l {
+	case LayerDefault:
+		return "Default"
+	case LayerBase:
+		return "Base Profile"
+	case LayerParent:
+		return "Parent Profile"
+	case LayerProfile:
+		return "Profile"
+	case LayerRuntime:
+		return "Changed"
+	}
+	return fmt.Sprintf("Layer %d", int(l))
+}
+
+const baseProfileFileName = "base_profile.yaml"
+
+// GetBaseProfileFile returns the file of the base profile. It is not stored with the profiles, so it is not listed as profile.
+func GetBaseProfileFile() string {
+	return filepath.Join(GetUiDataDir(), baseProfileFileName)
+}
+
+// LayeredConfig resolves settings from the built-in defaults, the base profile, the parent profiles, the profile file and runtime changes.
+//
+// A layer only sets the settings it contains, the keys of a profile file are the settings set explicitly in that profile.
+// All other settings are inherited, even if their value equals the inherited one.
+// The backend gets the resolved settings of all layers in a separate file (see GetResolvedProfileFile).
+type LayeredConfig struct {
+	mutex  sync.Mutex
+	values [layerCount]map[string]interface{}
+
+	profileFile string
+	// extends is the file name of the parent profile, "" if the profile does not extend another profile.
+	extends string
+	// parentFiles maps the settings of the parent layer to the parent profile file they come from.
+	parentFiles map[string]string
+
+	// persistTimer writes the setting changes of the backend to the profile file, see PersistSettingChange.
+	persistMutex sync.Mutex
+	persistTimer *time.Timer
+}
+
+// persistDebounce is the time setting changes are collected before the profile file is written.
+const persistDebounce = 1 * time.Second
+
+// ownWrites are the checksums of the settings files as the application wrote them last.
+// The profile watcher does not report changes of files that still have this content.
+var ownWrites = struct {
+	sync.Mutex
+	checksums map[string][sha256.Size]byte
+}{checksums: map[string][sha256.Size]byte{}}
+
+func ownWriteKey(fileName string) string {
+	return strings.ToLower(filepath.Clean(fileName))
+}
+
+// writeOwnFile writes a settings file and records its content as written by the application.
+func writeOwnFile(fileName string, data []byte) error {
+	if err := os.WriteFile(fileName, data, 0644); err != nil {
+		return err
+	}
+	ownWrites.Lock()
+	ownWrites.checksums[ownWriteKey(fileName)] = sha256.Sum256(data)
+	ownWrites.Unlock()
+	return nil
+}
+
+// recordOwnWrite records the content of a settings file written by the application without writeOwnFile.
+func recordOwnWrite(fileName string) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return
+	}
+	ownWrites.Lock()
+	ownWrites.checksums[ownWriteKey(fileName)] = sha256.Sum256(data)
+	ownWrites.Unlock()
+}
+
+// isOwnWrite returns true if a settings file has the content the application wrote last.
+func isOwnWrite(fileName string) bool {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return false
+	}
+	ownWrites.Lock()
+	defer ownWrites.Unlock()
+	checksum, ok := ownWrites.checksums[ownWriteKey(fileName)]
+	return ok && checksum == sha256.Sum256(data)
+}
+
+// Layers of the currently loaded profile. nil until a profile is loaded.
+var Layers *LayeredConfig
+
+func NewLayeredConfig(defaults Conf) *LayeredConfig {
+	l := &LayeredConfig{parentFiles: map[string]string{}}
+	for layer := range l.values {
+		l.values[layer] = map[string]interface{}{}
+	}
+	l.values[LayerDefault] = confValues(defaults)
+	return l
+}
+
+// LoadProfileLayers creates the layers of a profile. Missing base profile or profile files are skipped.
+// The preset only applies to new profiles: its values that differ from the built-in defaults are set in the profile layer.
+// Invalid entries are skipped and returned as error after loading the valid ones (see LoadYamlSettings).
+// If the parents of the profile can not be resolved, the profile is loaded without them and the error is returned.
+func LoadProfileLayers(preset Conf, profileFile string) (*LayeredConfig, error) {
+	l := NewLayeredConfig(DefaultConf)
+	l.profileFile = profileFile
+	if profileFile == "" || !FileExists(profileFile) {
+		defaults := l.values[LayerDefault]
+		for name, value := range confValues(preset) {
+			if !reflect.DeepEqual(value, defaults[name]) {
+				l.values[LayerProfile][name] = value
+			}
+		}
+	}
+	var errs []error
+	for layer, fileName := range map[Layer]string{LayerBase: GetBaseProfileFile(), LayerProfile: profileFile} {
+		if fileName == "" || !FileExists(fileName) {
+			continue
+		}
+		if err := l.LoadLayerFile(layer, fileName); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if profileFile != "" && FileExists(profileFile) {
+		parent, err := ReadExtends(profileFile)
+		if err == nil && parent != "" {
+			err = l.SetExtends(parent)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return l, errors.Join(errs...)
+}
+
+// Extends returns the file name of the parent profile, or "" if the profile does not extend another profile.
+func (l *LayeredConfig) Extends() string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.extends
+}
+
+// SetExtends changes the parent profile and reloads the parent layer. An empty parent removes the inheritance.
+// The parent is not changed if it can not be resolved or would extend the profile itself.
+func (l *LayeredConfig) SetExtends(parent string) error {
+	parentValues := map[string]interface{}{}
+	parentFiles := map[string]string{}
+	parentFile := ""
+	if parent != "" {
+		profilesDir := filepath.Dir(l.profileFile)
+		var ok bool
+		if parentFile, ok = findProfileFile(profilesDir, parent); !ok {
+			return fmt.Errorf("%w: %s", ErrParentNotFound, parent)
+		}
+		chain, err := ProfileChain(profilesDir, parentFile)
+		if err != nil {
+			return err
+		}
+		for _, fileName := range chain {
+			if strings.EqualFold(fileName, filepath.Base(l.profileFile)) {
+				return fmt.Errorf("%w: %s -> %s", ErrProfileCycle, filepath.Base(l.profileFile), strings.Join(chain, " -> "))
+			}
+		}
+		var errs []error
+		for _, fileName := range chain {
+			values, err := readLayerValues(filepath.Join(profilesDir, fileName))
+			if err != nil {
+				errs = append(errs, err)
+			}
+			for name, value := range values {
+				parentValues[name] = value
+				parentFiles[name] = fileName
+			}
+		}
+		if err := errors.Join(errs...); err != nil {
+			return err
+		}
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if parentFile == "" && l.extends != "" {
+		// the profile keeps the values of its parents when it no longer extends another profile
+		for name, value := range l.values[LayerParent] {
+			if _, set := l.values[LayerProfile][name]; !set {
+				l.values[LayerProfile][name] = value
+			}
+		}
+	}
+	l.values[LayerParent] = parentValues
+	l.parentFiles = parentFiles
+	l.extends = parentFile
+	if parentFile != "" {
+		l.values[LayerProfile][extendsKey] = parentFile
+	} else {
+		delete(l.values[LayerProfile], extendsKey)
+	}
+	return nil
+}
+
+// sameValue compares setting values. Empty and nil lists are the same, they are written the same way.
+func sameValue(a, b interface{}) bool {
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+	aValue, bValue := reflect.ValueOf(a), reflect.ValueOf(b)
+	if !aValue.IsValid() || !bValue.IsValid() || aValue.Type() != bValue.Type() {
+		return false
+	}
+	switch aValue.Kind() {
+	case reflect.Slice, reflect.Map:
+		return aValue.Len() == 0 && bValue.Len() == 0
+	}
+	return false
+}
+
+func confValues(conf Conf) map[string]interface{} {
+	values := map[string]interface{}{}
+	confValue := reflect.ValueOf(conf)
+	for _, fieldSchema := range schema {
+		values[fieldSchema.Name] = confValue.Field(fieldSchema.index).Interface()
+	}
+	return values
+}
+
+// LoadLayerFile replaces the values of a layer with the entries of a yaml file.
+func (l *LayeredConfig) LoadLayerFile(layer Layer, fileName string) error {
+	values, err := readLayerValues(fileName)
+	if values != nil {
+		l.mutex.Lock()
+		l.values[layer] = values
+		l.mutex.Unlock()
+	}
+	return err
+}
+
+// readLayerValues returns the valid entries of a yaml file. Invalid entries are returned as error.
+func readLayerValues(fileName string) (map[string]interface{}, error) {
+	yamlFile, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	var document yaml.Node
+	if err = yaml.Unmarshal(yamlFile, &document); err != nil {
+		return nil, err
+	}
+	values := map[string]interface{}{}
+	validationErrors := validateYamlDocument(&document)
+	if document.Kind == yaml.DocumentNode && len(document.Content) > 0 {
+		mapping := document.Content[0]
+		for i := 0; i+1 < len(mapping.Content); i += 2 {
+			fieldSchema, _ := SchemaForField(mapping.Content[i].Value)
+			value := reflect.New(fieldSchema.Type)
+			if err = mapping.Content[i+1].Decode(value.Interface()); err == nil {
+				values[fieldSchema.Name] = value.Elem().Interface()
+			}
+		}
+	}
+	if len(validationErrors) > 0 {
+		return values, fmt.Errorf("%s:\n%w", filepath.Base(fileName), validationErrors)
+	}
+	return values, nil
+}
+
+// SetLayerFromConf replaces the values of a layer with all fields of conf.
+func (l *LayeredConfig) SetLayerFromConf(layer Layer, conf Conf) {
+	values := confValues(conf)
+	l.mutex.Lock()
+	l.values[layer] = values
+	l.mutex.Unlock()
+}
+
+// Set sets a value of a layer after checking it against the schema.
+func (l *LayeredConfig) Set(layer Layer, optionName string, value interface{}) error {
+	fieldSchema, ok := SchemaForField(optionName)
+	if !ok {
+		return unknownSettingError(optionName, 0)
+	}
+	convertedValue, err := ValidateOption(optionName, value)
+	if err != nil {
+		return err
+	}
+	l.mutex.Lock()
+	l.values[layer][fieldSchema.Name] = convertedValue
+	l.mutex.Unlock()
+	return nil
+}
+
+// ClearLayer removes all values of a layer.
+func (l *LayeredConfig) ClearLayer(layer Layer) {
+	l.mutex.Lock()
+	l.values[layer] = map[string]interface{}{}
+	l.mutex.Unlock()
+}
+
+// resolve returns the effective value of a setting and the layer it came from, only looking at layers below maxLayer.
+// The highest layer that sets the setting is its source.
+func (l *LayeredConfig) resolve(name string, maxLayer Layer) (interface{}, Layer) {
+	for layer := maxLayer - 1; layer > LayerDefault; layer-- {
+		if value, ok := l.values[layer][name]; ok {
+			return value, layer
+		}
+	}
+	return l.values[LayerDefault][name], LayerDefault
+}
+
+// Value returns the effective value of a setting and the layer it came from.
+func (l *LayeredConfig) Value(optionName string) (interface{}, Layer) {
+	fieldSchema, ok := SchemaForField(optionName)
+	if !ok {
+		return nil, LayerDefault
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.resolve(fieldSchema.Name, layerCount)
+}
+
+// Source returns the layer the effective value of a setting came from.
+func (l *LayeredConfig) Source(optionName string) Layer {
+	_, source := l.Value(optionName)
+	return source
+}
+
+// IsSet returns true if a setting is set explicitly in the profile or was changed while the profile is loaded.
+func (l *LayeredConfig) IsSet(optionName string) bool {
+	_, source := l.Value(optionName)
+	return source >= LayerProfile
+}
+
+// Inherited returns the value a setting would have without the layer it currently comes from.
+func (l *LayeredConfig) Inherited(optionName string) interface{} {
+	fieldSchema, ok := SchemaForField(optionName)
+	if !ok {
+		return nil
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	_, source := l.resolve(fieldSchema.Name, layerCount)
+	value, _ := l.resolve(fieldSchema.Name, source)
+	return value
+}
+
+// Reset removes the value from the layer it currently comes from, so the inherited value applies.
+// Only profile values and runtime changes can be reset, the base profile is shared by all profiles.
+// Returns the new effective value.
+func (l *LayeredConfig) Reset(optionName string) (interface{}, error) {
+	fieldSchema, ok := SchemaForField(optionName)
+	if !ok {
+		return nil, unknownSettingError(optionName, 0)
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	value, source := l.resolve(fieldSchema.Name, layerCount)
+	if source < LayerProfile {
+		return value, nil
+	}
+	delete(l.values[LayerProfile], fieldSchema.Name)
+	delete(l.values[LayerRuntime], fieldSchema.Name)
+	value, _ = l.resolve(fieldSchema.Name, layerCount)
+	return value, nil
+}
+
+// Effective returns the resolved settings of all layers.
+func (l *LayeredConfig) Effective() Conf {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	var conf Conf
+	confValue := reflect.ValueOf(&conf).Elem()
+	for _, fieldSchema := range schema {
+		value, _ := l.resolve(fieldSchema.Name, layerCount)
+		if value == nil {
+			continue
+		}
+		confValue.Field(fieldSchema.index).Set(reflect.ValueOf(value))
+	}
+	return conf
+}
+
+// BackendSettingsFile returns the settings file the backend uses for the profile.
+func (l *LayeredConfig) BackendSettingsFile() string {
+	return GetResolvedProfileFile(l.profileFile)
+}
+
+// WriteProfile stores conf as the profile layer and writes the profile file and the resolved settings for the backend.
+// The profile file only stores the settings set explicitly in the profile and the values that differ from the inherited ones.
+func (l *LayeredConfig) WriteProfile(conf Conf) error {
+	conf.Extends = l.Extends()
+	// invalid values are reported, the valid ones are saved anyway
+	var validationErrors ValidationErrors
+	err := l.writeOverrides(conf)
+	if err != nil && !errors.As(err, &validationErrors) {
+		return err
+	}
+	if backendErr := l.writeBackendSettings(); backendErr != nil && !errors.As(backendErr, &validationErrors) {
+		return backendErr
+	}
+	return err
+}
+
+// writeOverrides writes the settings of conf that are set explicitly in the profile, or differ from the inherited values, as profile file.
+// Invalid values are not written, the previous value of the profile is kept. They are returned as ValidationErrors.
+func (l *LayeredConfig) writeOverrides(conf Conf) error {
+	validationErrors := conf.Validate()
+	if conf.Schema_version == 0 {
+		conf.Schema_version = Migrations.LatestVersion()
+	}
+
+	l.mutex.Lock()
+	overrides := map[string]interface{}{}
+	mapping := &yaml.Node{Kind: yaml.MappingNode}
+	values := confValues(conf)
+	for _, fieldSchema := range schema {
+		value := values[fieldSchema.Name]
+		if validationErrors.Field(fieldSchema.Name) != nil {
+			previousValue, ok := l.values[LayerProfile][fieldSchema.Name]
+			if !ok {
+				continue
+			}
+			value = previousValue
+		}
+		switch fieldSchema.Name {
+		case "settingsfilename", "process_id":
+			continue
+		case extendsKey:
+			if value == "" {
+				continue
+			}
+		case Migrations.SchemaVersionKey:
+		default:
+			_, setInProfile := l.values[LayerProfile][fieldSchema.Name]
+			_, changed := l.values[LayerRuntime][fieldSchema.Name]
+			inheritedValue, _ := l.resolve(fieldSchema.Name, LayerProfile)
+			if !setInProfile && !changed && sameValue(value, inheritedValue) {
+				continue
+			}
+		}
+		valueNode := &yaml.Node{}
+		if err := valueNode.Encode(value); err != nil {
+			l.mutex.Unlock()
+			return err
+		}
+		mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: fieldSchema.Name}, valueNode)
+		overrides[fieldSchema.Name] = value
+	}
+	l.values[LayerProfile] = overrides
+	l.mutex.Unlock()
+
+	yamlFile, err := yaml.Marshal(mapping)
+	if err != nil {
+		return err
+	}
+	if err = writeOwnFile(l.profileFile, yamlFile); err != nil {
+		return err
+	}
+	if len(validationErrors) > 0 {
+		return fmt.Errorf("%s: invalid settings were not saved:\n%w", filepath.Base(l.profileFile), validationErrors)
+	}
+	return nil
+}
+
+// writeBackendSettings writes the resolved settings of a profile that extends another profile for the backend.
+func (l *LayeredConfig) writeBackendSettings() error {
+	resolvedConf := l.Effective()
+	resolvedConf.Extends = ""
+	resolvedConf.SettingsFilename = filepath.Base(l.profileFile)
+	resolvedFile := GetResolvedProfileFile(l.profileFile)
+	if err := os.MkdirAll(filepath.Dir(resolvedFile), 0755); err != nil {
+		return err
+	}
+	err := resolvedConf.WriteYamlSettings(resolvedFile)
+	recordOwnWrite(resolvedFile)
+	return err
+}
+
+// IgnoreBackendChange reports settings whose value in the resolved settings file was not chosen by the user,
+// for example backend options the UI turned off while it does their work. They are not written back to the layers.
+var IgnoreBackendChange func(name string) bool
+
+// ApplyBackendSettings writes the settings the backend changed in the resolved settings file back to the layer they come from.
+// Settings of the base profile or a parent profile are written to that file, all others to the profile file.
+// Settings reported by IgnoreBackendChange are skipped. Returns the names of the written settings.
+func (l *LayeredConfig) ApplyBackendSettings() ([]string, error) {
+	values, err := readLayerValues(l.BackendSettingsFile())
+	if values == nil {
+		return nil, err
+	}
+	profilesDir := filepath.Dir(l.profileFile)
+
+	l.mutex.Lock()
+	var changed []string
+	var errs []error
+	if err != nil {
+		errs = append(errs, err)
+	}
+	for _, fieldSchema := range schema {
+		name := fieldSchema.Name
+		value, ok := values[name]
+		if !ok || Utilities.Contains(watchIgnoredFields, name) || name == extendsKey {
+			continue
+		}
+		if IgnoreBackendChange != nil && IgnoreBackendChange(name) {
+			continue
+		}
+		currentValue, source := l.resolve(name, layerCount)
+		if sameValue(value, currentValue) {
+			continue
+		}
+		switch source {
+		case LayerBase:
+			err = setYamlValue(GetBaseProfileFile(), name, value)
+		case LayerParent:
+			err = setYamlValue(filepath.Join(profilesDir, l.parentFiles[name]), name, value)
+		default:
+			source = LayerProfile
+			delete(l.values[LayerRuntime], name)
+			err = setYamlValue(l.profileFile, name, value)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		l.values[source][name] = value
+		changed = append(changed, name)
+	}
+	l.mutex.Unlock()
+	return changed, errors.Join(errs...)
+}
+
+// setYamlValue sets a single entry of a yaml file and keeps all other entries, as well as their order and comments.
+func setYamlValue(fileName string, name string, value interface{}) error {
+	var document yaml.Node
+	yamlFile, err := os.ReadFile(fileName)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err = yaml.Unmarshal(yamlFile, &document); err != nil {
+		return fmt.Errorf("%s: %w", filepath.Base(fileName), err)
+	}
+	if document.Kind == 0 {
+		document = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	if document.Kind != yaml.DocumentNode || len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("%s: not a yaml mapping", filepath.Base(fileName))
+	}
+	valueNode := &yaml.Node{}
+	if err = valueNode.Encode(value); err != nil {
+		return err
+	}
+	mapping := document.Content[0]
+	found := false
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == name {
+			mapping.Content[i+1] = valueNode
+			found = true
+			break
+		}
+	}
+	if !found {
+		mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, valueNode)
+	}
+	data, err := yaml.Marshal(&document)
+	if err != nil {
+		return err
+	}
+	return writeOwnFile(fileName, data)
+}
+
+// PersistSettingChange stores a setting changed while the profile is loaded in the profile file.
+// The file is written after persistDebounce, so a series of changes writes it once (see FlushSettingChanges).
+// The resolved settings are written by the backend itself.
+func PersistSettingChange(optionName string, value interface{}) error {
+	if Layers == nil {
+		return nil
+	}
+	if _, ok := SchemaForField(optionName); !ok {
+		return nil
+	}
+	convertedValue, err := ValidateOption(optionName, value)
+	if err != nil {
+		return err
+	}
+	// a reset setting is sent to the backend with its inherited value, it stays inherited
+	if currentValue, _ := Layers.Value(optionName); Layers.IsSet(optionName) || !sameValue(currentValue, convertedValue) {
+		if err = Layers.Set(LayerRuntime, optionName, convertedValue); err != nil {
+			return err
+		}
+	}
+	Layers.schedulePersist()
+	return nil
+}
+
+func (l *LayeredConfig) schedulePersist() {
+	l.persistMutex.Lock()
+	defer l.persistMutex.Unlock()
+	if l.persistTimer != nil {
+		l.persistTimer.Stop()
+	}
+	l.persistTimer = time.AfterFunc(persistDebounce, func() {
+		if err := l.FlushSettingChanges(); err != nil {
+			log.Printf("Error storing setting changes in profile: %v", err)
+		}
+	})
+}
+
+// FlushSettingChanges writes the setting changes of PersistSettingChange that are not written yet to the profile file.
+func (l *LayeredConfig) FlushSettingChanges() error {
+	l.persistMutex.Lock()
+	pending := l.persistTimer != nil
+	if pending {
+		l.persistTimer.Stop()
+		l.persistTimer = nil
+	}
+	l.persistMutex.Unlock()
+	if !pending {
+		return nil
+	}
+	conf := l.Effective()
+	conf.Extends = l.Extends()
+	return l.writeOverrides(conf)
+}
+
+// SetRuntimeOption sets a setting of the loaded profile and records it as runtime change.
+func SetRuntimeOption(optionName string, value interface{}) error {
+	if err := Config.SetOption(optionName, value); err != nil {
+		return err
+	}
+	if Layers != nil {
+		return Layers.Set(LayerRuntime, optionName, value)
+	}
+	return nil
+}
+
+// ResetOption resets a setting of the loaded profile to its inherited value and returns the new value.
+// The caller is responsible for sending the new value to the backend.
+func ResetOption(optionName string) (interface{}, error) {
+	if Layers == nil {
+		return nil, fmt.Errorf("no profile loaded")
+	}
+	value, err := Layers.Reset(optionName)
+	if err != nil {
+		return nil, err
+	}
+	return value, Config.SetOption(optionName, value)
+}
+
+// profileSpecificFields are never stored in the base profile.
+var profileSpecificFields = []string{
+	"settingsfilename",
+	"extends",
+	"schema_version",
+	"process_id",
+	"websocket_ip",
+	"websocket_port",
+	"device_index",
+	"device_out_index",
+	"audio_input_device",
+	"audio_output_device",
+}
+
+// WriteBaseProfile stores all values of conf that differ from the built-in defaults as base profile.
+func WriteBaseProfile(conf Conf) error {
+	if validationErrors := conf.Validate(); len(validationErrors) > 0 {
+		return validationErrors
+	}
+	defaults := confValues(DefaultConf)
+	values := confValues(conf)
+	mapping := &yaml.Node{Kind: yaml.MappingNode}
+	for _, fieldSchema := range schema {
+		if Utilities.Contains(profileSpecificFields, fieldSchema.Name) || reflect.DeepEqual(values[fieldSchema.Name], defaults[fieldSchema.Name]) {
+			continue
+		}
+		valueNode := &yaml.Node{}
+		if err := valueNode.Encode(values[fieldSchema.Name]); err != nil {
+			return err
+		}
+		mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: fieldSchema.Name}, valueNode)
+	}
+	yamlFile, err := yaml.Marshal(mapping)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(GetBaseProfileFile(), yamlFile, 0644)
+}
+
+// DefaultChange is a setting whose value differs from the built-in default.
+type DefaultChange struct {
+	Name    string
+	Default interface{}
+	Value   interface{}
+}
+
+// ChangedFromDefaults returns all settings of conf that differ from the built-in defaults, in schema order.
+// Settings that are specific to each profile, like the audio devices, are skipped.
+func ChangedFromDefaults(conf Conf) []DefaultChange {
+	var changes []DefaultChange
+	defaults := confValues(DefaultConf)
+	values := confValues(conf)
+	for _, fieldSchema := range schema {
+		if Utilities.Contains(profileSpecificFields, fieldSchema.Name) || reflect.DeepEqual(values[fieldSchema.Name], defaults[fieldSchema.Name]) {
+			continue
+		}
+		changes = append(changes, DefaultChange{Name: fieldSchema.Name, Default: defaults[fieldSchema.Name], Value: values[fieldSchema.Name]})
+	}
+	return changes
+}
@@ -0,0 +1,298 @@
+package Settings
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// withBaseProfile writes the base profile for the duration of a test. An empty content removes it.
+func withBaseProfile(t *testing.T, content string) {
+	t.Helper()
+	baseFile := GetBaseProfileFile()
+	previous, err := os.ReadFile(baseFile)
+	existed := err == nil
+	t.Cleanup(func() {
+		if existed {
+			_ = os.WriteFile(baseFile, previous, 0644)
+		} else {
+			_ = os.Remove(baseFile)
+		}
+	})
+	if content == "" {
+		_ = os.Remove(baseFile)
+		return
+	}
+	if err := os.WriteFile(baseFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func loadTestLayers(t *testing.T, files map[string]string, profile string) *LayeredConfig {
+	t.Helper()
+	dir := t.TempDir()
+	writeTestFiles(t, dir, files)
+	t.Cleanup(func() { _ = os.Remove(GetResolvedProfileFile(profile)) })
+	l, err := LoadProfileLayers(DefaultConf, filepath.Join(dir, profile))
+	if err != nil {
+		t.Fatalf("LoadProfileLayers() error = %v", err)
+	}
+	return l
+}
+
+func TestLayerResolution(t *testing.T) {
+	tests := []struct {
+		name       string
+		base       string
+		files      map[string]string
+		wantPort   int
+		wantSource Layer
+	}{
+		{name: "default", files: map[string]string{"p.yaml": "tts_answer: true\n"}, wantPort: DefaultConf.Osc_port, wantSource: LayerDefault},
+		{name: "base profile", base: "osc_port: 9001\n", files: map[string]string{"p.yaml": "tts_answer: true\n"}, wantPort: 9001, wantSource: LayerBase},
+		{name: "profile value equal to the default is set", base: "osc_port: 9001\n", files: map[string]string{"p.yaml": "osc_port: 9000\n"}, wantPort: 9000, wantSource: LayerProfile},
+		{name: "profile value equal to the base profile is set", base: "osc_port: 9001\n", files: map[string]string{"p.yaml": "osc_port: 9001\n"}, wantPort: 9001, wantSource: LayerProfile},
+		{name: "parent profile", base: "osc_port: 9001\n", files: map[string]string{"parent.yaml": "osc_port: 9002\n", "p.yaml": "extends: parent.yaml\n"}, wantPort: 9002, wantSource: LayerParent},
+		{name: "topmost parent value equal to the default is set", base: "osc_port: 9001\n", files: map[string]string{"top.yaml": "osc_port: 9000\n", "parent.yaml": "extends: top\n", "p.yaml": "extends: parent.yaml\n"}, wantPort: 9000, wantSource: LayerParent},
+		{name: "profile overrides parent", files: map[string]string{"parent.yaml": "osc_port: 9002\n", "p.yaml": "extends: parent.yaml\nosc_port: 9003\n"}, wantPort: 9003, wantSource: LayerProfile},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withBaseProfile(t, tt.base)
+			l := loadTestLayers(t, tt.files, "p.yaml")
+			value, source := l.Value("osc_port")
+			if value != tt.wantPort || source != tt.wantSource {
+				t.Errorf("Value() = %v from %v, want %v from %v", value, source, tt.wantPort, tt.wantSource)
+			}
+			if got := l.Effective().Osc_port; got != tt.wantPort {
+				t.Errorf("Effective().Osc_port = %d, want %d", got, tt.wantPort)
+			}
+		})
+	}
+}
+
+func TestLoadProfileLayersPreset(t *testing.T) {
+	withBaseProfile(t, "")
+	preset := DefaultConf
+	preset.Osc_port = 9005
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"existing.yaml": "tts_answer: true\n"})
+
+	newLayers, _ := LoadProfileLayers(preset, filepath.Join(dir, "new.yaml"))
+	if value, source := newLayers.Value("osc_port"); value != 9005 || source != LayerProfile {
+		t.Errorf("new profile osc_port = %v from %v, want the preset value set in the profile", value, source)
+	}
+	if newLayers.IsSet("tts_answer") {
+		t.Errorf("preset value equal to the default is set in the new profile")
+	}
+	existingLayers, _ := LoadProfileLayers(preset, filepath.Join(dir, "existing.yaml"))
+	if value, _ := existingLayers.Value("osc_port"); value != DefaultConf.Osc_port {
+		t.Errorf("existing profile osc_port = %v, want the default, the preset only applies to new profiles", value)
+	}
+}
+
+func TestResetAndWriteProfile(t *testing.T) {
+	withBaseProfile(t, "osc_port: 9001\nosc_ip: 10.0.0.1\n")
+	l := loadTestLayers(t, map[string]string{"p.yaml": "osc_port: 9000\ntts_answer: true\n"}, "p.yaml")
+
+	value, err := l.Reset("osc_port")
+	if err != nil || value != 9001 || l.Source("osc_port") != LayerBase {
+		t.Fatalf("Reset() = %v, %v from %v, want the base profile value", value, err, l.Source("osc_port"))
+	}
+	if value, _ := l.Reset("osc_ip"); value != "10.0.0.1" || l.Source("osc_ip") != LayerBase {
+		t.Errorf("Reset() of a base profile value changed it to %v", value)
+	}
+
+	conf := l.Effective()
+	conf.Osc_type_transfer = "both"
+	if err := l.WriteProfile(conf); err != nil {
+		t.Fatalf("WriteProfile() error = %v", err)
+	}
+	data, _ := os.ReadFile(l.profileFile)
+	for _, want := range []string{"tts_answer: true", "osc_type_transfer: both", "schema_version:"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("profile file %q does not contain %q", data, want)
+		}
+	}
+	for _, unwanted := range []string{"osc_port", "osc_ip", "extends", "settingsfilename", "websocket_port"} {
+		if strings.Contains(string(data), unwanted) {
+			t.Errorf("profile file %q contains the inherited %q", data, unwanted)
+		}
+	}
+
+	var resolved Conf
+	if err := resolved.LoadYamlSettings(l.BackendSettingsFile()); err != nil {
+		t.Fatalf("resolved settings are invalid: %v", err)
+	}
+	if resolved.Osc_port != 9001 || resolved.Osc_ip != "10.0.0.1" || !resolved.Tts_answer || resolved.Osc_type_transfer != "both" || resolved.SettingsFilename != "p.yaml" {
+		t.Errorf("resolved settings = port %d, ip %s, tts %v, transfer %s, file %s", resolved.Osc_port, resolved.Osc_ip, resolved.Tts_answer, resolved.Osc_type_transfer, resolved.SettingsFilename)
+	}
+}
+
+func TestSetExtends(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		parent  string
+		wantErr error
+	}{
+		{name: "parent without extension", files: map[string]string{"parent.yaml": "osc_port: 9002\n"}, parent: "parent"},
+		{name: "missing parent", parent: "missing", wantErr: ErrParentNotFound},
+		{name: "parent extends the profile", files: map[string]string{"parent.yaml": "extends: p.yaml\n"}, parent: "parent.yaml", wantErr: ErrProfileCycle},
+		{name: "cycle between parents", files: map[string]string{"a.yaml": "extends: b.yaml\n", "b.yaml": "extends: a.yaml\n"}, parent: "a.yaml", wantErr: ErrProfileCycle},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withBaseProfile(t, "")
+			files := map[string]string{"p.yaml": "tts_answer: true\n"}
+			for name, content := range tt.files {
+				files[name] = content
+			}
+			l := loadTestLayers(t, files, "p.yaml")
+			err := l.SetExtends(tt.parent)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) || l.Extends() != "" {
+					t.Errorf("SetExtends() error = %v, extends = %q, want %v and no parent", err, l.Extends(), tt.wantErr)
+				}
+				return
+			}
+			if err != nil || l.Extends() != "parent.yaml" || l.Effective().Osc_port != 9002 {
+				t.Fatalf("SetExtends() error = %v, extends = %q, osc_port = %d", err, l.Extends(), l.Effective().Osc_port)
+			}
+			// the profile keeps the values of its parent when the parent is removed
+			if err = l.SetExtends(""); err != nil {
+				t.Fatal(err)
+			}
+			if value, source := l.Value("osc_port"); value != 9002 || source != LayerProfile || l.Extends() != "" {
+				t.Errorf("after removing the parent osc_port = %v from %v, extends = %q", value, source, l.Extends())
+			}
+		})
+	}
+}
+
+func TestApplyBackendSettings(t *testing.T) {
+	withBaseProfile(t, "osc_port: 9001\n")
+	l := loadTestLayers(t, map[string]string{
+		"parent.yaml": "osc_ip: 10.0.0.2\n",
+		"p.yaml":      "extends: parent.yaml\ntts_answer: true\n",
+	}, "p.yaml")
+	if err := l.WriteProfile(l.Effective()); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := l.ApplyBackendSettings(); err != nil || len(changed) > 0 {
+		t.Fatalf("ApplyBackendSettings() = %v, %v, want no changes for unchanged settings", changed, err)
+	}
+
+	// the backend changes settings of each layer
+	resolved := l.Effective()
+	resolved.Extends = ""
+	resolved.Osc_port = 9101
+	resolved.Osc_ip = "10.0.0.3"
+	resolved.Tts_answer = false
+	resolved.Osc_type_transfer = "both"
+	if err := resolved.WriteYamlSettings(l.BackendSettingsFile()); err != nil {
+		t.Fatal(err)
+	}
+	changed, err := l.ApplyBackendSettings()
+	if err != nil || len(changed) != 4 {
+		t.Fatalf("ApplyBackendSettings() = %v, %v, want 4 changed settings", changed, err)
+	}
+
+	profilesDir := filepath.Dir(l.profileFile)
+	tests := []struct {
+		file string
+		want []string
+	}{
+		{file: GetBaseProfileFile(), want: []string{"osc_port: 9101"}},
+		{file: filepath.Join(profilesDir, "parent.yaml"), want: []string{"osc_ip: 10.0.0.3"}},
+		{file: l.profileFile, want: []string{"extends: parent.yaml", "tts_answer: false", "osc_type_transfer: both"}},
+	}
+	for _, tt := range tests {
+		data, _ := os.ReadFile(tt.file)
+		for _, want := range tt.want {
+			if !strings.Contains(string(data), want) {
+				t.Errorf("%s = %q, want it to contain %q", filepath.Base(tt.file), data, want)
+			}
+		}
+	}
+	if value, source := l.Value("osc_port"); value != 9101 || source != LayerBase {
+		t.Errorf("osc_port = %v from %v, want the backend value in the base profile layer", value, source)
+	}
+}
+
+func TestApplyBackendSettingsIgnoredChange(t *testing.T) {
+	withBaseProfile(t, "")
+	l := loadTestLayers(t, map[string]string{"p.yaml": "tts_answer: true\nosc_port: 9002\n"}, "p.yaml")
+	if err := l.WriteProfile(l.Effective()); err != nil {
+		t.Fatal(err)
+	}
+	IgnoreBackendChange = func(name string) bool { return name == "tts_answer" }
+	defer func() { IgnoreBackendChange = nil }()
+
+	// the backend stores the held option turned off
+	resolved := l.Effective()
+	resolved.Tts_answer = false
+	resolved.Osc_port = 9102
+	if err := resolved.WriteYamlSettings(l.BackendSettingsFile()); err != nil {
+		t.Fatal(err)
+	}
+	changed, err := l.ApplyBackendSettings()
+	if err != nil || strings.Join(changed, ",") != "osc_port" {
+		t.Fatalf("ApplyBackendSettings() = %v, %v, want only osc_port", changed, err)
+	}
+	if data, _ := os.ReadFile(l.profileFile); !strings.Contains(string(data), "tts_answer: true") {
+		t.Errorf("profile = %q, want the chosen tts_answer value", data)
+	}
+	if value, _ := l.Value("tts_answer"); value != true {
+		t.Errorf("tts_answer = %v, want the chosen value", value)
+	}
+}
+
+func TestPersistSettingChange(t *testing.T) {
+	withBaseProfile(t, "osc_port: 9001\n")
+	l := loadTestLayers(t, map[string]string{"p.yaml": "osc_port: 9002\n"}, "p.yaml")
+	previousLayers := Layers
+	Layers = l
+	defer func() { Layers = previousLayers }()
+
+	if err := PersistSettingChange("tts_answer", true); err != nil {
+		t.Fatal(err)
+	}
+	if !l.IsSet("tts_answer") {
+		t.Errorf("changed setting is not set in the profile")
+	}
+	if data, _ := os.ReadFile(l.profileFile); string(data) != "osc_port: 9002\n" {
+		t.Errorf("profile file = %q, want it written after the changes are collected", data)
+	}
+	// a reset sends the inherited value, it must stay inherited
+	if _, err := l.Reset("osc_port"); err != nil {
+		t.Fatal(err)
+	}
+	if err := PersistSettingChange("osc_port", 9001); err != nil {
+		t.Fatal(err)
+	}
+	if l.IsSet("osc_port") {
+		t.Errorf("reset setting was set again")
+	}
+	if err := l.FlushSettingChanges(); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(l.profileFile)
+	if strings.Contains(string(data), "osc_port") || !strings.Contains(string(data), "tts_answer: true") {
+		t.Errorf("profile file = %q", data)
+	}
+	if !isOwnWrite(l.profileFile) {
+		t.Error("written profile file is not recorded as own write")
+	}
+}
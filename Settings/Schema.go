@@ -0,0 +1,512 @@
+package Settings
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Error kinds of a ValidationError. Use errors.Is to check for them.
+var (
+	ErrUnknownSetting = errors.New("unknown setting")
+	ErrInvalidType    = errors.New("invalid type")
+	ErrOutOfRange     = errors.New("value out of range")
+	ErrInvalidEnum    = errors.New("value not allowed")
+	ErrDependency     = errors.New("conflicting settings")
+)
+
+// ValidationError describes a single invalid setting.
+type ValidationError struct {
+	Field  string
+	Value  interface{}
+	Err    error
+	Reason string
+	// Line is the line in the settings file, 0 if the value was not loaded from a file.
+	Line int
+}
+
+func (e *ValidationError) Error() string {
+	message := fmt.Sprintf("setting '%s': %v", e.Field, e.Err)
+	if e.Reason != "" {
+		message += " (" + e.Reason + ")"
+	}
+	if e.Line > 0 {
+		message = fmt.Sprintf("line %d: %s", e.Line, message)
+	}
+	return message
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationErrors collects all invalid settings of a profile.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	var messages []string
+	for _, validationError := range e {
+		messages = append(messages, validationError.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Field returns the error of a single setting or nil.
+func (e ValidationErrors) Field(name string) *ValidationError {
+	name = strings.ToLower(name)
+	for _, validationError := range e {
+		if validationError.Field == name {
+			return validationError
+		}
+	}
+	return nil
+}
+
+// FieldSchema describes type, allowed values and dependencies of a Conf field.
+type FieldSchema struct {
+	// Name is the name used in profile files and setting_change messages.
+	Name string
+	Type reflect.Type
+	// Min and Max limit numeric values. nil means no limit.
+	Min *float64
+	Max *float64
+	// Enum lists the allowed values of a string field. An empty value is always allowed (backend default).
+	Enum []string
+	// FloatString marks string fields that contain a float, "none" or "".
+	FloatString bool
+	// Required fields are checked even if they have their zero value.
+	// Zero values of all other fields mean "not set" and use the backend default.
+	Required bool
+	// DependsOn is the setting that must be enabled (or set) for this setting to have any effect.
+	DependsOn string
+	// RequiresRestart marks settings the backend only reads at startup.
+	RequiresRestart bool
+
+	index     int
+	omitEmpty bool
+}
+
+func limit(value float64) *float64 {
+	return &value
+}
+
+// fieldRules extends the schema derived from the Conf struct.
+var fieldRules = map[string]FieldSchema{
+	"schema_version":                {Min: limit(0)},
+	"process_id":                    {Min: limit(0)},
+	"phrase_time_limit":             {Min: limit(0)},
+	"pause":                         {Min: limit(0)},
+	"energy":                        {Min: limit(0)},
+	"vad_on_full_clip":              {DependsOn: "vad_enabled"},
+	"vad_confidence_threshold":      {Min: limit(0), Max: limit(1), DependsOn: "vad_enabled"},
+	"vad_frames_per_buffer":         {Min: limit(0), DependsOn: "vad_enabled"},
+	"vad_thread_num":                {Min: limit(0), DependsOn: "vad_enabled"},
+	"speaker_change_split":          {DependsOn: "speaker_diarization"},
+	"min_speaker_length":            {Min: limit(0), DependsOn: "speaker_diarization"},
+	"min_speakers":                  {Min: limit(0), DependsOn: "speaker_diarization"},
+	"max_speakers":                  {Min: limit(0), DependsOn: "speaker_diarization"},
+	"whisper_task":                  {Enum: []string{"transcribe", "translate"}},
+	"prompt_reset_on_temperature":   {Min: limit(0), Max: limit(1)},
+	"logprob_threshold":             {FloatString: true},
+	"no_speech_threshold":           {FloatString: true},
+	"beam_size":                     {Min: limit(1)},
+	"length_penalty":                {Min: limit(0)},
+	"beam_search_patience":          {Min: limit(0)},
+	"repetition_penalty":            {Min: limit(0)},
+	"no_repeat_ngram_size":          {Min: limit(0)},
+	"whisper_cpu_threads":           {Min: limit(0)},
+	"whisper_num_workers":           {Min: limit(0)},
+	"realtime_frame_multiply":       {Min: limit(0), DependsOn: "realtime"},
+	"realtime_frequency_time":       {Min: limit(0), DependsOn: "realtime"},
+	"realtime_whisper_model":        {DependsOn: "realtime"},
+	"realtime_whisper_precision":    {DependsOn: "realtime"},
+	"realtime_whisper_beam_size":    {Min: limit(1), DependsOn: "realtime"},
+	"realtime_temperature_fallback": {DependsOn: "realtime"},
+	"faster_without_timestamps":     {DependsOn: "word_timestamps"},
+	"denoise_audio":                 {Enum: []string{"deepfilter", "noise_reduce"}, RequiresRestart: true},
+	"denoise_audio_post_filter":     {DependsOn: "denoise_audio"},
+	"denoise_audio_before_trigger":  {DependsOn: "denoise_audio"},
+	"denoise_strength":              {Min: limit(0), Max: limit(1), DependsOn: "denoise_audio"},
+	"max_sentence_repetition":       {Min: limit(-1)},
+	"silence_offset":                {DependsOn: "silence_cutting_enabled"},
+	"max_silence_length":            {Min: limit(0), DependsOn: "silence_cutting_enabled"},
+	"keep_silence_length":           {Min: limit(0), DependsOn: "silence_cutting_enabled"},
+	"normalize_lower_threshold":     {DependsOn: "normalize_enabled"},
+	"normalize_upper_threshold":     {DependsOn: "normalize_enabled"},
+	"normalize_gain_factor":         {Min: limit(0), DependsOn: "normalize_enabled"},
+
+	"txt_translate_realtime_sync":      {DependsOn: "txt_translate_realtime"},
+	"txt_second_translation_languages": {DependsOn: "txt_second_translation_enabled"},
+	"txt_second_translation_wrap":      {DependsOn: "txt_second_translation_enabled"},
+
+	"websocket_ip":   {RequiresRestart: true},
+	"websocket_port": {Min: limit(1), Max: limit(65535), Required: true, RequiresRestart: true},
+	"run_backend":    {RequiresRestart: true},
+
+	"audio_api":           {RequiresRestart: true},
+	"device_index":        {RequiresRestart: true},
+	"device_out_index":    {RequiresRestart: true},
+	"audio_input_device":  {RequiresRestart: true},
+	"audio_output_device": {RequiresRestart: true},
+
+	"osc_port":                           {Min: limit(1), Max: limit(65535)},
+	"osc_min_time_between_messages":      {Min: limit(0)},
+	"osc_chat_limit":                     {Min: limit(0)},
+	"osc_type_transfer":                  {Enum: []string{"translation_result", "source", "both", "both_inverted"}},
+	"osc_time_limit":                     {Min: limit(0)},
+	"osc_scroll_time_limit":              {Min: limit(0)},
+	"osc_initial_time_limit":             {Min: limit(0)},
+	"osc_scroll_size":                    {Min: limit(0)},
+	"osc_max_scroll_size":                {Min: limit(0)},
+	"osc_send_type":                      {Enum: []string{"chunks", "full", "full_or_scroll", "scroll"}},
+	"osc_delay_until_audio_playback_tag": {DependsOn: "osc_delay_until_audio_playback"},
+	"osc_delay_timeout":                  {Min: limit(0), DependsOn: "osc_delay_until_audio_playback"},
+	"osc_server_ip":                      {RequiresRestart: true},
+	"osc_server_port":                    {Min: limit(1), Max: limit(65535), RequiresRestart: true},
+
+	"tts_secondary_playback_device": {Min: limit(-1), DependsOn: "tts_use_secondary_playback"},
+	"tts_volume":                    {Min: limit(0)},
+	"tts_streamed_chunk_size":       {Min: limit(0), DependsOn: "tts_streamed_playback"},
+	"tts_streamed_min_play_time":    {Min: limit(0), DependsOn: "tts_streamed_playback"},
+}
+
+var schema = buildSchema()
+
+func buildSchema() []FieldSchema {
+	var fields []FieldSchema
+	confType := reflect.TypeOf(Conf{})
+	for i := 0; i < confType.NumField(); i++ {
+		field := confType.Field(i)
+		name := strings.ToLower(field.Name)
+		tag, options, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if tag != "" {
+			name = tag
+		}
+		fieldSchema := fieldRules[name]
+		fieldSchema.omitEmpty = strings.Contains(options, "omitempty")
+		fieldSchema.Name = name
+		fieldSchema.Type = field.Type
+		fieldSchema.index = i
+		fields = append(fields, fieldSchema)
+	}
+	return fields
+}
+
+// Schema returns the schema of all Conf fields.
+func Schema() []FieldSchema {
+	return append([]FieldSchema{}, schema...)
+}
+
+// SchemaForField returns the schema of a setting by name (case-insensitive).
+func SchemaForField(name string) (FieldSchema, bool) {
+	name = strings.ToLower(name)
+	for _, fieldSchema := range schema {
+		if fieldSchema.Name == name {
+			return fieldSchema, true
+		}
+	}
+	return FieldSchema{}, false
+}
+
+// convert converts a value to the field type without silently falling back to zero values.
+func (s FieldSchema) convert(value interface{}) (reflect.Value, error) {
+	invalidType := func(reason string) (reflect.Value, error) {
+		return reflect.Value{}, &ValidationError{Field: s.Name, Value: value, Err: ErrInvalidType, Reason: reason}
+	}
+	if value == nil {
+		return reflect.Zero(s.Type), nil
+	}
+	rawValue := reflect.ValueOf(value)
+	if rawValue.Type() == s.Type {
+		return rawValue, nil
+	}
+
+	switch s.Type.Kind() {
+	case reflect.Interface:
+		// keep the previous behaviour for fields like device_index, numeric strings become integers
+		if stringValue, ok := value.(string); ok {
+			if intValue, err := strconv.Atoi(stringValue); err == nil {
+				return reflect.ValueOf(intValue), nil
+			}
+		}
+		return rawValue, nil
+	case reflect.String:
+		switch v := value.(type) {
+		case string:
+			return reflect.ValueOf(v), nil
+		case int:
+			return reflect.ValueOf(strconv.Itoa(v)), nil
+		case float64:
+			return reflect.ValueOf(strconv.FormatFloat(v, 'f', -1, 64)), nil
+		}
+		return invalidType("expected text")
+	case reflect.Int:
+		switch v := value.(type) {
+		case int:
+			return reflect.ValueOf(v), nil
+		case float64:
+			if v != math.Trunc(v) {
+				return invalidType("expected a whole number")
+			}
+			return reflect.ValueOf(int(v)), nil
+		case string:
+			intValue, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil {
+				return invalidType("expected a whole number")
+			}
+			return reflect.ValueOf(intValue), nil
+		}
+		return invalidType("expected a whole number")
+	case reflect.Float64:
+		switch v := value.(type) {
+		case float64:
+			return reflect.ValueOf(v), nil
+		case int:
+			return reflect.ValueOf(float64(v)), nil
+		case string:
+			floatValue, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return invalidType("expected a number")
+			}
+			return reflect.ValueOf(floatValue), nil
+		}
+		return invalidType("expected a number")
+	case reflect.Bool:
+		if stringValue, ok := value.(string); ok {
+			boolValue, err := strconv.ParseBool(strings.TrimSpace(stringValue))
+			if err != nil {
+				return invalidType("expected true or false")
+			}
+			return reflect.ValueOf(boolValue), nil
+		}
+		return invalidType("expected true or false")
+	case reflect.Slice:
+		// a single string is a list with one entry (like tts_model)
+		if stringValue, ok := value.(string); ok && s.Type.Elem().Kind() == reflect.String {
+			if stringValue == "" {
+				return reflect.Zero(s.Type), nil
+			}
+			return reflect.ValueOf([]string{stringValue}), nil
+		}
+	}
+
+	// lists and maps (for example from JSON) are converted like they would be loaded from a profile
+	yamlValue, err := yaml.Marshal(value)
+	if err != nil {
+		return invalidType(err.Error())
+	}
+	convertedValue := reflect.New(s.Type)
+	if err = yaml.Unmarshal(yamlValue, convertedValue.Interface()); err != nil {
+		return invalidType(fmt.Sprintf("expected %s", s.Type))
+	}
+	return convertedValue.Elem(), nil
+}
+
+// check validates range and allowed values of an already converted value.
+func (s FieldSchema) check(value interface{}) *ValidationError {
+	if !s.Required && (value == nil || reflect.ValueOf(value).IsZero()) {
+		return nil
+	}
+	var number float64
+	isNumber := true
+	switch v := value.(type) {
+	case int:
+		number = float64(v)
+	case float64:
+		number = v
+	case string:
+		isNumber = false
+		if len(s.Enum) > 0 && !containsString(s.Enum, v) {
+			return &ValidationError{Field: s.Name, Value: value, Err: ErrInvalidEnum, Reason: "allowed: " + strings.Join(s.Enum, ", ")}
+		}
+		if s.FloatString && !strings.EqualFold(v, "none") {
+			floatValue, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return &ValidationError{Field: s.Name, Value: value, Err: ErrInvalidType, Reason: "expected a number or none"}
+			}
+			isNumber = true
+			number = floatValue
+		}
+	default:
+		isNumber = false
+	}
+	if isNumber {
+		if s.Min != nil && number < *s.Min {
+			return &ValidationError{Field: s.Name, Value: value, Err: ErrOutOfRange, Reason: s.rangeDescription()}
+		}
+		if s.Max != nil && number > *s.Max {
+			return &ValidationError{Field: s.Name, Value: value, Err: ErrOutOfRange, Reason: s.rangeDescription()}
+		}
+	}
+	return nil
+}
+
+func (s FieldSchema) rangeDescription() string {
+	format := func(value float64) string {
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	switch {
+	case s.Min != nil && s.Max != nil:
+		return "must be between " + format(*s.Min) + " and " + format(*s.Max)
+	case s.Min != nil:
+		return "must be at least " + format(*s.Min)
+	case s.Max != nil:
+		return "must be at most " + format(*s.Max)
+	}
+	return ""
+}
+
+func containsString(list []string, value string) bool {
+	for _, entry := range list {
+		if entry == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateOption converts a value to the type of the setting and checks it against the schema.
+// Returns the converted value.
+func ValidateOption(optionName string, value interface{}) (interface{}, error) {
+	fieldSchema, ok := SchemaForField(optionName)
+	if !ok {
+		return nil, unknownSettingError(optionName, 0)
+	}
+	convertedValue, err := fieldSchema.convert(value)
+	if err != nil {
+		return nil, err
+	}
+	if validationError := fieldSchema.check(convertedValue.Interface()); validationError != nil {
+		return nil, validationError
+	}
+	return convertedValue.Interface(), nil
+}
+
+// DependencyActive returns false if the setting only has an effect when another, currently disabled, setting is enabled.
+func (c *Conf) DependencyActive(optionName string) bool {
+	fieldSchema, ok := SchemaForField(optionName)
+	if !ok || fieldSchema.DependsOn == "" {
+		return true
+	}
+	parentSchema, ok := SchemaForField(fieldSchema.DependsOn)
+	if !ok {
+		return true
+	}
+	return !reflect.ValueOf(c).Elem().Field(parentSchema.index).IsZero() && c.DependencyActive(parentSchema.Name)
+}
+
+// Validate checks all fields against the schema and the relations between them.
+func (c *Conf) Validate() ValidationErrors {
+	var validationErrors ValidationErrors
+	confValue := reflect.ValueOf(c).Elem()
+	for _, fieldSchema := range schema {
+		if validationError := fieldSchema.check(confValue.Field(fieldSchema.index).Interface()); validationError != nil {
+			validationErrors = append(validationErrors, validationError)
+		}
+	}
+
+	if c.Speaker_diarization && c.Min_speakers > 0 && c.Max_speakers > 0 && c.Min_speakers > c.Max_speakers {
+		validationErrors = append(validationErrors, &ValidationError{Field: "min_speakers", Value: c.Min_speakers, Err: ErrDependency, Reason: "must not be greater than max_speakers"})
+	}
+	if c.Normalize_enabled && c.Normalize_lower_threshold > c.Normalize_upper_threshold {
+		validationErrors = append(validationErrors, &ValidationError{Field: "normalize_lower_threshold", Value: c.Normalize_lower_threshold, Err: ErrDependency, Reason: "must not be greater than normalize_upper_threshold"})
+	}
+	if c.Osc_scroll_size > 0 && c.Osc_max_scroll_size > 0 && c.Osc_scroll_size > c.Osc_max_scroll_size {
+		validationErrors = append(validationErrors, &ValidationError{Field: "osc_scroll_size", Value: c.Osc_scroll_size, Err: ErrDependency, Reason: "must not be greater than osc_max_scroll_size"})
+	}
+	return validationErrors
+}
+
+// ValidateField returns the validation error of a single field of the current values or nil.
+func (c *Conf) ValidateField(optionName string) *ValidationError {
+	return c.Validate().Field(optionName)
+}
+
+func unknownSettingError(name string, line int) *ValidationError {
+	validationError := &ValidationError{Field: name, Err: ErrUnknownSetting, Line: line}
+	if suggestion := suggestSetting(name); suggestion != "" {
+		validationError.Reason = "did you mean '" + suggestion + "'?"
+	}
+	return validationError
+}
+
+// suggestSetting returns the known setting with the smallest edit distance, if it is close enough to be a typo.
+func suggestSetting(name string) string {
+	name = strings.ToLower(name)
+	type candidate struct {
+		name     string
+		distance int
+	}
+	var candidates []candidate
+	for _, fieldSchema := range schema {
+		distance := editDistance(name, fieldSchema.Name)
+		if distance <= 2 || distance <= len(name)/5 {
+			candidates = append(candidates, candidate{fieldSchema.Name, distance})
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].distance < candidates[j].distance
+	})
+	return candidates[0].name
+}
+
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// validateYamlDocument checks every entry of a profile file.
+// Invalid entries are removed from the document, so the previous value of the field is kept when decoding it.
+func validateYamlDocument(document *yaml.Node) ValidationErrors {
+	if document.Kind != yaml.DocumentNode || len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	mapping := document.Content[0]
+	var validationErrors ValidationErrors
+	var validContent []*yaml.Node
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		keyNode, valueNode := mapping.Content[i], mapping.Content[i+1]
+		fieldSchema, ok := SchemaForField(keyNode.Value)
+		if !ok || fieldSchema.Name != keyNode.Value {
+			validationErrors = append(validationErrors, unknownSettingError(keyNode.Value, keyNode.Line))
+			continue
+		}
+		decodedValue := reflect.New(fieldSchema.Type)
+		if err := valueNode.Decode(decodedValue.Interface()); err != nil {
+			validationErrors = append(validationErrors, &ValidationError{Field: fieldSchema.Name, Value: valueNode.Value, Err: ErrInvalidType, Reason: fmt.Sprintf("expected %s", fieldSchema.Type), Line: valueNode.Line})
+			continue
+		}
+		if validationError := fieldSchema.check(decodedValue.Elem().Interface()); validationError != nil {
+			validationError.Line = valueNode.Line
+			validationErrors = append(validationErrors, validationError)
+			continue
+		}
+		validContent = append(validContent, keyNode, valueNode)
+	}
+	mapping.Content = validContent
+	return validationErrors
+}
@@ -0,0 +1,143 @@
+package Settings
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func validConf() Conf {
+	return Conf{Websocket_ip: "127.0.0.1", Websocket_port: 5000, Osc_port: 9000}
+}
+
+func TestSetOption(t *testing.T) {
+	tests := []struct {
+		name    string
+		option  string
+		value   interface{}
+		wantErr error
+	}{
+		{name: "valid number", option: "osc_port", value: 9001},
+		{name: "number from string", option: "osc_port", value: "9002"},
+		{name: "number out of range", option: "osc_port", value: 70000, wantErr: ErrOutOfRange},
+		{name: "allowed enum value", option: "osc_type_transfer", value: "both"},
+		{name: "enum value not allowed", option: "osc_type_transfer", value: "everything", wantErr: ErrInvalidEnum},
+		{name: "wrong type", option: "tts_answer", value: "maybe", wantErr: ErrInvalidType},
+		{name: "unknown setting", option: "osc_prot", value: 1, wantErr: ErrUnknownSetting},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := validConf()
+			err := conf.SetOption(tt.option, tt.value)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("SetOption() error = %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SetOption() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil && conf.Osc_port != 9000 {
+				t.Errorf("invalid value was set")
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		change    func(c *Conf)
+		wantField string
+	}{
+		{name: "valid", change: func(c *Conf) {}},
+		{name: "missing required port", change: func(c *Conf) { c.Websocket_port = 0 }, wantField: "websocket_port"},
+		{name: "min speakers above max", change: func(c *Conf) {
+			c.Speaker_diarization = true
+			c.Min_speakers = 3
+			c.Max_speakers = 2
+		}, wantField: "min_speakers"},
+		{name: "cross field check of a disabled feature", change: func(c *Conf) {
+			c.Min_speakers = 3
+			c.Max_speakers = 2
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := validConf()
+			tt.change(&conf)
+			validationErrors := conf.Validate()
+			if tt.wantField == "" {
+				if len(validationErrors) > 0 {
+					t.Errorf("Validate() = %v, want no errors", validationErrors)
+				}
+				return
+			}
+			if validationErrors.Field(tt.wantField) == nil {
+				t.Errorf("Validate() = %v, want an error for %s", validationErrors, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestLoadYamlSettingsSkipsInvalidEntries(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "profile.yaml")
+	profile := "websocket_port: 5001\nosc_port: 99999\nosc_prot: 1\ntts_answer: true\n"
+	if err := os.WriteFile(fileName, []byte(profile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	conf := validConf()
+	err := conf.LoadYamlSettings(fileName)
+	var validationErrors ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		t.Fatalf("LoadYamlSettings() error = %v, want ValidationErrors", err)
+	}
+	if validationErrors.Field("osc_port") == nil || validationErrors.Field("osc_prot") == nil {
+		t.Errorf("LoadYamlSettings() errors = %v, want osc_port and osc_prot", validationErrors)
+	}
+	if validationErrors.Field("osc_prot").Line != 3 {
+		t.Errorf("unknown setting reported on line %d, want 3", validationErrors.Field("osc_prot").Line)
+	}
+	if conf.Websocket_port != 5001 || !conf.Tts_answer || conf.Osc_port != 9000 {
+		t.Errorf("loaded websocket_port=%d tts_answer=%v osc_port=%d, want valid entries loaded and osc_port kept", conf.Websocket_port, conf.Tts_answer, conf.Osc_port)
+	}
+}
+
+func TestWriteYamlSettingsSavesValidSettings(t *testing.T) {
+	tests := []struct {
+		name         string
+		existing     string
+		wantOscPort  int
+		wantInFile   bool
+		wantTtsValue bool
+	}{
+		{name: "invalid value keeps the previous value", existing: "osc_port: 9123\n", wantOscPort: 9123, wantInFile: true, wantTtsValue: true},
+		{name: "invalid value without previous value is left out", existing: "", wantOscPort: 0, wantInFile: false, wantTtsValue: true},
+		{name: "invalid previous value is left out", existing: "osc_port: -1\n", wantOscPort: 0, wantInFile: false, wantTtsValue: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileName := filepath.Join(t.TempDir(), "profile.yaml")
+			if tt.existing != "" {
+				if err := os.WriteFile(fileName, []byte(tt.existing), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			conf := validConf()
+			conf.Osc_port = 70000
+			conf.Tts_answer = true
+			err := conf.WriteYamlSettings(fileName)
+			var validationErrors ValidationErrors
+			if !errors.As(err, &validationErrors) || validationErrors.Field("osc_port") == nil {
+				t.Fatalf("WriteYamlSettings() error = %v, want a ValidationError for osc_port", err)
+			}
+
+			var written Conf
+			if err := written.LoadYamlSettings(fileName); err != nil {
+				t.Fatalf("written file is invalid: %v", err)
+			}
+			if written.Osc_port != tt.wantOscPort || written.Tts_answer != tt.wantTtsValue || written.Websocket_port != 5000 {
+				t.Errorf("written osc_port=%d tts_answer=%v websocket_port=%d", written.Osc_port, written.Tts_answer, written.Websocket_port)
+			}
+		})
+	}
+}
@@ -0,0 +1,362 @@
+package Settings
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"whispering-tiger-ui/SendMessageChannel"
+	"whispering-tiger-ui/Utilities"
+
+	"fyne.io/fyne/v2"
+	"github.com/fsnotify/fsnotify"
+)
+
+// SettingDiff is a setting whose value in a layer file differs from the loaded settings.
+type SettingDiff struct {
+	Name string
+	// Current is the value of the loaded settings.
+	Current interface{}
+	// File is the value in the changed file, or the resolved value if the base profile or a parent profile changed.
+	File interface{}
+	// Layer is the layer whose file was changed.
+	Layer Layer
+}
+
+// watchIgnoredFields change while the profile is loaded and are never reported as external change.
+var watchIgnoredFields = []string{"settingsfilename", "process_id", "schema_version"}
+
+const watchDebounce = 300 * time.Millisecond
+
+// ProfileWatcher detects changes made to the layer files of the loaded profile outside the application,
+// for example in a text editor: the profile file, the base profile and the parent profiles.
+// Changes of the resolved settings file, which is written by the backend, are written back to their layers.
+//
+// The backend writes its settings as well, so only values that differ from the loaded settings
+// are reported. They stay pending until they are reloaded or overwritten.
+type ProfileWatcher struct {
+	mutex    sync.Mutex
+	layers   *LayeredConfig
+	watcher  *fsnotify.Watcher
+	timers   map[string]*time.Timer
+	snapshot map[string]interface{}
+	pending  []SettingDiff
+
+	// OnChange is called with all pending changes when new external changes are detected.
+	OnChange func(diffs []SettingDiff)
+}
+
+// ActiveProfileWatcher watches the layer files of the loaded profile. nil until a profile is loaded.
+var ActiveProfileWatcher *ProfileWatcher
+
+// OnPendingProfileChanges is called by the settings form before it overwrites pending external changes of the profile.
+// The handler shows the changes and calls overwrite if the loaded settings are kept. Without handler the changes are overwritten.
+var OnPendingProfileChanges func(overwrite func())
+
+// WatchProfile starts watching the layer files of a profile. The current content is the state changes are compared against.
+func WatchProfile(layers *LayeredConfig, onChange func(diffs []SettingDiff)) (*ProfileWatcher, error) {
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	// watch the directories, editors often replace the files instead of writing them
+	for _, dir := range []string{filepath.Dir(layers.profileFile), filepath.Dir(GetBaseProfileFile()), filepath.Dir(layers.BackendSettingsFile())} {
+		if err = os.MkdirAll(dir, 0755); err == nil {
+			err = fsWatcher.Add(dir)
+		}
+		if err != nil {
+			_ = fsWatcher.Close()
+			return nil, err
+		}
+	}
+	w := &ProfileWatcher{
+		layers:   layers,
+		watcher:  fsWatcher,
+		timers:   map[string]*time.Timer{},
+		OnChange: onChange,
+	}
+	w.snapshot, _ = readLayerValues(layers.profileFile)
+	go w.run()
+	return w, nil
+}
+
+// fileLayer returns the layer of a watched file. ok is false for other files.
+// The resolved settings file is returned as LayerRuntime, since it contains the settings of the running backend.
+func (w *ProfileWatcher) fileLayer(fileName string) (layer Layer, ok bool) {
+	switch {
+	case strings.EqualFold(fileName, filepath.Clean(w.layers.profileFile)):
+		return LayerProfile, true
+	case strings.EqualFold(fileName, filepath.Clean(w.layers.BackendSettingsFile())):
+		return LayerRuntime, true
+	case strings.EqualFold(fileName, filepath.Clean(GetBaseProfileFile())):
+		return LayerBase, true
+	}
+	profilesDir := filepath.Dir(w.layers.profileFile)
+	if extends := w.layers.Extends(); extends != "" && strings.EqualFold(filepath.Dir(fileName), filepath.Clean(profilesDir)) {
+		chain, _ := ProfileChain(profilesDir, extends)
+		for _, parentFile := range chain {
+			if strings.EqualFold(filepath.Base(fileName), parentFile) {
+				return LayerParent, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func (w *ProfileWatcher) run() {
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if !event.Has(fsnotify.Write | fsnotify.Create | fsnotify.Rename | fsnotify.Remove) {
+				continue
+			}
+			fileName := filepath.Clean(event.Name)
+			layer, watched := w.fileLayer(fileName)
+			if !watched {
+				continue
+			}
+			handler := w.notify
+			if layer == LayerRuntime {
+				handler = w.applyBackendSettings
+			}
+			key := strings.ToLower(fileName)
+			w.mutex.Lock()
+			if timer, exists := w.timers[key]; exists {
+				timer.Stop()
+			}
+			w.timers[key] = time.AfterFunc(watchDebounce, func() {
+				// the application wrote the file itself, its changes are already loaded
+				if isOwnWrite(fileName) {
+					if layer == LayerProfile {
+						w.updateSnapshot()
+					}
+					return
+				}
+				handler()
+			})
+			w.mutex.Unlock()
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("error watching profile files: %v", err)
+		}
+	}
+}
+
+// applyBackendSettings writes the settings the backend changed back to their layers and updates the loaded settings.
+// The loaded settings are updated on the main thread, like all other changes of them.
+func (w *ProfileWatcher) applyBackendSettings() {
+	changed, err := w.layers.ApplyBackendSettings()
+	if err != nil {
+		log.Printf("error writing backend settings to the profile layers: %v", err)
+	}
+	if len(changed) == 0 {
+		return
+	}
+	fyne.Do(func() {
+		for _, name := range changed {
+			value, _ := w.layers.Value(name)
+			_ = Config.SetOption(name, value)
+		}
+	})
+}
+
+// updateSnapshot sets the profile file content as the state external changes are compared against.
+func (w *ProfileWatcher) updateSnapshot() {
+	values, err := readLayerValues(w.layers.profileFile)
+	if values == nil {
+		log.Printf("error reading profile file: %v", err)
+		return
+	}
+	w.mutex.Lock()
+	w.snapshot = values
+	w.mutex.Unlock()
+}
+
+func (w *ProfileWatcher) notify() {
+	w.mutex.Lock()
+	pendingCount := len(w.pending)
+	w.mutex.Unlock()
+	diffs := w.Check()
+	if len(diffs) > pendingCount && w.OnChange != nil {
+		w.OnChange(diffs)
+	}
+}
+
+// reloadInheritedLayers reads the base profile and the parent profiles again.
+// Returns the settings whose inherited value changed, with the layer they now come from.
+func (w *ProfileWatcher) reloadInheritedLayers() []SettingDiff {
+	before := confValues(w.layers.Effective())
+	baseFile := GetBaseProfileFile()
+	if FileExists(baseFile) {
+		if err := w.layers.LoadLayerFile(LayerBase, baseFile); err != nil {
+			log.Printf("error reading base profile: %v", err)
+		}
+	} else {
+		w.layers.ClearLayer(LayerBase)
+	}
+	if err := w.layers.SetExtends(w.layers.Extends()); err != nil {
+		log.Printf("error reading parent profiles: %v", err)
+	}
+	after := confValues(w.layers.Effective())
+
+	var diffs []SettingDiff
+	for _, fieldSchema := range schema {
+		name := fieldSchema.Name
+		if Utilities.Contains(watchIgnoredFields, name) || name == extendsKey || sameValue(before[name], after[name]) {
+			continue
+		}
+		if _, source := w.layers.Value(name); source < LayerProfile {
+			diffs = append(diffs, SettingDiff{Name: name, File: after[name], Layer: source})
+		}
+	}
+	return diffs
+}
+
+// Check reads the layer files and returns all pending external changes.
+func (w *ProfileWatcher) Check() []SettingDiff {
+	inheritedDiffs := w.reloadInheritedLayers()
+	values, err := readLayerValues(w.layers.profileFile)
+	if values == nil && err != nil {
+		log.Printf("error reading profile file: %v", err)
+	}
+	currentValues := confValues(Config)
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	for _, diff := range inheritedDiffs {
+		w.pending = setDiff(w.pending, diff)
+	}
+	if values != nil {
+		for _, fieldSchema := range schema {
+			name := fieldSchema.Name
+			fileValue, inFile := values[name]
+			if !inFile || Utilities.Contains(watchIgnoredFields, name) || reflect.DeepEqual(fileValue, w.snapshot[name]) {
+				continue
+			}
+			w.pending = setDiff(w.pending, SettingDiff{Name: name, File: fileValue, Layer: LayerProfile})
+		}
+		w.snapshot = values
+	}
+
+	// drop changes that match the loaded settings, for example because the backend wrote them
+	pending := w.pending[:0]
+	for _, diff := range w.pending {
+		diff.Current = currentValues[diff.Name]
+		if !sameValue(diff.Current, diff.File) {
+			pending = append(pending, diff)
+		}
+	}
+	w.pending = pending
+	return append([]SettingDiff{}, w.pending...)
+}
+
+func setDiff(diffs []SettingDiff, diff SettingDiff) []SettingDiff {
+	for i := range diffs {
+		if diffs[i].Name == diff.Name {
+			diffs[i] = diff
+			return diffs
+		}
+	}
+	return append(diffs, diff)
+}
+
+// Pending returns the external changes that were neither reloaded nor overwritten.
+func (w *ProfileWatcher) Pending() []SettingDiff {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return append([]SettingDiff{}, w.pending...)
+}
+
+// Reload applies the pending external changes to the loaded settings and sends them to the backend.
+func (w *ProfileWatcher) Reload() []SettingDiff {
+	w.mutex.Lock()
+	diffs := w.pending
+	w.pending = nil
+	w.mutex.Unlock()
+
+	for _, diff := range diffs {
+		if err := Config.SetOption(diff.Name, diff.File); err != nil {
+			log.Printf("error reloading setting %s: %v", diff.Name, err)
+			continue
+		}
+		// the base profile and parent profiles are already reloaded
+		if diff.Layer == LayerProfile {
+			_ = w.layers.Set(LayerProfile, diff.Name, diff.File)
+		}
+		sendMessage := SendMessageChannel.SendMessageStruct{
+			Type:  "setting_change",
+			Name:  diff.Name,
+			Value: diff.File,
+		}
+		sendMessage.SendMessage()
+	}
+	if len(diffs) > 0 {
+		sendMessage := SendMessageChannel.SendMessageStruct{
+			Type: "setting_update_req",
+		}
+		sendMessage.SendMessage()
+	}
+	return diffs
+}
+
+// Dismiss discards the pending external changes, they are overwritten by the next save.
+func (w *ProfileWatcher) Dismiss() {
+	w.mutex.Lock()
+	w.pending = nil
+	w.mutex.Unlock()
+}
+
+// Close stops watching the layer files.
+func (w *ProfileWatcher) Close() error {
+	w.mutex.Lock()
+	for _, timer := range w.timers {
+		timer.Stop()
+	}
+	w.mutex.Unlock()
+	return w.watcher.Close()
+}
+
+// ActiveProfileFile returns the profile file of the loaded profile.
+func ActiveProfileFile() string {
+	if Layers != nil && Layers.profileFile != "" {
+		return Layers.profileFile
+	}
+	return filepath.Join(GetConfProfileDir(), Config.SettingsFilename)
+}
+
+// WriteActiveProfile writes the loaded settings to the profile file.
+func WriteActiveProfile() error {
+	if Layers != nil {
+		conf := Config
+		conf.Extends = Layers.Extends()
+		return Layers.writeOverrides(conf)
+	}
+	return Config.WriteYamlSettings(ActiveProfileFile())
+}
+
+// StartProfileWatcher watches the layer files of the loaded profile. onChange is called with the pending changes
+// when new external changes are detected, the caller asks to reload or overwrite them.
+func StartProfileWatcher(onChange func(diffs []SettingDiff)) error {
+	if ActiveProfileWatcher != nil {
+		_ = ActiveProfileWatcher.Close()
+		ActiveProfileWatcher = nil
+	}
+	if Layers == nil {
+		return fmt.Errorf("no profile loaded")
+	}
+	profileWatcher, err := WatchProfile(Layers, onChange)
+	if err != nil {
+		return err
+	}
+	ActiveProfileWatcher = profileWatcher
+	return nil
+}
@@ -0,0 +1,179 @@
+package Settings
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"whispering-tiger-ui/SendMessageChannel"
+)
+
+func TestProfileWatcherFileLayer(t *testing.T) {
+	withBaseProfile(t, "")
+	l := loadTestLayers(t, map[string]string{
+		"top.yaml":    "osc_port: 9002\n",
+		"parent.yaml": "extends: top.yaml\n",
+		"other.yaml":  "",
+		"p.yaml":      "extends: parent.yaml\n",
+	}, "p.yaml")
+	w := &ProfileWatcher{layers: l}
+	profilesDir := filepath.Dir(l.profileFile)
+	tests := []struct {
+		file      string
+		wantLayer Layer
+		watched   bool
+	}{
+		{file: l.profileFile, wantLayer: LayerProfile, watched: true},
+		{file: filepath.Join(profilesDir, "parent.yaml"), wantLayer: LayerParent, watched: true},
+		{file: filepath.Join(profilesDir, "top.yaml"), wantLayer: LayerParent, watched: true},
+		{file: GetBaseProfileFile(), wantLayer: LayerBase, watched: true},
+		{file: l.BackendSettingsFile(), wantLayer: LayerRuntime, watched: true},
+		{file: filepath.Join(profilesDir, "other.yaml")},
+		{file: filepath.Join(profilesDir, "p.yaml.tmp")},
+	}
+	for _, tt := range tests {
+		t.Run(filepath.Base(tt.file), func(t *testing.T) {
+			layer, watched := w.fileLayer(filepath.Clean(tt.file))
+			if watched != tt.watched || (watched && layer != tt.wantLayer) {
+				t.Errorf("fileLayer() = %v, %v, want %v, %v", layer, watched, tt.wantLayer, tt.watched)
+			}
+		})
+	}
+}
+
+func TestProfileWatcherCheck(t *testing.T) {
+	withBaseProfile(t, "osc_port: 9001\n")
+	l := loadTestLayers(t, map[string]string{
+		"parent.yaml": "osc_ip: 10.0.0.1\n",
+		"p.yaml":      "extends: parent.yaml\ntts_answer: true\n",
+	}, "p.yaml")
+	previousConfig, previousLayers := Config, Layers
+	Config, Layers = l.Effective(), l
+	defer func() { Config, Layers = previousConfig, previousLayers }()
+
+	w, err := WatchProfile(l, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if diffs := w.Check(); len(diffs) > 0 {
+		t.Fatalf("Check() = %v, want no changes", diffs)
+	}
+
+	profilesDir := filepath.Dir(l.profileFile)
+	writeTestFiles(t, profilesDir, map[string]string{
+		"p.yaml":      "extends: parent.yaml\ntts_answer: false\nosc_port: 9001\n",
+		"parent.yaml": "osc_ip: 10.0.0.2\n",
+	})
+	withBaseProfile(t, "osc_port: 9001\nosc_type_transfer: both\n")
+
+	diffs := w.Check()
+	want := map[string]SettingDiff{
+		"tts_answer":        {File: false, Layer: LayerProfile},
+		"osc_ip":            {File: "10.0.0.2", Layer: LayerParent},
+		"osc_type_transfer": {File: "both", Layer: LayerBase},
+	}
+	if len(diffs) != len(want) {
+		t.Fatalf("Check() = %+v, want %d changes", diffs, len(want))
+	}
+	for _, diff := range diffs {
+		wantDiff, ok := want[diff.Name]
+		if !ok || diff.File != wantDiff.File || diff.Layer != wantDiff.Layer {
+			t.Errorf("Check() diff = %+v, want %+v", diff, wantDiff)
+		}
+	}
+	// osc_port in the profile file equals the loaded value, it is not reported
+	if diffs := w.Check(); len(diffs) != len(want) {
+		t.Errorf("pending changes = %v, want them kept until reloaded or dismissed", diffs)
+	}
+
+	sent := make(chan string, 10)
+	go func() {
+		for message := range SendMessageChannel.SendMessageChannel {
+			sent <- message.Type + " " + message.Name
+			if message.Type == "setting_update_req" {
+				return
+			}
+		}
+	}()
+	reloaded := w.Reload()
+	if len(reloaded) != len(want) || Config.Tts_answer || Config.Osc_ip != "10.0.0.2" || Config.Osc_type_transfer != "both" {
+		t.Errorf("Reload() = %v, Config = tts %v ip %s transfer %s", reloaded, Config.Tts_answer, Config.Osc_ip, Config.Osc_type_transfer)
+	}
+	if l.Source("tts_answer") != LayerProfile || l.Source("osc_type_transfer") != LayerBase {
+		t.Errorf("reloaded settings have the sources %v and %v", l.Source("tts_answer"), l.Source("osc_type_transfer"))
+	}
+	for i := 0; i < len(want)+1; i++ {
+		select {
+		case <-sent:
+		case <-time.After(time.Second):
+			t.Fatalf("reloaded settings were not sent to the backend")
+		}
+	}
+	if diffs := w.Pending(); len(diffs) > 0 {
+		t.Errorf("Pending() = %v after Reload()", diffs)
+	}
+}
+
+func TestProfileWatcherEvents(t *testing.T) {
+	withBaseProfile(t, "")
+	l := loadTestLayers(t, map[string]string{"p.yaml": "tts_answer: true\n"}, "p.yaml")
+	previousConfig, previousLayers := Config, Layers
+	Config, Layers = l.Effective(), l
+	defer func() { Config, Layers = previousConfig, previousLayers }()
+
+	changes := make(chan []SettingDiff, 1)
+	w, err := WatchProfile(l, func(diffs []SettingDiff) { changes <- diffs })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if err := os.WriteFile(l.profileFile, []byte("tts_answer: false\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case diffs := <-changes:
+		if len(diffs) != 1 || diffs[0].Name != "tts_answer" {
+			t.Errorf("OnChange() diffs = %v", diffs)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("change of the profile file was not reported")
+	}
+}
+
+func TestProfileWatcherOwnWrites(t *testing.T) {
+	withBaseProfile(t, "")
+	l := loadTestLayers(t, map[string]string{"p.yaml": "tts_answer: true\n"}, "p.yaml")
+	previousConfig, previousLayers := Config, Layers
+	Config, Layers = l.Effective(), l
+	defer func() { Config, Layers = previousConfig, previousLayers }()
+
+	changes := make(chan []SettingDiff, 1)
+	w, err := WatchProfile(l, func(diffs []SettingDiff) { changes <- diffs })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// a file written by the application is no external change, even if it differs from the loaded settings
+	if err := writeOwnFile(l.profileFile, []byte("tts_answer: false\n")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case diffs := <-changes:
+		t.Fatalf("own write reported as change: %v", diffs)
+	case <-time.After(4 * watchDebounce):
+	}
+
+	if err := os.WriteFile(l.profileFile, []byte("tts_answer: false\nosc_port: 9005\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case diffs := <-changes:
+		if len(diffs) != 1 || diffs[0].Name != "osc_port" {
+			t.Errorf("OnChange() diffs = %v, want only the external change", diffs)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("external change after an own write was not reported")
+	}
+}
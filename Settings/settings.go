@@ -18,6 +18,7 @@ import (
 	"strconv"
 	"strings"
 	"whispering-tiger-ui/Logging"
+	"whispering-tiger-ui/Migrations"
 	"whispering-tiger-ui/SendMessageChannel"
 	"whispering-tiger-ui/Utilities"
 )
@@ -41,10 +42,37 @@ func GetConfProfileDir() string {
 	return dir
 }
 
+// GetUiDataDir returns the directory for data that is only used by the UI (not loaded as profile).
+func GetUiDataDir() string {
+	exe, err := os.Executable()
+	if err != nil {
+		// Fallback to current working directory
+		dir := filepath.Join(".", "UiData")
+		_ = os.MkdirAll(dir, 0o755)
+		return dir
+	}
+
+	// Resolve symlinks where possible
+	if realExe, e := filepath.EvalSymlinks(exe); e == nil {
+		exe = realExe
+	}
+
+	dir := filepath.Join(filepath.Dir(exe), "UiData")
+	_ = os.MkdirAll(dir, 0o755)
+	return dir
+}
+
+// GetProfileBackupDir returns the directory for profile backups made before migrations.
+func GetProfileBackupDir() string {
+	return filepath.Join(GetUiDataDir(), "ProfileBackups")
+}
+
 //goland:noinspection GoSnakeCaseUsage
 type Conf struct {
 	// Internal Profile Settings
 	SettingsFilename string
+	Schema_version   int         `yaml:"schema_version" json:"schema_version"`
+	Extends          string      `yaml:"extends,omitempty" json:"extends,omitempty"`
 	Process_id       int         `yaml:"process_id" json:"process_id"`
 	Device_index     interface{} `yaml:"device_index,omitempty" json:"device_index,omitempty"`
 	Device_out_index interface{} `yaml:"device_out_index,omitempty" json:"device_out_index,omitempty"`
@@ -227,6 +255,8 @@ var ExcludeConfigFields = []string{
 	"run_backend",
 	"ui_download",
 	"settingsfilename",
+	"schema_version",
+	"extends",
 	"tts_model",
 	"tts_answer",
 	"device_index",
@@ -266,7 +296,8 @@ func confLoader(c interface{}, configFile string) interface{} {
 		}
 		err = yaml.Unmarshal(yamlFile, c)
 		if err != nil {
-			log.Fatalf("Unmarshal: %v", err)
+			log.Printf("Unmarshal: %v", err)
+			Logging.CaptureException(err)
 		}
 	} else {
 		err := fmt.Errorf("Config file %s not found", configFile)
@@ -291,87 +322,144 @@ func (c *Conf) GetOption(option string) (interface{}, error) {
 	return nil, fmt.Errorf("option %s not found", option)
 }
 
-func (c *Conf) SetOption(optionName string, value interface{}) {
-	switch value.(type) {
-	case string:
-		// if string value is an integer, convert it
-		intValue, err := strconv.Atoi(value.(string))
-		if err == nil {
-			value = intValue
-		}
+// SetOption sets a field by its (case-insensitive) name.
+// The value is converted to the field type and checked against the schema. Invalid values are not set.
+func (c *Conf) SetOption(optionName string, value interface{}) error {
+	fieldSchema, ok := SchemaForField(optionName)
+	if !ok {
+		return unknownSettingError(optionName, 0)
 	}
-
-	values := reflect.ValueOf(c)
-	indirectValues := reflect.Indirect(values) // required to indirect the pointer
-	types := indirectValues.Type()
-	for i := 0; i < indirectValues.NumField(); i++ {
-		if strings.ToLower(types.Field(i).Name) == strings.ToLower(optionName) {
-			setValue := reflect.ValueOf(value)
-			if value == nil {
-				setValue = reflect.Zero(types.Field(i).Type)
-			}
-			switch types.Field(i).Type.Kind() {
-			// TODO: fix case where the value is a string and the field is a slice (like in tts_model)
-			case reflect.Slice:
-				switch value.(type) {
-				case string:
-					setValue = reflect.ValueOf([]string{value.(string)})
-				}
-			case reflect.String:
-				switch value.(type) {
-				case int:
-					setValue = reflect.ValueOf(strconv.Itoa(value.(int)))
-				}
-			case reflect.Int:
-				switch value.(type) {
-				case string:
-					tmpValue, _ := strconv.Atoi(value.(string))
-					setValue = reflect.ValueOf(tmpValue)
-				case float64:
-					tmpValue := int(value.(float64))
-					setValue = reflect.ValueOf(tmpValue)
-				}
-			case reflect.Float64:
-				switch value.(type) {
-				case string:
-					tmpValue, _ := strconv.ParseFloat(value.(string), 64)
-					setValue = reflect.ValueOf(tmpValue)
-				case int:
-					tmpValue := float64(value.(int))
-					setValue = reflect.ValueOf(tmpValue)
-				}
-
-			}
-			indirectValues.Field(i).Set(setValue)
-			return
-		}
+	setValue, err := fieldSchema.convert(value)
+	if err != nil {
+		return err
 	}
+	if validationError := fieldSchema.check(setValue.Interface()); validationError != nil {
+		return validationError
+	}
+	reflect.ValueOf(c).Elem().Field(fieldSchema.index).Set(setValue)
+	return nil
 }
 
+// LoadYamlSettings loads a profile file.
+// Invalid entries are skipped (keeping the current value) and returned as ValidationErrors after loading the valid ones.
 func (c *Conf) LoadYamlSettings(fileName string) error {
 	yamlFile, err := os.ReadFile(fileName)
 	if err != nil {
 		log.Printf("yamlFile.Get err   #%v ", err)
 		return err
 	}
-	err = yaml.Unmarshal(yamlFile, &c)
+	var document yaml.Node
+	err = yaml.Unmarshal(yamlFile, &document)
 	if err != nil {
 		//log.Fatalf("Unmarshal: %v", err)
 		return err
 	}
+	if document.Kind == 0 {
+		return nil
+	}
+	validationErrors := validateYamlDocument(&document)
+	err = document.Decode(c)
+	if err != nil {
+		return err
+	}
+	if len(validationErrors) > 0 {
+		return fmt.Errorf("%s:\n%w", filepath.Base(fileName), validationErrors)
+	}
 	return nil
 }
 
-func (c *Conf) WriteYamlSettings(fileName string) {
+// WriteYamlSettings saves the settings as profile file.
+// Invalid settings are not written: the file keeps its previous valid value or the key is left out.
+// The valid settings are saved in any case, the invalid ones are returned as ValidationErrors.
+func (c *Conf) WriteYamlSettings(fileName string) error {
+	if c.Schema_version == 0 {
+		c.Schema_version = Migrations.LatestVersion()
+	}
 	// marshal the struct to yaml and save as file
 	yamlFile, err := yaml.Marshal(c)
 	if err != nil {
 		log.Printf("error: %v", err)
+		return err
+	}
+	validationErrors := c.Validate()
+	if len(validationErrors) > 0 {
+		log.Printf("error: not saving invalid settings to %s: %v", fileName, validationErrors)
+		if yamlFile, err = replaceInvalidSettings(yamlFile, fileName, validationErrors); err != nil {
+			log.Printf("error: %v", err)
+			return err
+		}
 	}
 	err = os.WriteFile(fileName, yamlFile, 0644)
 	if err != nil {
 		log.Printf("error: %v", err)
+		return err
+	}
+	if len(validationErrors) > 0 {
+		return fmt.Errorf("%s: invalid settings were not saved:\n%w", filepath.Base(fileName), validationErrors)
+	}
+	return nil
+}
+
+// replaceInvalidSettings replaces the invalid settings of a marshalled profile with their valid values in the existing file.
+// Settings without valid value in the existing file are removed.
+func replaceInvalidSettings(yamlFile []byte, fileName string, validationErrors ValidationErrors) ([]byte, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(yamlFile, &document); err != nil {
+		return nil, err
+	}
+	previousValues := map[string]*yaml.Node{}
+	if previousFile, err := os.ReadFile(fileName); err == nil {
+		var previousDocument yaml.Node
+		if yaml.Unmarshal(previousFile, &previousDocument) == nil {
+			// removes the invalid entries, so only valid previous values are kept
+			validateYamlDocument(&previousDocument)
+			if len(previousDocument.Content) > 0 && previousDocument.Content[0].Kind == yaml.MappingNode {
+				previousMapping := previousDocument.Content[0]
+				for i := 0; i+1 < len(previousMapping.Content); i += 2 {
+					previousValues[previousMapping.Content[i].Value] = previousMapping.Content[i+1]
+				}
+			}
+		}
+	}
+
+	mapping := document.Content[0]
+	var content []*yaml.Node
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		keyNode, valueNode := mapping.Content[i], mapping.Content[i+1]
+		if validationErrors.Field(keyNode.Value) != nil {
+			previousValue, ok := previousValues[keyNode.Value]
+			if !ok {
+				continue
+			}
+			valueNode = previousValue
+		}
+		content = append(content, keyNode, valueNode)
+	}
+	mapping.Content = content
+	return yaml.Marshal(&document)
+}
+
+// LoadBackendSettings returns the settings of the loaded profile from the settings file of the backend,
+// which keeps values like the plugin settings up to date.
+// Invalid entries of the file keep their current value, if the file cannot be read the loaded settings are returned.
+func LoadBackendSettings() Conf {
+	settingsFile := Conf{}
+	err := settingsFile.LoadYamlSettings(GetBackendSettingsFile())
+	var validationErrors ValidationErrors
+	if err != nil && !errors.As(err, &validationErrors) {
+		return Config
 	}
+	return settingsFile
+}
+
+// LoadPluginSettings returns the plugin settings of the loaded profile from the settings file of the backend.
+// The returned map is never nil.
+func LoadPluginSettings() map[string]interface{} {
+	pluginSettings, _ := LoadBackendSettings().Plugin_settings.(map[string]interface{})
+	if pluginSettings == nil {
+		pluginSettings = map[string]interface{}{}
+	}
+	return pluginSettings
 }
 
 var Form *widget.Form
@@ -390,26 +478,19 @@ func GetSettingValues(settingField string) ([]string, error) {
 	return nil, errors.New("no values for field '" + settingField + "'")
 }
 
+// MergeSettings returns firstConf with the values of secondConf applied on top.
+// Empty optional values (omitempty) of secondConf keep the value of firstConf.
 func MergeSettings(firstConf Conf, secondConf Conf) Conf {
-	yamlFileFirst, err := yaml.Marshal(firstConf)
-	if err != nil {
-		log.Printf("error: %v", err)
-	}
-	yamlFileSecond, err := yaml.Marshal(secondConf)
-	if err != nil {
-		log.Printf("error: %v", err)
-	}
-
-	var mergedConf Conf
-	err = yaml.Unmarshal(yamlFileFirst, &mergedConf)
-	if err != nil {
-		log.Printf("error: %v", err)
-	}
-	err = yaml.Unmarshal(yamlFileSecond, &mergedConf)
-	if err != nil {
-		log.Printf("error: %v", err)
+	merged := NewLayeredConfig(firstConf)
+	secondValue := reflect.ValueOf(secondConf)
+	for _, fieldSchema := range schema {
+		field := secondValue.Field(fieldSchema.index)
+		if fieldSchema.omitEmpty && field.IsZero() {
+			continue
+		}
+		merged.values[LayerRuntime][fieldSchema.Name] = field.Interface()
 	}
-	return mergedConf
+	return merged.Effective()
 }
 
 func BuildSettingsForm(includeConfigFields []string, settingsFile string) fyne.CanvasObject {
@@ -425,7 +506,9 @@ func BuildSettingsForm(includeConfigFields []string, settingsFile string) fyne.C
 	var settingsFileConf = Conf{}
 	MergedConfig := settingsFileConf
 	if Config.Run_backend {
-		settingsFileConf.GetConf(settingsFile)
+		if err := settingsFileConf.LoadYamlSettings(settingsFile); err != nil {
+			log.Printf("error loading %s: %v", settingsFile, err)
+		}
 		MergedConfig = MergeSettings(Config, settingsFileConf)
 	} else {
 		MergedConfig = Config
@@ -543,11 +626,34 @@ func BuildSettingsForm(includeConfigFields []string, settingsFile string) fyne.C
 		}
 	}
 
+	// mark invalid fields and validate entered values against the schema
+	validationErrors := MergedConfig.Validate()
+	for _, item := range settingsForm.Items {
+		if entry, ok := item.Widget.(*widget.Entry); ok {
+			optionName := item.Text
+			entry.Validator = func(s string) error {
+				if s == "None" {
+					return nil
+				}
+				_, err := ValidateOption(optionName, s)
+				return err
+			}
+		}
+		if validationError := validationErrors.Field(item.Text); validationError != nil {
+			item.HintText = validationError.Error()
+		}
+	}
+
 	settingsForm.SubmitText = "Save"
 
 	if settingsFile != "" {
 		settingsForm.OnSubmit = func() {
-			needsSettingUpdate := false
+			type changedSetting struct {
+				name  string
+				value interface{}
+			}
+			var changedSettings []changedSetting
+			var submitErrors ValidationErrors
 			for _, item := range settingsForm.Items {
 				var value interface{} = nil
 				switch item.Widget.(type) {
@@ -563,36 +669,46 @@ func BuildSettingsForm(includeConfigFields []string, settingsFile string) fyne.C
 					}
 				case *widget.Check:
 					value = item.Widget.(*widget.Check).Checked
+				default:
+					continue
 				}
 
 				preChangeOption, err := MergedConfig.GetOption(item.Text)
-				sendValue := value
-				if err == nil {
-					switch preChangeOption.(type) {
-					case int:
-						switch value.(type) {
-						case string:
-							sendValue, _ = strconv.Atoi(value.(string))
-						}
-					case float64:
-						switch value.(type) {
-						case string:
-							sendValue, _ = strconv.ParseFloat(value.(string), 64)
-						}
+				if err != nil {
+					continue
+				}
+				sendValue, err := ValidateOption(item.Text, value)
+				if err != nil {
+					var validationError *ValidationError
+					if errors.As(err, &validationError) {
+						submitErrors = append(submitErrors, validationError)
 					}
-					if preChangeOption != sendValue {
-						needsSettingUpdate = true
-						sendMessage := SendMessageChannel.SendMessageStruct{
-							Type:  "setting_change",
-							Name:  item.Text,
-							Value: sendValue,
-						}
-						sendMessage.SendMessage()
+					continue
+				}
+				if value == nil {
+					sendValue = nil
+				}
+				if !reflect.DeepEqual(preChangeOption, sendValue) {
+					changedSettings = append(changedSettings, changedSetting{name: item.Text, value: sendValue})
+				}
+			}
+			if len(submitErrors) > 0 {
+				dialog.ShowError(submitErrors, fyne.CurrentApp().Driver().AllWindows()[0])
+				return
+			}
 
-						Config.SetOption(item.Text, value)
-						MergedConfig.SetOption(item.Text, value)
-					}
+			needsSettingUpdate := false
+			for _, setting := range changedSettings {
+				needsSettingUpdate = true
+				sendMessage := SendMessageChannel.SendMessageStruct{
+					Type:  "setting_change",
+					Name:  setting.name,
+					Value: setting.value,
 				}
+				sendMessage.SendMessage()
+
+				_ = Config.SetOption(setting.name, setting.value)
+				_ = MergedConfig.SetOption(setting.name, setting.value)
 			}
 			if needsSettingUpdate {
 				sendMessage := SendMessageChannel.SendMessageStruct{
@@ -601,11 +717,23 @@ func BuildSettingsForm(includeConfigFields []string, settingsFile string) fyne.C
 				sendMessage.SendMessage()
 			}
 
-			if Config.Run_backend {
-				MergedConfig.WriteYamlSettings(settingsFile)
+			writeSettings := func() {
+				if Config.Run_backend {
+					if err := MergedConfig.WriteYamlSettings(settingsFile); err != nil {
+						dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
+						return
+					}
+				}
+
+				dialog.ShowInformation(lang.L("Settings Saved"), lang.L("Settings have been saved to settingsFile This might require a restart of the application for some changes to take effect.", map[string]interface{}{"SettingsFile": settingsFile}), fyne.CurrentApp().Driver().AllWindows()[0])
 			}
 
-			dialog.ShowInformation(lang.L("Settings Saved"), lang.L("Settings have been saved to settingsFile This might require a restart of the application for some changes to take effect.", map[string]interface{}{"SettingsFile": settingsFile}), fyne.CurrentApp().Driver().AllWindows()[0])
+			// ask before overwriting changes made to the profile files outside the application
+			if ActiveProfileWatcher != nil && OnPendingProfileChanges != nil && len(ActiveProfileWatcher.Check()) > 0 {
+				OnPendingProfileChanges(writeSettings)
+				return
+			}
+			writeSettings()
 		}
 	}
 
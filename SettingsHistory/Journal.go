@@ -0,0 +1,318 @@
+package SettingsHistory
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// pluginSettingPrefix marks entries of plugin settings, named "plugin_settings.<PluginClass>.<setting>".
+const pluginSettingPrefix = "plugin_settings."
+
+// pluginEnabledPrefix marks entries that enable or disable a plugin, named "plugins.<PluginClass>".
+const pluginEnabledPrefix = "plugins."
+
+// DefaultMaxEntries limits the number of entries of a journal. The oldest entries are removed first.
+const DefaultMaxEntries = 500
+
+// mergeWindow is the time in which changes of the same setting are merged into one entry, so typing into a field is one change.
+const mergeWindow = 3 * time.Second
+
+// Entry is a single change of a setting.
+type Entry struct {
+	Time     time.Time   `yaml:"time"`
+	Name     string      `yaml:"name"`
+	OldValue interface{} `yaml:"old_value"`
+	NewValue interface{} `yaml:"new_value"`
+}
+
+// Journal records the setting changes of a profile. Undone entries are kept for redo until a new change is recorded.
+type Journal struct {
+	mutex      sync.Mutex
+	fileName   string
+	maxEntries int
+
+	entries []Entry
+	// position is the number of entries that are applied. Entries after it can be redone.
+	position int
+
+	pluginSettings map[string]map[string]interface{}
+
+	// Apply sets a setting to a value during undo, redo or revert. It must not record the change again.
+	Apply func(name string, value interface{})
+	// OnChanged is called after entries were added or the position changed.
+	OnChanged func()
+}
+
+type journalFile struct {
+	Position int     `yaml:"position"`
+	Entries  []Entry `yaml:"entries"`
+}
+
+// Active is the journal of the loaded profile. nil until a profile is loaded.
+var Active *Journal
+
+// New creates an empty journal that is stored in fileName. An empty fileName keeps the journal in memory.
+func New(fileName string) *Journal {
+	return &Journal{
+		fileName:       fileName,
+		maxEntries:     DefaultMaxEntries,
+		pluginSettings: map[string]map[string]interface{}{},
+	}
+}
+
+// Load reads a journal file. A missing file results in an empty journal.
+func Load(fileName string) (*Journal, error) {
+	j := New(fileName)
+	data, err := os.ReadFile(fileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return j, nil
+	}
+	if err != nil {
+		return j, err
+	}
+	var file journalFile
+	if err = yaml.Unmarshal(data, &file); err != nil {
+		return j, err
+	}
+	j.entries = file.Entries
+	j.position = min(max(file.Position, 0), len(j.entries))
+	return j, nil
+}
+
+// Save writes the journal file.
+func (j *Journal) Save() error {
+	j.mutex.Lock()
+	file := journalFile{Position: j.position, Entries: j.entries}
+	j.mutex.Unlock()
+	if j.fileName == "" {
+		return nil
+	}
+	data, err := yaml.Marshal(file)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(j.fileName), 0755); err != nil {
+		return err
+	}
+	tmpFile := j.fileName + ".tmp"
+	if err = os.WriteFile(tmpFile, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, j.fileName)
+}
+
+func (j *Journal) changed() {
+	_ = j.Save()
+	if j.OnChanged != nil {
+		j.OnChanged()
+	}
+}
+
+// Record adds a change. Changes that can be redone are discarded.
+// A change of the same setting shortly after the previous one is merged into it.
+func (j *Journal) Record(name string, oldValue, newValue interface{}) {
+	if reflect.DeepEqual(oldValue, newValue) {
+		return
+	}
+	now := time.Now()
+	j.mutex.Lock()
+	j.entries = j.entries[:j.position]
+	if last := len(j.entries) - 1; last >= 0 && j.entries[last].Name == name && now.Sub(j.entries[last].Time) < mergeWindow {
+		j.entries[last].Time = now
+		j.entries[last].NewValue = newValue
+		if reflect.DeepEqual(j.entries[last].OldValue, newValue) {
+			j.entries = j.entries[:last]
+		}
+	} else {
+		j.entries = append(j.entries, Entry{Time: now, Name: name, OldValue: oldValue, NewValue: newValue})
+	}
+	if j.maxEntries > 0 && len(j.entries) > j.maxEntries {
+		j.entries = append([]Entry{}, j.entries[len(j.entries)-j.maxEntries:]...)
+	}
+	j.position = len(j.entries)
+	j.mutex.Unlock()
+	j.changed()
+}
+
+// TrackPluginSettings remembers the settings of a plugin, so later changes can be recorded per setting.
+func (j *Journal) TrackPluginSettings(pluginClass string, settings map[string]interface{}) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.pluginSettings[pluginClass] = copyValue(settings).(map[string]interface{})
+}
+
+// RecordPluginSettings records every setting of a plugin that changed since the settings were tracked or last recorded.
+func (j *Journal) RecordPluginSettings(pluginClass string, settings map[string]interface{}) {
+	j.mutex.Lock()
+	previousSettings, tracked := j.pluginSettings[pluginClass]
+	j.pluginSettings[pluginClass] = copyValue(settings).(map[string]interface{})
+	j.mutex.Unlock()
+	if !tracked {
+		return
+	}
+	for name, value := range settings {
+		if oldValue, ok := previousSettings[name]; !ok || !reflect.DeepEqual(oldValue, value) {
+			j.Record(PluginSettingName(pluginClass, name), oldValue, copyValue(value))
+		}
+	}
+}
+
+// Entries returns all entries, including the ones that can be redone.
+func (j *Journal) Entries() []Entry {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return append([]Entry{}, j.entries...)
+}
+
+// Position returns the number of applied entries.
+func (j *Journal) Position() int {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.position
+}
+
+func (j *Journal) CanUndo() bool {
+	return j.Position() > 0
+}
+
+func (j *Journal) CanRedo() bool {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.position < len(j.entries)
+}
+
+// Undo reverts the last applied change.
+func (j *Journal) Undo() bool {
+	return j.RevertTo(j.Position() - 1)
+}
+
+// Redo applies the next undone change.
+func (j *Journal) Redo() bool {
+	return j.RevertTo(j.Position() + 1)
+}
+
+// RevertTo undoes or redoes changes until the given number of entries is applied.
+// RevertTo(0) restores the settings from before the first recorded change.
+func (j *Journal) RevertTo(position int) bool {
+	j.mutex.Lock()
+	if position < 0 || position > len(j.entries) || position == j.position {
+		j.mutex.Unlock()
+		return false
+	}
+	// collect the value each setting has at the target position
+	values := map[string]interface{}{}
+	var names []string
+	for j.position != position {
+		var name string
+		var value interface{}
+		if j.position > position {
+			j.position--
+			name, value = j.entries[j.position].Name, j.entries[j.position].OldValue
+		} else {
+			name, value = j.entries[j.position].Name, j.entries[j.position].NewValue
+			j.position++
+		}
+		if _, ok := values[name]; !ok {
+			names = append(names, name)
+		}
+		values[name] = value
+	}
+	j.mutex.Unlock()
+
+	if j.Apply != nil {
+		for _, name := range names {
+			value := copyValue(values[name])
+			if pluginClass, setting, ok := SplitPluginSettingName(name); ok {
+				j.mutex.Lock()
+				if settings, tracked := j.pluginSettings[pluginClass]; tracked {
+					settings[setting] = copyValue(value)
+				}
+				j.mutex.Unlock()
+			}
+			j.Apply(name, value)
+		}
+	}
+	j.changed()
+	return true
+}
+
+// Clear removes all entries.
+func (j *Journal) Clear() {
+	j.mutex.Lock()
+	j.entries = nil
+	j.position = 0
+	j.mutex.Unlock()
+	j.changed()
+}
+
+// PluginSettingName returns the entry name of a plugin setting.
+func PluginSettingName(pluginClass, setting string) string {
+	return pluginSettingPrefix + pluginClass + "." + setting
+}
+
+// SplitPluginSettingName returns the plugin class and setting of a plugin setting entry name.
+func SplitPluginSettingName(name string) (string, string, bool) {
+	if !strings.HasPrefix(name, pluginSettingPrefix) {
+		return "", "", false
+	}
+	pluginClass, setting, ok := strings.Cut(strings.TrimPrefix(name, pluginSettingPrefix), ".")
+	return pluginClass, setting, ok
+}
+
+// PluginEnabledName returns the entry name of the enabled state of a plugin.
+func PluginEnabledName(pluginClass string) string {
+	return pluginEnabledPrefix + pluginClass
+}
+
+// SplitPluginEnabledName returns the plugin class of an enabled state entry name.
+func SplitPluginEnabledName(name string) (string, bool) {
+	pluginClass, ok := strings.CutPrefix(name, pluginEnabledPrefix)
+	return pluginClass, ok && pluginClass != ""
+}
+
+// copyValue copies maps and lists, since plugin settings are changed in place.
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			copied[key] = copyValue(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = copyValue(item)
+		}
+		return copied
+	}
+	return value
+}
+
+// Record adds a change to the journal of the loaded profile.
+func Record(name string, oldValue, newValue interface{}) {
+	if Active != nil {
+		Active.Record(name, copyValue(oldValue), copyValue(newValue))
+	}
+}
+
+// TrackPluginSettings remembers the settings of a plugin in the journal of the loaded profile.
+func TrackPluginSettings(pluginClass string, settings map[string]interface{}) {
+	if Active != nil && settings != nil {
+		Active.TrackPluginSettings(pluginClass, settings)
+	}
+}
+
+// RecordPluginSettings records the changed settings of a plugin in the journal of the loaded profile.
+func RecordPluginSettings(pluginClass string, settings map[string]interface{}) {
+	if Active != nil && settings != nil {
+		Active.RecordPluginSettings(pluginClass, settings)
+	}
+}
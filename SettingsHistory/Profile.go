@@ -0,0 +1,88 @@
+package SettingsHistory
+
+import (
+	"errors"
+	"log"
+	"path/filepath"
+	"strings"
+	"whispering-tiger-ui/SendMessageChannel"
+	"whispering-tiger-ui/Settings"
+)
+
+const historyDir = "SettingsHistory"
+
+// GetHistoryFile returns the journal file of a profile.
+func GetHistoryFile(profileFileName string) string {
+	profileName := strings.TrimSuffix(filepath.Base(profileFileName), filepath.Ext(profileFileName))
+	return filepath.Join(Settings.GetUiDataDir(), historyDir, profileName+".yaml")
+}
+
+// LoadActive loads the journal of the loaded profile. A journal that can not be read is replaced by an empty one.
+func LoadActive(profileFileName string) error {
+	journal, err := Load(GetHistoryFile(profileFileName))
+	if err != nil {
+		journal = New(GetHistoryFile(profileFileName))
+	}
+	journal.Apply = ApplySetting
+	Active = journal
+	return err
+}
+
+// ApplySetting sets a setting of the loaded profile and sends it to the backend, without recording the change.
+func ApplySetting(name string, value interface{}) {
+	if pluginClass, setting, ok := SplitPluginSettingName(name); ok {
+		applyPluginSetting(pluginClass, setting, value)
+		return
+	}
+	if pluginClass, ok := SplitPluginEnabledName(name); ok {
+		enabled, _ := value.(bool)
+		if Settings.Config.Plugins == nil {
+			Settings.Config.Plugins = map[string]bool{}
+		}
+		Settings.Config.Plugins[pluginClass] = enabled
+		sendMessage := SendMessageChannel.SendMessageStruct{
+			Type:  "setting_change",
+			Name:  "plugins",
+			Value: Settings.Config.Plugins,
+		}
+		sendMessage.SendMessage()
+		return
+	}
+
+	if err := Settings.SetRuntimeOption(name, value); err != nil && !errors.Is(err, Settings.ErrUnknownSetting) {
+		log.Printf("error applying setting %s: %v", name, err)
+		return
+	}
+	// send the converted value, values read from the journal file can have another type than the setting
+	if convertedValue, err := Settings.Config.GetOption(name); err == nil && convertedValue != nil {
+		value = convertedValue
+	}
+	sendMessage := SendMessageChannel.SendMessageStruct{
+		Type:  "setting_change",
+		Name:  name,
+		Value: value,
+	}
+	sendMessage.SendMessage()
+}
+
+func applyPluginSetting(pluginClass, setting string, value interface{}) {
+	pluginSettings := Settings.LoadPluginSettings()
+	classSettings, _ := pluginSettings[pluginClass].(map[string]interface{})
+	if classSettings == nil {
+		classSettings = map[string]interface{}{}
+	}
+	if value == nil {
+		delete(classSettings, setting)
+	} else {
+		classSettings[setting] = value
+	}
+	pluginSettings[pluginClass] = classSettings
+	Settings.Config.Plugin_settings = pluginSettings
+
+	sendMessage := SendMessageChannel.SendMessageStruct{
+		Type:  "setting_change",
+		Name:  "plugin_settings",
+		Value: pluginSettings,
+	}
+	sendMessage.SendMessage()
+}
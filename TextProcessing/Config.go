@@ -0,0 +1,155 @@
+package TextProcessing
+
+import (
+	"gopkg.in/yaml.v3"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"whispering-tiger-ui/Settings"
+)
+
+const configFileName = "text_processing.yaml"
+
+// Target is the place where processed text is used.
+type Target string
+
+const (
+	TargetTranscript  Target = "transcript"
+	TargetTranslation Target = "translation"
+	TargetTts         Target = "tts"
+	TargetOsc         Target = "osc"
+)
+
+// ReplaceRule is a single find/replace rule. Rules are applied in list order.
+type ReplaceRule struct {
+	Enabled       bool   `yaml:"enabled" json:"enabled"`
+	Find          string `yaml:"find" json:"find"`
+	Replace       string `yaml:"replace" json:"replace"`
+	Regex         bool   `yaml:"regex" json:"regex"`
+	CaseSensitive bool   `yaml:"case_sensitive" json:"case_sensitive"`
+}
+
+// GlossaryEntry protects a term from being translated.
+// Language limits the entry to a source language code (empty = all languages).
+// Translations optionally maps a target language code to the term used in that language.
+type GlossaryEntry struct {
+	Term         string            `yaml:"term" json:"term"`
+	Language     string            `yaml:"language,omitempty" json:"language,omitempty"`
+	Translations map[string]string `yaml:"translations,omitempty" json:"translations,omitempty"`
+}
+
+type Config struct {
+	Enabled            bool `yaml:"enabled" json:"enabled"`
+	ApplyTranscripts   bool `yaml:"apply_transcripts" json:"apply_transcripts"`
+	ApplyTranslations  bool `yaml:"apply_translations" json:"apply_translations"`
+	ApplyTts           bool `yaml:"apply_tts" json:"apply_tts"`
+	ApplyOsc           bool `yaml:"apply_osc" json:"apply_osc"`
+	GlossaryProtection bool `yaml:"glossary_protection" json:"glossary_protection"`
+
+	ReplaceRules   []ReplaceRule   `yaml:"replace_rules" json:"replace_rules"`
+	Glossary       []GlossaryEntry `yaml:"glossary" json:"glossary"`
+	ProfanityWords []string        `yaml:"profanity_words" json:"profanity_words"`
+	ProfanityMask  string          `yaml:"profanity_mask" json:"profanity_mask"`
+}
+
+var DefaultConfig = Config{
+	Enabled:            false,
+	ApplyTranscripts:   true,
+	ApplyTranslations:  true,
+	ApplyTts:           true,
+	ApplyOsc:           true,
+	GlossaryProtection: true,
+	ProfanityMask:      "*",
+}
+
+var (
+	currentConfig = DefaultConfig
+	configMutex   sync.RWMutex
+)
+
+func ConfigFilePath() string {
+	return filepath.Join(Settings.GetUiDataDir(), configFileName)
+}
+
+// Load reads the text processing configuration from disk. A missing file keeps the default configuration.
+func Load() error {
+	conf := DefaultConfig
+	fileName := ConfigFilePath()
+	if _, err := os.Stat(fileName); err == nil {
+		yamlFile, err := os.ReadFile(fileName)
+		if err != nil {
+			return err
+		}
+		if err = yaml.Unmarshal(yamlFile, &conf); err != nil {
+			return err
+		}
+	}
+	SetConfig(conf)
+	return nil
+}
+
+// Save writes the current configuration to disk.
+func Save() error {
+	yamlFile, err := yaml.Marshal(GetConfig())
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(ConfigFilePath(), yamlFile, 0644)
+}
+
+// GetConfig returns a copy of the current configuration.
+func GetConfig() Config {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+	return currentConfig.clone()
+}
+
+// SetConfig replaces the current configuration and recompiles the rules.
+func SetConfig(conf Config) {
+	compiled := compile(conf)
+
+	configMutex.Lock()
+	currentConfig = conf.clone()
+	compiledPipeline = compiled
+	configMutex.Unlock()
+
+	if compiled.errors != nil {
+		for _, err := range compiled.errors {
+			log.Printf("text processing: %v", err)
+		}
+	}
+}
+
+func (c Config) clone() Config {
+	cloned := c
+	cloned.ReplaceRules = append([]ReplaceRule(nil), c.ReplaceRules...)
+	cloned.ProfanityWords = append([]string(nil), c.ProfanityWords...)
+	cloned.Glossary = make([]GlossaryEntry, 0, len(c.Glossary))
+	for _, entry := range c.Glossary {
+		translations := make(map[string]string, len(entry.Translations))
+		for k, v := range entry.Translations {
+			translations[k] = v
+		}
+		entry.Translations = translations
+		cloned.Glossary = append(cloned.Glossary, entry)
+	}
+	return cloned
+}
+
+func (c Config) appliesTo(target Target) bool {
+	if !c.Enabled {
+		return false
+	}
+	switch target {
+	case TargetTranscript:
+		return c.ApplyTranscripts
+	case TargetTranslation:
+		return c.ApplyTranslations
+	case TargetTts:
+		return c.ApplyTts
+	case TargetOsc:
+		return c.ApplyOsc
+	}
+	return false
+}
@@ -0,0 +1,267 @@
+package TextProcessing
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// glossaryPlaceholder is inserted instead of protected glossary terms before translation.
+// Translators tend to keep numbers in curly braces untouched.
+const glossaryPlaceholder = "{{%d}}"
+
+var glossaryPlaceholderRegex = regexp.MustCompile(`\{\{\s*(\d+)\s*}}`)
+
+type compiledRule struct {
+	rule  ReplaceRule
+	regex *regexp.Regexp
+}
+
+type compiledGlossaryEntry struct {
+	entry GlossaryEntry
+	regex *regexp.Regexp
+}
+
+type pipeline struct {
+	conf      Config
+	rules     []compiledRule
+	glossary  []compiledGlossaryEntry
+	profanity *regexp.Regexp
+	errors    []error
+}
+
+var compiledPipeline = compile(DefaultConfig)
+
+// wordBoundaryPattern wraps the quoted term in word boundaries where the term starts or ends with a word character.
+// Terms in scripts without spaces (e.g. CJK) are matched anywhere.
+func wordBoundaryPattern(term string) string {
+	pattern := regexp.QuoteMeta(term)
+	first, _ := utf8.DecodeRuneInString(term)
+	last, _ := utf8.DecodeLastRuneInString(term)
+	if first < utf8.RuneSelf && (unicode.IsLetter(first) || unicode.IsDigit(first)) {
+		pattern = `\b` + pattern
+	}
+	if last < utf8.RuneSelf && (unicode.IsLetter(last) || unicode.IsDigit(last)) {
+		pattern = pattern + `\b`
+	}
+	return pattern
+}
+
+func compile(conf Config) *pipeline {
+	p := &pipeline{conf: conf}
+
+	for i, rule := range conf.ReplaceRules {
+		if !rule.Enabled || rule.Find == "" {
+			continue
+		}
+		pattern := rule.Find
+		if !rule.Regex {
+			pattern = regexp.QuoteMeta(pattern)
+		}
+		if !rule.CaseSensitive {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			p.errors = append(p.errors, fmt.Errorf("replace rule %d (%s): %w", i+1, rule.Find, err))
+			continue
+		}
+		p.rules = append(p.rules, compiledRule{rule: rule, regex: re})
+	}
+
+	for _, entry := range conf.Glossary {
+		term := strings.TrimSpace(entry.Term)
+		if term == "" {
+			// keep index positions stable for placeholders
+			p.glossary = append(p.glossary, compiledGlossaryEntry{entry: entry})
+			continue
+		}
+		re, err := regexp.Compile("(?i)" + wordBoundaryPattern(term))
+		if err != nil {
+			p.errors = append(p.errors, fmt.Errorf("glossary term %s: %w", term, err))
+		}
+		p.glossary = append(p.glossary, compiledGlossaryEntry{entry: entry, regex: re})
+	}
+
+	var profanityPatterns []string
+	for _, word := range conf.ProfanityWords {
+		word = strings.TrimSpace(word)
+		if word == "" {
+			continue
+		}
+		profanityPatterns = append(profanityPatterns, wordBoundaryPattern(word))
+	}
+	if len(profanityPatterns) > 0 {
+		re, err := regexp.Compile("(?i)(?:" + strings.Join(profanityPatterns, "|") + ")")
+		if err != nil {
+			p.errors = append(p.errors, fmt.Errorf("profanity list: %w", err))
+		} else {
+			p.profanity = re
+		}
+	}
+
+	return p
+}
+
+func (p *pipeline) process(text string) string {
+	for _, rule := range p.rules {
+		if rule.rule.Regex {
+			text = rule.regex.ReplaceAllString(text, rule.rule.Replace)
+		} else {
+			text = rule.regex.ReplaceAllLiteralString(text, rule.rule.Replace)
+		}
+	}
+	if p.profanity != nil {
+		mask := p.conf.ProfanityMask
+		if mask == "" {
+			mask = "*"
+		}
+		text = p.profanity.ReplaceAllStringFunc(text, func(match string) string {
+			maskRunes := []rune(mask)
+			if len(maskRunes) == 1 {
+				return strings.Repeat(mask, utf8.RuneCountInString(match))
+			}
+			return mask
+		})
+	}
+	return text
+}
+
+func languageMatches(entryLanguage string, language string) bool {
+	if entryLanguage == "" || language == "" || strings.EqualFold(language, "auto") {
+		return true
+	}
+	if strings.EqualFold(entryLanguage, language) {
+		return true
+	}
+	// allow matching "eng" against "eng_Latn" and similar
+	entryBase, _, _ := strings.Cut(entryLanguage, "_")
+	languageBase, _, _ := strings.Cut(language, "_")
+	return strings.EqualFold(entryBase, languageBase)
+}
+
+func (p *pipeline) protect(text string, sourceLanguage string) (string, bool) {
+	protected := false
+	for i, entry := range p.glossary {
+		if entry.regex == nil || !languageMatches(entry.entry.Language, sourceLanguage) {
+			continue
+		}
+		placeholder := fmt.Sprintf(glossaryPlaceholder, i)
+		if entry.regex.MatchString(text) {
+			text = entry.regex.ReplaceAllLiteralString(text, placeholder)
+			protected = true
+		}
+	}
+	return text, protected
+}
+
+func (p *pipeline) restore(text string, targetLanguage string) string {
+	return glossaryPlaceholderRegex.ReplaceAllStringFunc(text, func(match string) string {
+		submatch := glossaryPlaceholderRegex.FindStringSubmatch(match)
+		index, err := strconv.Atoi(submatch[1])
+		if err != nil || index < 0 || index >= len(p.glossary) {
+			return match
+		}
+		entry := p.glossary[index].entry
+		if term, ok := glossaryTranslation(entry.Translations, targetLanguage); ok {
+			return term
+		}
+		return entry.Term
+	})
+}
+
+// glossaryTranslation returns the translation of a glossary term for the target language.
+// An exact language match is preferred, otherwise the first matching language in sorted order is used,
+// so the result does not depend on the order of the map.
+func glossaryTranslation(translations map[string]string, targetLanguage string) (string, bool) {
+	if targetLanguage == "" {
+		return "", false
+	}
+	languages := slices.Sorted(maps.Keys(translations))
+	for _, language := range languages {
+		if translations[language] != "" && strings.EqualFold(language, targetLanguage) {
+			return translations[language], true
+		}
+	}
+	for _, language := range languages {
+		if translations[language] != "" && languageMatches(language, targetLanguage) {
+			return translations[language], true
+		}
+	}
+	return "", false
+}
+
+func currentPipeline() *pipeline {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+	return compiledPipeline
+}
+
+// Process applies the replace rules and the profanity filter to text that is used for the given target.
+func Process(text string, target Target) string {
+	p := currentPipeline()
+	if text == "" || !p.conf.appliesTo(target) {
+		return text
+	}
+	return p.process(text)
+}
+
+// OutputBackendOptions returns the backend options that would output text the pipeline changes.
+// The backend outputs transcripts before the UI gets them, so these outputs have to be done by the UI.
+func OutputBackendOptions() []string {
+	p := currentPipeline()
+	if len(p.rules) == 0 && p.profanity == nil {
+		return nil
+	}
+	var options []string
+	if p.conf.appliesTo(TargetTts) {
+		options = append(options, "tts_answer")
+	}
+	if p.conf.appliesTo(TargetOsc) {
+		options = append(options, "osc_auto_processing_enabled")
+	}
+	return options
+}
+
+// ProtectGlossary replaces glossary terms with placeholders, so they survive a translation.
+// Returns true if at least one term was protected.
+// Only translations requested by the UI are protected. Transcripts the backend translates by itself (txt_translate)
+// are translated before the UI gets them, their glossary terms are not protected.
+func ProtectGlossary(text string, sourceLanguage string) (string, bool) {
+	p := currentPipeline()
+	if text == "" || !p.conf.Enabled || !p.conf.GlossaryProtection {
+		return text, false
+	}
+	return p.protect(text, sourceLanguage)
+}
+
+// RestoreGlossary replaces glossary placeholders with the term for the target language.
+func RestoreGlossary(text string, targetLanguage string) string {
+	p := currentPipeline()
+	if text == "" || !strings.Contains(text, "{") {
+		return text
+	}
+	return p.restore(text, targetLanguage)
+}
+
+// Preview runs the full pipeline with a not yet applied configuration.
+// It returns the text sent to the translator, the final text and any rule errors.
+func Preview(conf Config, text string, sourceLanguage string, targetLanguage string) (protectedText string, result string, errs []error) {
+	p := compile(conf)
+	protectedText = text
+	if conf.GlossaryProtection {
+		protectedText, _ = p.protect(text, sourceLanguage)
+	}
+	result = p.process(p.restore(protectedText, targetLanguage))
+	return protectedText, result, p.errors
+}
+
+// ValidationErrors returns the errors of rules that could not be compiled.
+func ValidationErrors(conf Config) []error {
+	return compile(conf).errors
+}
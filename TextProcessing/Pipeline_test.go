@@ -0,0 +1,218 @@
+package TextProcessing
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProcess(t *testing.T) {
+	tests := []struct {
+		name   string
+		conf   Config
+		target Target
+		text   string
+		want   string
+	}{
+		{
+			name:   "disabled pipeline keeps the text",
+			conf:   Config{Enabled: false, ApplyTts: true, ProfanityWords: []string{"darn"}},
+			target: TargetTts,
+			text:   "darn it",
+			want:   "darn it",
+		},
+		{
+			name:   "target not applied keeps the text",
+			conf:   Config{Enabled: true, ApplyTts: false, ProfanityWords: []string{"darn"}},
+			target: TargetTts,
+			text:   "darn it",
+			want:   "darn it",
+		},
+		{
+			name:   "profanity is masked per character",
+			conf:   Config{Enabled: true, ApplyOsc: true, ProfanityWords: []string{"darn"}, ProfanityMask: "*"},
+			target: TargetOsc,
+			text:   "Darn it, darnation",
+			want:   "**** it, darnation",
+		},
+		{
+			name:   "profanity with a mask word",
+			conf:   Config{Enabled: true, ApplyOsc: true, ProfanityWords: []string{"darn"}, ProfanityMask: "[beep]"},
+			target: TargetOsc,
+			text:   "darn",
+			want:   "[beep]",
+		},
+		{
+			name: "replace rules in order",
+			conf: Config{Enabled: true, ApplyTranscripts: true, ReplaceRules: []ReplaceRule{
+				{Enabled: true, Find: "colour", Replace: "color"},
+				{Enabled: true, Find: `(\d+)\s*km`, Replace: "$1 kilometers", Regex: true},
+				{Enabled: false, Find: "color", Replace: "hue"},
+			}},
+			target: TargetTranscript,
+			text:   "Colour 5km",
+			want:   "color 5 kilometers",
+		},
+		{
+			name: "case sensitive rule",
+			conf: Config{Enabled: true, ApplyTranscripts: true, ReplaceRules: []ReplaceRule{
+				{Enabled: true, Find: "Tiger", Replace: "Lion", CaseSensitive: true},
+			}},
+			target: TargetTranscript,
+			text:   "tiger Tiger",
+			want:   "tiger Lion",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetConfig(tt.conf)
+			t.Cleanup(func() { SetConfig(DefaultConfig) })
+			if got := Process(tt.text, tt.target); got != tt.want {
+				t.Errorf("Process(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGlossary(t *testing.T) {
+	glossary := []GlossaryEntry{
+		{Term: "Whispering Tiger"},
+		{Term: "Katze", Language: "de", Translations: map[string]string{"en": "Kitty"}},
+	}
+	tests := []struct {
+		name          string
+		protection    bool
+		text          string
+		fromLang      string
+		toLang        string
+		wantProtected string
+		wantOk        bool
+		wantRestored  string
+	}{
+		{
+			name:          "term is protected and restored",
+			protection:    true,
+			text:          "I like Whispering Tiger",
+			fromLang:      "en",
+			toLang:        "de",
+			wantProtected: "I like {{0}}",
+			wantOk:        true,
+			wantRestored:  "I like Whispering Tiger",
+		},
+		{
+			name:          "term of another source language is not protected",
+			protection:    true,
+			text:          "Katze",
+			fromLang:      "en",
+			wantProtected: "Katze",
+			wantOk:        false,
+			wantRestored:  "Katze",
+		},
+		{
+			name:          "translated term for the target language",
+			protection:    true,
+			text:          "Die Katze",
+			fromLang:      "de",
+			toLang:        "en",
+			wantProtected: "Die {{1}}",
+			wantOk:        true,
+			wantRestored:  "Die Kitty",
+		},
+		{
+			name:          "protection disabled",
+			protection:    false,
+			text:          "Whispering Tiger",
+			wantProtected: "Whispering Tiger",
+			wantOk:        false,
+			wantRestored:  "Whispering Tiger",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetConfig(Config{Enabled: true, GlossaryProtection: tt.protection, Glossary: glossary})
+			t.Cleanup(func() { SetConfig(DefaultConfig) })
+			protected, ok := ProtectGlossary(tt.text, tt.fromLang)
+			if protected != tt.wantProtected || ok != tt.wantOk {
+				t.Fatalf("ProtectGlossary(%q) = %q, %v, want %q, %v", tt.text, protected, ok, tt.wantProtected, tt.wantOk)
+			}
+			if restored := RestoreGlossary(protected, tt.toLang); restored != tt.wantRestored {
+				t.Errorf("RestoreGlossary(%q) = %q, want %q", protected, restored, tt.wantRestored)
+			}
+		})
+	}
+}
+
+func TestGlossaryTranslation(t *testing.T) {
+	translations := map[string]string{"por_Latn": "gato (pt)", "por": "gato", "por_Arab": "", "eng_Latn": "cat", "deu_Latn": "Katze"}
+	tests := []struct {
+		name           string
+		targetLanguage string
+		want           string
+		wantOk         bool
+	}{
+		{name: "exact match before prefix matches", targetLanguage: "por", want: "gato", wantOk: true},
+		{name: "exact match with script", targetLanguage: "por_Latn", want: "gato (pt)", wantOk: true},
+		{name: "exact match ignores case", targetLanguage: "ENG_LATN", want: "cat", wantOk: true},
+		{name: "first prefix match in sorted order", targetLanguage: "por_Cyrl", want: "gato", wantOk: true},
+		{name: "empty translation is skipped", targetLanguage: "por_Arab", want: "gato", wantOk: true},
+		{name: "prefix match", targetLanguage: "deu", want: "Katze", wantOk: true},
+		{name: "no translation", targetLanguage: "fra_Latn"},
+		{name: "no target language"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the map order changes between runs, the result must not
+			for i := 0; i < 20; i++ {
+				got, ok := glossaryTranslation(translations, tt.targetLanguage)
+				if got != tt.want || ok != tt.wantOk {
+					t.Fatalf("glossaryTranslation(%q) = %q, %v, want %q, %v", tt.targetLanguage, got, ok, tt.want, tt.wantOk)
+				}
+			}
+		})
+	}
+}
+
+func TestOutputBackendOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		conf Config
+		want []string
+	}{
+		{
+			name: "disabled",
+			conf: Config{Enabled: false, ApplyTts: true, ApplyOsc: true, ProfanityWords: []string{"darn"}},
+		},
+		{
+			name: "nothing that changes text",
+			conf: Config{Enabled: true, ApplyTts: true, ApplyOsc: true, Glossary: []GlossaryEntry{{Term: "Tiger"}}},
+		},
+		{
+			name: "profanity filter on TTS and OSC",
+			conf: Config{Enabled: true, ApplyTts: true, ApplyOsc: true, ProfanityWords: []string{"darn"}},
+			want: []string{"tts_answer", "osc_auto_processing_enabled"},
+		},
+		{
+			name: "replace rule on OSC only",
+			conf: Config{Enabled: true, ApplyOsc: true, ReplaceRules: []ReplaceRule{{Enabled: true, Find: "a", Replace: "b"}}},
+			want: []string{"osc_auto_processing_enabled"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetConfig(tt.conf)
+			t.Cleanup(func() { SetConfig(DefaultConfig) })
+			if got := OutputBackendOptions(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("OutputBackendOptions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidationErrors(t *testing.T) {
+	conf := Config{ReplaceRules: []ReplaceRule{
+		{Enabled: true, Find: "(", Regex: true},
+		{Enabled: true, Find: "(", Regex: false},
+	}}
+	if errs := ValidationErrors(conf); len(errs) != 1 {
+		t.Errorf("ValidationErrors() = %v, want one error for the invalid regex", errs)
+	}
+}
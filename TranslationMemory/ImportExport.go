@@ -0,0 +1,252 @@
+package TranslationMemory
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TMX 1.4 structures. Reading and writing use different types, because encoding/xml
+// can not match the xml:lang attribute by the same tag it writes.
+
+const tmxTimeFormat = "20060102T150405Z"
+
+type tmxProp struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type tmxWriteTuv struct {
+	Lang string `xml:"xml:lang,attr"`
+	Seg  string `xml:"seg"`
+}
+
+type tmxWriteTu struct {
+	LastUsageDate string        `xml:"lastusagedate,attr,omitempty"`
+	UsageCount    int           `xml:"usagecount,attr,omitempty"`
+	Props         []tmxProp     `xml:"prop"`
+	Tuvs          []tmxWriteTuv `xml:"tuv"`
+}
+
+type tmxHeader struct {
+	CreationTool        string `xml:"creationtool,attr"`
+	CreationToolVersion string `xml:"creationtoolversion,attr"`
+	SegType             string `xml:"segtype,attr"`
+	OTmf                string `xml:"o-tmf,attr"`
+	AdminLang           string `xml:"adminlang,attr"`
+	SrcLang             string `xml:"srclang,attr"`
+	DataType            string `xml:"datatype,attr"`
+}
+
+type tmxWriteDocument struct {
+	XMLName xml.Name     `xml:"tmx"`
+	Version string       `xml:"version,attr"`
+	Header  tmxHeader    `xml:"header"`
+	Tus     []tmxWriteTu `xml:"body>tu"`
+}
+
+type tmxReadTuv struct {
+	Lang    string `xml:"lang,attr"`
+	OldLang string `xml:"http://www.lisa.org/tmx14 lang,attr"`
+	Seg     string `xml:"seg"`
+}
+
+type tmxReadTu struct {
+	LastUsageDate string       `xml:"lastusagedate,attr"`
+	UsageCount    int          `xml:"usagecount,attr"`
+	SrcLang       string       `xml:"srclang,attr"`
+	Props         []tmxProp    `xml:"prop"`
+	Tuvs          []tmxReadTuv `xml:"tuv"`
+}
+
+type tmxReadDocument struct {
+	Header struct {
+		SrcLang string `xml:"srclang,attr"`
+	} `xml:"header"`
+	Tus []tmxReadTu `xml:"body>tu"`
+}
+
+const (
+	tmxPropModel     = "x-model"
+	tmxPropCorrected = "x-corrected"
+)
+
+var csvHeader = []string{"source", "translation", "from_lang", "to_lang", "model", "corrected"}
+
+// ExportTMX writes all entries as TMX 1.4 document.
+func ExportTMX(writer io.Writer, entries []Entry) error {
+	document := tmxWriteDocument{
+		Version: "1.4",
+		Header: tmxHeader{
+			CreationTool:        "Whispering Tiger",
+			CreationToolVersion: "1",
+			SegType:             "sentence",
+			OTmf:                "whispering-tiger",
+			AdminLang:           "en",
+			SrcLang:             "*all*",
+			DataType:            "plaintext",
+		},
+	}
+	for _, entry := range entries {
+		tu := tmxWriteTu{
+			UsageCount: entry.Hits,
+			Props: []tmxProp{
+				{Type: tmxPropModel, Value: entry.Model},
+				{Type: tmxPropCorrected, Value: strconv.FormatBool(entry.Corrected)},
+			},
+			Tuvs: []tmxWriteTuv{
+				{Lang: entry.FromLang, Seg: entry.Source},
+				{Lang: entry.ToLang, Seg: entry.Translation},
+			},
+		}
+		if !entry.LastUsed.IsZero() {
+			tu.LastUsageDate = entry.LastUsed.UTC().Format(tmxTimeFormat)
+		}
+		document.Tus = append(document.Tus, tu)
+	}
+	if _, err := io.WriteString(writer, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(writer)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// ImportTMX reads a TMX document. Translation units with more than two languages are split
+// into one entry per target language, using the header or unit source language as source.
+func ImportTMX(reader io.Reader) ([]Entry, error) {
+	var document tmxReadDocument
+	if err := xml.NewDecoder(reader).Decode(&document); err != nil {
+		return nil, fmt.Errorf("invalid TMX file: %w", err)
+	}
+	var entries []Entry
+	for _, tu := range document.Tus {
+		if len(tu.Tuvs) < 2 {
+			continue
+		}
+		entry := Entry{Hits: tu.UsageCount}
+		for _, prop := range tu.Props {
+			switch prop.Type {
+			case tmxPropModel:
+				entry.Model = prop.Value
+			case tmxPropCorrected:
+				entry.Corrected, _ = strconv.ParseBool(prop.Value)
+			}
+		}
+		if tu.LastUsageDate != "" {
+			entry.LastUsed, _ = time.Parse(tmxTimeFormat, tu.LastUsageDate)
+		}
+
+		sourceLang := tu.SrcLang
+		if sourceLang == "" {
+			sourceLang = document.Header.SrcLang
+		}
+		sourceIndex := 0
+		for i, tuv := range tu.Tuvs {
+			if sourceLang != "" && strings.EqualFold(tuvLang(tuv), sourceLang) {
+				sourceIndex = i
+				break
+			}
+		}
+		source := tu.Tuvs[sourceIndex]
+		for i, tuv := range tu.Tuvs {
+			if i == sourceIndex {
+				continue
+			}
+			unitEntry := entry
+			unitEntry.Source = source.Seg
+			unitEntry.FromLang = tuvLang(source)
+			unitEntry.Translation = tuv.Seg
+			unitEntry.ToLang = tuvLang(tuv)
+			entries = append(entries, unitEntry)
+		}
+	}
+	return entries, nil
+}
+
+func tuvLang(tuv tmxReadTuv) string {
+	if tuv.Lang != "" {
+		return tuv.Lang
+	}
+	return tuv.OldLang
+}
+
+// ExportCSV writes all entries as CSV with a header row.
+func ExportCSV(writer io.Writer, entries []Entry) error {
+	csvWriter := csv.NewWriter(writer)
+	if err := csvWriter.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		err := csvWriter.Write([]string{
+			entry.Source,
+			entry.Translation,
+			entry.FromLang,
+			entry.ToLang,
+			entry.Model,
+			strconv.FormatBool(entry.Corrected),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// ImportCSV reads CSV written by ExportCSV. Columns are matched by the header row,
+// "source" and "translation" are required.
+func ImportCSV(reader io.Reader) ([]Entry, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV file: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	if _, ok := columns["source"]; !ok {
+		return nil, errors.New("invalid CSV file: missing \"source\" column")
+	}
+	if _, ok := columns["translation"]; !ok {
+		return nil, errors.New("invalid CSV file: missing \"translation\" column")
+	}
+	column := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return record[i]
+	}
+
+	var entries []Entry
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV file: %w", err)
+		}
+		corrected, _ := strconv.ParseBool(column(record, "corrected"))
+		entries = append(entries, Entry{
+			Source:      column(record, "source"),
+			Translation: column(record, "translation"),
+			FromLang:    column(record, "from_lang"),
+			ToLang:      column(record, "to_lang"),
+			Model:       column(record, "model"),
+			Corrected:   corrected,
+		})
+	}
+	return entries, nil
+}
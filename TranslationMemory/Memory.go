@@ -0,0 +1,271 @@
+package TranslationMemory
+
+import (
+	"container/list"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"whispering-tiger-ui/Settings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const memoryFileName = "translation_memory.json"
+
+const DefaultMaxEntries = 5000
+
+// Entry is a single translation unit of the memory.
+// Corrected entries were edited by the user and are never overwritten by machine translations.
+type Entry struct {
+	Source      string    `json:"source"`
+	Translation string    `json:"translation"`
+	FromLang    string    `json:"from_lang"`
+	ToLang      string    `json:"to_lang"`
+	Model       string    `json:"model"`
+	Corrected   bool      `json:"corrected"`
+	Hits        int       `json:"hits"`
+	LastUsed    time.Time `json:"last_used"`
+}
+
+type memoryFile struct {
+	// MaxEntries is nil in files without the key, which use DefaultMaxEntries. 0 means unlimited.
+	MaxEntries *int    `json:"max_entries"`
+	Entries    []Entry `json:"entries"`
+}
+
+type Memory struct {
+	sync.Mutex
+	maxEntries int
+	// lru holds *Entry values, the most recently used entry is at the front.
+	lru   *list.List
+	index map[string]*list.Element
+	dirty bool
+}
+
+var Default = New(DefaultMaxEntries)
+
+func New(maxEntries int) *Memory {
+	return &Memory{
+		maxEntries: maxEntries,
+		lru:        list.New(),
+		index:      map[string]*list.Element{},
+	}
+}
+
+// NormalizeText unifies unicode representation and whitespace, so trivially different inputs share an entry.
+func NormalizeText(text string) string {
+	text = norm.NFC.String(text)
+	return strings.Join(strings.FieldsFunc(text, unicode.IsSpace), " ")
+}
+
+// CurrentModel returns the identifier of the configured text translator.
+// Options that change the translation result are part of it, so they don't share entries.
+func CurrentModel() string {
+	model := Settings.Config.Txt_translator
+	if Settings.Config.Txt_translator_size != "" {
+		model += "/" + Settings.Config.Txt_translator_size
+	}
+	if Settings.Config.Txt_romaji {
+		model += "/romaji"
+	}
+	if Settings.Config.Txt_second_translation_enabled && Settings.Config.Txt_second_translation_languages != "" {
+		model += "/+" + Settings.Config.Txt_second_translation_languages
+	}
+	return model
+}
+
+func entryKey(source, fromLang, toLang, model string) string {
+	return strings.Join([]string{
+		NormalizeText(source),
+		strings.ToLower(fromLang),
+		strings.ToLower(toLang),
+		strings.ToLower(model),
+	}, "\x00")
+}
+
+func (m *Memory) FilePath() string {
+	return filepath.Join(Settings.GetUiDataDir(), memoryFileName)
+}
+
+// Lookup returns the translation for the given source text, if it is known.
+func (m *Memory) Lookup(source, fromLang, toLang, model string) (Entry, bool) {
+	m.Lock()
+	defer m.Unlock()
+	element, ok := m.index[entryKey(source, fromLang, toLang, model)]
+	if !ok {
+		return Entry{}, false
+	}
+	entry := element.Value.(*Entry)
+	entry.Hits++
+	entry.LastUsed = time.Now()
+	m.lru.MoveToFront(element)
+	m.dirty = true
+	return *entry, true
+}
+
+// Store adds a machine translation. Existing user corrections are kept.
+func (m *Memory) Store(source, translation, fromLang, toLang, model string) {
+	m.put(Entry{Source: source, Translation: translation, FromLang: fromLang, ToLang: toLang, Model: model}, false)
+}
+
+// Correct stores a translation edited by the user. It replaces any machine translation of the same source.
+func (m *Memory) Correct(source, translation, fromLang, toLang, model string) {
+	m.put(Entry{Source: source, Translation: translation, FromLang: fromLang, ToLang: toLang, Model: model, Corrected: true}, true)
+}
+
+func (m *Memory) put(entry Entry, overwriteCorrected bool) {
+	if NormalizeText(entry.Source) == "" || strings.TrimSpace(entry.Translation) == "" {
+		return
+	}
+	m.Lock()
+	defer m.Unlock()
+	entry.Source = NormalizeText(entry.Source)
+	if entry.LastUsed.IsZero() {
+		entry.LastUsed = time.Now()
+	}
+	key := entryKey(entry.Source, entry.FromLang, entry.ToLang, entry.Model)
+	if element, ok := m.index[key]; ok {
+		existing := element.Value.(*Entry)
+		if existing.Corrected && !overwriteCorrected {
+			m.lru.MoveToFront(element)
+			return
+		}
+		entry.Hits = existing.Hits
+		*existing = entry
+		m.lru.MoveToFront(element)
+	} else {
+		m.index[key] = m.lru.PushFront(&entry)
+	}
+	m.dirty = true
+	m.evict()
+}
+
+// evict removes the least recently used entries above the size limit. Expects the lock to be held.
+func (m *Memory) evict() {
+	if m.maxEntries <= 0 {
+		return
+	}
+	for m.lru.Len() > m.maxEntries {
+		element := m.lru.Back()
+		entry := element.Value.(*Entry)
+		delete(m.index, entryKey(entry.Source, entry.FromLang, entry.ToLang, entry.Model))
+		m.lru.Remove(element)
+		m.dirty = true
+	}
+}
+
+func (m *Memory) MaxEntries() int {
+	m.Lock()
+	defer m.Unlock()
+	return m.maxEntries
+}
+
+// SetMaxEntries changes the size limit. 0 means unlimited.
+func (m *Memory) SetMaxEntries(maxEntries int) {
+	m.Lock()
+	defer m.Unlock()
+	if maxEntries < 0 {
+		maxEntries = 0
+	}
+	m.maxEntries = maxEntries
+	m.dirty = true
+	m.evict()
+}
+
+func (m *Memory) Len() int {
+	m.Lock()
+	defer m.Unlock()
+	return m.lru.Len()
+}
+
+// Entries returns a copy of all entries, most recently used first.
+func (m *Memory) Entries() []Entry {
+	m.Lock()
+	defer m.Unlock()
+	entries := make([]Entry, 0, m.lru.Len())
+	for element := m.lru.Front(); element != nil; element = element.Next() {
+		entries = append(entries, *element.Value.(*Entry))
+	}
+	return entries
+}
+
+func (m *Memory) Clear() {
+	m.Lock()
+	defer m.Unlock()
+	m.lru.Init()
+	m.index = map[string]*list.Element{}
+	m.dirty = true
+}
+
+// AddEntries merges entries (e.g. from an import) into the memory and returns the number of added entries.
+func (m *Memory) AddEntries(entries []Entry) int {
+	added := 0
+	// insert oldest first, so the most recently used entries end up at the front
+	for i := len(entries) - 1; i >= 0; i-- {
+		if NormalizeText(entries[i].Source) == "" || strings.TrimSpace(entries[i].Translation) == "" {
+			continue
+		}
+		m.put(entries[i], entries[i].Corrected)
+		added++
+	}
+	return added
+}
+
+// Load reads the memory from disk. A missing file results in an empty memory.
+func (m *Memory) Load() error {
+	data, err := os.ReadFile(m.FilePath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var file memoryFile
+	if err = json.Unmarshal(data, &file); err != nil {
+		return err
+	}
+	m.Lock()
+	m.lru.Init()
+	m.index = map[string]*list.Element{}
+	m.maxEntries = DefaultMaxEntries
+	if file.MaxEntries != nil && *file.MaxEntries >= 0 {
+		m.maxEntries = *file.MaxEntries
+	}
+	m.Unlock()
+	m.AddEntries(file.Entries)
+	m.Lock()
+	m.dirty = false
+	m.Unlock()
+	return nil
+}
+
+// Save writes the memory to disk if it was changed since the last load or save.
+func (m *Memory) Save() error {
+	m.Lock()
+	if !m.dirty {
+		m.Unlock()
+		return nil
+	}
+	maxEntries := m.maxEntries
+	file := memoryFile{MaxEntries: &maxEntries}
+	for element := m.lru.Front(); element != nil; element = element.Next() {
+		file.Entries = append(file.Entries, *element.Value.(*Entry))
+	}
+	m.dirty = false
+	m.Unlock()
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err == nil {
+		err = os.WriteFile(m.FilePath(), data, 0644)
+	}
+	if err != nil {
+		m.Lock()
+		m.dirty = true
+		m.Unlock()
+	}
+	return err
+}
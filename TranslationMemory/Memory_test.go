@@ -0,0 +1,132 @@
+package TranslationMemory
+
+import (
+	"os"
+	"testing"
+)
+
+func TestLoadMaxEntries(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		want int
+	}{
+		{name: "missing key uses default", file: `{"entries": []}`, want: DefaultMaxEntries},
+		{name: "zero is unlimited", file: `{"max_entries": 0, "entries": []}`, want: 0},
+		{name: "explicit limit", file: `{"max_entries": 10, "entries": []}`, want: 10},
+		{name: "negative uses default", file: `{"max_entries": -1, "entries": []}`, want: DefaultMaxEntries},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New(123)
+			if err := os.WriteFile(m.FilePath(), []byte(tt.file), 0644); err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { _ = os.Remove(m.FilePath()) })
+			if err := m.Load(); err != nil {
+				t.Fatal(err)
+			}
+			if got := m.MaxEntries(); got != tt.want {
+				t.Errorf("MaxEntries() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveLoadKeepsUnlimited(t *testing.T) {
+	m := New(DefaultMaxEntries)
+	m.SetMaxEntries(0)
+	m.Store("hello", "hallo", "en", "de", "m")
+	t.Cleanup(func() { _ = os.Remove(m.FilePath()) })
+	if err := m.Save(); err != nil {
+		t.Fatal(err)
+	}
+	loaded := New(DefaultMaxEntries)
+	if err := loaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.MaxEntries() != 0 || loaded.Len() != 1 {
+		t.Errorf("loaded MaxEntries() = %d, Len() = %d, want 0 and 1", loaded.MaxEntries(), loaded.Len())
+	}
+}
+
+func TestMemory(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxEntries int
+		run        func(m *Memory)
+		source     string
+		want       string
+		wantFound  bool
+		wantLen    int
+	}{
+		{
+			name:       "normalized source is found",
+			maxEntries: 10,
+			run:        func(m *Memory) { m.Store("hello   world", "hallo welt", "en", "de", "m") },
+			source:     " hello world ",
+			want:       "hallo welt",
+			wantFound:  true,
+			wantLen:    1,
+		},
+		{
+			name:       "correction is not overwritten by machine translation",
+			maxEntries: 10,
+			run: func(m *Memory) {
+				m.Correct("hello", "servus", "en", "de", "m")
+				m.Store("hello", "hallo", "en", "de", "m")
+			},
+			source:    "hello",
+			want:      "servus",
+			wantFound: true,
+			wantLen:   1,
+		},
+		{
+			name:       "least recently used entry is evicted",
+			maxEntries: 2,
+			run: func(m *Memory) {
+				m.Store("one", "eins", "en", "de", "m")
+				m.Store("two", "zwei", "en", "de", "m")
+				m.Lookup("one", "en", "de", "m")
+				m.Store("three", "drei", "en", "de", "m")
+			},
+			source:    "two",
+			wantFound: false,
+			wantLen:   2,
+		},
+		{
+			name:       "unlimited memory keeps all entries",
+			maxEntries: 0,
+			run: func(m *Memory) {
+				m.Store("one", "eins", "en", "de", "m")
+				m.Store("two", "zwei", "en", "de", "m")
+				m.Store("three", "drei", "en", "de", "m")
+			},
+			source:    "one",
+			want:      "eins",
+			wantFound: true,
+			wantLen:   3,
+		},
+		{
+			name:       "other model does not share entries",
+			maxEntries: 10,
+			run:        func(m *Memory) { m.Store("hello", "hallo", "en", "de", "other") },
+			source:     "hello",
+			wantFound:  false,
+			wantLen:    1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New(tt.maxEntries)
+			tt.run(m)
+			entry, found := m.Lookup(tt.source, "en", "de", "m")
+			if found != tt.wantFound || entry.Translation != tt.want {
+				t.Errorf("Lookup(%q) = %q, %v, want %q, %v", tt.source, entry.Translation, found, tt.want, tt.wantFound)
+			}
+			if m.Len() != tt.wantLen {
+				t.Errorf("Len() = %d, want %d", m.Len(), tt.wantLen)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package Translator
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Request is a translate_req that was sent to the backend and waits for its translate_result.
+// translate_result has no request id, so results are matched by the original text the backend echoes.
+type Request struct {
+	SourceText     string // text before glossary protection
+	SentText       string // text as sent to the backend
+	SourceLanguage string
+	TargetLanguage string
+	Model          string
+	// Protected is true if glossary terms in SentText were replaced with placeholders.
+	Protected bool
+	// DeferredSend is true if the UI sends the result to the enabled outputs instead of the backend.
+	DeferredSend bool
+
+	sentAt time.Time
+}
+
+var (
+	requestsMutex   sync.Mutex
+	pendingRequests []*Request
+)
+
+// AddRequest adds a sent translate_req to the pending requests.
+func AddRequest(request Request) {
+	request.sentAt = time.Now()
+	requestsMutex.Lock()
+	defer requestsMutex.Unlock()
+	pendingRequests = append(pendingRequests, &request)
+}
+
+// TakeRequest removes and returns the pending request a translate_result belongs to.
+// The oldest request with the echoed original text (and target language, if known) wins.
+// If no request matches the text, the oldest pending request is taken, since the backend answers in order.
+func TakeRequest(originalText string, targetLanguage string) (Request, bool) {
+	requestsMutex.Lock()
+	defer requestsMutex.Unlock()
+
+	// requests without answer (e.g. the backend failed) are dropped after the timeout
+	for len(pendingRequests) > 0 && time.Since(pendingRequests[0].sentAt) > Timeout {
+		pendingRequests = pendingRequests[1:]
+	}
+	if len(pendingRequests) == 0 {
+		return Request{}, false
+	}
+
+	index := 0
+	originalText = strings.TrimSpace(originalText)
+	for i, request := range pendingRequests {
+		if strings.TrimSpace(request.SentText) != originalText {
+			continue
+		}
+		if targetLanguage != "" && request.TargetLanguage != "" && !strings.EqualFold(request.TargetLanguage, targetLanguage) {
+			continue
+		}
+		index = i
+		break
+	}
+	request := pendingRequests[index]
+	pendingRequests = append(pendingRequests[:index:index], pendingRequests[index+1:]...)
+	return *request, true
+}
@@ -0,0 +1,93 @@
+package Translator
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTakeRequest(t *testing.T) {
+	tests := []struct {
+		name           string
+		pending        []Request
+		originalText   string
+		targetLanguage string
+		wantFound      bool
+		wantSource     string
+		wantLeft       int
+	}{
+		{
+			name:      "no pending request",
+			wantFound: false,
+		},
+		{
+			name: "result of the second request arrives first",
+			pending: []Request{
+				{SourceText: "first", SentText: "first", TargetLanguage: "de"},
+				{SourceText: "second", SentText: "second", TargetLanguage: "de"},
+			},
+			originalText: "second",
+			wantFound:    true,
+			wantSource:   "second",
+			wantLeft:     1,
+		},
+		{
+			name: "matched by the protected text",
+			pending: []Request{
+				{SourceText: "I use Whispering Tiger", SentText: "I use {{0}}", TargetLanguage: "de", Protected: true},
+			},
+			originalText: " I use {{0}} ",
+			wantFound:    true,
+			wantSource:   "I use Whispering Tiger",
+		},
+		{
+			name: "same text into another language",
+			pending: []Request{
+				{SourceText: "hello", SentText: "hello", TargetLanguage: "de"},
+				{SourceText: "hello", SentText: "hello", TargetLanguage: "ja"},
+			},
+			originalText:   "hello",
+			targetLanguage: "ja",
+			wantFound:      true,
+			wantSource:     "hello",
+			wantLeft:       1,
+		},
+		{
+			name: "unknown text falls back to the oldest request",
+			pending: []Request{
+				{SourceText: "first", SentText: "first"},
+				{SourceText: "second", SentText: "second"},
+			},
+			originalText: "changed by the backend",
+			wantFound:    true,
+			wantSource:   "first",
+			wantLeft:     1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pendingRequests = nil
+			for _, request := range tt.pending {
+				AddRequest(request)
+			}
+			request, found := TakeRequest(tt.originalText, tt.targetLanguage)
+			if found != tt.wantFound || request.SourceText != tt.wantSource {
+				t.Fatalf("TakeRequest() = %q, %v, want %q, %v", request.SourceText, found, tt.wantSource, tt.wantFound)
+			}
+			if tt.targetLanguage != "" && request.TargetLanguage != tt.targetLanguage {
+				t.Errorf("TakeRequest() target language = %q, want %q", request.TargetLanguage, tt.targetLanguage)
+			}
+			if len(pendingRequests) != tt.wantLeft {
+				t.Errorf("%d pending requests left, want %d", len(pendingRequests), tt.wantLeft)
+			}
+		})
+	}
+}
+
+func TestTakeRequestDropsExpired(t *testing.T) {
+	pendingRequests = []*Request{{SourceText: "old", SentText: "old", sentAt: time.Now().Add(-2 * Timeout)}}
+	AddRequest(Request{SourceText: "new", SentText: "new"})
+	request, found := TakeRequest("old", "")
+	if !found || request.SourceText != "new" {
+		t.Errorf("TakeRequest() = %q, %v, want the not expired request", request.SourceText, found)
+	}
+}
@@ -0,0 +1,101 @@
+package Translator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+	"whispering-tiger-ui/SendMessageChannel"
+	"whispering-tiger-ui/Settings"
+	"whispering-tiger-ui/TranslationMemory"
+)
+
+// Timeout is the maximum time to wait for a single translate_result.
+const Timeout = 5 * time.Minute
+
+// waiter is a Translate call waiting for the result of its request.
+type waiter struct {
+	text     string
+	fromLang string
+	toLang   string
+	result   chan string
+}
+
+var (
+	waitersMutex sync.Mutex
+	waiters      []*waiter
+)
+
+// HandleTranslateResult passes a translate_result to the Translate call that sent the request.
+// Several callers (batch jobs, conversation mode) can translate at the same time, each gets the result of its own request.
+// Returns true if the result was consumed and must not be shown in the Text Translate page.
+func HandleTranslateResult(request Request, translation string) bool {
+	waitersMutex.Lock()
+	defer waitersMutex.Unlock()
+	for i, w := range waiters {
+		if w.text == request.SourceText && w.fromLang == request.SourceLanguage && w.toLang == request.TargetLanguage {
+			w.result <- translation
+			waiters = append(waiters[:i:i], waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func removeWaiter(w *waiter) {
+	waitersMutex.Lock()
+	defer waitersMutex.Unlock()
+	for i, existing := range waiters {
+		if existing == w {
+			waiters = append(waiters[:i:i], waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+// Translate sends a translate request without send options and waits for the result.
+// Known translations are taken from the translation memory.
+func Translate(ctx context.Context, text, fromLang, toLang string) (string, error) {
+	if strings.TrimSpace(text) == "" {
+		return "", nil
+	}
+	model := TranslationMemory.CurrentModel()
+	if entry, found := TranslationMemory.Default.Lookup(text, fromLang, toLang, model); found {
+		return entry.Translation, nil
+	}
+
+	w := &waiter{text: text, fromLang: fromLang, toLang: toLang, result: make(chan string, 1)}
+	waitersMutex.Lock()
+	waiters = append(waiters, w)
+	waitersMutex.Unlock()
+	defer removeWaiter(w)
+
+	//goland:noinspection GoSnakeCaseUsage
+	sendMessage := SendMessageChannel.SendMessageStruct{
+		Type: "translate_req",
+		Value: struct {
+			Text                string `json:"text"`
+			From_lang           string `json:"from_lang"`
+			To_lang             string `json:"to_lang"`
+			To_romaji           bool   `json:"to_romaji"`
+			Ignore_send_options bool   `json:"ignore_send_options"`
+		}{
+			Text:                text,
+			From_lang:           fromLang,
+			To_lang:             toLang,
+			To_romaji:           Settings.Config.Txt_romaji,
+			Ignore_send_options: true,
+		},
+	}
+	sendMessage.SendMessage()
+
+	select {
+	case translation := <-w.result:
+		return translation, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case <-time.After(Timeout):
+		return "", errors.New("timeout waiting for translation")
+	}
+}
@@ -0,0 +1,90 @@
+package Translator
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"whispering-tiger-ui/SendMessageChannel"
+)
+
+// fakeBackend receives count translate requests like the websocket client and answers them in reverse order.
+func fakeBackend(t *testing.T, count int, translate func(text string) string) {
+	var requests []Request
+	for len(requests) < count {
+		message := <-SendMessageChannel.SendMessageChannel
+		var value struct {
+			Text     string `json:"text"`
+			FromLang string `json:"from_lang"`
+			ToLang   string `json:"to_lang"`
+		}
+		data, _ := json.Marshal(message.Value)
+		if err := json.Unmarshal(data, &value); err != nil {
+			t.Error(err)
+			return
+		}
+		request := Request{SourceText: value.Text, SentText: value.Text, SourceLanguage: value.FromLang, TargetLanguage: value.ToLang}
+		AddRequest(request)
+		requests = append(requests, request)
+	}
+	for i := len(requests) - 1; i >= 0; i-- {
+		request, found := TakeRequest(requests[i].SentText, requests[i].TargetLanguage)
+		if !found {
+			t.Errorf("no pending request for %q", requests[i].SentText)
+			continue
+		}
+		if !HandleTranslateResult(request, translate(request.SourceText)) {
+			t.Errorf("result for %q was not consumed", request.SourceText)
+		}
+	}
+}
+
+func TestTranslateInterleavedSenders(t *testing.T) {
+	pendingRequests = nil
+	translations := map[string]string{
+		"batch line":   "Stapelzeile",
+		"conversation": "Unterhaltung",
+	}
+	go fakeBackend(t, len(translations), func(text string) string { return translations[text] })
+
+	var wg sync.WaitGroup
+	for text, want := range translations {
+		wg.Add(1)
+		go func(text, want string) {
+			defer wg.Done()
+			got, err := Translate(context.Background(), text, "en", "de")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if got != want {
+				t.Errorf("Translate(%q) = %q, want %q", text, got, want)
+			}
+		}(text, want)
+	}
+	wg.Wait()
+	if len(waiters) != 0 {
+		t.Errorf("%d waiters left", len(waiters))
+	}
+}
+
+func TestHandleTranslateResultWithoutWaiter(t *testing.T) {
+	waiters = nil
+	if HandleTranslateResult(Request{SourceText: "page", TargetLanguage: "de"}, "Seite") {
+		t.Error("result of a Text Translate page request was consumed")
+	}
+}
+
+func TestTranslateCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-SendMessageChannel.SendMessageChannel
+		cancel()
+	}()
+	if _, err := Translate(ctx, "cancelled", "en", "de"); err == nil {
+		t.Error("Translate() returned no error after cancel")
+	}
+	if len(waiters) != 0 {
+		t.Errorf("%d waiters left after cancel", len(waiters))
+	}
+}
@@ -0,0 +1,233 @@
+package UpdateUtility
+
+import (
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+	"whispering-tiger-ui/Logging"
+	"whispering-tiger-ui/RuntimeBackend"
+	"whispering-tiger-ui/Settings"
+	"whispering-tiger-ui/Updater"
+	"whispering-tiger-ui/Utilities"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
+	"fyne.io/fyne/v2/widget"
+	"github.com/getsentry/sentry-go"
+)
+
+const (
+	platformPackage = "ai_platform"
+	appPackage      = "app"
+	// DefaultKeepPreviousVersionStarts is the number of successful backend starts after which the previous versions are removed.
+	DefaultKeepPreviousVersionStarts = 3
+)
+
+// platformEntries are the directories of the platform package in the application directory.
+var platformEntries = []string{"audioWhisper", "toolchain", "ffmpeg"}
+
+// UpdateChannel returns the update channel selected in the preferences.
+func UpdateChannel() string {
+	return fyne.CurrentApp().Preferences().StringWithFallback("UpdateChannel", Updater.ChannelStable)
+}
+
+func keepPreviousVersionStarts() int {
+	return fyne.CurrentApp().Preferences().IntWithFallback("KeepPreviousVersionStarts", DefaultKeepPreviousVersionStarts)
+}
+
+func installation(packageName string) (*Updater.Installation, error) {
+	if packageName == platformPackage {
+		installed := Updater.UpdateInfo{}
+		if data, err := os.ReadFile(currentPlatformFile); err == nil {
+			_ = installed.ReadYaml(data)
+		}
+		return Updater.LoadInstallation(appPath, platformPackage, platformEntries, installed)
+	}
+	installed := Updater.UpdateInfo{}
+	if packageName == appPackage {
+		installed.Version = Utilities.AppVersion + "." + Utilities.AppBuild
+	}
+	return Updater.LoadInstallation(appPath, packageName, nil, installed)
+}
+
+// ExtractionDirs returns the directories that update packages and plugins are extracted into,
+// to finish their interrupted extractions with Updater.FinishInterruptedExtractions.
+func ExtractionDirs() []string {
+	return []string{filepath.Join(appPath, Updater.VersionsDir), PluginDir}
+}
+
+// afterSwitch updates the version file of the platform, which is also used by the error reporting.
+func afterSwitch(packageName string, inst *Updater.Installation) {
+	if packageName == platformPackage {
+		current := inst.State().Current
+		current.WriteYaml(currentPlatformFile)
+	}
+}
+
+// PreviousVersion returns the retained previous version of an update package ("ai_platform" or "app"), false if there is none.
+func PreviousVersion(packageName string) (string, bool) {
+	inst, err := installation(packageName)
+	if err != nil || !inst.CanRollback() {
+		return "", false
+	}
+	return inst.State().Previous.Version, true
+}
+
+// rolledBackVersion returns the version of a package that was rolled back, it is not offered as update again.
+func rolledBackVersion(packageName string) string {
+	inst, err := installation(packageName)
+	if err != nil {
+		return ""
+	}
+	return inst.State().RolledBack
+}
+
+// RecoverInstallations completes updates that were interrupted while switching versions. Must be called before the backend is started.
+func RecoverInstallations() {
+	for _, packageName := range []string{platformPackage, appPackage} {
+		inst, err := installation(packageName)
+		if err == nil {
+			var recovered bool
+			if recovered, err = inst.Recover(); recovered && err == nil {
+				log.Printf("Completed the interrupted update of %s to version %s", packageName, inst.State().Current.Version)
+				afterSwitch(packageName, inst)
+			}
+		}
+		if err != nil {
+			Logging.CaptureException(err)
+			log.Printf("Error recovering the update of %s: %v", packageName, err)
+		}
+	}
+}
+
+// BackendStartupResult counts successful starts of the installed versions and offers a rollback if an updated backend failed to start.
+// Set as RuntimeBackend.OnStartupResult.
+func BackendStartupResult(started bool, startErr error) {
+	defer Logging.GoRoutineErrorHandler(func(scope *sentry.Scope) {
+		scope.SetTag("GoRoutine", "UpdateUtility\\Installations->BackendStartupResult")
+	})
+
+	if started {
+		for _, packageName := range []string{platformPackage, appPackage} {
+			inst, err := installation(packageName)
+			if err == nil {
+				var removed bool
+				if removed, err = inst.ReportSuccessfulStart(keepPreviousVersionStarts()); removed {
+					log.Printf("Removed the previous version of %s", packageName)
+				}
+			}
+			if err != nil {
+				Logging.CaptureException(err)
+			}
+		}
+		return
+	}
+
+	inst, err := installation(platformPackage)
+	// only offer a rollback if the updated version never started
+	if err != nil || !inst.CanRollback() || inst.State().SuccessfulStarts > 0 {
+		return
+	}
+	state := inst.State()
+	fyne.Do(func() {
+		window, _ := Utilities.GetCurrentMainWindow(lang.L("Backend failed to start"))
+		dialog.ShowConfirm(lang.L("Backend failed to start"), lang.L("BackendFailedRollback", map[string]interface{}{
+			"Version":         state.Current.Version,
+			"PreviousVersion": state.Previous.Version,
+			"Error":           startErr.Error(),
+		}), func(b bool) {
+			if b {
+				go RollbackPlatform(window)
+			}
+		}, window)
+	})
+}
+
+// RollbackPlatform stops the backend, switches back to the previous platform version and starts the backend again.
+func RollbackPlatform(window fyne.Window) {
+	defer Logging.GoRoutineErrorHandler(func(scope *sentry.Scope) {
+		scope.SetTag("GoRoutine", "UpdateUtility\\Installations->RollbackPlatform")
+	})
+
+	progressDialog := dialog.NewCustomWithoutButtons(lang.L("Rolling back..."), container.NewVBox(widget.NewLabel(lang.L("Rolling back to the previous version...")), widget.NewProgressBarInfinite()), window)
+	fyne.Do(func() {
+		progressDialog.Show()
+	})
+
+	wasRunning := len(RuntimeBackend.BackendsList) > 0 && RuntimeBackend.BackendsList[0].IsRunning()
+	if wasRunning {
+		RuntimeBackend.BackendsList[0].Stop()
+		time.Sleep(1 * time.Second)
+	}
+
+	inst, err := installation(platformPackage)
+	if err == nil {
+		err = inst.Rollback()
+	}
+	fyne.Do(func() {
+		progressDialog.Hide()
+	})
+	if err != nil {
+		Logging.CaptureException(err)
+		fyne.Do(func() {
+			dialog.ShowError(err, window)
+		})
+		return
+	}
+	afterSwitch(platformPackage, inst)
+
+	if len(RuntimeBackend.BackendsList) > 0 && (wasRunning || Settings.Config.Run_backend) && !RuntimeBackend.BackendsList[0].IsRunning() {
+		RuntimeBackend.BackendsList[0].Start()
+	}
+}
+
+// RollbackApp switches back to the previous app version and asks to restart the app.
+func RollbackApp(window fyne.Window) {
+	inst, err := installation(appPackage)
+	if err == nil {
+		err = inst.Rollback()
+	}
+	if err != nil {
+		Logging.CaptureException(err)
+		fyne.Do(func() {
+			dialog.ShowError(err, window)
+		})
+		return
+	}
+	askRestartApp(window)
+}
+
+func askRestartApp(window fyne.Window) {
+	fyne.Do(func() {
+		dialog.ShowConfirm(lang.L("Restart required"), lang.L("The App was changed. Restart Whispering Tiger now?"), func(b bool) {
+			if b {
+				go restartApp(window)
+			}
+		}, window)
+	})
+}
+
+// restartApp starts the executable of the app again, which can be a new version after a switch, and quits the running one.
+// appExec is the path at startup, since the running executable can be moved into the versions directory by a switch.
+func restartApp(window fyne.Window) {
+	if len(RuntimeBackend.BackendsList) > 0 && RuntimeBackend.BackendsList[0].IsRunning() {
+		RuntimeBackend.BackendsList[0].Stop()
+	}
+	cmd := exec.Command(appExec, os.Args[1:]...)
+	cmd.Dir, _ = os.Getwd()
+	err := cmd.Start()
+	if err != nil {
+		Logging.CaptureException(err)
+		fyne.Do(func() {
+			dialog.ShowError(err, window)
+		})
+		return
+	}
+	fyne.Do(func() {
+		fyne.CurrentApp().Quit()
+	})
+}
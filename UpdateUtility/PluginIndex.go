@@ -0,0 +1,387 @@
+package UpdateUtility
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"whispering-tiger-ui/Updater"
+	"whispering-tiger-ui/Utilities"
+
+	"fyne.io/fyne/v2/lang"
+	"gopkg.in/yaml.v3"
+)
+
+// PluginIndexUrl is the machine-readable index of the plugin repository. It can be YAML or JSON.
+const PluginIndexUrl = "https://raw.githubusercontent.com/Sharrnah/whispering-plugins/main/plugins.yaml"
+
+// PluginIndexVersion is the newest plugin index format this version can read.
+const PluginIndexVersion = 1
+
+var ErrPluginChecksum = errors.New("the downloaded plugin does not match the checksum of the plugin index")
+var ErrPluginMissingChecksum = errors.New("the plugin index has no checksum for the plugin")
+
+// PluginIndexEntry is a plugin offered in the plugin list.
+type PluginIndexEntry struct {
+	// Id is the class name of the plugin, which is also the name of its settings.
+	Id          string `yaml:"id"`
+	Name        string `yaml:"name"`
+	Version     string `yaml:"version"`
+	Description string `yaml:"description,omitempty"`
+	Author      string `yaml:"author,omitempty"`
+	// Homepage is the page of the plugin and Url the download of its python file. Relative links are relative to the index.
+	Homepage string `yaml:"homepage,omitempty"`
+	Preview  string `yaml:"preview,omitempty"`
+	Url      string `yaml:"url"`
+	// SHA256 is the checksum of the plugin file, plugins of the index are only installed with a matching checksum.
+	SHA256 string `yaml:"sha256,omitempty"`
+	// The app and platform versions the plugin works with, an empty version is not limited.
+	MinAppVersion     string `yaml:"min_app_version,omitempty"`
+	MaxAppVersion     string `yaml:"max_app_version,omitempty"`
+	MinBackendVersion string `yaml:"min_backend_version,omitempty"`
+	MaxBackendVersion string `yaml:"max_backend_version,omitempty"`
+	// Dependencies are the ids of plugins that are installed together with the plugin.
+	Dependencies []string `yaml:"dependencies,omitempty"`
+	// FromReadme is set for plugins of the README table, which have no id, version and checksum until the plugin file is fetched.
+	FromReadme bool `yaml:"-"`
+}
+
+// PluginIndex is the content of the plugin index file.
+type PluginIndex struct {
+	Version int                `yaml:"version"`
+	Plugins []PluginIndexEntry `yaml:"plugins"`
+}
+
+// LoadPluginIndex returns the plugins of the plugin index.
+// If the index can not be loaded, the plugins are read from the table of the plugin README.
+func LoadPluginIndex() ([]PluginIndexEntry, error) {
+	return loadPluginIndex(PluginIndexUrl, PluginListUrl)
+}
+
+func loadPluginIndex(indexUrl, readmeUrl string) ([]PluginIndexEntry, error) {
+	plugins, indexErr := fetchPluginIndex(indexUrl)
+	if indexErr == nil {
+		return plugins, nil
+	}
+	log.Printf("Plugin index not available, using the plugin README: %v", indexErr)
+
+	md, err := DownloadFile(readmeUrl)
+	if err != nil {
+		return nil, errors.Join(indexErr, err)
+	}
+	return pluginsFromReadme(md), nil
+}
+
+func fetchPluginIndex(indexUrl string) ([]PluginIndexEntry, error) {
+	data, err := DownloadFile(indexUrl)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is read by the YAML parser as well
+	index := PluginIndex{}
+	if err := yaml.Unmarshal([]byte(data), &index); err != nil {
+		return nil, fmt.Errorf("invalid plugin index: %w", err)
+	}
+	if index.Version > PluginIndexVersion {
+		return nil, fmt.Errorf("plugin index version %d is not supported, please update the app", index.Version)
+	}
+
+	var plugins []PluginIndexEntry
+	for _, plugin := range index.Plugins {
+		if plugin.Id == "" || plugin.Url == "" {
+			log.Printf("Skipping plugin %q of the plugin index without id or url", plugin.Name)
+			continue
+		}
+		if plugin.Name == "" {
+			plugin.Name = plugin.Id
+		}
+		plugin.Url = resolvePluginUrl(indexUrl, plugin.Url)
+		plugin.Homepage = resolvePluginUrl(indexUrl, plugin.Homepage)
+		plugin.Preview = resolvePluginUrl(indexUrl, plugin.Preview)
+		plugins = append(plugins, plugin)
+	}
+	return plugins, nil
+}
+
+func resolvePluginUrl(indexUrl, link string) string {
+	if link == "" {
+		return ""
+	}
+	base, err := url.Parse(indexUrl)
+	if err != nil {
+		return link
+	}
+	ref, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// pluginsFromReadme reads the plugins of the README table, which is the fallback if the plugin index is not available.
+func pluginsFromReadme(md string) []PluginIndexEntry {
+	var plugins []PluginIndexEntry
+	for _, row := range ParseTableIntoStruct(ExtractTable(md), PluginRelativeUrlPrefix) {
+		plugins = append(plugins, PluginIndexEntry{
+			Name:        row.Title,
+			Description: row.Description,
+			Author:      row.Author,
+			Homepage:    row.TitleLink,
+			Preview:     row.PreviewLink,
+			Url:         rawPluginUrl(row.TitleLink),
+			FromReadme:  true,
+		})
+	}
+	return plugins
+}
+
+// rawPluginUrl returns the download url of a plugin page on GitHub.
+func rawPluginUrl(url string) string {
+	// Check for GitHub domain in the URL
+	if strings.Contains(url, "github.com") {
+		// Handle GitHub URLs
+		if strings.Contains(url, "/blob/") {
+			// Replace "/blob/" with "/raw/" for regular GitHub files
+			url = strings.Replace(url, "/blob/", "/raw/", 1)
+		} else if strings.Contains(url, "gist.") {
+			// For Gist links, append "/raw" at the end of the URL
+			url += "/raw"
+		}
+	}
+	// Future extension: Add else if conditions for other domains like GitLab
+	return url
+}
+
+// resolveReadmePlugin fetches the plugin file of a README plugin to read its id, version and checksum.
+func resolveReadmePlugin(plugin *PluginIndexEntry) {
+	if !plugin.FromReadme || plugin.Id != "" {
+		return
+	}
+	version, class, hash, content := FetchAndAnalyzePluginUrl(plugin.Url)
+	if content == nil {
+		return
+	}
+	plugin.Id = class
+	plugin.Version = version
+	plugin.SHA256 = hash
+}
+
+// compareVersions compares dotted version numbers like 1.3.10.2, returns -1, 0 or 1.
+// Parts that are not numbers are compared as text.
+func compareVersions(a, b string) int {
+	aParts := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bParts := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < max(len(aParts), len(bParts)); i++ {
+		aPart, bPart := "0", "0"
+		if i < len(aParts) {
+			aPart = aParts[i]
+		}
+		if i < len(bParts) {
+			bPart = bParts[i]
+		}
+		aNumber, aErr := strconv.Atoi(aPart)
+		bNumber, bErr := strconv.Atoi(bPart)
+		if aErr == nil && bErr == nil {
+			if aNumber != bNumber {
+				return compareInts(aNumber, bNumber)
+			}
+		} else if cmp := strings.Compare(aPart, bPart); cmp != 0 {
+			return cmp
+		}
+	}
+	return 0
+}
+
+func compareInts(a, b int) int {
+	if a < b {
+		return -1
+	}
+	return 1
+}
+
+// Incompatibility returns why the plugin does not work with the app and platform version, or an empty string if it does.
+// Limits of an unknown version are not checked.
+func (p PluginIndexEntry) Incompatibility(appVersion, backendVersion string) string {
+	switch {
+	case appVersion != "" && p.MinAppVersion != "" && compareVersions(appVersion, p.MinAppVersion) < 0:
+		return lang.L("Requires a newer App version", map[string]interface{}{"Version": p.MinAppVersion})
+	case appVersion != "" && p.MaxAppVersion != "" && compareVersions(appVersion, p.MaxAppVersion) > 0:
+		return lang.L("Requires an older App version", map[string]interface{}{"Version": p.MaxAppVersion})
+	case backendVersion != "" && p.MinBackendVersion != "" && compareVersions(backendVersion, p.MinBackendVersion) < 0:
+		return lang.L("Requires a newer Platform version", map[string]interface{}{"Version": p.MinBackendVersion})
+	case backendVersion != "" && p.MaxBackendVersion != "" && compareVersions(backendVersion, p.MaxBackendVersion) > 0:
+		return lang.L("Requires an older Platform version", map[string]interface{}{"Version": p.MaxBackendVersion})
+	}
+	return ""
+}
+
+// PluginIncompatibility returns why the plugin does not work with the running app and the installed platform, or an empty string if it does.
+func PluginIncompatibility(plugin PluginIndexEntry) string {
+	return plugin.Incompatibility(Utilities.AppVersion+"."+Utilities.AppBuild, GetCurrentPlatformVersion())
+}
+
+// FindPlugin returns a plugin of the index by its id.
+func FindPlugin(plugins []PluginIndexEntry, id string) (PluginIndexEntry, bool) {
+	for _, plugin := range plugins {
+		if plugin.Id == id {
+			return plugin, true
+		}
+	}
+	return PluginIndexEntry{}, false
+}
+
+// PluginInstallOrder returns the plugin and its dependencies that are not installed yet, dependencies first.
+func PluginInstallOrder(plugins []PluginIndexEntry, plugin PluginIndexEntry, localPluginFilesData []LocalPluginFilesData) ([]PluginIndexEntry, error) {
+	var order []PluginIndexEntry
+	visited := map[string]bool{}
+	var visit func(plugin PluginIndexEntry, path []string) error
+	visit = func(plugin PluginIndexEntry, path []string) error {
+		for _, id := range plugin.Dependencies {
+			for _, pathId := range path {
+				if pathId == id {
+					return fmt.Errorf("plugin %s has a circular dependency on %s", plugin.Id, id)
+				}
+			}
+			if visited[id] || FindLocalPluginFileByClass(localPluginFilesData, id).Class != "" {
+				continue
+			}
+			dependency, ok := FindPlugin(plugins, id)
+			if !ok {
+				return fmt.Errorf("dependency %s of plugin %s is not in the plugin index", id, plugin.Id)
+			}
+			if err := visit(dependency, append(path, id)); err != nil {
+				return err
+			}
+		}
+		if !visited[plugin.Id] {
+			visited[plugin.Id] = true
+			order = append(order, plugin)
+		}
+		return nil
+	}
+	return order, visit(plugin, []string{plugin.Id})
+}
+
+// installedPluginsFile records the index entries of installed plugin files, so their id and version are known without parsing the file.
+const installedPluginsFile = PluginDir + ".installed_plugins.yaml"
+
+// installedPlugin is the recorded index entry of an installed plugin file.
+type installedPlugin struct {
+	Id      string `yaml:"id"`
+	Version string `yaml:"version"`
+	SHA256  string `yaml:"sha256"`
+	// PinnedVersion is set while the plugin is pinned to its installed version, updates are not offered then.
+	PinnedVersion string `yaml:"pinned_version,omitempty"`
+	// RolledBack is the version that was rolled back from, it is not offered as update again.
+	RolledBack string `yaml:"rolled_back,omitempty"`
+	// Previous is the file that was replaced by the last install, kept in PluginBackupDir for a rollback.
+	Previous *installedPlugin `yaml:"previous,omitempty"`
+}
+
+func loadInstalledPlugins() map[string]installedPlugin {
+	installed := map[string]installedPlugin{}
+	data, err := os.ReadFile(installedPluginsFile)
+	if err != nil {
+		return installed
+	}
+	if err := yaml.Unmarshal(data, &installed); err != nil {
+		log.Printf("Error reading %s: %v", installedPluginsFile, err)
+	}
+	return installed
+}
+
+func saveInstalledPlugins(installed map[string]installedPlugin) error {
+	data, err := yaml.Marshal(installed)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(installedPluginsFile, data)
+}
+
+// writeFileAtomic writes a file next to its destination first, so an interrupted write does not leave a broken file.
+func writeFileAtomic(fileName string, data []byte) error {
+	tempFile := fileName + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tempFile, fileName)
+}
+
+// InstallPlugin downloads a plugin into the plugin directory, replacing an installed file of the same plugin.
+// The download is checked against the checksum of the index, plugins of the index without checksum are not installed.
+// Returns the installed plugin file.
+func InstallPlugin(plugin PluginIndexEntry, localPluginFilesData []LocalPluginFilesData) (LocalPluginFilesData, error) {
+	if !plugin.FromReadme && plugin.SHA256 == "" {
+		return LocalPluginFilesData{}, fmt.Errorf("%w: %s", ErrPluginMissingChecksum, plugin.Name)
+	}
+	resp, err := Updater.HttpClient().Get(plugin.Url)
+	if err != nil {
+		return LocalPluginFilesData{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return LocalPluginFilesData{}, fmt.Errorf("downloading plugin %s failed: %s", plugin.Name, resp.Status)
+	}
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return LocalPluginFilesData{}, err
+	}
+
+	version, class, hash := getVersionAndClassFromCode(content)
+	if !plugin.FromReadme {
+		// README plugins have no checksum, the one of the update check is from an earlier download
+		if !strings.EqualFold(plugin.SHA256, hash) {
+			return LocalPluginFilesData{}, fmt.Errorf("%w: %s", ErrPluginChecksum, plugin.Name)
+		}
+		version, class = plugin.Version, plugin.Id
+	}
+	if class == "" {
+		return LocalPluginFilesData{}, fmt.Errorf("no plugin class found in %s", plugin.Url)
+	}
+
+	pluginFile := LocalPluginFilesData{
+		Class:        class,
+		FilePath:     PluginDir + Utilities.CamelToSnake(class) + ".py",
+		LocalVersion: version,
+		SHA256:       hash,
+	}
+	installed := loadInstalledPlugins()
+	record := installedPlugin{Id: class, Version: version, SHA256: hash}
+
+	localPluginFile := FindLocalPluginFileByClass(localPluginFilesData, class)
+	if localPluginFile.Class != "" {
+		pluginFile.FilePath = localPluginFile.FilePath
+		record.Previous = installed[filepath.Base(localPluginFile.FilePath)].Previous
+		// the replaced file is kept for a rollback, a reinstall of the same file keeps the older one
+		if localPluginFile.SHA256 != hash {
+			if err := backupPluginFile(localPluginFile.FilePath); err != nil {
+				return pluginFile, err
+			}
+			record.Previous = &installedPlugin{Id: localPluginFile.Class, Version: localPluginFile.LocalVersion, SHA256: localPluginFile.SHA256}
+		}
+	}
+
+	if err := os.MkdirAll(PluginDir, 0755); err != nil {
+		return pluginFile, err
+	}
+	if err := writeFileAtomic(pluginFile.FilePath, content); err != nil {
+		return pluginFile, err
+	}
+	pluginFile.PreviousVersion = ""
+	pluginFile.HasPrevious = record.Previous != nil
+	if record.Previous != nil {
+		pluginFile.PreviousVersion = record.Previous.Version
+	}
+
+	installed[filepath.Base(pluginFile.FilePath)] = record
+	if err := saveInstalledPlugins(installed); err != nil {
+		log.Printf("Error recording installed plugin %s: %v", class, err)
+	}
+	return pluginFile, nil
+}
@@ -0,0 +1,246 @@
+package UpdateUtility
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFetchPluginIndex(t *testing.T) {
+	tests := []struct {
+		name    string
+		index   string
+		want    []PluginIndexEntry
+		wantErr string
+	}{
+		{
+			name: "yaml",
+			index: `version: 1
+plugins:
+  - id: ExamplePlugin
+    name: Example
+    version: 1.0.0
+    url: plugins/example_plugin.py
+    homepage: https://example.com/example
+    preview: images/example.png
+    sha256: abc
+    dependencies: [OtherPlugin]
+`,
+			want: []PluginIndexEntry{{
+				Id: "ExamplePlugin", Name: "Example", Version: "1.0.0", SHA256: "abc", Dependencies: []string{"OtherPlugin"},
+				Url: "/index/plugins/example_plugin.py", Homepage: "https://example.com/example", Preview: "/index/images/example.png",
+			}},
+		},
+		{
+			name:  "json",
+			index: `{"version": 1, "plugins": [{"id": "ExamplePlugin", "version": "1.0.0", "url": "/example_plugin.py", "sha256": "abc"}]}`,
+			// the name defaults to the id
+			want: []PluginIndexEntry{{Id: "ExamplePlugin", Name: "ExamplePlugin", Version: "1.0.0", SHA256: "abc", Url: "/example_plugin.py"}},
+		},
+		{
+			name: "entries without id or url are skipped",
+			index: `version: 1
+plugins:
+  - {name: No id, url: a.py}
+  - {id: NoUrl, name: No url}
+  - {id: ExamplePlugin, url: example_plugin.py}
+`,
+			want: []PluginIndexEntry{{Id: "ExamplePlugin", Name: "ExamplePlugin", Url: "/index/example_plugin.py"}},
+		},
+		{name: "empty index", index: "version: 1\n"},
+		{name: "newer version", index: "version: 2\nplugins:\n  - {id: ExamplePlugin, url: example_plugin.py}\n", wantErr: "plugin index version 2 is not supported"},
+		{name: "malformed", index: "version: 1\nplugins: [\n", wantErr: "invalid plugin index"},
+		{name: "missing index", wantErr: "404"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := map[string]string{}
+			if tt.index != "" {
+				files["/index/plugins.yaml"] = tt.index
+			}
+			server := testPluginServer(t, files)
+			plugins, err := fetchPluginIndex(server.URL + "/index/plugins.yaml")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("fetchPluginIndex() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("fetchPluginIndex() error = %v", err)
+			}
+			if len(plugins) != len(tt.want) {
+				t.Fatalf("fetchPluginIndex() = %+v, want %+v", plugins, tt.want)
+			}
+			for i, want := range tt.want {
+				// relative links are resolved against the index url
+				for _, link := range []*string{&want.Url, &want.Homepage, &want.Preview} {
+					if strings.HasPrefix(*link, "/") {
+						*link = server.URL + *link
+					}
+				}
+				got := plugins[i]
+				if got.Id != want.Id || got.Name != want.Name || got.Version != want.Version || got.SHA256 != want.SHA256 ||
+					got.Url != want.Url || got.Homepage != want.Homepage || got.Preview != want.Preview ||
+					strings.Join(got.Dependencies, ",") != strings.Join(want.Dependencies, ",") || got.FromReadme {
+					t.Errorf("plugin %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestResolvePluginUrl(t *testing.T) {
+	const indexUrl = "https://raw.example.com/plugins/main/plugins.yaml"
+	tests := []struct {
+		name     string
+		indexUrl string
+		link     string
+		want     string
+	}{
+		{name: "empty", indexUrl: indexUrl, link: ""},
+		{name: "absolute", indexUrl: indexUrl, link: "https://other.example.com/a.py", want: "https://other.example.com/a.py"},
+		{name: "relative", indexUrl: indexUrl, link: "plugins/a.py", want: "https://raw.example.com/plugins/main/plugins/a.py"},
+		{name: "parent directory", indexUrl: indexUrl, link: "../dev/a.py", want: "https://raw.example.com/plugins/dev/a.py"},
+		{name: "root relative", indexUrl: indexUrl, link: "/a.py", want: "https://raw.example.com/a.py"},
+		{name: "invalid index url", indexUrl: "://index", link: "a.py", want: "a.py"},
+		{name: "invalid link", indexUrl: indexUrl, link: "%zz", want: "%zz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolvePluginUrl(tt.indexUrl, tt.link); got != tt.want {
+				t.Errorf("resolvePluginUrl(%q) = %q, want %q", tt.link, got, tt.want)
+			}
+		})
+	}
+}
+
+const testPluginReadme = `# Plugins
+
+Plugins of the community.
+
+| Plugin | Preview | Description | Author |
+|---|---|---|---|
+| [**Example**](https://github.com/example/plugins/blob/main/example_plugin.py) | <img src=images/example.png width=200> | An example plugin | someone |
+| [Gist](https://gist.github.com/example/123) | | A gist plugin | other |
+`
+
+func TestPluginsFromReadme(t *testing.T) {
+	plugins := pluginsFromReadme(testPluginReadme)
+	want := []PluginIndexEntry{
+		{
+			Name: "Example", Description: "An example plugin", Author: "someone",
+			Homepage: "https://github.com/example/plugins/blob/main/example_plugin.py",
+			Url:      "https://github.com/example/plugins/raw/main/example_plugin.py",
+			Preview:  PluginRelativeUrlPrefix + "images/example.png",
+		},
+		{
+			Name: "Gist", Description: "A gist plugin", Author: "other",
+			Homepage: "https://gist.github.com/example/123",
+			Url:      "https://gist.github.com/example/123/raw",
+			Preview:  PluginRelativeUrlPrefix,
+		},
+	}
+	if len(plugins) != len(want) {
+		t.Fatalf("pluginsFromReadme() = %+v, want %d plugins", plugins, len(want))
+	}
+	for i, got := range plugins {
+		// README plugins have no id, version and checksum until the plugin file is fetched
+		if got.Name != want[i].Name || got.Description != want[i].Description || got.Author != want[i].Author ||
+			got.Homepage != want[i].Homepage || got.Url != want[i].Url || got.Preview != want[i].Preview ||
+			!got.FromReadme || got.Id != "" || got.SHA256 != "" {
+			t.Errorf("plugin %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+}
+
+func TestLoadPluginIndexFallback(t *testing.T) {
+	server := testPluginServer(t, map[string]string{
+		"/valid/plugins.yaml":   "version: 1\nplugins:\n  - {id: ExamplePlugin, url: example_plugin.py, sha256: abc}\n",
+		"/newer/plugins.yaml":   "version: 2\n",
+		"/README.md":            testPluginReadme,
+		"/empty/plugins.yaml":   "version: 1\n",
+		"/invalid/plugins.yaml": "plugins: [\n",
+	})
+	tests := []struct {
+		name       string
+		index      string
+		readme     string
+		wantReadme bool
+		wantErr    bool
+	}{
+		{name: "index", index: "/valid/plugins.yaml", readme: "/README.md"},
+		{name: "empty index", index: "/empty/plugins.yaml", readme: "/README.md"},
+		{name: "missing index", index: "/missing/plugins.yaml", readme: "/README.md", wantReadme: true},
+		{name: "unsupported index version", index: "/newer/plugins.yaml", readme: "/README.md", wantReadme: true},
+		{name: "invalid index", index: "/invalid/plugins.yaml", readme: "/README.md", wantReadme: true},
+		{name: "index and README missing", index: "/missing/plugins.yaml", readme: "/missing/README.md", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugins, err := loadPluginIndex(server.URL+tt.index, server.URL+tt.readme)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadPluginIndex() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if tt.wantReadme {
+				if len(plugins) != 2 || !plugins[0].FromReadme {
+					t.Errorf("loadPluginIndex() = %+v, want the README plugins", plugins)
+				}
+				return
+			}
+			for _, plugin := range plugins {
+				if plugin.FromReadme {
+					t.Errorf("loadPluginIndex() = %+v, want the index plugins", plugins)
+				}
+			}
+		})
+	}
+}
+
+func TestInstallPluginChecksum(t *testing.T) {
+	code := testPluginCode("1.0.0")
+	tests := []struct {
+		name    string
+		plugin  PluginIndexEntry
+		wantErr error
+		// wantVersion is the recorded version, README plugins use the version of the plugin file
+		wantVersion string
+	}{
+		{name: "index plugin", plugin: PluginIndexEntry{Id: "ExamplePlugin", Version: "1.0.0-index", SHA256: strings.ToUpper(testPluginHash(t, code))}, wantVersion: "1.0.0-index"},
+		{name: "index plugin without checksum", plugin: PluginIndexEntry{Id: "ExamplePlugin", Version: "1.0.0"}, wantErr: ErrPluginMissingChecksum},
+		{name: "index plugin with wrong checksum", plugin: PluginIndexEntry{Id: "ExamplePlugin", Version: "1.0.0", SHA256: testPluginHash(t, "other")}, wantErr: ErrPluginChecksum},
+		{name: "README plugin without checksum", plugin: PluginIndexEntry{FromReadme: true}, wantVersion: "1.0.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			server := testPluginServer(t, map[string]string{"/example_plugin.py": code})
+			tt.plugin.Name = "Example"
+			tt.plugin.Url = server.URL + "/example_plugin.py"
+
+			installed, err := InstallPlugin(tt.plugin, nil)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("InstallPlugin() error = %v, want %v", err, tt.wantErr)
+				}
+				if _, err := os.Stat(PluginDir + "example_plugin.py"); !os.IsNotExist(err) {
+					t.Errorf("plugin file was written: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("InstallPlugin() error = %v", err)
+			}
+			if installed.Class != "ExamplePlugin" || installed.LocalVersion != tt.wantVersion {
+				t.Errorf("InstallPlugin() = %+v, want version %s", installed, tt.wantVersion)
+			}
+			if data, _ := os.ReadFile(installed.FilePath); string(data) != code {
+				t.Errorf("plugin file = %q", data)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package UpdateUtility
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// PluginBackupDir keeps the file that was replaced by the last install of a plugin, for a rollback.
+const PluginBackupDir = PluginDir + ".previous/"
+
+var ErrNoPreviousPluginFile = errors.New("no previous plugin file to roll back to")
+
+func pluginBackupFile(filePath string) string {
+	return PluginBackupDir + filepath.Base(filePath)
+}
+
+func backupPluginFile(filePath string) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(PluginBackupDir, 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(pluginBackupFile(filePath), data)
+}
+
+// pluginRecord returns the record of an installed plugin file, updated to the file as it is now.
+func pluginRecord(installed map[string]installedPlugin, pluginFile LocalPluginFilesData) installedPlugin {
+	record := installed[filepath.Base(pluginFile.FilePath)]
+	record.Id = pluginFile.Class
+	record.Version = pluginFile.LocalVersion
+	record.SHA256 = pluginFile.SHA256
+	return record
+}
+
+// PinPlugin pins a plugin to its installed version, updates are not offered until it is unpinned. Returns the pinned version.
+func PinPlugin(pluginFile LocalPluginFilesData, pinned bool) (string, error) {
+	installed := loadInstalledPlugins()
+	record := pluginRecord(installed, pluginFile)
+	record.PinnedVersion = ""
+	if pinned {
+		record.PinnedVersion = pluginFile.LocalVersion
+		if record.PinnedVersion == "" && len(pluginFile.SHA256) >= 8 {
+			// files without version are pinned to their content
+			record.PinnedVersion = pluginFile.SHA256[:8]
+		}
+	}
+	installed[filepath.Base(pluginFile.FilePath)] = record
+	return record.PinnedVersion, saveInstalledPlugins(installed)
+}
+
+// RollbackPlugin replaces a plugin file with the file it replaced on the last install.
+// The version rolled back from is not offered as update again. Returns the restored plugin file.
+func RollbackPlugin(pluginFile LocalPluginFilesData) (LocalPluginFilesData, error) {
+	installed := loadInstalledPlugins()
+	record := pluginRecord(installed, pluginFile)
+	if record.Previous == nil {
+		return pluginFile, ErrNoPreviousPluginFile
+	}
+	data, err := os.ReadFile(pluginBackupFile(pluginFile.FilePath))
+	if err != nil {
+		return pluginFile, fmt.Errorf("%w: %v", ErrNoPreviousPluginFile, err)
+	}
+	if err := writeFileAtomic(pluginFile.FilePath, data); err != nil {
+		return pluginFile, err
+	}
+
+	previous := *record.Previous
+	if previous.Id == "" {
+		previous.Id = pluginFile.Class
+	}
+	installed[filepath.Base(pluginFile.FilePath)] = installedPlugin{
+		Id:         previous.Id,
+		Version:    previous.Version,
+		SHA256:     previous.SHA256,
+		RolledBack: pluginFile.LocalVersion,
+	}
+	if err := saveInstalledPlugins(installed); err != nil {
+		return pluginFile, err
+	}
+	if err := os.Remove(pluginBackupFile(pluginFile.FilePath)); err != nil && !os.IsNotExist(err) {
+		return pluginFile, err
+	}
+	return LocalPluginFilesData{
+		Class:             previous.Id,
+		FilePath:          pluginFile.FilePath,
+		LocalVersion:      previous.Version,
+		SHA256:            previous.SHA256,
+		RolledBackVersion: pluginFile.LocalVersion,
+	}, nil
+}
+
+// UninstallPlugin removes a plugin file together with its kept previous file and its record. The settings of the plugin are not changed.
+func UninstallPlugin(pluginFile LocalPluginFilesData) error {
+	if err := os.Remove(pluginFile.FilePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(pluginBackupFile(pluginFile.FilePath)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	installed := loadInstalledPlugins()
+	if _, ok := installed[filepath.Base(pluginFile.FilePath)]; !ok {
+		return nil
+	}
+	delete(installed, filepath.Base(pluginFile.FilePath))
+	return saveInstalledPlugins(installed)
+}
@@ -0,0 +1,206 @@
+package UpdateUtility
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"whispering-tiger-ui/Utilities"
+)
+
+func testPluginCode(version string) string {
+	return "# Example Plugin\n# V" + version + "\nimport Plugins\n\nclass ExamplePlugin(Plugins.Base):\n    pass\n"
+}
+
+func testPluginHash(t *testing.T, code string) string {
+	t.Helper()
+	hash, err := Utilities.FileHash(strings.NewReader(code))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+// testPluginServer serves plugin files by path.
+func testPluginServer(t *testing.T, files map[string]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(content))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func findLocalPlugin(t *testing.T, class string) LocalPluginFilesData {
+	t.Helper()
+	return FindLocalPluginFileByClass(ParseLocalPluginFiles(), class)
+}
+
+func TestPluginLifecycle(t *testing.T) {
+	t.Chdir(t.TempDir())
+	v1, v2 := testPluginCode("1.0.0"), testPluginCode("1.1.0")
+	server := testPluginServer(t, map[string]string{"/v1.py": v1, "/v2.py": v2})
+	entry := func(version, path, code string) PluginIndexEntry {
+		return PluginIndexEntry{Id: "ExamplePlugin", Name: "Example", Version: version, Url: server.URL + path, SHA256: testPluginHash(t, code)}
+	}
+	readPlugin := func() string {
+		data, _ := os.ReadFile(PluginDir + "example_plugin.py")
+		return string(data)
+	}
+
+	// install
+	installed, err := InstallPlugin(entry("1.0.0", "/v1.py", v1), ParseLocalPluginFiles())
+	if err != nil {
+		t.Fatalf("InstallPlugin(1.0.0) error = %v", err)
+	}
+	if installed.HasPrevious || readPlugin() != v1 {
+		t.Fatalf("InstallPlugin(1.0.0) = %+v, file %q", installed, readPlugin())
+	}
+	if local := findLocalPlugin(t, "ExamplePlugin"); local.LocalVersion != "1.0.0" || local.HasPrevious {
+		t.Fatalf("installed plugin = %+v", local)
+	}
+
+	// a download that does not match the checksum of the index does not replace the file
+	broken := entry("1.1.0", "/v2.py", v1)
+	if _, err := InstallPlugin(broken, ParseLocalPluginFiles()); !errors.Is(err, ErrPluginChecksum) {
+		t.Fatalf("InstallPlugin(wrong checksum) error = %v, want %v", err, ErrPluginChecksum)
+	}
+	if readPlugin() != v1 {
+		t.Fatal("plugin file was replaced by a download with wrong checksum")
+	}
+
+	// update keeps the replaced file
+	if _, err := InstallPlugin(entry("1.1.0", "/v2.py", v2), ParseLocalPluginFiles()); err != nil {
+		t.Fatalf("InstallPlugin(1.1.0) error = %v", err)
+	}
+	local := findLocalPlugin(t, "ExamplePlugin")
+	if local.LocalVersion != "1.1.0" || !local.HasPrevious || local.PreviousVersion != "1.0.0" || readPlugin() != v2 {
+		t.Fatalf("updated plugin = %+v, file %q", local, readPlugin())
+	}
+	// a reinstall of the same version keeps the older previous file
+	if _, err := InstallPlugin(entry("1.1.0", "/v2.py", v2), ParseLocalPluginFiles()); err != nil {
+		t.Fatalf("InstallPlugin(1.1.0) again error = %v", err)
+	}
+	if local = findLocalPlugin(t, "ExamplePlugin"); local.PreviousVersion != "1.0.0" {
+		t.Fatalf("reinstalled plugin previous version = %q, want 1.0.0", local.PreviousVersion)
+	}
+
+	// pin
+	pinned, err := PinPlugin(local, true)
+	if err != nil || pinned != "1.1.0" {
+		t.Fatalf("PinPlugin() = %q, %v", pinned, err)
+	}
+	if local = findLocalPlugin(t, "ExamplePlugin"); local.PinnedVersion != "1.1.0" || !local.HasPrevious {
+		t.Fatalf("pinned plugin = %+v", local)
+	}
+	if _, err := PinPlugin(local, false); err != nil {
+		t.Fatal(err)
+	}
+	local = findLocalPlugin(t, "ExamplePlugin")
+
+	// rollback
+	restored, err := RollbackPlugin(local)
+	if err != nil {
+		t.Fatalf("RollbackPlugin() error = %v", err)
+	}
+	if restored.LocalVersion != "1.0.0" || restored.RolledBackVersion != "1.1.0" || readPlugin() != v1 {
+		t.Fatalf("RollbackPlugin() = %+v, file %q", restored, readPlugin())
+	}
+	if local = findLocalPlugin(t, "ExamplePlugin"); local.LocalVersion != "1.0.0" || local.RolledBackVersion != "1.1.0" || local.HasPrevious {
+		t.Fatalf("rolled back plugin = %+v", local)
+	}
+	if _, err := os.Stat(pluginBackupFile(local.FilePath)); !os.IsNotExist(err) {
+		t.Errorf("previous file was kept after the rollback: %v", err)
+	}
+	if _, err := RollbackPlugin(local); !errors.Is(err, ErrNoPreviousPluginFile) {
+		t.Errorf("second RollbackPlugin() error = %v, want %v", err, ErrNoPreviousPluginFile)
+	}
+
+	// uninstall
+	if err := UninstallPlugin(local); err != nil {
+		t.Fatalf("UninstallPlugin() error = %v", err)
+	}
+	if _, err := os.Stat(local.FilePath); !os.IsNotExist(err) {
+		t.Errorf("plugin file was kept: %v", err)
+	}
+	if _, recorded := loadInstalledPlugins()["example_plugin.py"]; recorded {
+		t.Error("plugin record was kept")
+	}
+}
+
+func TestPluginUpdateAvailable(t *testing.T) {
+	tests := []struct {
+		name string
+		info PluginUpdateInfo
+		want bool
+	}{
+		{name: "newer version", info: PluginUpdateInfo{remoteVersion: "1.1.0", localVersion: "1.0.0"}, want: true},
+		{name: "same version", info: PluginUpdateInfo{remoteVersion: "1.0.0", localVersion: "1.0.0"}},
+		{name: "not installed", info: PluginUpdateInfo{remoteVersion: "1.0.0"}},
+		{name: "pinned", info: PluginUpdateInfo{remoteVersion: "1.1.0", localVersion: "1.0.0", pinnedVersion: "1.0.0"}},
+		{name: "rolled back from the version", info: PluginUpdateInfo{remoteVersion: "1.1.0", localVersion: "1.0.0", rolledBack: "1.1.0"}},
+		{name: "newer version than the rolled back one", info: PluginUpdateInfo{remoteVersion: "1.2.0", localVersion: "1.0.0", rolledBack: "1.1.0"}, want: true},
+		{name: "incompatible", info: PluginUpdateInfo{remoteVersion: "1.1.0", localVersion: "1.0.0", incompatibility: "requires a newer app"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.info.updateAvailable(); got != tt.want {
+				t.Errorf("updateAvailable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPluginInstallOrder(t *testing.T) {
+	plugins := []PluginIndexEntry{
+		{Id: "A", Dependencies: []string{"B", "C"}},
+		{Id: "B", Dependencies: []string{"C"}},
+		{Id: "C"},
+		{Id: "Loop1", Dependencies: []string{"Loop2"}},
+		{Id: "Loop2", Dependencies: []string{"Loop1"}},
+		{Id: "Missing", Dependencies: []string{"Unknown"}},
+	}
+	tests := []struct {
+		name      string
+		plugin    string
+		installed []string
+		want      string
+		wantErr   bool
+	}{
+		{name: "without dependencies", plugin: "C", want: "C"},
+		{name: "dependencies first", plugin: "A", want: "C,B,A"},
+		{name: "installed dependency", plugin: "A", installed: []string{"C"}, want: "B,A"},
+		{name: "circular dependency", plugin: "Loop1", wantErr: true},
+		{name: "missing dependency", plugin: "Missing", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var local []LocalPluginFilesData
+			for _, id := range tt.installed {
+				local = append(local, LocalPluginFilesData{Class: id})
+			}
+			plugin, _ := FindPlugin(plugins, tt.plugin)
+			order, err := PluginInstallOrder(plugins, plugin, local)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PluginInstallOrder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var ids []string
+			for _, entry := range order {
+				ids = append(ids, entry.Id)
+			}
+			if strings.Join(ids, ",") != tt.want {
+				t.Errorf("PluginInstallOrder() = %v, want %s", ids, tt.want)
+			}
+		})
+	}
+}
@@ -9,16 +9,17 @@ import (
 	"fyne.io/fyne/v2/widget"
 	"image/color"
 	"io"
-	"log"
 	"net/http"
 	"os"
 	"regexp"
 	"strings"
+	"whispering-tiger-ui/Updater"
 	"whispering-tiger-ui/Utilities"
 )
 
 const PluginDir = "./Plugins/"
 
+// PluginListUrl is the README of the plugin repository, its plugin table is used if the plugin index is not available.
 const PluginListUrl = "https://github.com/Sharrnah/whispering-plugins/raw/main/README.md"
 const PluginRelativeUrlPrefix = "https://raw.githubusercontent.com/Sharrnah/whispering-plugins/main/"
 
@@ -40,11 +41,14 @@ type TableData struct {
 }
 
 func DownloadFile(url string) (string, error) {
-	resp, err := http.Get(url)
+	resp, err := Updater.HttpClient().Get(url)
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("downloading %s failed: %s", url, resp.Status)
+	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -140,13 +144,9 @@ func ParseTableIntoStruct(table string, relativeUrlPreviewPart string) []TableDa
 	return tableData
 }
 
-func getVersionAndClassFromReader(pluginCode io.Reader) (string, string, string) {
-	// Read the entire content into a byte slice
-	content, err := io.ReadAll(pluginCode)
-	if err != nil {
-		log.Fatalf("Error reading content: %v", err)
-	}
-
+// getVersionAndClassFromCode reads the version and class of a plugin from its python code.
+// It is only used for plugin files that are not in the plugin index.
+func getVersionAndClassFromCode(content []byte) (string, string, string) {
 	version, class := "", ""
 	scanner := bufio.NewScanner(bytes.NewReader(content))
 	versionLine, classLine := "", ""
@@ -191,20 +191,7 @@ func getVersionAndClassFromReader(pluginCode io.Reader) (string, string, string)
 // FetchAndAnalyzePluginUrl fetches the gist at the given URL and analyzes it for version and class information
 // returns the version, class, hash and binary of the gist
 func FetchAndAnalyzePluginUrl(url string) (string, string, string, []byte) {
-	// Check for GitHub domain in the URL
-	if strings.Contains(url, "github.com") {
-		// Handle GitHub URLs
-		if strings.Contains(url, "/blob/") {
-			// Replace "/blob/" with "/raw/" for regular GitHub files
-			url = strings.Replace(url, "/blob/", "/raw/", 1)
-		} else if strings.Contains(url, "gist.") {
-			// For Gist links, append "/raw" at the end of the URL
-			url += "/raw"
-		}
-	}
-	// Future extension: Add else if conditions for other domains like GitLab
-
-	resp, err := http.Get(url)
+	resp, err := Updater.HttpClient().Get(rawPluginUrl(url))
 	if err != nil {
 		fmt.Printf("Error fetching gist: %v\n", err)
 		return "err", "err", "", nil
@@ -214,10 +201,11 @@ func FetchAndAnalyzePluginUrl(url string) (string, string, string, []byte) {
 	// Read the entire content into a byte slice
 	content, err := io.ReadAll(resp.Body)
 	if err != nil {
-		log.Fatalf("Error reading content: %v", err)
+		fmt.Printf("Error reading content: %v\n", err)
+		return "err", "err", "", nil
 	}
 
-	version, class, hash := getVersionAndClassFromReader(bytes.NewReader(content))
+	version, class, hash := getVersionAndClassFromCode(content)
 
 	return version, class, hash, content
 }
@@ -227,6 +215,13 @@ type LocalPluginFilesData struct {
 	FilePath     string
 	LocalVersion string
 	SHA256       string
+	// PinnedVersion is set if the plugin is pinned, its updates are skipped then.
+	PinnedVersion string
+	// HasPrevious is set if the file replaced by the last install is kept for a rollback.
+	HasPrevious     bool
+	PreviousVersion string
+	// RolledBackVersion is the version that was rolled back from, it is not offered as update again.
+	RolledBackVersion string
 }
 
 func ParseLocalPluginFiles() []LocalPluginFilesData {
@@ -237,6 +232,7 @@ func ParseLocalPluginFiles() []LocalPluginFilesData {
 	if err != nil {
 		println(err)
 	}
+	installedPlugins := loadInstalledPlugins()
 
 	for _, file := range files {
 		if !file.IsDir() && !strings.HasPrefix(file.Name(), ".") && !strings.HasPrefix(file.Name(), "__init__") && (strings.HasSuffix(file.Name(), ".py")) {
@@ -249,17 +245,29 @@ func ParseLocalPluginFiles() []LocalPluginFilesData {
 				fmt.Println("Error reading file:", err)
 				return nil
 			}
-			// Convert the byte slice to a string
-			pluginCode := string(data)
-			pluginCodeReader := strings.NewReader(pluginCode)
-			pluginVersion, pluginClass, sha256 := getVersionAndClassFromReader(pluginCodeReader)
+			pluginVersion, pluginClass, sha256 := getVersionAndClassFromCode(data)
+			installed, recorded := installedPlugins[file.Name()]
+			// files installed from the plugin index use its id and version, unless they were changed since
+			if recorded && installed.SHA256 == sha256 {
+				pluginClass = installed.Id
+				pluginVersion = installed.Version
+			}
 
-			localPluginFiles = append(localPluginFiles, LocalPluginFilesData{
+			localPluginFile := LocalPluginFilesData{
 				Class:        pluginClass,
 				FilePath:     pluginPath,
 				LocalVersion: pluginVersion,
 				SHA256:       sha256,
-			})
+			}
+			if recorded {
+				localPluginFile.PinnedVersion = installed.PinnedVersion
+				localPluginFile.RolledBackVersion = installed.RolledBack
+				if installed.Previous != nil {
+					localPluginFile.HasPrevious = true
+					localPluginFile.PreviousVersion = installed.Previous.Version
+				}
+			}
+			localPluginFiles = append(localPluginFiles, localPluginFile)
 		}
 	}
 	return localPluginFiles
@@ -280,71 +288,92 @@ type PluginUpdateInfo struct {
 	localVersion  string
 	localHash     string
 	class         string
+	// incompatibility is why the remote version does not work with the app or platform, empty if it does.
+	incompatibility string
+	pinnedVersion   string
+	rolledBack      string
 }
 
-func PluginsUpdateCheck(pluginLink string, localPluginFilesData []LocalPluginFilesData) PluginUpdateInfo {
-	remoteVersion, class, remoteHash, _ := FetchAndAnalyzePluginUrl(pluginLink)
+// updateAvailable returns true if an installed plugin has another version in the plugin list that works with the app and platform.
+// Pinned plugins and versions that were rolled back are not updated.
+func (i PluginUpdateInfo) updateAvailable() bool {
+	return i.remoteVersion != i.localVersion && i.localVersion != "" && i.remoteVersion != "" && i.incompatibility == "" &&
+		i.pinnedVersion == "" && i.remoteVersion != i.rolledBack
+}
+
+// PluginsUpdateCheck compares a plugin of the plugin list with the installed file.
+// Plugins of the README fallback are fetched to read their version.
+func PluginsUpdateCheck(plugin *PluginIndexEntry, localPluginFilesData []LocalPluginFilesData) PluginUpdateInfo {
+	resolveReadmePlugin(plugin)
 
-	localPluginFile := FindLocalPluginFileByClass(localPluginFilesData, class)
-	localVersion := localPluginFile.LocalVersion
-	localHash := localPluginFile.SHA256
+	localPluginFile := FindLocalPluginFileByClass(localPluginFilesData, plugin.Id)
 
 	return PluginUpdateInfo{
-		class:         class,
-		remoteVersion: remoteVersion,
-		remoteHash:    remoteHash,
-		localVersion:  localVersion,
-		localHash:     localHash,
+		class:           plugin.Id,
+		remoteVersion:   plugin.Version,
+		remoteHash:      plugin.SHA256,
+		localVersion:    localPluginFile.LocalVersion,
+		localHash:       localPluginFile.SHA256,
+		incompatibility: PluginIncompatibility(*plugin),
+		pinnedVersion:   localPluginFile.PinnedVersion,
+		rolledBack:      localPluginFile.RolledBackVersion,
 	}
 }
 
 func PluginsUpdateAvailable() bool {
-	md, err := DownloadFile(PluginListUrl)
+	plugins, err := LoadPluginIndex()
 	if err != nil {
 		print(err)
 		return false
 	}
 
-	table := ExtractTable(md)
-	tableData := ParseTableIntoStruct(table, PluginRelativeUrlPrefix)
 	localPluginFilesData := ParseLocalPluginFiles()
-	for _, row := range tableData {
-		pluginLink := row.TitleLink
-		pluginUpdateInfo := PluginsUpdateCheck(pluginLink, localPluginFilesData)
-		if pluginUpdateInfo.remoteVersion != pluginUpdateInfo.localVersion && pluginUpdateInfo.localVersion != "" && pluginUpdateInfo.remoteVersion != "" {
+	for i := range plugins {
+		if PluginsUpdateCheck(&plugins[i], localPluginFilesData).updateAvailable() {
 			return true
 		}
 	}
 	return false
 }
 
-func PluginsUpdateWidgetsRefresh(tableDataRow *TableData, localPluginFilesData []LocalPluginFilesData) {
-	titleLink := tableDataRow.TitleLink
-	fmt.Println("Checking update for: " + titleLink)
-	if tableDataRow.Widgets.RemoteVersion != nil {
-		pluginUpdateInfo := PluginsUpdateCheck(titleLink, localPluginFilesData)
+func PluginsUpdateWidgetsRefresh(plugin *PluginIndexEntry, widgets *TableDataWidgets, localPluginFilesData []LocalPluginFilesData) {
+	fmt.Println("Checking update for: " + plugin.Name)
+	if widgets.RemoteVersion != nil {
+		pluginUpdateInfo := PluginsUpdateCheck(plugin, localPluginFilesData)
 
-		tableDataRow.Widgets.RemoteVersion.SetText(lang.L("Newest V") + ": " + pluginUpdateInfo.remoteVersion)
+		widgets.RemoteVersion.SetText(lang.L("Newest V") + ": " + pluginUpdateInfo.remoteVersion)
 		//row.Widgets.CurrentVersion.SetText("Current V: " + localVersion)
-		tableDataRow.Widgets.CurrentVersion.Text = "  " + lang.L("Current V") + ": " + pluginUpdateInfo.localVersion
-
-		if pluginUpdateInfo.remoteVersion != pluginUpdateInfo.localVersion && pluginUpdateInfo.localVersion != "" {
-			tableDataRow.Widgets.CurrentVersion.Color = color.RGBA{R: 240, G: 0, B: 0, A: 255}
-			tableDataRow.Widgets.UpdateButton.Importance = widget.HighImportance
-			tableDataRow.Widgets.UpdateButton.SetText(lang.L("Update"))
+		widgets.CurrentVersion.Text = "  " + lang.L("Current V") + ": " + pluginUpdateInfo.localVersion
+		widgets.UpdateButton.Enable()
+
+		if pluginUpdateInfo.pinnedVersion != "" {
+			widgets.CurrentVersion.Color = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+			widgets.UpdateButton.Importance = widget.LowImportance
+			widgets.UpdateButton.SetText(lang.L("Pinned"))
+			widgets.UpdateButton.Disable()
+		} else if pluginUpdateInfo.incompatibility != "" {
+			widgets.CurrentVersion.Color = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+			widgets.UpdateButton.Importance = widget.LowImportance
+			widgets.UpdateButton.SetText(lang.L("Not compatible"))
+			widgets.UpdateButton.Disable()
+			widgets.RemoteVersion.SetText(lang.L("Newest V") + ": " + pluginUpdateInfo.remoteVersion + "\n" + pluginUpdateInfo.incompatibility)
+		} else if pluginUpdateInfo.updateAvailable() {
+			widgets.CurrentVersion.Color = color.RGBA{R: 240, G: 0, B: 0, A: 255}
+			widgets.UpdateButton.Importance = widget.HighImportance
+			widgets.UpdateButton.SetText(lang.L("Update"))
 		} else {
-			tableDataRow.Widgets.CurrentVersion.Color = color.RGBA{R: 255, G: 255, B: 255, A: 255}
-			tableDataRow.Widgets.UpdateButton.Importance = widget.LowImportance
+			widgets.CurrentVersion.Color = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+			widgets.UpdateButton.Importance = widget.LowImportance
 			if pluginUpdateInfo.remoteHash != pluginUpdateInfo.localHash {
-				tableDataRow.Widgets.UpdateButton.Importance = widget.HighImportance
+				widgets.UpdateButton.Importance = widget.HighImportance
 			}
-			if (pluginUpdateInfo.localVersion == "" && pluginUpdateInfo.remoteVersion != "") || (pluginUpdateInfo.localVersion == "" && pluginUpdateInfo.remoteVersion == "") {
-				tableDataRow.Widgets.UpdateButton.SetText(lang.L("Install"))
+			if pluginUpdateInfo.localVersion == "" {
+				widgets.UpdateButton.SetText(lang.L("Install"))
 			} else {
-				tableDataRow.Widgets.UpdateButton.SetText(lang.L("Reinstall"))
+				widgets.UpdateButton.SetText(lang.L("Reinstall"))
 			}
 		}
-		tableDataRow.Widgets.CurrentVersion.Refresh()
+		widgets.CurrentVersion.Refresh()
 
 		fmt.Println("found remote version: " + pluginUpdateInfo.remoteVersion + ", local version: " + pluginUpdateInfo.localVersion + " class: " + pluginUpdateInfo.class)
 		fmt.Println("remote sha256: " + pluginUpdateInfo.remoteHash + ", local sha256: " + pluginUpdateInfo.localHash)
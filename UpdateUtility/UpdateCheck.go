@@ -1,6 +1,7 @@
 package UpdateUtility
 
 import (
+	"errors"
 	"fmt"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
@@ -25,7 +26,9 @@ var appExec, _ = os.Executable()
 var appPath = filepath.Dir(appExec)
 var currentPlatformFile = filepath.Join(appPath, ".current_platform.yaml")
 
-func versionDownload(updater Updater.UpdatePackages, packageName, filename string, window fyne.Window, startBackend bool, progressTitle string, noDismiss bool, cleanUpFunc func()) error {
+// versionDownload downloads and verifies a package, extracts it into its versioned directory and switches it into the application directory.
+// The replaced version is kept for a rollback.
+func versionDownload(updater Updater.UpdatePackages, packageName, filename string, window fyne.Window, startBackend bool, progressTitle string, noDismiss bool) error {
 	statusBar := widget.NewProgressBar()
 	statusBarContainer := container.NewVBox(statusBar)
 	downloadDialog := dialog.NewCustomWithoutButtons(progressTitle, statusBarContainer, window)
@@ -135,7 +138,7 @@ func versionDownload(updater Updater.UpdatePackages, packageName, filename strin
 				Text: lang.L("Retry Download"),
 				OnTapped: func() {
 					downloadDialog.Hide()
-					err = versionDownload(updater, packageName, filename, window, startBackend, progressTitle, false, cleanUpFunc)
+					err = versionDownload(updater, packageName, filename, window, startBackend, progressTitle, false)
 					if err != nil {
 						Logging.CaptureException(err)
 						dialog.ShowError(err, window)
@@ -146,11 +149,9 @@ func versionDownload(updater Updater.UpdatePackages, packageName, filename strin
 		dialog.ShowError(err, window)
 		return err
 	}
-	appExec, _ := os.Executable()
-
-	// check if the file has the correct hash
+	// check if the file has the correct hash and is signed by the update key
 	statusBarContainer.Add(widget.NewLabel(lang.L("Checking checksum...")))
-	if err := Updater.CheckFileHash(filename, updater.Packages[packageName].SHA256); err != nil {
+	if err := updater.VerifyPackage(packageName, filename); err != nil {
 		fmt.Printf("Error: %s\n", err.Error())
 		Logging.CaptureException(err)
 		downloadDialog.SetButtons([]fyne.CanvasObject{
@@ -165,7 +166,7 @@ func versionDownload(updater Updater.UpdatePackages, packageName, filename strin
 				Text: lang.L("Retry Download"),
 				OnTapped: func() {
 					downloadDialog.Hide()
-					err = versionDownload(updater, packageName, filename, window, startBackend, progressTitle, false, cleanUpFunc)
+					err = versionDownload(updater, packageName, filename, window, startBackend, progressTitle, false)
 					if err != nil {
 						Logging.CaptureException(err)
 						dialog.ShowError(err, window)
@@ -174,33 +175,21 @@ func versionDownload(updater Updater.UpdatePackages, packageName, filename strin
 			},
 		})
 		dialog.ShowError(err, window)
+		if isSignatureError(err) {
+			// never keep a package that is not signed by the update key, it could be from a compromised mirror
+			_ = os.Remove(filename)
+			signatureCheckFailLabel := widget.NewLabel(lang.L("The update package is not signed by the Whispering Tiger update key and was removed. If it still fails, please contact support."))
+			signatureCheckFailLabel.Wrapping = fyne.TextWrapWord
+			statusBarContainer.Add(signatureCheckFailLabel)
+			return err
+		}
 		checksumCheckFailLabel := widget.NewLabel(lang.L("Checksum check failed. Please delete temporary file and download again. If it still fails, please contact support."))
 		checksumCheckFailLabel.Wrapping = fyne.TextWrapWord
 		statusBarContainer.Add(checksumCheckFailLabel)
 		return err
 	}
 
-	// close running backend process
-	if len(RuntimeBackend.BackendsList) > 0 && RuntimeBackend.BackendsList[0].IsRunning() {
-		statusBarContainer.Add(widget.NewLabel(lang.L("Stopping Backend...")))
-		RuntimeBackend.BackendsList[0].Stop()
-		time.Sleep(1 * time.Second)
-	}
-
-	// wait a bit before trying to extract
-	time.Sleep(2 * time.Second)
-
-	// clean up before extracting
-	if cleanUpFunc != nil {
-		statusBarContainer.Add(widget.NewLabel(lang.L("Removing old version...")))
-		cleanUpFunc()
-	}
-
-	// extract
-	statusBarContainer.Add(widget.NewLabel(lang.L("Extracting...")))
-	statusBarContainer.Refresh()
-	err = Updater.Unzip(filename, filepath.Dir(appExec))
-	if err != nil {
+	showInstallError := func(err error) error {
 		Logging.CaptureException(err)
 		downloadDialog.SetButtons([]fyne.CanvasObject{
 			&widget.Button{
@@ -213,19 +202,48 @@ func versionDownload(updater Updater.UpdatePackages, packageName, filename strin
 		dialog.ShowError(err, window)
 		return err
 	}
-	err = os.Remove(filename)
+
+	// extract into the versioned directory while the old version keeps running
+	packageInfo := updater.Packages[packageName]
+	inst, err := installation(packageName)
 	if err != nil {
-		Logging.CaptureException(err)
-		downloadDialog.SetButtons([]fyne.CanvasObject{
-			&widget.Button{
-				Text: lang.L("Close"),
-				OnTapped: func() {
-					downloadDialog.Hide()
-				},
-			},
+		return showInstallError(err)
+	}
+	extractBar := widget.NewProgressBar()
+	statusBarContainer.Add(widget.NewLabel(lang.L("Extracting...")))
+	statusBarContainer.Add(extractBar)
+	statusBarContainer.Refresh()
+	extract := func(src, dest string) error {
+		return Updater.Extract(src, dest, Updater.ArchiveZip, func(extracted, total uint64) {
+			fyne.Do(func() {
+				extractBar.Max = float64(total)
+				extractBar.SetValue(float64(extracted))
+			})
 		})
-		dialog.ShowError(err, window)
-		return err
+	}
+	if err = inst.Stage(packageInfo, filename, extract); err != nil {
+		return showInstallError(err)
+	}
+
+	// close running backend process
+	if packageName == platformPackage && len(RuntimeBackend.BackendsList) > 0 && RuntimeBackend.BackendsList[0].IsRunning() {
+		statusBarContainer.Add(widget.NewLabel(lang.L("Stopping Backend...")))
+		RuntimeBackend.BackendsList[0].Stop()
+		time.Sleep(1 * time.Second)
+
+		// wait a bit before trying to move the files of the backend
+		time.Sleep(2 * time.Second)
+	}
+
+	statusBarContainer.Add(widget.NewLabel(lang.L("Switching to the new version...")))
+	statusBarContainer.Refresh()
+	if err = inst.Switch(packageInfo); err != nil {
+		return showInstallError(err)
+	}
+	afterSwitch(packageName, inst)
+
+	if err = os.Remove(filename); err != nil {
+		return showInstallError(err)
 	}
 
 	statusBarContainer.Add(widget.NewLabel(lang.L("Finished.")))
@@ -245,14 +263,21 @@ func versionDownload(updater Updater.UpdatePackages, packageName, filename strin
 	statusBarContainer.Refresh()
 
 	// start backend
-	if startBackend && !RuntimeBackend.BackendsList[0].IsRunning() {
+	if packageName == platformPackage && startBackend && !RuntimeBackend.BackendsList[0].IsRunning() {
 		statusBarContainer.Add(widget.NewLabel(lang.L("Restarting Backend") + "..."))
 		RuntimeBackend.BackendsList[0].Start()
 	}
+	if packageName == appPackage {
+		askRestartApp(window)
+	}
 
 	return nil
 }
 
+func isSignatureError(err error) bool {
+	return errors.Is(err, Updater.ErrMissingSignature) || errors.Is(err, Updater.ErrInvalidSignature) || errors.Is(err, Updater.ErrMissingChecksum)
+}
+
 func GetCurrentPlatformVersion() string {
 	if Utilities.FileExists(currentPlatformFile) {
 		currentPlatformVersion := Updater.UpdateInfo{}
@@ -271,14 +296,21 @@ func VersionCheck(window fyne.Window, startBackend bool) bool {
 	updater := Updater.UpdatePackages{}
 	err := updater.GetUpdateInfo(updateInfoUrl)
 	if err != nil {
+		if isSignatureError(err) {
+			// a manifest with a wrong signature is not used, but the user should know why there are no updates
+			Logging.CaptureException(err)
+			dialog.ShowError(err, window)
+		}
 		return false
 	}
+	updater.SelectChannel(UpdateChannel())
 
 	// check platform version
 	platformFileWithoutVersion := !Utilities.FileExists(currentPlatformFile) && (Utilities.FileExists("audioWhisper/audioWhisper.exe") || Utilities.FileExists("audioWhisper.py"))
 	platformRequiresUpdate := false
 
-	if GetCurrentPlatformVersion() != updater.Packages["ai_platform"].Version {
+	// a version that was rolled back is not offered again until a newer one is released
+	if GetCurrentPlatformVersion() != updater.Packages["ai_platform"].Version && rolledBackVersion(platformPackage) != updater.Packages["ai_platform"].Version {
 		platformRequiresUpdate = true
 	}
 
@@ -298,32 +330,7 @@ func VersionCheck(window fyne.Window, startBackend bool) bool {
 		dialog.ShowConfirm(platformUpdateTitle, platformUpdateText, func(b bool) {
 			if b {
 				go func() {
-					cleanUpFunc := func() {
-						errors := []error{}
-						cleanupPaths := []string{
-							"audioWhisper",
-							"toolchain",
-							"ffmpeg",
-						}
-						appExec, _ := os.Executable()
-						for _, relPath := range cleanupPaths {
-							oldVersionDir := filepath.Join(filepath.Dir(appExec), relPath)
-							err := os.RemoveAll(oldVersionDir)
-							if err != nil {
-								errors = append(errors, err)
-							}
-						}
-						if len(errors) > 0 {
-							err := fmt.Errorf("Errors during cleanup: %v", errors)
-							Logging.CaptureException(err)
-							dialog.ShowError(err, window)
-						}
-					}
-					err = versionDownload(updater, "ai_platform", "audioWhisper_platform.zip", window, startBackend, progressTitle, true, cleanUpFunc)
-					if err == nil {
-						packageInfo := updater.Packages["ai_platform"]
-						packageInfo.WriteYaml(currentPlatformFile)
-					}
+					_ = versionDownload(updater, "ai_platform", "audioWhisper_platform.zip", window, startBackend, progressTitle, true)
 				}()
 			} else {
 				if platformFileWithoutVersion {
@@ -336,14 +343,26 @@ func VersionCheck(window fyne.Window, startBackend bool) bool {
 
 	// check app version
 	currentAppVersion := Utilities.AppVersion + "." + Utilities.AppBuild
-	if updater.Packages["app"].Version != currentAppVersion {
+	appInfo := updater.Packages["app"]
+	if appInfo.Version != currentAppVersion && appInfo.Version != rolledBackVersion(appPackage) {
 		updateAvailable = true
-		dialog.ShowConfirm(lang.L("App Update available"), lang.L("There is a new Update of the App available. Open GitHub Release page now?"), func(b bool) {
-			if b {
-				uiReleaseUrl, _ := url.Parse("https://github.com/Sharrnah/whispering-ui/releases/latest")
-				fyne.CurrentApp().OpenURL(uiReleaseUrl)
-			}
-		}, window)
+		if len(appInfo.LocationUrls) > 0 {
+			// install the app package like the platform, the running app is replaced on the next start
+			dialog.ShowConfirm(lang.L("App Update available"), lang.L("There is a new Update of the App available. Install new version now?", map[string]interface{}{"Version": appInfo.Version}), func(b bool) {
+				if b {
+					go func() {
+						_ = versionDownload(updater, "app", "whispering-tiger-ui_app.zip", window, startBackend, lang.L("Downloading App Update."), false)
+					}()
+				}
+			}, window)
+		} else {
+			dialog.ShowConfirm(lang.L("App Update available"), lang.L("There is a new Update of the App available. Open GitHub Release page now?"), func(b bool) {
+				if b {
+					uiReleaseUrl, _ := url.Parse("https://github.com/Sharrnah/whispering-ui/releases/latest")
+					fyne.CurrentApp().OpenURL(uiReleaseUrl)
+				}
+			}, window)
+		}
 	}
 
 	return updateAvailable
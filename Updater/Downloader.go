@@ -3,9 +3,8 @@ package Updater
 import (
 	"context"
 	"fmt"
-	"github.com/hashicorp/go-cleanhttp"
 	"io"
-	"io/ioutil"
+	"log"
 	"math"
 	"math/rand"
 	"net/http"
@@ -19,8 +18,6 @@ import (
 const DefaultChunkSize int64 = 20 * 1024 * 1024 // 20 MB
 const defaultConcurrentDownloads = 1
 
-var netClient = cleanhttp.DefaultPooledClient()
-
 type OnProgress func(bytesWritten, contentLength uint64, speed float64)
 
 type WriteCounter struct {
@@ -28,11 +25,15 @@ type WriteCounter struct {
 	ContentLength uint64
 	OnProgress    OnProgress
 	startTime     time.Time
-	LastUpdate    time.Time
-	speedMA       *MovingAverage
+	// startTotal is the size of the resumed part, which is not counted for the speed.
+	startTotal uint64
+	LastUpdate time.Time
+	speedMA    *MovingAverage
 }
 
 type Download struct {
+	// Context stops the download when it is cancelled. The partial file is kept, so the download can be resumed.
+	Context                context.Context
 	Url                    string
 	FallbackUrls           []string
 	UseMultiServerDownload bool
@@ -44,6 +45,7 @@ type Download struct {
 	serverResumeSupport    bool
 	maxRetries             int
 	urlIndex               int
+	urlMutex               sync.Mutex
 	mu                     sync.Mutex
 	cond                   *sync.Cond
 	downloaded             map[int64][]byte
@@ -52,21 +54,32 @@ type Download struct {
 }
 
 func (d *Download) getUserAgent() string {
+	return userAgent()
+}
+
+func userAgent() string {
 	build := Utilities.AppBuild
 
 	return "Whispering_Tiger_DL/" + Utilities.AppVersion + " (" + build + ")"
 }
 
+func (d *Download) context() context.Context {
+	if d.Context == nil {
+		return context.Background()
+	}
+	return d.Context
+}
+
 func (d *Download) getRemoteFileSize() (int64, error) {
-	currentUrl := d.getCurrentUrl()
+	currentUrl := d.CurrentUrl()
 
-	req, err := http.NewRequest("HEAD", currentUrl, nil)
+	req, err := http.NewRequestWithContext(d.context(), "HEAD", currentUrl, nil)
 	if err != nil {
 		return 0, err
 	}
 	req.Header.Set("User-Agent", d.getUserAgent())
 
-	resp, err := netClient.Do(req)
+	resp, err := HttpClient().Do(req)
 	if err != nil {
 		return 0, err
 	}
@@ -85,19 +98,22 @@ func (d *Download) getRemoteFileSizeWithRetry(retries int) (int64, error) {
 		if err == nil {
 			return remoteFileSize, nil
 		}
+		if d.context().Err() != nil {
+			return 0, d.context().Err()
+		}
+		Mirrors.ReportFailure(d.CurrentUrl())
 
 		if i < retries {
 			fmt.Printf("Error getting remote file size %s: %s. Retrying in 1 second...\n", d.Url, err.Error())
 			time.Sleep(1 * time.Second)
 		} else {
 			// Switch to the next fallback url if available
-			if d.urlIndex < len(d.FallbackUrls) {
-				fmt.Printf("All retries for URL %s have failed. Trying the next fallback URL...\n", d.getCurrentUrl())
-				d.urlIndex++
+			if failedUrl := d.CurrentUrl(); d.nextUrl() {
+				fmt.Printf("All retries for URL %s have failed. Trying the next fallback URL...\n", failedUrl)
 				i = -1 // reset retry count for the next url
 				continue
 			} else {
-				fmt.Printf("All retries for URL %s and all fallback URLs have failed.\n", d.getCurrentUrl())
+				fmt.Printf("All retries for URL %s and all fallback URLs have failed.\n", d.CurrentUrl())
 				return 0, err
 			}
 		}
@@ -111,7 +127,7 @@ func (d *Download) addBytes(n uint64) {
 	}
 	if time.Since(d.WriteCounter.LastUpdate).Seconds() >= 1 || n == 0 {
 		elapsed := time.Since(d.WriteCounter.startTime).Seconds()
-		speed := float64(d.WriteCounter.Total) / elapsed
+		speed := float64(d.WriteCounter.Total-min(d.WriteCounter.startTotal, d.WriteCounter.Total)) / elapsed
 		d.WriteCounter.speedMA.Add(speed)
 		avgSpeed := d.WriteCounter.speedMA.Average()
 		d.WriteCounter.OnProgress(d.WriteCounter.Total, d.WriteCounter.ContentLength, avgSpeed)
@@ -131,8 +147,13 @@ func (d *Download) GetTotalDownloadedSize() int64 {
 }
 
 func (d *Download) DownloadFile(retries int) error {
-	progressCtx, progressCancel := context.WithCancel(context.Background())
+	progressCtx, progressCancel := context.WithCancel(d.context())
 	defer progressCancel()
+	defer func() {
+		if err := Mirrors.Save(); err != nil {
+			log.Printf("Error saving mirror scores: %v", err)
+		}
+	}()
 
 	go func() {
 		for {
@@ -189,25 +210,75 @@ func (d *Download) DownloadFile(retries int) error {
 	return d.downloadFileWithRetry(retries, progressCtx, progressCancel)
 }
 
-func (d *Download) getCurrentUrl() string {
-	currentUrl := d.Url
-	if d.urlIndex > 0 && d.urlIndex <= len(d.FallbackUrls) {
-		currentUrl = d.FallbackUrls[d.urlIndex-1]
+// CurrentUrl returns the url that is currently downloaded from.
+func (d *Download) CurrentUrl() string {
+	d.urlMutex.Lock()
+	defer d.urlMutex.Unlock()
+	return d.urlAt(d.urlIndex)
+}
+
+// urlAt returns the url of an url index, 0 is Url and the following are the FallbackUrls.
+func (d *Download) urlAt(index int) string {
+	if index > 0 && index <= len(d.FallbackUrls) {
+		return d.FallbackUrls[index-1]
+	}
+	return d.Url
+}
+
+// nextUrl switches to the next fallback url. Returns false if there is none left.
+func (d *Download) nextUrl() bool {
+	d.urlMutex.Lock()
+	defer d.urlMutex.Unlock()
+	if d.urlIndex < len(d.FallbackUrls) {
+		d.urlIndex++
+		return true
+	}
+	return false
+}
+
+// switchSlowMirror switches to another mirror when the measured throughput of a chunk is far below the known throughput of that mirror.
+// Only mirrors that were measured before and had no recent failures are switched to. Not used with UseMultiServerDownload.
+func (d *Download) switchSlowMirror(mirrorUrl string, throughput float64) {
+	if d.UseMultiServerDownload {
+		return
+	}
+	d.urlMutex.Lock()
+	defer d.urlMutex.Unlock()
+	if d.urlAt(d.urlIndex) != mirrorUrl {
+		// already switched by another chunk
+		return
+	}
+	bestIndex, bestThroughput := -1, 0.0
+	for index := 0; index <= len(d.FallbackUrls); index++ {
+		candidateUrl := d.urlAt(index)
+		if mirrorKey(candidateUrl) == mirrorKey(mirrorUrl) {
+			continue
+		}
+		score, ok := Mirrors.Score(candidateUrl)
+		if ok && score.Failures == 0 && score.Throughput > bestThroughput {
+			bestIndex, bestThroughput = index, score.Throughput
+		}
+	}
+	if bestIndex >= 0 && throughput < bestThroughput*slowMirrorRatio {
+		log.Printf("Mirror %s is slow (%.0f B/s), switching to %s (%.0f B/s)", mirrorKey(mirrorUrl), throughput, mirrorKey(d.urlAt(bestIndex)), bestThroughput)
+		d.urlIndex = bestIndex
 	}
-	return currentUrl
 }
 
 func (d *Download) retryAction(retries int, err error, progressCtx context.Context, contextCancel context.CancelFunc) error {
-	currentUrl := d.getCurrentUrl()
+	currentUrl := d.CurrentUrl()
 
+	if d.context().Err() != nil {
+		return d.context().Err()
+	}
+	Mirrors.ReportFailure(currentUrl)
 	if retries > 0 {
 		fmt.Printf("Error downloading %s: %s. Retrying in 1 seconds...\n", d.Url, err.Error())
 		time.Sleep(2 * time.Second)
 		return d.downloadFileWithRetry(retries-1, progressCtx, contextCancel)
 	} else {
-		if d.urlIndex < len(d.FallbackUrls) {
+		if d.nextUrl() {
 			fmt.Printf("All retries for URL %s have failed. Trying the next fallback URL...\n", currentUrl)
-			d.urlIndex++
 			return d.downloadFileWithRetry(d.maxRetries, progressCtx, contextCancel)
 		} else {
 			fmt.Printf("All retries for URL %s and all fallback URLs have failed.\n", currentUrl)
@@ -232,18 +303,18 @@ func (d *Download) downloadFullFile(url string) error {
 	}
 	defer out.Close()
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(d.context(), "GET", url, nil)
 	if err != nil {
 		return err
 	}
 	req.Header.Set("User-Agent", d.getUserAgent())
-	resp, err := netClient.Do(req)
+	resp, err := HttpClient().Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	_, err = io.Copy(out, resp.Body)
+	_, err = io.Copy(out, &limitedReader{ctx: d.context(), reader: resp.Body})
 	if err != nil {
 		return err
 	}
@@ -254,12 +325,12 @@ func (d *Download) downloadFullFile(url string) error {
 func (d *Download) downloadFileWithRetry(retries int, progressCtx context.Context, contextCancel context.CancelFunc) error {
 	allUrls := append([]string{d.Url}, d.FallbackUrls...)
 	if !d.UseMultiServerDownload {
-		allUrls = []string{d.getCurrentUrl()}
+		allUrls = []string{d.CurrentUrl()}
 	}
 	if len(d.FallbackUrls) > 0 && d.UseMultiServerDownload {
 		rand.Shuffle(len(allUrls), func(i, j int) { allUrls[i], allUrls[j] = allUrls[j], allUrls[i] })
 	}
-	currentUrl := d.getCurrentUrl()
+	currentUrl := d.CurrentUrl()
 
 	err := error(nil)
 
@@ -283,6 +354,19 @@ func (d *Download) downloadFileWithRetry(retries int, progressCtx context.Contex
 		if _, err := os.Stat(d.Filepath); err == nil {
 			startBytes = d.getFileSize(d.Filepath)
 		}
+		// chunks are appended in order, an interrupted write can leave a partial chunk that is downloaded again
+		if startBytes < totalSize && startBytes%d.ChunkSize != 0 {
+			startBytes -= startBytes % d.ChunkSize
+			if err := os.Truncate(d.Filepath, startBytes); err != nil {
+				return err
+			}
+		}
+		if startBytes == totalSize {
+			// already downloaded, e.g. when the download was interrupted while checking or extracting it
+			d.WriteCounter.Total = uint64(totalSize)
+			d.addBytes(0)
+			return nil
+		}
 
 		// Set ResumeSupport to true if the file download is resumed and the server supports resuming
 		d.isResumed = startBytes > 0 && d.serverResumeSupport
@@ -307,6 +391,7 @@ func (d *Download) downloadFileWithRetry(retries int, progressCtx context.Contex
 
 		// Initialize the WriteCounter values
 		d.WriteCounter.Total = uint64(startBytes)
+		d.WriteCounter.startTotal = uint64(startBytes)
 		d.WriteCounter.ContentLength = uint64(totalSize)
 
 		// Initialize d.nextWrite
@@ -339,22 +424,26 @@ func (d *Download) downloadFileWithRetry(retries int, progressCtx context.Contex
 							end = totalSize - 1
 						}
 
-						if !d.UseMultiServerDownload {
-							currentUrl = d.getCurrentUrl()
-						} else {
+						// the mirror can change between chunks when a slow mirror is switched
+						chunkUrl := d.CurrentUrl()
+						if d.UseMultiServerDownload {
 							// cycle through the servers in allUrls in a round-robin fashion.
-							currentUrl = allUrls[chunkIndex%int64(len(allUrls))]
-							println("Downloading chunk %d of %d from %s", chunkIndex, totalChunks, currentUrl)
+							chunkUrl = allUrls[chunkIndex%int64(len(allUrls))]
+							println("Downloading chunk %d of %d from %s", chunkIndex, totalChunks, chunkUrl)
 						}
 
-						chunk, downloaded, err := d.downloadChunk(currentUrl, start, end)
+						chunk, downloaded, err := d.downloadChunk(chunkUrl, start, end)
 						if err != nil {
 							errorsChannel <- err
 							return
 						}
 
 						if downloaded {
-							chunksChannel <- *chunk
+							select {
+							case chunksChannel <- *chunk:
+							case <-progressCtx.Done():
+								return
+							}
 						}
 					}
 				}
@@ -366,6 +455,8 @@ func (d *Download) downloadFileWithRetry(retries int, progressCtx context.Contex
 			select {
 			case err := <-errorsChannel:
 				return d.retryAction(retries, err, progressCtx, contextCancel)
+			case <-d.context().Done():
+				return d.context().Err()
 			case chunk := <-chunksChannel:
 				d.mu.Lock()
 				d.downloaded[chunk.offset] = chunk.data
@@ -466,14 +557,15 @@ func (d *Download) CreateFinishedFile(fileExtension string, maxRetries int, retr
 }
 
 func (d *Download) downloadChunk(url string, start, end int64) (*Chunk, bool, error) {
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(d.context(), "GET", url, nil)
 	if err != nil {
 		return nil, false, err
 	}
 	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
 	req.Header.Set("User-Agent", d.getUserAgent())
 
-	resp, err := netClient.Do(req)
+	requestStart := time.Now()
+	resp, err := HttpClient().Do(req)
 	if err != nil {
 		return nil, false, err
 	}
@@ -485,11 +577,19 @@ func (d *Download) downloadChunk(url string, start, end int64) (*Chunk, bool, er
 		return nil, false, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
-	data, err := ioutil.ReadAll(resp.Body)
+	body := &limitedReader{ctx: d.context(), reader: resp.Body}
+	data, err := io.ReadAll(body)
 	if err != nil {
 		return nil, false, err
 	}
 
+	// the time waited for the bandwidth limit is not the fault of the mirror
+	transferTime := time.Since(requestStart) - body.waited
+	Mirrors.ReportThroughput(url, int64(len(data)), transferTime)
+	if len(data) >= minThroughputSample && transferTime > 0 {
+		d.switchSlowMirror(url, float64(len(data))/transferTime.Seconds())
+	}
+
 	return &Chunk{
 		offset: start,
 		data:   data,
@@ -0,0 +1,333 @@
+package Updater
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// VersionsDir is the directory inside the application directory that contains the staged and retained versions of the update packages.
+const VersionsDir = "versions"
+
+const (
+	installStateFile      = "install.json"
+	retainedVersionPrefix = "previous-"
+	stagingSuffix         = ".tmp"
+)
+
+var ErrNoPreviousVersion = errors.New("no previous version to roll back to")
+
+// InstallState is the persisted state of an installed update package.
+type InstallState struct {
+	Current UpdateInfo `json:"current"`
+	// Previous is the retained version that can be rolled back to, nil if there is none.
+	Previous *UpdateInfo `json:"previous,omitempty"`
+	// Replaced are earlier retained versions that were replaced as previous version by a switch.
+	// They are kept until the current version started successfully.
+	Replaced []UpdateInfo `json:"replaced,omitempty"`
+	// SuccessfulStarts counts the successful starts of the current version since it was installed.
+	SuccessfulStarts int `json:"successful_starts"`
+	// RolledBack is the version that was rolled back from, it is not offered as update again.
+	RolledBack string `json:"rolled_back,omitempty"`
+	// Switch is set while a version is switched into the application directory, so an interrupted switch is completed on the next start.
+	Switch *pendingSwitch `json:"switch,omitempty"`
+}
+
+// pendingSwitch moves the entries of the application directory to Target and the entries of Source into the application directory.
+type pendingSwitch struct {
+	Source   string     `json:"source"`
+	Target   string     `json:"target"`
+	To       UpdateInfo `json:"to"`
+	Rollback bool       `json:"rollback,omitempty"`
+	// Incoming are the entries of Source, Outgoing the package entries that are only moved out of the application directory.
+	Incoming []string `json:"incoming"`
+	Outgoing []string `json:"outgoing"`
+}
+
+// Installation installs versions of an update package into a versioned side directory and switches them into the application directory.
+// Every top level entry is switched with a rename, which is atomic on the same volume, and the replaced entries are kept as previous version for a rollback.
+type Installation struct {
+	// AppDir is the directory the package is installed into.
+	AppDir string
+	// Package is the name of the update package.
+	Package string
+	// Entries are the files and directories of the package in the application directory,
+	// they are moved out on a switch even if the new version does not contain them anymore.
+	Entries []string
+	state   InstallState
+}
+
+// LoadInstallation reads the install state of a package. installed is the currently installed version,
+// used if the package was not installed by an Installation yet.
+func LoadInstallation(appDir, packageName string, entries []string, installed UpdateInfo) (*Installation, error) {
+	i := &Installation{AppDir: appDir, Package: packageName, Entries: entries}
+	data, err := os.ReadFile(i.stateFile())
+	if os.IsNotExist(err) {
+		i.state.Current = installed
+		return i, nil
+	} else if err != nil {
+		return i, err
+	}
+	if err := json.Unmarshal(data, &i.state); err != nil {
+		return i, fmt.Errorf("invalid install state of %s: %w", packageName, err)
+	}
+	return i, nil
+}
+
+// State returns the install state of the package.
+func (i *Installation) State() InstallState {
+	return i.state
+}
+
+// CanRollback returns whether a previous version is retained.
+func (i *Installation) CanRollback() bool {
+	return i.state.Previous != nil && i.state.Switch == nil
+}
+
+func (i *Installation) dir() string {
+	return filepath.Join(i.AppDir, VersionsDir, i.Package)
+}
+
+func (i *Installation) stateFile() string {
+	return filepath.Join(i.dir(), installStateFile)
+}
+
+// versionDirName returns a directory name for a version, which is used as file name.
+func versionDirName(version string) string {
+	if version == "" {
+		version = "unknown"
+	}
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, version)
+}
+
+func (i *Installation) retainedDir(info UpdateInfo) string {
+	return filepath.Join(i.dir(), retainedVersionPrefix+versionDirName(info.Version))
+}
+
+func (i *Installation) stagedDir(info UpdateInfo) string {
+	return filepath.Join(i.dir(), versionDirName(info.Version))
+}
+
+func (i *Installation) save() error {
+	if err := os.MkdirAll(i.dir(), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(i.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tempFile := i.stateFile() + stagingSuffix
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tempFile, i.stateFile())
+}
+
+// isRetained returns true if a directory is the retained previous version or a replaced version.
+func (i *Installation) isRetained(dir string) bool {
+	if i.state.Previous != nil && dir == i.retainedDir(*i.state.Previous) {
+		return true
+	}
+	return slices.ContainsFunc(i.state.Replaced, func(info UpdateInfo) bool {
+		return dir == i.retainedDir(info)
+	})
+}
+
+// removeUnused removes the staged versions and leftovers of the versions directory, except the retained versions and the keep directories.
+func (i *Installation) removeUnused(keep ...string) error {
+	entries, err := os.ReadDir(i.dir())
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var errs []error
+	for _, entry := range entries {
+		entryDir := filepath.Join(i.dir(), entry.Name())
+		if entry.Name() == installStateFile || slices.Contains(keep, entryDir) || i.isRetained(entryDir) {
+			continue
+		}
+		errs = append(errs, os.RemoveAll(filepath.Join(i.dir(), entry.Name())))
+	}
+	return errors.Join(errs...)
+}
+
+// Stage extracts a downloaded package into the versioned directory of its version, the application directory is not changed.
+// extract writes the package into a directory, like Unzip. A failed extraction is kept, so staging the same version again can continue it.
+func (i *Installation) Stage(info UpdateInfo, fileName string, extract func(src, dest string) error) error {
+	if i.state.Switch != nil {
+		return fmt.Errorf("a previous switch of %s is not completed", i.Package)
+	}
+	stagedDir := i.stagedDir(info)
+	tempDir := stagedDir + stagingSuffix
+	// an interrupted staging of the same version is continued, see Extract
+	if err := i.removeUnused(tempDir); err != nil {
+		return err
+	}
+	if err := extract(fileName, tempDir); err != nil {
+		return err
+	}
+	return os.Rename(tempDir, stagedDir)
+}
+
+// Switch moves the staged version into the application directory and keeps the replaced entries as previous version.
+// The version that was the previous one so far is kept until the new version started successfully, see ReportSuccessfulStart.
+func (i *Installation) Switch(info UpdateInfo) error {
+	source := i.stagedDir(info)
+	if _, err := os.Stat(source); err != nil {
+		return fmt.Errorf("version %s of %s is not staged: %w", info.Version, i.Package, err)
+	}
+	target := i.retainedDir(i.state.Current)
+	if exists(target) {
+		// an older copy of the current version, retained by an earlier switch to the same version, is replaced by the current one
+		if i.state.Previous != nil && i.retainedDir(*i.state.Previous) == target {
+			i.state.Previous = nil
+		}
+		i.state.Replaced = slices.DeleteFunc(i.state.Replaced, func(replaced UpdateInfo) bool {
+			return i.retainedDir(replaced) == target
+		})
+		if err := i.save(); err != nil {
+			return err
+		}
+		if err := os.RemoveAll(target); err != nil {
+			return err
+		}
+	}
+	return i.beginSwitch(source, target, info, false)
+}
+
+// Rollback switches the retained previous version back into the application directory and removes the current one.
+func (i *Installation) Rollback() error {
+	if !i.CanRollback() {
+		return ErrNoPreviousVersion
+	}
+	target := i.stagedDir(i.state.Current)
+	if err := os.RemoveAll(target); err != nil {
+		return err
+	}
+	return i.beginSwitch(i.retainedDir(*i.state.Previous), target, *i.state.Previous, true)
+}
+
+func (i *Installation) beginSwitch(source, target string, to UpdateInfo, rollback bool) error {
+	entries, err := os.ReadDir(source)
+	if err != nil {
+		return err
+	}
+	pending := &pendingSwitch{Source: source, Target: target, To: to, Rollback: rollback}
+	for _, entry := range entries {
+		pending.Incoming = append(pending.Incoming, entry.Name())
+	}
+	for _, entry := range i.Entries {
+		if !slices.Contains(pending.Incoming, entry) {
+			pending.Outgoing = append(pending.Outgoing, entry)
+		}
+	}
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return err
+	}
+	i.state.Switch = pending
+	if err := i.save(); err != nil {
+		i.state.Switch = nil
+		return err
+	}
+	return i.completeSwitch()
+}
+
+func exists(fileName string) bool {
+	_, err := os.Lstat(fileName)
+	return err == nil
+}
+
+// completeSwitch moves the entries of a pending switch. Entries that were already moved are skipped, so it can continue an interrupted switch.
+func (i *Installation) completeSwitch() error {
+	pending := i.state.Switch
+	moveOut := func(name string) error {
+		current := filepath.Join(i.AppDir, name)
+		if !exists(current) {
+			return nil
+		}
+		if exists(filepath.Join(pending.Target, name)) {
+			return fmt.Errorf("cannot move %s out of the way, %s already exists", name, pending.Target)
+		}
+		return os.Rename(current, filepath.Join(pending.Target, name))
+	}
+	for _, name := range pending.Outgoing {
+		if err := moveOut(name); err != nil {
+			return err
+		}
+	}
+	for _, name := range pending.Incoming {
+		incoming := filepath.Join(pending.Source, name)
+		if !exists(incoming) {
+			continue
+		}
+		if err := moveOut(name); err != nil {
+			return err
+		}
+		if err := os.Rename(incoming, filepath.Join(i.AppDir, name)); err != nil {
+			return err
+		}
+	}
+
+	from := i.state.Current
+	i.state.Current = pending.To
+	i.state.SuccessfulStarts = 0
+	i.state.Switch = nil
+	if pending.Rollback {
+		i.state.Previous = nil
+		i.state.RolledBack = from.Version
+	} else {
+		i.state.RolledBack = ""
+		if i.state.Previous != nil {
+			i.state.Replaced = append(i.state.Replaced, *i.state.Previous)
+			i.state.Previous = nil
+		}
+		if entries, err := os.ReadDir(pending.Target); err == nil && len(entries) > 0 {
+			i.state.Previous = &from
+		}
+	}
+	if err := i.save(); err != nil {
+		return err
+	}
+	return i.removeUnused()
+}
+
+// Recover completes a switch that was interrupted, for example by a crash or power loss. Returns true if a switch was completed.
+func (i *Installation) Recover() (bool, error) {
+	if i.state.Switch == nil {
+		return false, nil
+	}
+	return true, i.completeSwitch()
+}
+
+// ReportSuccessfulStart counts a successful start of the current version.
+// The replaced versions are removed after the first successful start and the previous version after keepStarts successful starts,
+// returns true if the previous version was removed.
+func (i *Installation) ReportSuccessfulStart(keepStarts int) (bool, error) {
+	if (i.state.Previous == nil && len(i.state.Replaced) == 0) || i.state.Switch != nil {
+		return false, nil
+	}
+	i.state.SuccessfulStarts++
+	removeReplaced := len(i.state.Replaced) > 0
+	i.state.Replaced = nil
+	removePrevious := i.state.Previous != nil && i.state.SuccessfulStarts >= keepStarts
+	if removePrevious {
+		i.state.Previous = nil
+	}
+	if err := i.save(); err != nil {
+		return false, err
+	}
+	if !removeReplaced && !removePrevious {
+		return false, nil
+	}
+	return removePrevious, i.removeUnused()
+}
@@ -0,0 +1,209 @@
+package Updater
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// installStep is an action on an installation: "install" stages and switches a version, "start" reports a successful start,
+// "failed start" only checks that a rollback is offered and "rollback" switches back to the previous version.
+type installStep struct {
+	action  string
+	version string
+}
+
+func install(version string) installStep { return installStep{action: "install", version: version} }
+
+var (
+	started      = installStep{action: "start"}
+	failedStart  = installStep{action: "failed start"}
+	rollbackStep = installStep{action: "rollback"}
+)
+
+func TestInstallation(t *testing.T) {
+	tests := []struct {
+		name       string
+		keepStarts int
+		steps      []installStep
+		// wantVersion is the content of the version file in the application directory
+		wantVersion    string
+		wantPrevious   string
+		wantReplaced   []string
+		wantStarts     int
+		wantRolledBack string
+		// wantDirs are the retained directories of the versions directory
+		wantDirs []string
+	}{
+		{
+			name:        "install",
+			steps:       []installStep{install("2")},
+			wantVersion: "2", wantPrevious: "1", wantDirs: []string{"previous-1"},
+		},
+		{
+			name:        "successful starts below the kept starts",
+			keepStarts:  3,
+			steps:       []installStep{install("2"), started, started},
+			wantVersion: "2", wantPrevious: "1", wantStarts: 2, wantDirs: []string{"previous-1"},
+		},
+		{
+			name:        "previous version removed after the kept starts",
+			keepStarts:  3,
+			steps:       []installStep{install("2"), started, started, started},
+			wantVersion: "2", wantStarts: 3,
+		},
+		{
+			name:        "failed start and rollback",
+			steps:       []installStep{install("2"), failedStart, rollbackStep},
+			wantVersion: "1", wantRolledBack: "2",
+		},
+		{
+			name:        "install twice before a start keeps the replaced version",
+			steps:       []installStep{install("2"), install("3")},
+			wantVersion: "3", wantPrevious: "2", wantReplaced: []string{"1"}, wantDirs: []string{"previous-1", "previous-2"},
+		},
+		{
+			name:        "replaced version removed after the first start",
+			keepStarts:  3,
+			steps:       []installStep{install("2"), install("3"), started},
+			wantVersion: "3", wantPrevious: "2", wantStarts: 1, wantDirs: []string{"previous-2"},
+		},
+		{
+			name:        "failed start of the second install and rollback",
+			steps:       []installStep{install("2"), install("3"), failedStart, rollbackStep},
+			wantVersion: "2", wantReplaced: []string{"1"}, wantRolledBack: "3", wantDirs: []string{"previous-1"},
+		},
+		{
+			name:        "start after rollback removes the replaced version",
+			keepStarts:  3,
+			steps:       []installStep{install("2"), install("3"), rollbackStep, started},
+			wantVersion: "2", wantStarts: 1, wantRolledBack: "3",
+		},
+		{
+			name:        "install a retained version again",
+			steps:       []installStep{install("2"), install("1"), install("3")},
+			wantVersion: "3", wantPrevious: "1", wantReplaced: []string{"2"}, wantDirs: []string{"previous-1", "previous-2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			appDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(appDir, "version.txt"), []byte("1"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(appDir, "user.txt"), []byte("user"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			inst, err := LoadInstallation(appDir, "platform", []string{"version.txt", "old.txt"}, UpdateInfo{Version: "1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, step := range tt.steps {
+				switch step.action {
+				case "install":
+					extract := func(src, dest string) error {
+						if err := os.MkdirAll(dest, 0755); err != nil {
+							return err
+						}
+						return os.WriteFile(filepath.Join(dest, "version.txt"), []byte(step.version), 0644)
+					}
+					if err := inst.Stage(UpdateInfo{Version: step.version}, "", extract); err != nil {
+						t.Fatalf("Stage(%s) error = %v", step.version, err)
+					}
+					if err := inst.Switch(UpdateInfo{Version: step.version}); err != nil {
+						t.Fatalf("Switch(%s) error = %v", step.version, err)
+					}
+				case "start":
+					if _, err := inst.ReportSuccessfulStart(tt.keepStarts); err != nil {
+						t.Fatalf("ReportSuccessfulStart() error = %v", err)
+					}
+				case "failed start":
+					if !inst.CanRollback() || inst.State().SuccessfulStarts != 0 {
+						t.Fatalf("no rollback offered after a failed start: %+v", inst.State())
+					}
+				case "rollback":
+					if err := inst.Rollback(); err != nil {
+						t.Fatalf("Rollback() error = %v", err)
+					}
+				}
+			}
+
+			// the state is read again, like on the next start
+			inst, err = LoadInstallation(appDir, "platform", inst.Entries, UpdateInfo{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			state := inst.State()
+			if data, _ := os.ReadFile(filepath.Join(appDir, "version.txt")); string(data) != tt.wantVersion {
+				t.Errorf("installed version = %q, want %q", data, tt.wantVersion)
+			}
+			if state.Current.Version != tt.wantVersion {
+				t.Errorf("Current = %q, want %q", state.Current.Version, tt.wantVersion)
+			}
+			if data, _ := os.ReadFile(filepath.Join(appDir, "user.txt")); string(data) != "user" {
+				t.Errorf("file that is not part of the package was changed: %q", data)
+			}
+			previous := ""
+			if state.Previous != nil {
+				previous = state.Previous.Version
+			}
+			if previous != tt.wantPrevious || inst.CanRollback() != (tt.wantPrevious != "") {
+				t.Errorf("Previous = %q, CanRollback() = %v, want %q", previous, inst.CanRollback(), tt.wantPrevious)
+			}
+			var replaced []string
+			for _, info := range state.Replaced {
+				replaced = append(replaced, info.Version)
+			}
+			if !slices.Equal(replaced, tt.wantReplaced) {
+				t.Errorf("Replaced = %v, want %v", replaced, tt.wantReplaced)
+			}
+			if state.SuccessfulStarts != tt.wantStarts {
+				t.Errorf("SuccessfulStarts = %d, want %d", state.SuccessfulStarts, tt.wantStarts)
+			}
+			if state.RolledBack != tt.wantRolledBack {
+				t.Errorf("RolledBack = %q, want %q", state.RolledBack, tt.wantRolledBack)
+			}
+
+			var dirs []string
+			entries, _ := os.ReadDir(inst.dir())
+			for _, entry := range entries {
+				if entry.Name() != installStateFile {
+					dirs = append(dirs, entry.Name())
+				}
+			}
+			if !slices.Equal(dirs, tt.wantDirs) {
+				t.Errorf("versions directory = %v, want %v", dirs, tt.wantDirs)
+			}
+			// retained directories contain their version
+			for _, dir := range tt.wantDirs {
+				data, err := os.ReadFile(filepath.Join(inst.dir(), dir, "version.txt"))
+				if err != nil || "previous-"+string(data) != dir {
+					t.Errorf("%s contains version %q, %v", dir, data, err)
+				}
+			}
+		})
+	}
+}
+
+func TestInstallationErrors(t *testing.T) {
+	appDir := t.TempDir()
+	inst, err := LoadInstallation(appDir, "platform", nil, UpdateInfo{Version: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := inst.Rollback(); !errors.Is(err, ErrNoPreviousVersion) {
+		t.Errorf("Rollback() without previous version error = %v, want %v", err, ErrNoPreviousVersion)
+	}
+	if err := inst.Switch(UpdateInfo{Version: "2"}); err == nil {
+		t.Error("Switch() of a version that is not staged error = nil")
+	}
+	extractErr := errors.New("extract failed")
+	if err := inst.Stage(UpdateInfo{Version: "2"}, "", func(src, dest string) error { return extractErr }); !errors.Is(err, extractErr) {
+		t.Errorf("Stage() error = %v, want %v", err, extractErr)
+	}
+	if exists(inst.stagedDir(UpdateInfo{Version: "2"})) {
+		t.Error("failed staging created the version directory")
+	}
+}
@@ -0,0 +1,235 @@
+package Updater
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// mirrorProbeInterval is the time after which a mirror is probed again before a download.
+	mirrorProbeInterval = 6 * time.Hour
+	mirrorProbeTimeout  = 5 * time.Second
+	// unmeasuredThroughput is assumed for mirrors that were not downloaded from yet, in bytes per second.
+	unmeasuredThroughput = 1024 * 1024
+	// minThroughputSample is the minimum size of a transfer used to measure the throughput, smaller ones mostly measure the latency.
+	minThroughputSample = 256 * 1024
+	// slowMirrorRatio switches to another mirror during a download if the current one is slower than this ratio of the other one.
+	slowMirrorRatio = 0.3
+)
+
+// MirrorScore is the measured health of a download mirror.
+type MirrorScore struct {
+	// LatencyMs is the response time of the last probe in milliseconds.
+	LatencyMs    float64 `json:"latency_ms"`
+	RangeSupport bool    `json:"range_support"`
+	// Throughput is the moving average of the download speed per connection in bytes per second, 0 if not measured yet.
+	Throughput float64 `json:"throughput"`
+	// Failures counts the failed requests since the last successful one or the last successful probe.
+	Failures  int       `json:"failures"`
+	LastProbe time.Time `json:"last_probe"`
+}
+
+// Value returns the score of the mirror, higher is better.
+// The throughput is reduced by the latency, missing range support (no resuming and parallel chunks) and recent failures.
+func (s MirrorScore) Value() float64 {
+	throughput := s.Throughput
+	if throughput <= 0 {
+		throughput = unmeasuredThroughput
+	}
+	score := throughput / (1 + s.LatencyMs/1000)
+	if !s.RangeSupport {
+		score /= 2
+	}
+	return score / math.Pow(2, float64(min(s.Failures, 10)))
+}
+
+// MirrorHealth keeps the scores of download mirrors, persisted between runs.
+// Mirrors are identified by their host, so all files of a mirror share its score.
+type MirrorHealth struct {
+	mutex    sync.Mutex
+	mirrors  map[string]*MirrorScore
+	filePath string
+}
+
+// Mirrors are the mirror scores used by all downloads.
+var Mirrors = &MirrorHealth{mirrors: map[string]*MirrorScore{}}
+
+func mirrorKey(mirrorUrl string) string {
+	parsedUrl, err := url.Parse(mirrorUrl)
+	if err != nil || parsedUrl.Host == "" {
+		return mirrorUrl
+	}
+	return parsedUrl.Host
+}
+
+// Load reads the scores from a file, which is also used by Save. A missing file is not an error.
+func (h *MirrorHealth) Load(filePath string) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.filePath = filePath
+	data, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	mirrors := map[string]*MirrorScore{}
+	if err := json.Unmarshal(data, &mirrors); err != nil {
+		return err
+	}
+	h.mirrors = mirrors
+	return nil
+}
+
+// Save writes the scores to the file of Load. Does nothing if no file was loaded.
+func (h *MirrorHealth) Save() error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.filePath == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(h.mirrors, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(h.filePath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(h.filePath, data, 0644)
+}
+
+// Score returns the score of the mirror of an url, false if it was never used or probed.
+func (h *MirrorHealth) Score(mirrorUrl string) (MirrorScore, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	score, ok := h.mirrors[mirrorKey(mirrorUrl)]
+	if !ok {
+		return MirrorScore{}, false
+	}
+	return *score, true
+}
+
+func (h *MirrorHealth) update(mirrorUrl string, change func(score *MirrorScore)) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	key := mirrorKey(mirrorUrl)
+	score, ok := h.mirrors[key]
+	if !ok {
+		score = &MirrorScore{}
+		h.mirrors[key] = score
+	}
+	change(score)
+}
+
+// ReportThroughput adds a measured transfer to the throughput of a mirror. Small transfers are ignored.
+func (h *MirrorHealth) ReportThroughput(mirrorUrl string, bytes int64, duration time.Duration) {
+	if bytes < minThroughputSample || duration <= 0 {
+		return
+	}
+	throughput := float64(bytes) / duration.Seconds()
+	h.update(mirrorUrl, func(score *MirrorScore) {
+		if score.Throughput <= 0 {
+			score.Throughput = throughput
+		} else {
+			score.Throughput = 0.7*score.Throughput + 0.3*throughput
+		}
+		score.Failures = 0
+	})
+}
+
+// ReportFailure lowers the score of a mirror after a failed request.
+func (h *MirrorHealth) ReportFailure(mirrorUrl string) {
+	h.update(mirrorUrl, func(score *MirrorScore) {
+		score.Failures++
+	})
+}
+
+// Probe requests the first byte of a file to measure the latency and range support of its mirror.
+func (h *MirrorHealth) Probe(ctx context.Context, mirrorUrl string) error {
+	ctx, cancel := context.WithTimeout(ctx, mirrorProbeTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", mirrorUrl, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Range", "bytes=0-0")
+	req.Header.Set("User-Agent", userAgent())
+
+	start := time.Now()
+	resp, err := HttpClient().Do(req)
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+			err = fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		}
+	}
+	latency := time.Since(start)
+	if err != nil && ctx.Err() != nil && ctx.Err() != context.DeadlineExceeded {
+		// cancelled by the caller, not a problem of the mirror
+		return err
+	}
+	h.update(mirrorUrl, func(score *MirrorScore) {
+		score.LastProbe = time.Now()
+		if err != nil {
+			score.Failures++
+			return
+		}
+		score.Failures = 0
+		score.LatencyMs = float64(latency.Milliseconds())
+		score.RangeSupport = resp.StatusCode == http.StatusPartialContent
+	})
+	return err
+}
+
+// Rank returns the urls ordered by the score of their mirrors, the best first.
+// Mirrors that were not probed within the probe interval are probed first, in parallel.
+func (h *MirrorHealth) Rank(ctx context.Context, urls []string) []string {
+	var wg sync.WaitGroup
+	probed := map[string]bool{}
+	for _, mirrorUrl := range urls {
+		score, ok := h.Score(mirrorUrl)
+		if probed[mirrorKey(mirrorUrl)] || (ok && time.Since(score.LastProbe) < mirrorProbeInterval) {
+			continue
+		}
+		probed[mirrorKey(mirrorUrl)] = true
+		wg.Add(1)
+		go func(mirrorUrl string) {
+			defer wg.Done()
+			if err := h.Probe(ctx, mirrorUrl); err != nil {
+				log.Printf("Probing mirror %s failed: %v", mirrorKey(mirrorUrl), err)
+			}
+		}(mirrorUrl)
+	}
+	wg.Wait()
+	if len(probed) > 0 {
+		if err := h.Save(); err != nil {
+			log.Printf("Error saving mirror scores: %v", err)
+		}
+	}
+
+	values := map[string]float64{}
+	for _, mirrorUrl := range urls {
+		score, _ := h.Score(mirrorUrl)
+		values[mirrorUrl] = score.Value()
+	}
+	ranked := slices.Clone(urls)
+	slices.SortStableFunc(ranked, func(a, b string) int {
+		if values[a] > values[b] {
+			return -1
+		} else if values[a] < values[b] {
+			return 1
+		}
+		return 0
+	})
+	return ranked
+}
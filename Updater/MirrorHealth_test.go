@@ -0,0 +1,366 @@
+package Updater
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testMirror is a mirror server that counts its requests.
+type testMirror struct {
+	*httptest.Server
+	requests atomic.Int32
+}
+
+// newTestMirror serves content with a delay. Mirrors without range support ignore the Range header, failing mirrors answer with an error.
+func newTestMirror(t *testing.T, content string, delay time.Duration, rangeSupport, failing bool) *testMirror {
+	t.Helper()
+	mirror := &testMirror{}
+	mirror.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mirror.requests.Add(1)
+		time.Sleep(delay)
+		if failing {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if !rangeSupport {
+			r.Header.Del("Range")
+		}
+		http.ServeContent(w, r, r.URL.Path, time.Time{}, strings.NewReader(content))
+	}))
+	t.Cleanup(mirror.Close)
+	return mirror
+}
+
+// useTestMirrors replaces the mirror scores of all downloads during a test.
+func useTestMirrors(t *testing.T, scores map[string]*MirrorScore) *MirrorHealth {
+	t.Helper()
+	if scores == nil {
+		scores = map[string]*MirrorScore{}
+	}
+	previous := Mirrors
+	Mirrors = &MirrorHealth{mirrors: scores}
+	t.Cleanup(func() { Mirrors = previous })
+	return Mirrors
+}
+
+func TestMirrorHealthRank(t *testing.T) {
+	fast := newTestMirror(t, "model", 0, true, false)
+	slow := newTestMirror(t, "model", 300*time.Millisecond, true, false)
+	noRange := newTestMirror(t, "model", 0, false, false)
+	failing := newTestMirror(t, "model", 0, true, true)
+	measured := newTestMirror(t, "model", 0, true, false)
+
+	health := &MirrorHealth{mirrors: map[string]*MirrorScore{}}
+	filePath := filepath.Join(t.TempDir(), "mirrors.json")
+	if err := health.Load(filePath); err != nil {
+		t.Fatalf("Load() of a missing file error = %v", err)
+	}
+	// probed recently with a known throughput, it is not probed again
+	health.mirrors[mirrorKey(measured.URL)] = &MirrorScore{Throughput: 10 * unmeasuredThroughput, RangeSupport: true, LastProbe: time.Now()}
+
+	urls := []string{failing.URL + "/model.bin", noRange.URL + "/model.bin", slow.URL + "/model.bin", fast.URL + "/model.bin", measured.URL + "/model.bin", fast.URL + "/other.bin"}
+	ranked := health.Rank(context.Background(), urls)
+	want := []string{measured.URL + "/model.bin", fast.URL + "/model.bin", fast.URL + "/other.bin", slow.URL + "/model.bin", noRange.URL + "/model.bin", failing.URL + "/model.bin"}
+	if !slices.Equal(ranked, want) {
+		t.Errorf("Rank() = %v, want %v", ranked, want)
+	}
+
+	for _, tt := range []struct {
+		name         string
+		mirror       *testMirror
+		wantRequests int32
+		wantRange    bool
+		wantFailures int
+	}{
+		{name: "fast", mirror: fast, wantRequests: 1, wantRange: true},
+		{name: "slow", mirror: slow, wantRequests: 1, wantRange: true},
+		{name: "no range support", mirror: noRange, wantRequests: 1},
+		{name: "failing", mirror: failing, wantRequests: 1, wantFailures: 1},
+		{name: "recently probed", mirror: measured, wantRange: true},
+	} {
+		// mirrors are probed once per host
+		if requests := tt.mirror.requests.Load(); requests != tt.wantRequests {
+			t.Errorf("%s mirror: %d requests, want %d", tt.name, requests, tt.wantRequests)
+		}
+		score, ok := health.Score(tt.mirror.URL + "/any.bin")
+		if !ok || score.RangeSupport != tt.wantRange || score.Failures != tt.wantFailures {
+			t.Errorf("%s mirror score = %+v, %v", tt.name, score, ok)
+		}
+	}
+	if score, _ := health.Score(slow.URL); score.LatencyMs < 300 {
+		t.Errorf("slow mirror latency = %.0f ms", score.LatencyMs)
+	}
+
+	// the probes are saved and not repeated within the probe interval
+	saved := &MirrorHealth{mirrors: map[string]*MirrorScore{}}
+	if err := saved.Load(filePath); err != nil {
+		t.Fatalf("Load() of the saved scores error = %v", err)
+	}
+	if score, ok := saved.Score(failing.URL); !ok || score.Failures != 1 || score.LastProbe.IsZero() {
+		t.Errorf("saved score of the failing mirror = %+v, %v", score, ok)
+	}
+	if ranked := saved.Rank(context.Background(), urls); !slices.Equal(ranked, want) {
+		t.Errorf("Rank() with the saved scores = %v, want %v", ranked, want)
+	}
+	if requests := fast.requests.Load(); requests != 1 {
+		t.Errorf("fast mirror was probed again: %d requests", requests)
+	}
+}
+
+func TestMirrorHealthRankCancelled(t *testing.T) {
+	mirror := newTestMirror(t, "model", 200*time.Millisecond, true, false)
+	health := &MirrorHealth{mirrors: map[string]*MirrorScore{}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if ranked := health.Rank(ctx, []string{mirror.URL}); !slices.Equal(ranked, []string{mirror.URL}) {
+		t.Errorf("Rank() = %v", ranked)
+	}
+	// a cancelled probe is not a failure of the mirror
+	if score, ok := health.Score(mirror.URL); ok {
+		t.Errorf("cancelled probe changed the score: %+v", score)
+	}
+}
+
+func TestMirrorHealthReportThroughput(t *testing.T) {
+	const mirrorUrl = "https://mirror.example.com/model.bin"
+	tests := []struct {
+		name     string
+		previous *MirrorScore
+		bytes    int64
+		duration time.Duration
+		want     MirrorScore
+		wantOk   bool
+	}{
+		{name: "small transfer", bytes: minThroughputSample - 1, duration: time.Second},
+		{name: "no duration", bytes: minThroughputSample, duration: 0},
+		{name: "first measurement", bytes: 1000000, duration: time.Second, want: MirrorScore{Throughput: 1000000}, wantOk: true},
+		{
+			name:     "moving average",
+			previous: &MirrorScore{Throughput: 1000000, LatencyMs: 50},
+			bytes:    2000000, duration: 500 * time.Millisecond,
+			want: MirrorScore{Throughput: 0.7*1000000 + 0.3*4000000, LatencyMs: 50}, wantOk: true,
+		},
+		{
+			name:     "resets the failures",
+			previous: &MirrorScore{Throughput: 1000000, Failures: 3},
+			bytes:    1000000, duration: time.Second,
+			want: MirrorScore{Throughput: 1000000}, wantOk: true,
+		},
+		{
+			name:     "small transfer keeps the failures",
+			previous: &MirrorScore{Throughput: 1000000, Failures: 3},
+			bytes:    1000, duration: time.Millisecond,
+			want: MirrorScore{Throughput: 1000000, Failures: 3}, wantOk: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			health := &MirrorHealth{mirrors: map[string]*MirrorScore{}}
+			if tt.previous != nil {
+				health.mirrors[mirrorKey(mirrorUrl)] = tt.previous
+			}
+			health.ReportThroughput(mirrorUrl, tt.bytes, tt.duration)
+			// other files of the mirror share the score
+			got, ok := health.Score("https://mirror.example.com/other.bin")
+			if ok != tt.wantOk || math.Abs(got.Throughput-tt.want.Throughput) > 0.001 || got.Failures != tt.want.Failures || got.LatencyMs != tt.want.LatencyMs {
+				t.Errorf("Score() = %+v, %v, want %+v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestSwitchSlowMirror(t *testing.T) {
+	const (
+		primary  = "https://primary.example.com/model.bin"
+		fallback = "https://fallback.example.com/model.bin"
+		other    = "https://other.example.com/model.bin"
+		// sameHost is another url of the primary mirror
+		sameHost = "https://primary.example.com/mirror/model.bin"
+	)
+	tests := []struct {
+		name       string
+		scores     map[string]*MirrorScore
+		fallbacks  []string
+		multi      bool
+		urlIndex   int
+		mirrorUrl  string
+		throughput float64
+		want       string
+	}{
+		{
+			name:   "slower than a measured mirror",
+			scores: map[string]*MirrorScore{"fallback.example.com": {Throughput: 1000}},
+			// below 30 percent of the fallback
+			fallbacks: []string{fallback}, mirrorUrl: primary, throughput: 299,
+			want: fallback,
+		},
+		{
+			name:      "not slow enough",
+			scores:    map[string]*MirrorScore{"fallback.example.com": {Throughput: 1000}},
+			fallbacks: []string{fallback}, mirrorUrl: primary, throughput: 301,
+			want: primary,
+		},
+		{
+			name:      "fastest measured mirror",
+			scores:    map[string]*MirrorScore{"fallback.example.com": {Throughput: 1000}, "other.example.com": {Throughput: 5000}},
+			fallbacks: []string{fallback, other}, mirrorUrl: primary, throughput: 100,
+			want: other,
+		},
+		{
+			name:      "mirror with failures",
+			scores:    map[string]*MirrorScore{"fallback.example.com": {Throughput: 1000, Failures: 1}},
+			fallbacks: []string{fallback}, mirrorUrl: primary, throughput: 100,
+			want: primary,
+		},
+		{
+			name:      "unmeasured mirror",
+			scores:    map[string]*MirrorScore{"fallback.example.com": {LatencyMs: 10, RangeSupport: true}},
+			fallbacks: []string{fallback}, mirrorUrl: primary, throughput: 100,
+			want: primary,
+		},
+		{
+			name:      "same mirror",
+			scores:    map[string]*MirrorScore{"primary.example.com": {Throughput: 1000}},
+			fallbacks: []string{sameHost}, mirrorUrl: primary, throughput: 100,
+			want: primary,
+		},
+		{
+			name:      "back to the primary mirror",
+			scores:    map[string]*MirrorScore{"primary.example.com": {Throughput: 1000}},
+			fallbacks: []string{fallback}, urlIndex: 1, mirrorUrl: fallback, throughput: 100,
+			want: primary,
+		},
+		{
+			name:      "already switched by another chunk",
+			scores:    map[string]*MirrorScore{"other.example.com": {Throughput: 1000}},
+			fallbacks: []string{fallback, other}, urlIndex: 1, mirrorUrl: primary, throughput: 100,
+			want: fallback,
+		},
+		{
+			name:      "multi server download",
+			scores:    map[string]*MirrorScore{"fallback.example.com": {Throughput: 1000}},
+			fallbacks: []string{fallback}, multi: true, mirrorUrl: primary, throughput: 100,
+			want: primary,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestMirrors(t, tt.scores)
+			d := &Download{Url: primary, FallbackUrls: tt.fallbacks, UseMultiServerDownload: tt.multi, urlIndex: tt.urlIndex}
+			d.switchSlowMirror(tt.mirrorUrl, tt.throughput)
+			if got := d.CurrentUrl(); got != tt.want {
+				t.Errorf("CurrentUrl() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDownloadChunkSwitchesSlowMirror(t *testing.T) {
+	content := strings.Repeat("m", minThroughputSample)
+	primary := newTestMirror(t, content, 0, true, false)
+	fallback := newTestMirror(t, content, 0, true, false)
+	// the fallback was measured much faster than any local transfer
+	health := useTestMirrors(t, map[string]*MirrorScore{mirrorKey(fallback.URL): {Throughput: 1e15}})
+
+	d := &Download{Context: context.Background(), Url: primary.URL + "/model.bin", FallbackUrls: []string{fallback.URL + "/model.bin"}}
+	chunk, _, err := d.downloadChunk(d.Url, 0, int64(len(content))-1)
+	if err != nil {
+		t.Fatalf("downloadChunk() error = %v", err)
+	}
+	if string(chunk.data) != content {
+		t.Errorf("chunk = %d bytes, want %d", len(chunk.data), len(content))
+	}
+	if got := d.CurrentUrl(); got != d.FallbackUrls[0] {
+		t.Errorf("CurrentUrl() = %s, want the fallback", got)
+	}
+	if score, ok := health.Score(primary.URL); !ok || score.Throughput <= 0 {
+		t.Errorf("throughput of the chunk was not reported: %+v, %v", score, ok)
+	}
+}
+
+func TestLimitedReader(t *testing.T) {
+	content := bytes.Repeat([]byte("b"), 512*1024)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(content)
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { SetBandwidthLimit(0) })
+
+	tests := []struct {
+		name  string
+		limit int64
+		// the first second of the limit is not a burst, the whole content is waited for
+		wantMinWait time.Duration
+		wantMaxWait time.Duration
+	}{
+		{name: "unlimited", limit: 0, wantMaxWait: 0},
+		{name: "limited", limit: 1024 * 1024, wantMinWait: 400 * time.Millisecond, wantMaxWait: 2 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetBandwidthLimit(tt.limit)
+			resp, err := http.Get(server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			reader := &limitedReader{ctx: context.Background(), reader: resp.Body}
+			start := time.Now()
+			data, err := io.ReadAll(reader)
+			elapsed := time.Since(start)
+			if err != nil || !bytes.Equal(data, content) {
+				t.Fatalf("ReadAll() = %d bytes, %v", len(data), err)
+			}
+			if reader.waited < tt.wantMinWait || reader.waited > tt.wantMaxWait {
+				t.Errorf("waited %v, want between %v and %v", reader.waited, tt.wantMinWait, tt.wantMaxWait)
+			}
+			if elapsed < reader.waited {
+				t.Errorf("read took %v, less than the recorded wait %v", elapsed, reader.waited)
+			}
+		})
+	}
+}
+
+func TestLimitedReaderReadSize(t *testing.T) {
+	SetBandwidthLimit(0)
+	reader := &limitedReader{ctx: context.Background(), reader: bytes.NewReader(make([]byte, 3*limitedReadSize))}
+	n, err := reader.Read(make([]byte, 2*limitedReadSize))
+	if err != nil || n != limitedReadSize {
+		t.Errorf("Read() = %d, %v, want %d bytes", n, err, limitedReadSize)
+	}
+}
+
+func TestLimitedReaderCancelled(t *testing.T) {
+	SetBandwidthLimit(1024)
+	t.Cleanup(func() { SetBandwidthLimit(0) })
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	reader := &limitedReader{ctx: ctx, reader: bytes.NewReader(make([]byte, 64*1024))}
+	if _, err := io.ReadAll(reader); !errors.Is(err, context.Canceled) {
+		t.Errorf("ReadAll() with cancelled context error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestMirrorHealthLoadInvalid(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "mirrors.json")
+	if err := os.WriteFile(filePath, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	health := &MirrorHealth{mirrors: map[string]*MirrorScore{}}
+	if err := health.Load(filePath); err == nil {
+		t.Error("Load() of an invalid file error = nil")
+	}
+}
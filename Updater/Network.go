@@ -0,0 +1,147 @@
+package Updater
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+)
+
+var (
+	clientMutex sync.RWMutex
+	netClient   = cleanhttp.DefaultPooledClient()
+)
+
+// HttpClient returns the client used for downloads, with the configured proxy.
+func HttpClient() *http.Client {
+	clientMutex.RLock()
+	defer clientMutex.RUnlock()
+	return netClient
+}
+
+// ParseProxy parses a proxy url. A missing scheme defaults to http, an empty proxy returns nil.
+func ParseProxy(proxy string) (*url.URL, error) {
+	proxy = strings.TrimSpace(proxy)
+	if proxy == "" {
+		return nil, nil
+	}
+	if !strings.Contains(proxy, "://") {
+		proxy = "http://" + proxy
+	}
+	proxyUrl, err := url.Parse(proxy)
+	if err != nil || proxyUrl.Host == "" {
+		return nil, fmt.Errorf("invalid proxy url: %s", proxy)
+	}
+	switch proxyUrl.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme: %s", proxyUrl.Scheme)
+	}
+	return proxyUrl, nil
+}
+
+// SetProxy sets the HTTP proxy used for downloads.
+// An empty proxy uses the proxy of the environment (HTTP_PROXY, HTTPS_PROXY and NO_PROXY).
+func SetProxy(proxy string) error {
+	proxyUrl, err := ParseProxy(proxy)
+	if err != nil {
+		return err
+	}
+	transport := cleanhttp.DefaultPooledTransport()
+	if proxyUrl != nil {
+		transport.Proxy = http.ProxyURL(proxyUrl)
+	}
+	clientMutex.Lock()
+	defer clientMutex.Unlock()
+	netClient.CloseIdleConnections()
+	netClient = &http.Client{Transport: transport}
+	return nil
+}
+
+// bandwidthLimiter limits the combined transfer rate of all downloads.
+// Transfers take from a budget that refills with the limit per second, a negative budget is waited for.
+type bandwidthLimiter struct {
+	mutex sync.Mutex
+	// limit is in bytes per second, 0 is unlimited.
+	limit     int64
+	available float64
+	last      time.Time
+}
+
+var bandwidth bandwidthLimiter
+
+// SetBandwidthLimit limits the combined download speed in bytes per second. 0 removes the limit.
+func SetBandwidthLimit(bytesPerSecond int64) {
+	bandwidth.mutex.Lock()
+	defer bandwidth.mutex.Unlock()
+	bandwidth.limit = max(bytesPerSecond, 0)
+	bandwidth.available = 0
+	bandwidth.last = time.Now()
+}
+
+// BandwidthLimit returns the download speed limit in bytes per second, 0 if unlimited.
+func BandwidthLimit() int64 {
+	bandwidth.mutex.Lock()
+	defer bandwidth.mutex.Unlock()
+	return bandwidth.limit
+}
+
+// wait blocks until a transfer of n bytes fits into the limit and returns the time waited.
+func (b *bandwidthLimiter) wait(ctx context.Context, n int) (time.Duration, error) {
+	b.mutex.Lock()
+	if b.limit <= 0 {
+		b.mutex.Unlock()
+		return 0, nil
+	}
+	now := time.Now()
+	// allow bursts of at most one second
+	b.available = min(b.available+now.Sub(b.last).Seconds()*float64(b.limit), float64(b.limit))
+	b.last = now
+	b.available -= float64(n)
+	delay := time.Duration(0)
+	if b.available < 0 {
+		delay = time.Duration(-b.available / float64(b.limit) * float64(time.Second))
+	}
+	b.mutex.Unlock()
+
+	if delay == 0 {
+		return 0, nil
+	}
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	case <-time.After(delay):
+		return delay, nil
+	}
+}
+
+// limitedReader reads within the bandwidth limit and records the time spent waiting for it,
+// so the measured throughput of a mirror is not reduced by the limit.
+type limitedReader struct {
+	ctx    context.Context
+	reader io.Reader
+	waited time.Duration
+}
+
+const limitedReadSize = 32 * 1024
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if len(p) > limitedReadSize {
+		p = p[:limitedReadSize]
+	}
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		waited, waitErr := bandwidth.wait(r.ctx, n)
+		r.waited += waited
+		if waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
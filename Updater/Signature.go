@@ -0,0 +1,146 @@
+package Updater
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	_ "embed"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"whispering-tiger-ui/Utilities"
+)
+
+// SignatureFileExtension is appended to the url of an update manifest to get its detached signature.
+const SignatureFileExtension = ".sig"
+
+//go:embed update_public_keys.txt
+var embeddedPublicKeys []byte
+
+var (
+	ErrMissingSignature = errors.New("the update is not signed")
+	ErrInvalidSignature = errors.New("the update signature does not match the update signing key")
+	ErrMissingChecksum  = errors.New("the update package has no checksum")
+)
+
+// Verifier checks update manifests and packages against the public keys of the update signing keys.
+type Verifier struct {
+	publicKeys []ed25519.PublicKey
+}
+
+// NewVerifier returns a verifier that accepts signatures of any of the public keys.
+func NewVerifier(publicKeys ...ed25519.PublicKey) *Verifier {
+	return &Verifier{publicKeys: publicKeys}
+}
+
+// ParsePublicKeys reads base64 encoded ed25519 public keys, one per line. Empty lines and lines starting with # are skipped.
+func ParsePublicKeys(data []byte) ([]ed25519.PublicKey, error) {
+	var publicKeys []ed25519.PublicKey
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(line)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid update public key: %q", line)
+		}
+		publicKeys = append(publicKeys, key)
+	}
+	return publicKeys, scanner.Err()
+}
+
+// HasKeys returns false if no update signing key is configured, signatures can not be checked then.
+func (v *Verifier) HasKeys() bool {
+	return len(v.publicKeys) > 0
+}
+
+// DefaultVerifier returns a verifier with the public keys embedded in the binary, or an error if they are malformed.
+var DefaultVerifier = sync.OnceValues(func() (*Verifier, error) {
+	publicKeys, err := ParsePublicKeys(embeddedPublicKeys)
+	if err != nil {
+		return nil, err
+	}
+	return NewVerifier(publicKeys...), nil
+})
+
+func decodeSignature(signature string) ([]byte, error) {
+	signature = strings.TrimSpace(signature)
+	if signature == "" {
+		return nil, ErrMissingSignature
+	}
+	decoded, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil || len(decoded) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("%w (malformed signature)", ErrInvalidSignature)
+	}
+	return decoded, nil
+}
+
+func (v *Verifier) verify(message []byte, signature string) error {
+	decoded, err := decodeSignature(signature)
+	if err != nil {
+		return err
+	}
+	for _, publicKey := range v.publicKeys {
+		if ed25519.Verify(publicKey, message, decoded) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// VerifyManifest checks the detached signature of an update manifest, signature is the base64 encoded content of the signature file.
+func (v *Verifier) VerifyManifest(data []byte, signature string) error {
+	if err := v.verify(data, signature); err != nil {
+		return fmt.Errorf("update manifest: %w", err)
+	}
+	return nil
+}
+
+// packageSignatureMessage is the signed content of a package: its name, version and the SHA256 checksum of the file.
+// Signing the checksum instead of the file allows verifying large packages without loading them into memory.
+func packageSignatureMessage(packageName string, info UpdateInfo) []byte {
+	return []byte("whispering-tiger-package\n" + packageName + "\n" + info.Version + "\n" + strings.ToLower(info.SHA256))
+}
+
+func hasChecksum(info UpdateInfo) bool {
+	checksum := strings.TrimSpace(info.SHA256)
+	return checksum != "" && strings.Trim(checksum, "0") != ""
+}
+
+// VerifyPackage checks the signature of a package and the checksum of the downloaded file.
+func (v *Verifier) VerifyPackage(packageName string, info UpdateInfo, fileName string) error {
+	if !hasChecksum(info) {
+		return fmt.Errorf("package %s: %w", packageName, ErrMissingChecksum)
+	}
+	if err := v.verify(packageSignatureMessage(packageName, info), info.Signature); err != nil {
+		return fmt.Errorf("package %s %s: %w", packageName, info.Version, err)
+	}
+	if err := CheckFileHash(fileName, info.SHA256); err != nil {
+		return fmt.Errorf("package %s %s: %w", packageName, info.Version, err)
+	}
+	return nil
+}
+
+// SignManifest returns the base64 encoded detached signature of an update manifest, used when publishing updates.
+func SignManifest(privateKey ed25519.PrivateKey, data []byte) string {
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, data))
+}
+
+// SignPackage sets the SHA256 checksum and the signature of a package file, used when publishing updates.
+func SignPackage(privateKey ed25519.PrivateKey, packageName string, info UpdateInfo, fileName string) (UpdateInfo, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return info, err
+	}
+	defer file.Close()
+	if info.SHA256, err = Utilities.FileHash(file); err != nil {
+		return info, err
+	}
+	info.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, packageSignatureMessage(packageName, info)))
+	return info, nil
+}
@@ -0,0 +1,204 @@
+package Updater
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// signedUpdateServer serves an update manifest with one package at /manifest.yaml and its signature at /manifest.yaml.sig.
+type signedUpdateServer struct {
+	manifest  []byte
+	signature string
+	// noSignature serves no signature file.
+	noSignature bool
+}
+
+func (s *signedUpdateServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/manifest.yaml":
+		_, _ = w.Write(s.manifest)
+	case "/manifest.yaml" + SignatureFileExtension:
+		if s.noSignature {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(s.signature))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestParsePublicKeys(t *testing.T) {
+	publicKey, _, _ := ed25519.GenerateKey(rand.Reader)
+	encodedKey := base64.StdEncoding.EncodeToString(publicKey)
+	tests := []struct {
+		name     string
+		data     string
+		wantKeys int
+		wantErr  bool
+	}{
+		{name: "comments only", data: "# no key yet\n\n", wantKeys: 0},
+		{name: "key", data: "# comment\n" + encodedKey + "\n", wantKeys: 1},
+		{name: "not base64", data: "not a key!\n", wantErr: true},
+		{name: "wrong length", data: base64.StdEncoding.EncodeToString([]byte("short")) + "\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := ParsePublicKeys([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePublicKeys() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(keys) != tt.wantKeys {
+				t.Errorf("ParsePublicKeys() = %d keys, want %d", len(keys), tt.wantKeys)
+			}
+		})
+	}
+}
+
+func TestDefaultVerifier(t *testing.T) {
+	verifier, err := DefaultVerifier()
+	if err != nil || verifier == nil {
+		t.Fatalf("DefaultVerifier() = %v, %v, the embedded key file must be valid", verifier, err)
+	}
+	if !verifier.HasKeys() {
+		t.Error("DefaultVerifier() has no keys, updates would not be verified")
+	}
+}
+
+func TestUpdateSignatures(t *testing.T) {
+	publicKey, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	tests := []struct {
+		name string
+		// verifier of the client, nil is a verifier with publicKey
+		verifier *Verifier
+		// prepare changes the manifest, signature and package file after signing
+		prepare     func(server *signedUpdateServer, packageFile string)
+		signWith    ed25519.PrivateKey
+		wantInfoErr error
+		wantPackage string
+	}{
+		{name: "valid signature"},
+		{
+			name: "tampered manifest",
+			prepare: func(server *signedUpdateServer, packageFile string) {
+				server.manifest = []byte(strings.Replace(string(server.manifest), "1.0.0", "6.6.6", 1))
+			},
+			wantInfoErr: ErrInvalidSignature,
+		},
+		{name: "signed by another key", signWith: otherKey, wantInfoErr: ErrInvalidSignature},
+		{
+			name: "malformed signature",
+			prepare: func(server *signedUpdateServer, packageFile string) {
+				server.signature = "not a signature"
+			},
+			wantInfoErr: ErrInvalidSignature,
+		},
+		{
+			name: "empty signature",
+			prepare: func(server *signedUpdateServer, packageFile string) {
+				server.signature = ""
+			},
+			wantInfoErr: ErrMissingSignature,
+		},
+		{
+			name: "tampered package",
+			prepare: func(server *signedUpdateServer, packageFile string) {
+				_ = os.WriteFile(packageFile, []byte("tampered"), 0644)
+			},
+			wantPackage: "hash does not match",
+		},
+		{
+			name: "unsigned package in signed manifest",
+			prepare: func(server *signedUpdateServer, packageFile string) {
+				var manifest UpdatePackages
+				_ = yaml.Unmarshal(server.manifest, &manifest)
+				info := manifest.Packages["app"]
+				info.Signature = ""
+				manifest.Packages["app"] = info
+				server.manifest, _ = yaml.Marshal(manifest)
+				server.signature = SignManifest(privateKey, server.manifest)
+			},
+			wantPackage: ErrMissingSignature.Error(),
+		},
+		{
+			name: "no signature file",
+			prepare: func(server *signedUpdateServer, packageFile string) {
+				server.noSignature = true
+			},
+			wantInfoErr: ErrMissingSignature,
+		},
+		{
+			name: "no signature file and tampered package",
+			prepare: func(server *signedUpdateServer, packageFile string) {
+				server.noSignature = true
+				_ = os.WriteFile(packageFile, []byte("tampered"), 0644)
+			},
+			wantInfoErr: ErrMissingSignature,
+		},
+		{
+			name:     "no key configured",
+			verifier: NewVerifier(),
+			prepare: func(server *signedUpdateServer, packageFile string) {
+				server.signature = "not checked"
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			packageFile := filepath.Join(t.TempDir(), "app.zip")
+			if err := os.WriteFile(packageFile, []byte("package content"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			signWith := tt.signWith
+			if signWith == nil {
+				signWith = privateKey
+			}
+			info, err := SignPackage(signWith, "app", UpdateInfo{Version: "1.0.0"}, packageFile)
+			if err != nil {
+				t.Fatalf("SignPackage() error = %v", err)
+			}
+			manifest, _ := yaml.Marshal(UpdatePackages{Packages: map[string]UpdateInfo{"app": info}})
+			server := &signedUpdateServer{manifest: manifest, signature: SignManifest(signWith, manifest)}
+			if tt.prepare != nil {
+				tt.prepare(server, packageFile)
+			}
+			httpServer := httptest.NewServer(server)
+			defer httpServer.Close()
+
+			verifier := tt.verifier
+			if verifier == nil {
+				verifier = NewVerifier(publicKey)
+			}
+			updater := UpdatePackages{Verifier: verifier}
+			err = updater.GetUpdateInfo(httpServer.URL + "/manifest.yaml")
+			if !errors.Is(err, tt.wantInfoErr) {
+				t.Fatalf("GetUpdateInfo() error = %v, want %v", err, tt.wantInfoErr)
+			}
+			if tt.wantInfoErr != nil {
+				if len(updater.Packages) != 0 {
+					t.Errorf("GetUpdateInfo() used the rejected manifest: %v", updater.Packages)
+				}
+				return
+			}
+
+			err = updater.VerifyPackage("app", packageFile)
+			if tt.wantPackage == "" && err != nil {
+				t.Errorf("VerifyPackage() error = %v", err)
+			} else if tt.wantPackage != "" && (err == nil || !strings.Contains(err.Error(), tt.wantPackage)) {
+				t.Errorf("VerifyPackage() error = %v, want %q", err, tt.wantPackage)
+			}
+		})
+	}
+}
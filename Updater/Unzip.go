@@ -4,138 +4,399 @@ import (
 	"archive/tar"
 	"archive/zip"
 	"compress/gzip"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
+	"whispering-tiger-ui/Utilities/Hardwareinfo"
 )
 
+// Archive formats supported by Extract.
+const (
+	ArchiveZip   = "zip"
+	ArchiveTarGz = "tar.gz"
+)
+
+const (
+	extractTempSuffix  = ".extract"
+	extractMarkerName  = ".extract.json"
+	extractPartSuffix  = ".part"
+	extractProgressGap = 100 * time.Millisecond
+)
+
+var ErrNotEnoughSpace = errors.New("not enough free disk space")
+
+// ExtractProgress is called during an extraction with the extracted and total uncompressed bytes.
+type ExtractProgress func(extracted, total uint64)
+
+// extractMarker identifies the archive of a temporary extraction directory, so only extractions of the same archive are resumed.
+type extractMarker struct {
+	Source   string    `json:"source"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	// Complete is set when all files are extracted and only need to be moved into the destination.
+	Complete bool `json:"complete,omitempty"`
+}
+
 func Unzip(src, dest string) error {
-	r, err := zip.OpenReader(src)
+	return Extract(src, dest, ArchiveZip, nil)
+}
+
+func Untar(src, dest string) error {
+	return Extract(src, dest, ArchiveTarGz, nil)
+}
+
+// ExtractTempDir returns the temporary directory an archive is extracted into before its files are moved into dest.
+func ExtractTempDir(src, dest string) string {
+	return filepath.Join(dest, "."+filepath.Base(src)+extractTempSuffix)
+}
+
+// Extract extracts an archive into dest. The files are streamed into a temporary directory inside dest first,
+// each one is written as .part file and renamed when it is complete, and the files are moved into dest when all are extracted.
+// An interrupted extraction of the same archive continues with the files that are not complete yet.
+// The extraction is marked complete before the files are moved, each file and new directory is moved by a single rename.
+// An interrupted move is finished by the next Extract or FinishInterruptedExtractions, so dest never keeps a partial update.
+func Extract(src, dest, format string, onProgress ExtractProgress) error {
+	stat, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return err
+	}
+	tempDir := ExtractTempDir(src, dest)
+	marker, err := prepareExtractTempDir(src, stat, tempDir)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		if err := r.Close(); err != nil {
-			panic(err)
-		}
-	}()
-
-	os.MkdirAll(dest, 0755)
 
-	// Closure to address file descriptors issue with all the deferred .Close() methods
-	extractAndWriteFile := func(f *zip.File) error {
-		rc, err := f.Open()
+	if !marker.Complete {
+		switch format {
+		case ArchiveZip:
+			err = extractZip(src, tempDir, onProgress)
+		case ArchiveTarGz:
+			err = extractTarGz(src, tempDir, onProgress)
+		default:
+			err = fmt.Errorf("unsupported archive format: %s", format)
+		}
 		if err != nil {
 			return err
 		}
-		defer func() {
-			if err := rc.Close(); err != nil {
-				panic(err)
-			}
-		}()
+		marker.Complete = true
+		if err := writeExtractMarker(tempDir, marker); err != nil {
+			return err
+		}
+	}
+
+	return finishExtraction(tempDir, dest)
+}
 
-		path := filepath.Join(dest, f.Name)
+// finishExtraction moves the files of a complete extraction into dest and removes the temporary directory.
+func finishExtraction(tempDir, dest string) error {
+	if err := moveInto(tempDir, dest); err != nil {
+		return err
+	}
+	return os.RemoveAll(tempDir)
+}
 
-		// Check for ZipSlip (Directory traversal)
-		if !strings.HasPrefix(path, filepath.Clean(dest)+string(os.PathSeparator)) {
-			return fmt.Errorf("illegal file path: %s", path)
+// prepareExtractTempDir keeps the temporary directory of an interrupted extraction of the same archive, otherwise it is recreated.
+func prepareExtractTempDir(src string, stat fs.FileInfo, tempDir string) (extractMarker, error) {
+	absSource, err := filepath.Abs(src)
+	if err != nil {
+		return extractMarker{}, err
+	}
+	marker := extractMarker{Source: absSource, Size: stat.Size(), Modified: stat.ModTime().UTC()}
+	if existing, err := readExtractMarker(tempDir); err == nil &&
+		existing.Source == marker.Source && existing.Size == marker.Size && existing.Modified.Equal(marker.Modified) {
+		return existing, nil
+	}
+	if err := os.RemoveAll(tempDir); err != nil {
+		return marker, err
+	}
+	if err := os.MkdirAll(tempDir, 0755); err != nil {
+		return marker, err
+	}
+	return marker, writeExtractMarker(tempDir, marker)
+}
+
+func readExtractMarker(tempDir string) (extractMarker, error) {
+	var marker extractMarker
+	data, err := os.ReadFile(filepath.Join(tempDir, extractMarkerName))
+	if err != nil {
+		return marker, err
+	}
+	return marker, json.Unmarshal(data, &marker)
+}
+
+func writeExtractMarker(tempDir string, marker extractMarker) error {
+	data, err := json.Marshal(marker)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(tempDir, extractMarkerName), data, 0644)
+}
+
+// FinishInterruptedExtractions handles the temporary directories of interrupted extractions below root:
+// complete extractions are moved into their destination, incomplete ones whose archive does not exist anymore are removed,
+// since they can not be continued.
+func FinishInterruptedExtractions(root string) error {
+	var errs []error
+	// the trailing separator follows root if it is a link, like a moved model cache
+	err := filepath.WalkDir(root+string(os.PathSeparator), func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.IsDir() || !strings.HasSuffix(entry.Name(), extractTempSuffix) {
+			return nil
+		}
+		marker, err := readExtractMarker(path)
+		if err != nil {
+			return filepath.SkipDir
+		}
+		if marker.Complete {
+			errs = append(errs, finishExtraction(path, filepath.Dir(path)))
+		} else if _, err := os.Stat(marker.Source); os.IsNotExist(err) {
+			errs = append(errs, os.RemoveAll(path))
 		}
+		return filepath.SkipDir
+	})
+	return errors.Join(append(errs, err)...)
+}
 
-		if f.FileInfo().IsDir() {
-			os.MkdirAll(path, f.Mode())
-		} else {
-			os.MkdirAll(filepath.Dir(path), f.Mode())
-			f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
-			if err != nil {
-				return err
-			}
-			defer func() {
-				if err := f.Close(); err != nil {
-					panic(err)
-				}
-			}()
+// checkFreeSpace returns ErrNotEnoughSpace if the volume of dir has less than required bytes free. It is skipped if the free space is unknown.
+func checkFreeSpace(dir string, required uint64) error {
+	free, err := Hardwareinfo.GetFreeSpace(dir)
+	if err != nil || free >= required {
+		return nil
+	}
+	return fmt.Errorf("%w: %d MiB needed, %d MiB free", ErrNotEnoughSpace, required/1024/1024, free/1024/1024)
+}
 
-			_, err = io.Copy(f, rc)
-			if err != nil {
-				return err
+// extractPath returns the path of an archive entry inside dest and rejects entries outside of it (zip slip).
+func extractPath(dest, name string) (string, error) {
+	path := filepath.Join(dest, name)
+	if !strings.HasPrefix(path, filepath.Clean(dest)+string(os.PathSeparator)) {
+		return "", fmt.Errorf("illegal file path: %s", path)
+	}
+	return path, nil
+}
+
+// progressCounter counts the extracted bytes and reports them in intervals.
+type progressCounter struct {
+	extracted  uint64
+	total      uint64
+	onProgress ExtractProgress
+	lastReport time.Time
+}
+
+func (p *progressCounter) add(n uint64) {
+	p.extracted += n
+	if p.onProgress != nil && (time.Since(p.lastReport) >= extractProgressGap || p.extracted >= p.total) {
+		p.lastReport = time.Now()
+		p.onProgress(p.extracted, p.total)
+	}
+}
+
+func (p *progressCounter) Write(b []byte) (int, error) {
+	p.add(uint64(len(b)))
+	return len(b), nil
+}
+
+// writeFile writes a file as .part file and renames it when it is complete. Files that are complete already are skipped.
+func writeFile(path string, mode fs.FileMode, reader io.Reader) (bool, error) {
+	if _, err := os.Stat(path); err == nil {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return false, err
+	}
+	partFile := path + extractPartSuffix
+	file, err := os.OpenFile(partFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm()|0600)
+	if err != nil {
+		return false, err
+	}
+	_, err = io.Copy(file, reader)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(partFile)
+		return false, err
+	}
+	return true, os.Rename(partFile, path)
+}
+
+func extractZip(src, dest string, onProgress ExtractProgress) error {
+	reader, err := zip.OpenReader(src)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	progress := &progressCounter{onProgress: onProgress}
+	var remaining uint64
+	for _, file := range reader.File {
+		progress.total += file.UncompressedSize64
+		if path, err := extractPath(dest, file.Name); err == nil && !file.FileInfo().IsDir() {
+			if _, err := os.Stat(path); err != nil {
+				remaining += file.UncompressedSize64
 			}
 		}
-		return nil
+	}
+	if err := checkFreeSpace(dest, remaining); err != nil {
+		return err
 	}
 
-	for _, f := range r.File {
-		err := extractAndWriteFile(f)
+	for _, file := range reader.File {
+		path, err := extractPath(dest, file.Name)
 		if err != nil {
 			return err
 		}
+		if file.FileInfo().IsDir() {
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		entryReader, err := file.Open()
+		if err != nil {
+			return err
+		}
+		written, err := writeFile(path, file.Mode(), io.TeeReader(entryReader, progress))
+		if closeErr := entryReader.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", file.Name, err)
+		}
+		if !written {
+			progress.add(file.UncompressedSize64)
+		}
 	}
-
 	return nil
 }
 
-func Untar(src, dest string) error {
-	r, err := os.Open(src)
+// openTarGz opens a tar.gz archive, close closes the gzip reader and the file.
+func openTarGz(src string) (*tar.Reader, func(), error) {
+	file, err := os.Open(src)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	defer func() {
-		if err := r.Close(); err != nil {
-			panic(err)
-		}
-	}()
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	return tar.NewReader(gzipReader), func() {
+		gzipReader.Close()
+		file.Close()
+	}, nil
+}
 
-	gzr, err := gzip.NewReader(r)
+// tarGzSize returns the uncompressed size of the files of a tar.gz archive and of the ones not extracted into dest yet.
+// The sizes are only stored in the tar headers, so the archive is read once without writing the files.
+func tarGzSize(src, dest string) (total, remaining uint64, err error) {
+	tarReader, closeArchive, err := openTarGz(src)
 	if err != nil {
-		return err
+		return 0, 0, err
 	}
-	defer func() {
-		if err := gzr.Close(); err != nil {
-			panic(err)
+	defer closeArchive()
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return total, remaining, nil
 		}
-	}()
-
-	tr := tar.NewReader(gzr)
+		if err != nil {
+			return total, remaining, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		total += uint64(header.Size)
+		if path, err := extractPath(dest, header.Name); err == nil {
+			if _, err := os.Stat(path); err != nil {
+				remaining += uint64(header.Size)
+			}
+		}
+	}
+}
 
-	// Ensure the destination directory exists
-	os.MkdirAll(dest, 0755)
+func extractTarGz(src, dest string, onProgress ExtractProgress) error {
+	total, remaining, err := tarGzSize(src, dest)
+	if err != nil {
+		return err
+	}
+	if err := checkFreeSpace(dest, remaining); err != nil {
+		return err
+	}
 
-	// Iterate over files in .tar.gz
+	tarReader, closeArchive, err := openTarGz(src)
+	if err != nil {
+		return err
+	}
+	defer closeArchive()
+	progress := &progressCounter{total: total, onProgress: onProgress}
 	for {
-		hdr, err := tr.Next()
+		header, err := tarReader.Next()
 		if err == io.EOF {
-			break
+			return nil
 		}
 		if err != nil {
 			return err
 		}
-
-		path := filepath.Join(dest, hdr.Name)
-
-		// Check for TarSlip (Directory traversal)
-		if !strings.HasPrefix(path, filepath.Clean(dest)+string(os.PathSeparator)) {
-			return fmt.Errorf("illegal file path: %s", path)
+		path, err := extractPath(dest, header.Name)
+		if err != nil {
+			return err
 		}
-
-		// Check if it's a dir or a file
-		if hdr.Typeflag == tar.TypeDir {
-			os.MkdirAll(path, 0755)
-		} else if hdr.Typeflag == tar.TypeReg {
-			os.MkdirAll(filepath.Dir(path), 0755)
-			f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.FileMode(hdr.Mode))
-			if err != nil {
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0755); err != nil {
 				return err
 			}
-			defer func() {
-				if err := f.Close(); err != nil {
-					panic(err)
-				}
-			}()
+		case tar.TypeReg:
+			// completed files are skipped, their content is still read from the stream
+			written, err := writeFile(path, os.FileMode(header.Mode), io.TeeReader(tarReader, progress))
+			if err != nil {
+				return fmt.Errorf("%s: %w", header.Name, err)
+			}
+			if !written {
+				progress.add(uint64(header.Size))
+			}
+		}
+	}
+}
 
-			if _, err := io.Copy(f, tr); err != nil {
-				return err
+// moveInto moves the extracted files into dest. Existing directories are merged, existing files replaced.
+// Moved entries are removed from src, so an interrupted move continues with the remaining ones.
+func moveInto(src, dest string) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.Name() == extractMarkerName {
+			continue
+		}
+		from := filepath.Join(src, entry.Name())
+		to := filepath.Join(dest, entry.Name())
+		if targetStat, err := os.Stat(to); err == nil {
+			if entry.IsDir() && targetStat.IsDir() {
+				if err := moveInto(from, to); err != nil {
+					return err
+				}
+				continue
 			}
+			// a file replaces a file by the rename, a file and a directory can not replace each other
+			if entry.IsDir() || targetStat.IsDir() {
+				if err := os.RemoveAll(to); err != nil {
+					return err
+				}
+			}
+		}
+		if err := os.Rename(from, to); err != nil {
+			return err
 		}
 	}
 	return nil
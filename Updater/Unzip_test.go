@@ -0,0 +1,252 @@
+package Updater
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestArchive writes a zip or tar.gz archive with the files in the order of names.
+func writeTestArchive(t *testing.T, fileName, format string, names []string, files map[string]string) {
+	t.Helper()
+	var buffer bytes.Buffer
+	switch format {
+	case ArchiveZip:
+		zipWriter := zip.NewWriter(&buffer)
+		for _, name := range names {
+			writer, err := zipWriter.Create(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _ = writer.Write([]byte(files[name]))
+		}
+		if err := zipWriter.Close(); err != nil {
+			t.Fatal(err)
+		}
+	case ArchiveTarGz:
+		gzipWriter := gzip.NewWriter(&buffer)
+		tarWriter := tar.NewWriter(gzipWriter)
+		for _, name := range names {
+			if strings.HasSuffix(name, "/") {
+				_ = tarWriter.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeDir, Mode: 0755})
+				continue
+			}
+			if err := tarWriter.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(files[name]))}); err != nil {
+				t.Fatal(err)
+			}
+			_, _ = tarWriter.Write([]byte(files[name]))
+		}
+		if err := tarWriter.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := gzipWriter.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(fileName, buffer.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	files := map[string]string{
+		"dir/":         "",
+		"dir/a.txt":    strings.Repeat("a", 1000),
+		"b.txt":        "b",
+		"../slip.txt":  "slip",
+		"dir/../c.txt": "c",
+		"c.txt":        "c",
+	}
+	tests := []struct {
+		name   string
+		format string
+		names  []string
+		// partial is a file that is already complete in the temporary directory of an interrupted extraction
+		partial   string
+		wantErr   string
+		wantFiles []string
+	}{
+		{name: "zip", format: ArchiveZip, names: []string{"dir/", "dir/a.txt", "b.txt"}, wantFiles: []string{"b.txt", "dir/a.txt"}},
+		{name: "tar.gz", format: ArchiveTarGz, names: []string{"dir/", "dir/a.txt", "b.txt"}, wantFiles: []string{"b.txt", "dir/a.txt"}},
+		{name: "zip entry inside after cleaning", format: ArchiveZip, names: []string{"dir/../c.txt"}, wantFiles: []string{"c.txt"}},
+		{name: "zip slip", format: ArchiveZip, names: []string{"b.txt", "../slip.txt"}, wantErr: "illegal file path"},
+		{name: "tar.gz slip", format: ArchiveTarGz, names: []string{"b.txt", "../slip.txt"}, wantErr: "illegal file path"},
+		{name: "continue zip", format: ArchiveZip, names: []string{"dir/", "dir/a.txt", "b.txt"}, partial: "dir/a.txt", wantFiles: []string{"b.txt", "dir/a.txt"}},
+		{name: "continue tar.gz", format: ArchiveTarGz, names: []string{"dir/", "dir/a.txt", "b.txt"}, partial: "dir/a.txt", wantFiles: []string{"b.txt", "dir/a.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			archive := filepath.Join(dir, "archive."+tt.format)
+			writeTestArchive(t, archive, tt.format, tt.names, files)
+			dest := filepath.Join(dir, "dest")
+
+			if tt.partial != "" {
+				// an interrupted extraction of the same archive
+				stat, _ := os.Stat(archive)
+				tempDir := ExtractTempDir(archive, dest)
+				if _, err := prepareExtractTempDir(archive, stat, tempDir); err != nil {
+					t.Fatal(err)
+				}
+				partialFile := filepath.Join(tempDir, filepath.FromSlash(tt.partial))
+				_ = os.MkdirAll(filepath.Dir(partialFile), 0755)
+				if err := os.WriteFile(partialFile, []byte(files[tt.partial]), 0644); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(tempDir, "b.txt"+extractPartSuffix), []byte("broken"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var lastExtracted, lastTotal uint64
+			err := Extract(archive, dest, tt.format, func(extracted, total uint64) {
+				lastExtracted, lastTotal = extracted, total
+			})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Extract() error = %v, want %q", err, tt.wantErr)
+				}
+				if _, err := os.Stat(filepath.Join(dir, "slip.txt")); err == nil {
+					t.Error("file outside of the destination was written")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+
+			var got []string
+			_ = filepath.WalkDir(dest, func(path string, entry os.DirEntry, err error) error {
+				if err == nil && !entry.IsDir() {
+					relativePath, _ := filepath.Rel(dest, path)
+					got = append(got, filepath.ToSlash(relativePath))
+					if data, _ := os.ReadFile(path); string(data) != files[filepath.ToSlash(relativePath)] {
+						t.Errorf("%s = %q", relativePath, data)
+					}
+				}
+				return nil
+			})
+			if strings.Join(got, ",") != strings.Join(tt.wantFiles, ",") {
+				t.Errorf("extracted files = %v, want %v", got, tt.wantFiles)
+			}
+			var uncompressed uint64
+			for _, name := range tt.names {
+				uncompressed += uint64(len(files[name]))
+			}
+			if lastTotal != uncompressed || lastExtracted != uncompressed {
+				t.Errorf("progress = %d of %d, want %d uncompressed bytes", lastExtracted, lastTotal, uncompressed)
+			}
+		})
+	}
+}
+
+func TestTarGzSize(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "archive.tar.gz")
+	// well compressible content, the archive is much smaller than the extracted files
+	files := map[string]string{"a.txt": strings.Repeat("a", 100000), "b.txt": strings.Repeat("b", 50000)}
+	writeTestArchive(t, archive, ArchiveTarGz, []string{"a.txt", "b.txt"}, files)
+	dest := filepath.Join(dir, "dest")
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dest, "b.txt"), []byte(files["b.txt"]), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	total, remaining, err := tarGzSize(archive, dest)
+	if err != nil {
+		t.Fatalf("tarGzSize() error = %v", err)
+	}
+	if total != 150000 || remaining != 100000 {
+		t.Errorf("tarGzSize() = %d, %d, want 150000, 100000", total, remaining)
+	}
+	if stat, _ := os.Stat(archive); uint64(stat.Size()) >= total {
+		t.Errorf("archive size %d is not smaller than the uncompressed size %d", stat.Size(), total)
+	}
+}
+
+func TestFinishInterruptedExtractions(t *testing.T) {
+	root := t.TempDir()
+	existing := filepath.Join(root, "existing.zip")
+	writeTestArchive(t, existing, ArchiveZip, nil, nil)
+	removed := filepath.Join(root, "removed.zip")
+	writeTestArchive(t, removed, ArchiveZip, nil, nil)
+	complete := filepath.Join(root, "complete.zip")
+	writeTestArchive(t, complete, ArchiveZip, nil, nil)
+
+	dest := filepath.Join(root, "models")
+	extractionDirs := map[string]string{}
+	for _, archive := range []string{existing, removed, complete} {
+		stat, _ := os.Stat(archive)
+		tempDir := ExtractTempDir(archive, dest)
+		marker, err := prepareExtractTempDir(archive, stat, tempDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		extractionDirs[archive] = tempDir
+		if archive == complete {
+			// all files were extracted, the move into the destination was interrupted
+			marker.Complete = true
+			if err := writeExtractMarker(tempDir, marker); err != nil {
+				t.Fatal(err)
+			}
+			writeTestFiles(t, tempDir, map[string]string{"model.bin": "new", "dir/config.json": "{}"})
+		}
+	}
+	writeTestFiles(t, dest, map[string]string{"model.bin": "old", "other.bin": "other"})
+	// a directory with the suffix but without marker is not an extraction
+	other := filepath.Join(root, "other"+extractTempSuffix)
+	if err := os.MkdirAll(other, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// the archive of a complete extraction is not needed to finish it
+	for _, archive := range []string{removed, complete} {
+		if err := os.Remove(archive); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := FinishInterruptedExtractions(root); err != nil {
+		t.Fatalf("FinishInterruptedExtractions() error = %v", err)
+	}
+	if !exists(extractionDirs[existing]) {
+		t.Error("extraction of an existing archive was removed")
+	}
+	if exists(extractionDirs[removed]) {
+		t.Error("extraction of a removed archive was kept")
+	}
+	if exists(extractionDirs[complete]) {
+		t.Error("complete extraction was not moved into its destination")
+	}
+	for name, want := range map[string]string{"model.bin": "new", "dir/config.json": "{}", "other.bin": "other"} {
+		if data, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(name))); err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v, want %q", name, data, err, want)
+		}
+	}
+	if !exists(other) {
+		t.Error("directory without extract marker was removed")
+	}
+	if err := FinishInterruptedExtractions(filepath.Join(root, "missing")); err != nil {
+		t.Errorf("FinishInterruptedExtractions() of a missing directory error = %v", err)
+	}
+}
+
+// writeTestFiles writes files with their content, names use slashes.
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		fileName := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fileName, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
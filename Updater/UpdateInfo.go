@@ -7,6 +7,7 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"slices"
 )
 
 /* example yaml
@@ -26,12 +27,24 @@ packages:
       US:
         - https://usc1.someurl.com/data1.0.0.3_win.zip
     SHA256: 0
+    signature: <base64 ed25519 signature of the package, see SignPackage>
+
+channels:
+  beta:
+    app:
+      version: 1.0.0.17
+      ...
+
+The packages are the stable channel, the beta and nightly channels only list the packages that differ from the channel before.
+The manifest itself is signed by a detached signature at the manifest url + SignatureFileExtension.
 */
 
 type UpdateInfo struct {
 	Version      string              `yaml:"version"`
 	LocationUrls map[string][]string `yaml:"locationUrls"`
 	SHA256       string              `yaml:"SHA256"`
+	// Signature is the base64 encoded ed25519 signature of the package, see Verifier.VerifyPackage.
+	Signature string `yaml:"signature,omitempty"`
 }
 
 func (i *UpdateInfo) WriteYaml(fileName string) {
@@ -56,8 +69,23 @@ func (i *UpdateInfo) ReadYaml(data []byte) error {
 	return err
 }
 
+// Update channels, ordered from the most to the least stable.
+const (
+	ChannelStable  = "stable"
+	ChannelBeta    = "beta"
+	ChannelNightly = "nightly"
+)
+
+var Channels = []string{ChannelStable, ChannelBeta, ChannelNightly}
+
 type UpdatePackages struct {
 	Packages map[string]UpdateInfo `yaml:"packages"`
+	// Channels are the packages of the beta and nightly channels that differ from the more stable channels.
+	Channels map[string]map[string]UpdateInfo `yaml:"channels,omitempty"`
+	// Verifier checks the signatures of the manifest and packages, DefaultVerifier if nil.
+	Verifier *Verifier `yaml:"-"`
+	// signed is set if the manifest was verified by its signature, its packages must be signed then.
+	signed bool
 	//DoNotAskAgain bool                  `yaml:"doNotAskAgain,omitempty"`
 }
 
@@ -71,7 +99,7 @@ func (u *UpdatePackages) parsePackagesFromYaml(data []byte) error {
 }
 
 func (u *UpdatePackages) getYaml(url string) ([]byte, error) {
-	resp, err := http.Get(url)
+	resp, err := HttpClient().Get(url)
 	if err != nil {
 		return []byte{}, fmt.Errorf("GET error: %v", err)
 	}
@@ -101,10 +129,69 @@ func (u *UpdatePackages) WriteYaml(fileName string) {
 	}
 }
 
+func (u *UpdatePackages) verifier() (*Verifier, error) {
+	if u.Verifier == nil {
+		return DefaultVerifier()
+	}
+	return u.Verifier, nil
+}
+
+// GetUpdateInfo downloads the update manifest and its signature, the manifest is only used if the signature is valid.
+// Manifests are only accepted without signature if no signing key is configured, their packages are only checked by their checksum.
 func (u *UpdatePackages) GetUpdateInfo(url string) error {
+	verifier, err := u.verifier()
+	if err != nil {
+		return err
+	}
 	data, err := u.getYaml(url)
 	if err != nil {
 		return err
 	}
+	u.signed = false
+	if verifier.HasKeys() {
+		signature, err := u.getYaml(url + SignatureFileExtension)
+		if err != nil {
+			return fmt.Errorf("update manifest: %w: %v", ErrMissingSignature, err)
+		}
+		if err := verifier.VerifyManifest(data, string(signature)); err != nil {
+			return err
+		}
+		u.signed = true
+	}
 	return u.parsePackagesFromYaml(data)
 }
+
+// SelectChannel sets the packages to the ones of an update channel.
+// Packages missing in the channel are taken from the next more stable channel, unknown channels use the stable packages.
+func (u *UpdatePackages) SelectChannel(channel string) {
+	packages := map[string]UpdateInfo{}
+	for name, info := range u.Packages {
+		packages[name] = info
+	}
+	for _, name := range Channels[1:max(slices.Index(Channels, channel)+1, 1)] {
+		for packageName, info := range u.Channels[name] {
+			packages[packageName] = info
+		}
+	}
+	u.Packages = packages
+}
+
+// VerifyPackage checks the signature and checksum of a downloaded package of the manifest.
+// Without a configured signing key, packages are only checked by their checksum.
+func (u *UpdatePackages) VerifyPackage(packageName, fileName string) error {
+	info, ok := u.Packages[packageName]
+	if !ok {
+		return fmt.Errorf("unknown update package: %s", packageName)
+	}
+	if !u.signed {
+		if err := CheckFileHash(fileName, info.SHA256); err != nil {
+			return fmt.Errorf("package %s %s: %w", packageName, info.Version, err)
+		}
+		return nil
+	}
+	verifier, err := u.verifier()
+	if err != nil {
+		return err
+	}
+	return verifier.VerifyPackage(packageName, info, fileName)
+}
@@ -2,6 +2,7 @@ package Hardwareinfo
 
 import (
 	"strings"
+	"whispering-tiger-ui/ModelRegistry"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/widget"
@@ -14,93 +15,30 @@ type AIModel struct {
 	Float32PrecisionMemoryUsage float64
 }
 
-// Models is a list of AIModel which is a struct that contains the name of the model and the memory usage in MB for Float32
-var Models = []AIModel{
-	// Official Whisper models
-	{"Whisper", "O", "tiny", 1676.0},
-	{"Whisper", "O", "base", 1932.0},
-	{"Whisper", "O", "small", 3432.0},
-	{"Whisper", "O", "medium", 7634.0},
-	{"Whisper", "O", "large", 13702.0},
-	// Duplicate for original_whisper Type mapping
-	{"Whisper", "original_whisper", "tiny", 1676.0},
-	{"Whisper", "original_whisper", "base", 1932.0},
-	{"Whisper", "original_whisper", "small", 3432.0},
-	{"Whisper", "original_whisper", "medium", 7634.0},
-	{"Whisper", "original_whisper", "large", 13702.0},
-	// WhisperCT2 models
-	{"Whisper", "faster_whisper", "tiny", 1054.0},
-	{"Whisper", "faster_whisper", "base", 1185.0},
-	{"Whisper", "faster_whisper", "small", 1873.0},
-	{"Whisper", "faster_whisper", "medium", 3905.0},
-	{"Whisper", "faster_whisper", "large", 6985.0},
-	{"Whisper", "faster_whisper", "medium-distilled", 1898.0},
-	{"Whisper", "faster_whisper", "large-distilled", 3339.0},
-	// Transformer Whisper models (angenommen ähnlich Original)
-	{"Whisper", "transformer_whisper", "tiny", 1676.0},
-	{"Whisper", "transformer_whisper", "base", 1932.0},
-	{"Whisper", "transformer_whisper", "small", 3432.0},
-	{"Whisper", "transformer_whisper", "medium", 7634.0},
-	{"Whisper", "transformer_whisper", "large", 13702.0},
-	// Speech T5
-	{"Whisper", "speech_t5", "tiny", 927.0},
-	{"Whisper", "speech_t5", "base", 927.0},
-	{"Whisper", "speech_t5", "small", 927.0},
-	{"Whisper", "speech_t5", "medium", 927.0},
-	{"Whisper", "speech_t5", "large", 927.0},
-	// Seamless M4T
-	{"Whisper", "seamless_m4t", "medium", 6250.0},
-	{"Whisper", "seamless_m4t", "large", 10518.0},
-	// wav2vec bert2.0 models
-	{"Whisper", "wav2vec_bert", "tiny", 2989.0},
-	{"Whisper", "wav2vec_bert", "base", 2989.0},
-	{"Whisper", "wav2vec_bert", "small", 2989.0},
-	{"Whisper", "wav2vec_bert", "medium", 2989.0},
-	{"Whisper", "wav2vec_bert", "large", 2989.0},
-	// NeMo Canary models
-	{"Whisper", "nemo_canary", "canary-1b", 4509.0},
-	{"Whisper", "nemo_canary", "canary-1b-v2", 6650.0},
-	{"Whisper", "nemo_canary", "canary-180m-flash", 702.0},
-	{"Whisper", "nemo_canary", "canary-1b-flash", 4000.0},
-	{"Whisper", "nemo_canary", "parakeet-tdt-0_6b-v2", 2828.0},
-	{"Whisper", "nemo_canary", "parakeet-tdt-0_6b-v3", 2928.0},
-	// MMS models
-	{"Whisper", "mms", "1b-all", 4646.0},
-	{"Whisper", "mms", "mms-1b-fl102", 4544.0},
-	{"Whisper", "mms", "mms-1b-l1107", 4623.0},
-	// Phi-4 model
-	{"Whisper", "phi4", "", 22531.0},
-	// Voxtral model
-	{"Whisper", "voxtral", "", 18852.0},
-	// NLLB200CT2 models
-	{"TxtTranslator", "NLLB200_CT2", "small", 3087.0},
-	{"TxtTranslator", "NLLB200_CT2", "medium", 6069.0},
-	{"TxtTranslator", "NLLB200_CT2", "large", 13803.0},
-	// NLLB200 models
-	{"TxtTranslator", "NLLB200", "small", 3657.0},
-	{"TxtTranslator", "NLLB200", "medium", 6620.0},
-	{"TxtTranslator", "NLLB200", "large", 14837.0},
-	// M2M100 models
-	{"TxtTranslator", "M2M100", "small", 2197.0},
-	{"TxtTranslator", "M2M100", "large", 5211.0},
-	// Seamless M4T models
-	{"TxtTranslator", "seamless_m4t", "medium", 6250.0},
-	{"TxtTranslator", "seamless_m4t", "large", 10518.0},
-	{"TxtTranslator", "seamless_m4t", "large-v2", 10518.0},
-	// Phi-4 model
-	{"TxtTranslator", "phi4", "", 22531.0},
-	// Voxtral model
-	{"TxtTranslator", "voxtral", "", 18852.0},
-	// TTS types
-	{"ttsType", "silero", "", 1533.0},
-	{"ttsType", "f5_e2", "", 1200.0},
-	{"ttsType", "zonos", "", 3030.0},
-	{"ttsType", "kokoro", "", 312.0},
-	{"ttsType", "chatterbox", "", 3470.0},
-	// OCR types
-	{"ocrType", "easyocr", "", 520.0},
-	{"ocrType", "got_ocr_20", "", 1559.0},
-	{"ocrType", "phi4", "", 22531.0},
+// Models is a list of AIModel which is a struct that contains the name of the model and the memory usage in MB for Float32.
+// The estimates are defined in the model registry.
+var Models = modelsFromRegistry()
+
+// registryBaseNames maps the model registry categories to the BaseName of the AIModel.
+var registryBaseNames = map[string]string{
+	ModelRegistry.CategorySTT:            "Whisper",
+	ModelRegistry.CategoryTextTranslator: "TxtTranslator",
+	ModelRegistry.CategoryTTS:            "ttsType",
+	ModelRegistry.CategoryOCR:            "ocrType",
+}
+
+func modelsFromRegistry() []AIModel {
+	var models []AIModel
+	for _, modelType := range ModelRegistry.Current().ModelTypes {
+		baseName, ok := registryBaseNames[modelType.Category]
+		if !ok {
+			continue
+		}
+		for _, estimate := range modelType.Estimates {
+			models = append(models, AIModel{baseName, modelType.Type, estimate.Size, estimate.MemoryFloat32})
+		}
+	}
+	return models
 }
 
 const (
@@ -6,7 +6,6 @@ import (
 	"fyne.io/fyne/v2/widget"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
-	"path/filepath"
 	"strings"
 	"whispering-tiger-ui/Fields"
 	"whispering-tiger-ui/Settings"
@@ -28,7 +27,7 @@ var TranslateSettings TranslateSetting
 
 func (res TranslateSetting) Update() *TranslateSetting {
 
-	Settings.Form = Settings.BuildSettingsForm(nil, filepath.Join(Settings.GetConfProfileDir(), Settings.Config.SettingsFilename)).(*widget.Form)
+	Settings.Form = Settings.BuildSettingsForm(nil, Settings.GetBackendSettingsFile()).(*widget.Form)
 	Settings.Form.Refresh()
 
 	// fill combo-box with whisper languages
@@ -130,6 +130,9 @@ func (c *Client) Start() {
 		connectingStateDialog.Hide()
 	})
 	previouslyConnected = true
+	if runBackend {
+		RuntimeBackend.BackendConnected()
+	}
 
 	defer c.Conn.Close()
 	c.Conn.SetReadLimit(maxMessageSize)
@@ -173,6 +176,7 @@ func (c *Client) Start() {
 					})
 				}
 				if runBackend {
+					RuntimeBackend.BackendConnected()
 					log.Println("send ui_connected")
 					// send info that backend is running locally
 					sendMessage := SendMessageChannel.SendMessageStruct{
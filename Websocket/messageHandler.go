@@ -2,6 +2,7 @@ package Websocket
 
 import (
 	"encoding/json"
+	"errors"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/lang"
@@ -10,10 +11,16 @@ import (
 	"strings"
 	"sync"
 	"time"
+	"whispering-tiger-ui/BatchJobs"
+	"whispering-tiger-ui/Conversation"
 	"whispering-tiger-ui/Fields"
 	"whispering-tiger-ui/Logging"
+	"whispering-tiger-ui/ModelDownloader"
 	"whispering-tiger-ui/SendMessageChannel"
 	"whispering-tiger-ui/Settings"
+	"whispering-tiger-ui/TextProcessing"
+	"whispering-tiger-ui/TranslationMemory"
+	"whispering-tiger-ui/Translator"
 	"whispering-tiger-ui/Utilities"
 	"whispering-tiger-ui/Websocket/Messages"
 )
@@ -40,6 +47,7 @@ type MessageStruct struct {
 	TranslateResult string `json:"translate_result,omitempty"`
 	OriginalText    string `json:"original_text,omitempty"`
 	TxtFromLang     string `json:"txt_from_lang,omitempty"`
+	TxtToLang       string `json:"txt_to_lang,omitempty"`
 
 	// only in case of LLM message
 	LlmAnswer string `json:"llm_answer,omitempty"`
@@ -252,6 +260,14 @@ func (c *MessageStruct) HandleReceiveMessage() {
 		if Settings.ConfigValues, ok = i.(map[string]interface{}); !ok {
 			log.Println("failed to type assert data")
 		}
+	case "settings_schema":
+		var options []Settings.BackendOption
+		options, err = Settings.ParseBackendSchema(c.Data)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		Settings.SetBackendSchema(options)
 	case "translate_settings":
 		// skip received run_backend value from receiving
 		var runBackend = true
@@ -271,12 +287,30 @@ func (c *MessageStruct) HandleReceiveMessage() {
 			Messages.TranslateSettings.Websocket_port = websocketPort
 		}
 
+		// the backend has held options turned off, keep the values chosen by the user
+		backendOptions := Fields.HeldBackendOptions()
+		for _, option := range backendOptions {
+			if value, err := Settings.Config.GetOption(option); err == nil {
+				_ = Messages.TranslateSettings.SetOption(option, value)
+				if option == "osc_auto_processing_enabled" {
+					Messages.TranslateSettings.OscAutoProcessingEnabled = value.(bool)
+				}
+			}
+		}
+
 		fyne.Do(func() {
 			Messages.TranslateSettings.Update()
 		})
+		if len(backendOptions) > 0 {
+			go Fields.SendBackendOptions(backendOptions)
+		}
 	case "transcript":
-		c.Text = strings.TrimSpace(c.Text)
-		c.TxtTranslation = strings.TrimSpace(c.TxtTranslation)
+		outputText, outputTranslation := strings.TrimSpace(c.Text), strings.TrimSpace(c.TxtTranslation)
+		c.Text = TextProcessing.Process(outputText, TextProcessing.TargetTranscript)
+		c.TxtTranslation = TextProcessing.Process(outputTranslation, TextProcessing.TargetTranslation)
+		if !Conversation.HandleTranscript(c.Text, c.Language) {
+			go sendHeldAutomaticOutputs(outputText, outputTranslation)
+		}
 		whisperResultMessage := Messages.WhisperResult{
 			Text:                 c.Text,
 			Language:             c.Language,
@@ -321,6 +355,25 @@ func (c *MessageStruct) HandleReceiveMessage() {
 	case "translate_result":
 		//Messages.LastTranslationResult = c.TranslateResult
 		//Fields.Field.TranscriptionTranslationSpeechToTextInput.SetText(c.TranslateResult)
+		request, found := Translator.TakeRequest(c.OriginalText, c.TxtToLang)
+		targetLanguage := request.TargetLanguage
+		if targetLanguage == "" {
+			targetLanguage = c.TxtToLang
+		}
+		if request.Protected {
+			c.OriginalText = TextProcessing.RestoreGlossary(c.OriginalText, c.TxtFromLang)
+			c.TranslateResult = TextProcessing.RestoreGlossary(c.TranslateResult, targetLanguage)
+		}
+		c.TranslateResult = TextProcessing.Process(c.TranslateResult, TextProcessing.TargetTranslation)
+		if request.DeferredSend {
+			go Fields.SendTextToEnabledOutputs(c.TranslateResult)
+		}
+		if request.SourceText != "" && fyne.CurrentApp().Preferences().BoolWithFallback("TranslationMemoryEnabled", true) {
+			TranslationMemory.Default.Store(request.SourceText, c.TranslateResult, request.SourceLanguage, request.TargetLanguage, request.Model)
+		}
+		if found && Translator.HandleTranslateResult(request, c.TranslateResult) {
+			return
+		}
 		fyne.Do(func() {
 			Fields.DataBindings.TranscriptionTranslationInputBinding.Set(c.TranslateResult)
 			if c.OriginalText != "" {
@@ -354,6 +407,12 @@ func (c *MessageStruct) HandleReceiveMessage() {
 		//	audioData.WavData = ""
 		//	Audio.LastFile = audioData
 		//	go Audio.LastFile.Play()
+	case "transcribe_file_result":
+		err = BatchJobs.HandleTranscribeFileResult(c.Raw)
+		if err != nil {
+			log.Println(err)
+			return
+		}
 	case "ocr_result":
 		err = json.Unmarshal(c.Data, &Messages.OcrResult)
 		if err != nil {
@@ -369,8 +428,8 @@ func (c *MessageStruct) HandleReceiveMessage() {
 
 	// special case for LLM plugin
 	case "llm_answer":
-		c.Text = strings.TrimSpace(c.Text)
-		c.TxtTranslation = strings.TrimSpace(c.LlmAnswer)
+		c.Text = TextProcessing.Process(strings.TrimSpace(c.Text), TextProcessing.TargetTranscript)
+		c.TxtTranslation = TextProcessing.Process(strings.TrimSpace(c.LlmAnswer), TextProcessing.TargetTranslation)
 		whisperResultMessage := Messages.WhisperResult{
 			Text:                 c.Text,
 			Language:             c.Language,
@@ -505,6 +564,9 @@ func (c *MessageStruct) HandleReceiveMessage() {
 		}
 		go func(dl_ Messages.DownloadMessage) {
 			err = dl_.StartDownload()
+			if errors.Is(err, ModelDownloader.ErrCancelled) {
+				return
+			}
 			if err != nil {
 				fyne.Do(func() {
 					if len(fyne.CurrentApp().Driver().AllWindows()) > 0 {
@@ -549,6 +611,12 @@ func HandleSendMessage(sendMessage *SendMessageChannel.SendMessageStruct) {
 	})
 
 	switch sendMessage.Type {
+	case "tts_req":
+		processMessageText(sendMessage, TextProcessing.TargetTts)
+	case "send_osc":
+		processMessageText(sendMessage, TextProcessing.TargetOsc)
+	case "translate_req":
+		prepareTranslateRequest(sendMessage)
 	case "setting_change":
 		switch sendMessage.Name {
 		case "src_lang", "ocr_txt_src_lang":
@@ -634,5 +702,142 @@ func HandleSendMessage(sendMessage *SendMessageChannel.SendMessageStruct) {
 			Settings.Config.Osc_auto_processing_enabled = val
 			Fields.DataBindings.OSCEnabledDataBinding.Set(val)
 		}
+		if sendMessage.Value != SkipMessage {
+			if err := Settings.PersistSettingChange(sendMessage.Name, sendMessage.Value); err != nil {
+				log.Printf("Error storing setting %s in profile: %v", sendMessage.Name, err)
+			}
+		}
+		sendMessage.Value = Fields.BackendOptionValue(sendMessage.Name, sendMessage.Value)
+	}
+}
+
+// sendHeldAutomaticOutputs sends a transcript to TTS and OSC the way the backend would,
+// if the UI holds the backend's automatic outputs. The text is processed for each output when it is sent.
+func sendHeldAutomaticOutputs(text string, translation string) {
+	defer Logging.GoRoutineErrorHandler(func(scope *sentry.Scope) {
+		scope.SetTag("GoRoutine", "Websocket\\messageHandler->sendHeldAutomaticOutputs")
+	})
+	if text == "" {
+		return
+	}
+	translationOrText := translation
+	if translationOrText == "" {
+		translationOrText = text
+	}
+
+	if Settings.Config.Tts_answer && Fields.IsBackendOptionHeld("tts_answer") {
+		sendMessage := SendMessageChannel.SendMessageStruct{
+			Type: "tts_req",
+			Value: struct {
+				Text     string `json:"text"`
+				ToDevice bool   `json:"to_device"`
+				Download bool   `json:"download"`
+			}{
+				Text:     translationOrText,
+				ToDevice: true,
+				Download: false,
+			},
+		}
+		sendMessage.SendMessage()
+	}
+
+	if Settings.Config.Osc_auto_processing_enabled && Fields.IsBackendOptionHeld("osc_auto_processing_enabled") {
+		oscText := translationOrText
+		if translation != "" {
+			switch Settings.Config.Osc_type_transfer {
+			case "source":
+				oscText = text
+			case "both":
+				oscText = text + Settings.Config.Osc_type_transfer_split + translation
+			case "both_inverted":
+				oscText = translation + Settings.Config.Osc_type_transfer_split + text
+			}
+		}
+		sendMessage := SendMessageChannel.SendMessageStruct{
+			Type: "send_osc",
+			Value: struct {
+				Text string `json:"text"`
+			}{
+				Text: oscText,
+			},
+		}
+		sendMessage.SendMessage()
+	}
+}
+
+// messageValueToMap converts the value of a send message (usually an anonymous struct) into a map,
+// so single fields can be changed before sending.
+func messageValueToMap(value interface{}) (map[string]interface{}, error) {
+	valueBytes, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var valueMap map[string]interface{}
+	err = json.Unmarshal(valueBytes, &valueMap)
+	if err != nil {
+		return nil, err
+	}
+	return valueMap, nil
+}
+
+// processMessageText runs the text processing pipeline on the "text" field of a send message.
+func processMessageText(sendMessage *SendMessageChannel.SendMessageStruct, target TextProcessing.Target) {
+	if sendMessage.Value == nil {
+		return
+	}
+	valueMap, err := messageValueToMap(sendMessage.Value)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	text, ok := valueMap["text"].(string)
+	if !ok || text == "" {
+		return
+	}
+	processedText := TextProcessing.Process(text, target)
+	if processedText != text {
+		valueMap["text"] = processedText
+		sendMessage.Value = valueMap
+	}
+}
+
+// prepareTranslateRequest adds the translate request to the pending requests and replaces glossary terms with placeholders.
+// If the request contains protected glossary terms or outputs are processed, send options are handled by the UI,
+// so neither placeholders nor unprocessed text reach TTS or OSC.
+func prepareTranslateRequest(sendMessage *SendMessageChannel.SendMessageStruct) {
+	if sendMessage.Value == nil {
+		return
+	}
+	valueMap, err := messageValueToMap(sendMessage.Value)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	text, _ := valueMap["text"].(string)
+	fromLang, _ := valueMap["from_lang"].(string)
+	toLang, _ := valueMap["to_lang"].(string)
+	ignoreSendOptions, _ := valueMap["ignore_send_options"].(bool)
+
+	protectedText, protected := TextProcessing.ProtectGlossary(text, fromLang)
+
+	request := Translator.Request{
+		SourceText:     text,
+		SentText:       text,
+		SourceLanguage: fromLang,
+		TargetLanguage: toLang,
+		Model:          TranslationMemory.CurrentModel(),
+	}
+	if protected {
+		request.SentText = protectedText
+		request.Protected = true
+		valueMap["text"] = protectedText
+		sendMessage.Value = valueMap
+	}
+	if !ignoreSendOptions && (protected || len(TextProcessing.OutputBackendOptions()) > 0) {
+		// backend would send the placeholders or the unprocessed text, so the UI sends the result itself.
+		valueMap["ignore_send_options"] = true
+		request.DeferredSend = true
+		sendMessage.Value = valueMap
 	}
+	Translator.AddRequest(request)
 }
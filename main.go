@@ -10,14 +10,20 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"whispering-tiger-ui/Conversation"
 	"whispering-tiger-ui/Fields"
 	"whispering-tiger-ui/Logging"
+	"whispering-tiger-ui/ModelDownloader"
 	"whispering-tiger-ui/Pages"
 	"whispering-tiger-ui/Pages/Advanced"
 	"whispering-tiger-ui/Resources"
 	"whispering-tiger-ui/RuntimeBackend"
 	"whispering-tiger-ui/Settings"
+	"whispering-tiger-ui/SettingsHistory"
+	"whispering-tiger-ui/TextProcessing"
+	"whispering-tiger-ui/TranslationMemory"
 	"whispering-tiger-ui/UpdateUtility"
+	"whispering-tiger-ui/Updater"
 	"whispering-tiger-ui/Utilities"
 	"whispering-tiger-ui/Utilities/Hardwareinfo"
 	"whispering-tiger-ui/Websocket"
@@ -107,9 +113,53 @@ func main() {
 	// initialize global fields (so they can use initialized languages)
 	Fields.InitializeGlobalFields()
 
+	// load text replacement, glossary and profanity filter rules
+	if err := TextProcessing.Load(); err != nil {
+		log.Printf("Error loading text processing config: %v", err)
+	}
+	// sent to the backend with its settings, once it is running
+	Fields.HoldBackendOptions("text_processing", TextProcessing.OutputBackendOptions())
+	if err := TranslationMemory.Default.Load(); err != nil {
+		log.Printf("Error loading translation memory: %v", err)
+	}
+	if err := Conversation.Load(); err != nil {
+		log.Printf("Error loading conversation config: %v", err)
+	}
+
+	// download network settings and the mirror scores of the last runs
+	Updater.SetBandwidthLimit(int64(a.Preferences().IntWithFallback("DownloadBandwidthLimit", 0)) * 1024)
+	if err := Updater.SetProxy(a.Preferences().StringWithFallback("DownloadProxy", "")); err != nil {
+		log.Printf("Error setting download proxy: %v", err)
+	}
+	if err := Updater.Mirrors.Load(filepath.Join(Settings.GetUiDataDir(), "mirrors.json")); err != nil {
+		log.Printf("Error loading mirror scores: %v", err)
+	}
+
+	// complete updates that were interrupted while switching versions, and count the backend starts of updated versions
+	UpdateUtility.RecoverInstallations()
+	RuntimeBackend.OnStartupResult = UpdateUtility.BackendStartupResult
+
+	// continue the downloads of the last run and show the download queue when a new download starts
+	ModelDownloader.Default.SetMaxConcurrent(a.Preferences().IntWithFallback("MaxConcurrentDownloads", ModelDownloader.DefaultMaxConcurrent))
+	ModelDownloader.Default.SetOnAdded(func(job *ModelDownloader.Job) {
+		fyne.Do(Pages.ShowDownloadsWindow)
+	})
+	// finish interrupted extractions before the downloads continue, which could extract into the same directories
+	for _, dir := range append([]string{ModelDownloader.CacheDir()}, UpdateUtility.ExtractionDirs()...) {
+		if err := Updater.FinishInterruptedExtractions(dir); err != nil {
+			log.Printf("Error finishing interrupted extractions: %v", err)
+		}
+	}
+	if err := ModelDownloader.Default.Load(); err != nil {
+		log.Printf("Error loading downloads: %v", err)
+	}
+
 	w.SetOnClosed(func() {
 		fyne.CurrentApp().Preferences().SetFloat("MainWindowWidth", float64(w.Canvas().Size().Width))
 		fyne.CurrentApp().Preferences().SetFloat("MainWindowHeight", float64(w.Canvas().Size().Height))
+		if err := TranslationMemory.Default.Save(); err != nil {
+			log.Printf("Error saving translation memory: %v", err)
+		}
 	})
 
 	// initialize whisper process
@@ -139,7 +189,7 @@ func main() {
 		RuntimeBackend.BackendsList = append(RuntimeBackend.BackendsList, RuntimeBackend.NewWhisperProcess())
 		RuntimeBackend.BackendsList[0].DeviceIndex = strconv.Itoa(Settings.Config.Device_index.(int))
 		RuntimeBackend.BackendsList[0].DeviceOutIndex = strconv.Itoa(Settings.Config.Device_out_index.(int))
-		RuntimeBackend.BackendsList[0].SettingsFile = filepath.Join(Settings.GetConfProfileDir(), Settings.Config.SettingsFilename)
+		RuntimeBackend.BackendsList[0].SettingsFile = Settings.GetBackendSettingsFile()
 		// Setting this to use UTF-8 encoding for Python does not work when build using PyInstaller
 		if fyne.CurrentApp().Preferences().BoolWithFallback("RunWithUTF8", true) {
 			log.Printf("Running with UTF-8 encoding")
@@ -190,6 +240,11 @@ func main() {
 			}
 		}
 
+		// load the settings history of the profile, used by undo and redo
+		if err := SettingsHistory.LoadActive(Settings.ActiveProfileFile()); err != nil {
+			log.Printf("error loading settings history: %v", err)
+		}
+
 		// initialize status bar
 		Fields.Field.StatusBar = widget.NewProgressBar()
 		Fields.Field.StatusBar.TextFormatter = func() string {
@@ -206,7 +261,8 @@ func main() {
 		// initialize main window
 		appTabs := container.NewAppTabs(
 			container.NewTabItemWithIcon(lang.L("Speech-to-Text"), theme.NewThemedResource(Resources.ResourceSpeechToTextIconSvg), Pages.CreateSpeechToTextWindow()),
-			container.NewTabItemWithIcon(lang.L("Text-Translate"), theme.NewThemedResource(Resources.ResourceTranslateIconSvg), Pages.CreateTextTranslateWindow()),
+			container.NewTabItemWithIcon(lang.L("Text-Translate"), theme.NewThemedResource(Resources.ResourceTranslateIconSvg), Pages.CreateTextTranslateWindow(w)),
+			container.NewTabItemWithIcon(lang.L("Conversation"), theme.AccountIcon(), Pages.CreateConversationWindow()),
 			container.NewTabItemWithIcon(lang.L("Text-to-Speech"), theme.NewThemedResource(Resources.ResourceTextToSpeechIconSvg), Pages.CreateTextToSpeechWindow()),
 			container.NewTabItemWithIcon(lang.L("Image-to-Text"), theme.NewThemedResource(Resources.ResourceImageRecognitionIconSvg), Pages.CreateOcrWindow()),
 			container.NewTabItemWithIcon(lang.L("Plugins"), theme.NewThemedResource(Resources.ResourcePluginsIconSvg), Advanced.CreatePluginSettingsPage()),
@@ -214,6 +270,14 @@ func main() {
 			container.NewTabItemWithIcon(lang.L("Advanced"), theme.MoreVerticalIcon(), Pages.CreateAdvancedWindow()),
 		)
 		appTabs.SetTabLocation(container.TabLocationTop)
+		Pages.SelectMainTab = func(title string) {
+			for _, item := range appTabs.Items {
+				if item.Text == title {
+					appTabs.Select(item)
+					return
+				}
+			}
+		}
 
 		appTabs.OnSelected = func(tab *container.TabItem) {
 			if tab.Text == lang.L("Text-to-Speech") {
@@ -221,6 +285,9 @@ func main() {
 			} else {
 				Pages.OnCloseTextToSpeechWindow(tab.Content)
 			}
+			if tab.Text == lang.L("Conversation") {
+				Pages.OnOpenConversationWindow()
+			}
 			if tab.Text == lang.L("Settings") {
 				tab.Content = Pages.CreateSettingsWindow()
 				tab.Content.Refresh()
@@ -265,6 +332,9 @@ func main() {
 
 		go WebsocketClient.Start()
 
+		// ask to reload the profile if it or the profiles it inherits from are changed outside the application
+		Pages.StartProfileWatcher(w)
+
 		fyne.CurrentApp().Preferences().SetFloat("ProfileWindowWidth", float64(profileWindow.Canvas().Size().Width))
 		fyne.CurrentApp().Preferences().SetFloat("ProfileWindowHeight", float64(profileWindow.Canvas().Size().Height))
 
@@ -321,6 +391,12 @@ func main() {
 	}()
 
 	a.Lifecycle().SetOnStopped(func() {
+		// store the setting changes that are not written to the profile yet
+		if Settings.Layers != nil {
+			if err := Settings.Layers.FlushSettingChanges(); err != nil {
+				log.Printf("Error storing setting changes in profile: %v", err)
+			}
+		}
 		// after run (app exit), send whisper process signal to stop
 		if len(RuntimeBackend.BackendsList) > 0 {
 			RuntimeBackend.BackendsList[0].Stop()